@@ -16,6 +16,7 @@ import (
 	"github.com/Seklfreak/Robyul2/metrics"
 	"github.com/Seklfreak/Robyul2/models"
 	"github.com/Seklfreak/Robyul2/modules"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/ratelimits"
 	"github.com/bwmarrin/discordgo"
 	raven "github.com/getsentry/raven-go"
@@ -471,30 +472,47 @@ func BotOnMessageCreate(session *discordgo.Session, message *discordgo.MessageCr
 		return
 	}
 
-	// Check if the user is allowed to request commands
-	if !ratelimits.Container.HasKeys(message.Author.ID) && !helpers.IsBotAdmin(message.Author.ID) {
-		helpers.SendMessage(message.ChannelID, helpers.GetTextF("bot.ratelimit.hit", message.Author.ID))
-
-		ratelimits.Container.Set(message.Author.ID, -1)
-		return
-	}
-
 	// Split the message into parts
 	parts := strings.Fields(message.Content)
 
 	// Save a sanitized version of the command (no prefix)
 	cmd := strings.Replace(parts[0], prefix, "", 1)
 
+	// Check if the user is allowed to request commands, and consume the keys for this command
+	// messages that only look like a command, for example with an unknown or misspelled name, cost nothing
+	if _, known := router.Resolve(cmd); known && !helpers.IsBotAdmin(message.Author.ID) {
+		err = ratelimits.Container.DrainForCommand(channel.GuildID, channel.ID, message.Author.ID, cmd)
+		if err != nil {
+			switch err := err.(type) {
+			case *ratelimits.CooldownError:
+				if ratelimits.Container.ClaimCooldownNotice(channel.ID, message.Author.ID, err.Remaining) {
+					helpers.SendMessage(message.ChannelID, helpers.GetTextF("bot.ratelimit.cooldown",
+						message.Author.ID, helpers.HumanizeDuration(err.Remaining+time.Second)))
+				}
+			default:
+				if err == ratelimits.ErrNoKeys {
+					helpers.SendMessage(message.ChannelID, helpers.GetTextF("bot.ratelimit.hit", message.Author.ID))
+
+					ratelimits.Container.Chill(message.Author.ID)
+				}
+			}
+			return
+		}
+	}
+
+	// Separate arguments from the command
+	content := strings.TrimSpace(strings.Replace(message.Content, prefix+cmd, "", -1))
+
 	// Check if the user calls for help
 	if cmd == "h" || cmd == "help" {
 		metrics.CommandsExecuted.Add(1)
-		sendHelp(message)
+		// try to show the help for a specific command or module first
+		if !router.SendHelp(message.Message, content) {
+			sendHelp(message)
+		}
 		return
 	}
 
-	// Separate arguments from the command
-	content := strings.TrimSpace(strings.Replace(message.Content, prefix+cmd, "", -1))
-
 	// Log commands
 	cache.GetLogger().WithFields(logrus.Fields{
 		"module":    "bot",
@@ -518,6 +518,11 @@ func RemoveMutePersistency(guildID string, userID string) (err error) {
 }
 
 func RemovePendingUnmutes(guildID string, userID string) (err error) {
+	return removePendingTasks("unmute_user", guildID, userID)
+}
+
+// removePendingTasks removes all delayed machinery tasks $taskName with the arguments $guildID and $userID
+func removePendingTasks(taskName string, guildID string, userID string) (err error) {
 	key := "delayed_tasks"
 	delayedTasks, err := cache.GetMachineryRedisClient().ZCard(key).Result()
 	if err != nil {
@@ -535,17 +540,17 @@ func RemovePendingUnmutes(guildID string, userID string) (err error) {
 			return err
 		}
 
-		if task.Path("Name").Data().(string) != "unmute_user" {
+		if task.Path("Name").Data().(string) != taskName {
 			continue
 		}
 
-		unmuteGuildID := task.Path("Args").Index(0).Path("Value").Data().(string)
-		unmuteUserID := task.Path("Args").Index(1).Path("Value").Data().(string)
+		taskGuildID := task.Path("Args").Index(0).Path("Value").Data().(string)
+		taskUserID := task.Path("Args").Index(1).Path("Value").Data().(string)
 
-		if unmuteGuildID != guildID {
+		if taskGuildID != guildID {
 			continue
 		}
-		if unmuteUserID != userID {
+		if taskUserID != userID {
 			continue
 		}
 
@@ -612,6 +617,100 @@ func UnmuteUserSignature(guildID string, userID string) (signature *tasks.Signat
 	return signature
 }
 
+// UnbanUserMachinery is called by machinery when a temporary ban expires
+func UnbanUserMachinery(guildID string, userID string) (err error) {
+	err = cache.GetSession().SessionForGuildS(guildID).GuildBanDelete(guildID, userID)
+	if err != nil {
+		if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+			errD.Message.Code == 10026 { // Unknown Ban
+			// user has already been unbanned
+			return nil
+		}
+	}
+	return err
+}
+
+func UnbanUserSignature(guildID string, userID string) (signature *tasks.Signature) {
+	signature = &tasks.Signature{
+		Name: "unban_user",
+		Args: []tasks.Arg{
+			{
+				Type:  "string",
+				Value: guildID,
+			},
+			{
+				Type:  "string",
+				Value: userID,
+			},
+		},
+	}
+	signature.RetryCount = 3
+	signature.OnError = []*tasks.Signature{{Name: "log_error"}}
+	return signature
+}
+
+// AddRoleMachinery is called by machinery to add a role to a member
+// missing permissions, unknown roles, and unknown members are not retried
+func AddRoleMachinery(guildID string, userID string, roleID string) (err error) {
+	err = cache.GetSession().SessionForGuildS(guildID).GuildMemberRoleAdd(guildID, userID, roleID)
+	if err != nil {
+		if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+			(errD.Message.Code == discordgo.ErrCodeMissingPermissions ||
+				errD.Message.Code == discordgo.ErrCodeMissingAccess ||
+				errD.Message.Code == discordgo.ErrCodeUnknownRole ||
+				errD.Message.Code == discordgo.ErrCodeUnknownMember) {
+			return nil
+		}
+	}
+	return err
+}
+
+func AddRoleSignature(guildID string, userID string, roleID string) (signature *tasks.Signature) {
+	signature = &tasks.Signature{
+		Name: "add_role",
+		Args: []tasks.Arg{
+			{
+				Type:  "string",
+				Value: guildID,
+			},
+			{
+				Type:  "string",
+				Value: userID,
+			},
+			{
+				Type:  "string",
+				Value: roleID,
+			},
+		},
+	}
+	signature.RetryCount = 3
+	signature.OnError = []*tasks.Signature{{Name: "log_error"}}
+	return signature
+}
+
+// CreatePendingUnban schedules an unban of $userID on $guildID, replacing previously scheduled unbans
+// the task is stored in the machinery redis, so it survives restarts
+func CreatePendingUnban(guildID string, userID string, unbanAt time.Time) (err error) {
+	err = RemovePendingUnbans(guildID, userID)
+	if err != nil {
+		return err
+	}
+
+	if unbanAt.IsZero() || !time.Now().Before(unbanAt) {
+		return nil
+	}
+
+	signature := UnbanUserSignature(guildID, userID)
+	signature.ETA = &unbanAt
+
+	_, err = cache.GetMachineryServer().SendTask(signature)
+	return err
+}
+
+func RemovePendingUnbans(guildID string, userID string) (err error) {
+	return removePendingTasks("unban_user", guildID, userID)
+}
+
 func AddMuteRole(guildID string, userID string) (err error) {
 	muteRole, err := GetMuteRole(guildID)
 	if err != nil {
@@ -80,11 +80,30 @@ func EventlogLog(createdAt time.Time, guildID, targetID, targetType, userID, act
 		)
 	*/
 
+	// assign a case number to moderation actions
+	var modCase models.ModCaseEntry
+	if isModCaseEvent(actionType, options) {
+		modCase, err = createModCase(createdAt, guildID, "", actionType, targetID)
+		if err != nil {
+			RelaxLog(err)
+		} else {
+			options = append(options, models.ElasticEventlogOption{
+				Key:   eventlogCaseNumberKey,
+				Value: strconv.Itoa(modCase.CaseNumber),
+			})
+		}
+	}
+
 	eventlogID, err := ElasticAddEventlog(createdAt, guildID, targetID, targetType, userID, actionType, reason, changes, options, waitingForAuditLogBackfill, nil)
 	if err != nil {
 		return false, err
 	}
 
+	if modCase.ID.Valid() {
+		err = setModCaseEventlogID(modCase, eventlogID)
+		RelaxLog(err)
+	}
+
 	messageIDs := make([]string, 0)
 	eventlogChannelIDs := GuildSettingsGetCached(guildID).EventlogChannelIDs
 	for _, eventlogChannelID := range eventlogChannelIDs {
@@ -120,23 +139,46 @@ func EventlogLogUpdate(elasticID string, UserID string,
 		return
 	}
 
-	if eventlogItem != nil && eventlogItem.EventlogMessages != nil && len(eventlogItem.EventlogMessages) > 0 {
-		embed := getEventlogEmbed(elasticID, eventlogItem.CreatedAt, eventlogItem.GuildID, eventlogItem.TargetID,
-			eventlogItem.TargetType, eventlogItem.UserID, eventlogItem.ActionType, eventlogItem.Reason,
-			eventlogItem.Changes, eventlogItem.Options, eventlogItem.WaitingFor.AuditLogBackfill)
-		for _, messageID := range eventlogItem.EventlogMessages {
-			if strings.Contains(messageID, "|") {
-				parts := strings.SplitN(messageID, "|", 2)
-				if len(parts) >= 2 {
-					EditEmbed(parts[0], parts[1], embed)
-				}
-			}
+	// leaves turn into moderation actions once the audit log tells us they were kicks
+	if eventlogItem != nil && getEventlogCaseNumber(eventlogItem.Options) == "" &&
+		isModCaseEvent(eventlogItem.ActionType, eventlogItem.Options) {
+		modCase, err := createModCase(eventlogItem.CreatedAt, eventlogItem.GuildID, elasticID,
+			eventlogItem.ActionType, eventlogItem.TargetID)
+		if err == nil {
+			eventlogItem, err = ElasticUpdateEventLog(elasticID, "", []models.ElasticEventlogOption{{
+				Key:   eventlogCaseNumberKey,
+				Value: strconv.Itoa(modCase.CaseNumber),
+			}}, nil, "", false, false, nil)
+		}
+		if err != nil {
+			return err
 		}
 	}
 
+	editEventlogMessages(elasticID, eventlogItem)
+
 	return
 }
 
+// editEventlogMessages updates all eventlog channel messages of the eventlog entry
+func editEventlogMessages(elasticID string, eventlogItem *models.ElasticEventlog) {
+	if eventlogItem == nil || len(eventlogItem.EventlogMessages) <= 0 {
+		return
+	}
+
+	embed := getEventlogEmbed(elasticID, eventlogItem.CreatedAt, eventlogItem.GuildID, eventlogItem.TargetID,
+		eventlogItem.TargetType, eventlogItem.UserID, eventlogItem.ActionType, eventlogItem.Reason,
+		eventlogItem.Changes, eventlogItem.Options, eventlogItem.WaitingFor.AuditLogBackfill)
+	for _, messageID := range eventlogItem.EventlogMessages {
+		if strings.Contains(messageID, "|") {
+			parts := strings.SplitN(messageID, "|", 2)
+			if len(parts) >= 2 {
+				EditEmbed(parts[0], parts[1], embed)
+			}
+		}
+	}
+}
+
 func eventlogTargetsToText(guildID, targetType, idsText string) (names []string) {
 	names = make([]string, 0)
 	ids := strings.Split(idsText, ";")
@@ -300,6 +342,11 @@ func getEventlogEmbed(eventlogID string, createdAt time.Time, guildID, targetID,
 		Color:  GetDiscordColorFromHex("#73d016"),                                                                 // lime gree
 	}
 
+	// show the case number of moderation actions in the title
+	if caseNumber := getEventlogCaseNumber(options); caseNumber != "" {
+		embed.Title = "Case " + caseNumber + " • " + embed.Title
+	}
+
 	// mark possibly destructive events red
 	if actionType == models.EventlogTypeMemberLeave ||
 		actionType == models.EventlogTypeChannelDelete ||
@@ -316,6 +363,7 @@ func getEventlogEmbed(eventlogID string, createdAt time.Time, guildID, targetID,
 		actionType == models.EventlogTypeRobyulFacebookFeedRemove ||
 		actionType == models.EventlogTypeRobyulCleanup ||
 		actionType == models.EventlogTypeRobyulMute ||
+		actionType == models.EventlogTypeRobyulEscalation ||
 		actionType == models.EventlogTypeRobyulUnmute ||
 		actionType == models.EventlogTypeRobyulChatlogUpdate ||
 		actionType == models.EventlogTypeRobyulBiasConfigDelete ||
@@ -361,6 +409,9 @@ func getEventlogEmbed(eventlogID string, createdAt time.Time, guildID, targetID,
 	// display options as fields
 	if options != nil {
 		for _, option := range options {
+			if option.Key == eventlogCaseNumberKey {
+				continue
+			}
 			valueText := "`" + option.Value + "`"
 			if option.Value == "" {
 				valueText = "_/_"
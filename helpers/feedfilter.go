@@ -0,0 +1,105 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Seklfreak/Robyul2/models"
+)
+
+// FeedTextFilterMatches returns true if the texts (e.g. title and body of an item) pass the filter
+// keywords are case insensitive, invalid regexes never match
+func FeedTextFilterMatches(filter models.FeedTextFilter, texts ...string) bool {
+	text := strings.Join(texts, "\n")
+	lowerText := strings.ToLower(text)
+
+	if len(filter.IncludeKeywords) > 0 {
+		var found bool
+		for _, keyword := range filter.IncludeKeywords {
+			if strings.Contains(lowerText, strings.ToLower(keyword)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, keyword := range filter.ExcludeKeywords {
+		if strings.Contains(lowerText, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+
+	if filter.IncludeRegex != "" {
+		regex, err := regexp.Compile(filter.IncludeRegex)
+		if err != nil || !regex.MatchString(text) {
+			return false
+		}
+	}
+
+	if filter.ExcludeRegex != "" {
+		regex, err := regexp.Compile(filter.ExcludeRegex)
+		if err != nil || regex.MatchString(text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FeedSetTextFilterRule sets a rule of the filter (include, exclude, include-regex, or exclude-regex) to the value
+// keywords are separated by commas, an empty value removes the rule, known is false for other rules
+func FeedSetTextFilterRule(filter *models.FeedTextFilter, rule, value string) (known bool, err error) {
+	switch rule {
+	case "include":
+		filter.IncludeKeywords = FeedSplitFilterList(value)
+	case "exclude":
+		filter.ExcludeKeywords = FeedSplitFilterList(value)
+	case "include-regex", "exclude-regex":
+		if value != "" {
+			_, err = regexp.Compile(value)
+			if err != nil {
+				return true, err
+			}
+		}
+		if rule == "include-regex" {
+			filter.IncludeRegex = value
+		} else {
+			filter.ExcludeRegex = value
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// FeedDescribeTextFilter returns one line for every rule of the filter
+func FeedDescribeTextFilter(filter models.FeedTextFilter) (lines []string) {
+	if len(filter.IncludeKeywords) > 0 {
+		lines = append(lines, "include keywords: `"+strings.Join(filter.IncludeKeywords, "`, `")+"`")
+	}
+	if len(filter.ExcludeKeywords) > 0 {
+		lines = append(lines, "exclude keywords: `"+strings.Join(filter.ExcludeKeywords, "`, `")+"`")
+	}
+	if filter.IncludeRegex != "" {
+		lines = append(lines, fmt.Sprintf("include regex: `%s`", filter.IncludeRegex))
+	}
+	if filter.ExcludeRegex != "" {
+		lines = append(lines, fmt.Sprintf("exclude regex: `%s`", filter.ExcludeRegex))
+	}
+	return lines
+}
+
+// FeedSplitFilterList splits a comma separated list of filter values, and removes empty values
+func FeedSplitFilterList(value string) (list []string) {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Seklfreak/Robyul2/models"
+)
+
+func TestFeedTextFilterMatches(t *testing.T) {
+	for _, testCase := range []struct {
+		filter   models.FeedTextFilter
+		texts    []string
+		expected bool
+	}{
+		{models.FeedTextFilter{}, []string{"anything"}, true},
+		{models.FeedTextFilter{IncludeKeywords: []string{"Comeback", "teaser"}}, []string{"New TEASER out"}, true},
+		{models.FeedTextFilter{IncludeKeywords: []string{"comeback"}}, []string{"new teaser"}, false},
+		{models.FeedTextFilter{ExcludeKeywords: []string{"spoiler"}}, []string{"title", "contains a Spoiler"}, false},
+		{models.FeedTextFilter{IncludeKeywords: []string{"teaser"}, ExcludeKeywords: []string{"fake"}},
+			[]string{"fake teaser"}, false},
+		{models.FeedTextFilter{IncludeRegex: `^\[MV\]`}, []string{"[MV] Song"}, true},
+		{models.FeedTextFilter{IncludeRegex: `^\[MV\]`}, []string{"Song [MV]"}, false},
+		{models.FeedTextFilter{IncludeRegex: `^second$`}, []string{"first", "second"}, false},
+		{models.FeedTextFilter{IncludeRegex: `(?m)^second$`}, []string{"first", "second"}, true},
+		{models.FeedTextFilter{ExcludeRegex: `(?i)live`}, []string{"LIVE now"}, false},
+		{models.FeedTextFilter{IncludeRegex: `(`}, []string{"anything"}, false},
+	} {
+		if matched := FeedTextFilterMatches(testCase.filter, testCase.texts...); matched != testCase.expected {
+			t.Fatalf("FeedTextFilterMatches(%+v, %q) returned %v, expected %v",
+				testCase.filter, testCase.texts, matched, testCase.expected)
+		}
+	}
+}
+
+func TestFeedSetTextFilterRule(t *testing.T) {
+	var filter models.FeedTextFilter
+
+	known, err := FeedSetTextFilterRule(&filter, "include", "a, b ,,c")
+	if !known || err != nil || strings.Join(filter.IncludeKeywords, "|") != "a|b|c" {
+		t.Fatalf("FeedSetTextFilterRule() set include keywords %q, expected a, b and c", filter.IncludeKeywords)
+	}
+	known, err = FeedSetTextFilterRule(&filter, "exclude", "d")
+	if !known || err != nil || strings.Join(filter.ExcludeKeywords, "|") != "d" {
+		t.Fatalf("FeedSetTextFilterRule() set exclude keywords %q, expected d", filter.ExcludeKeywords)
+	}
+	known, err = FeedSetTextFilterRule(&filter, "include-regex", "^a")
+	if !known || err != nil || filter.IncludeRegex != "^a" {
+		t.Fatalf("FeedSetTextFilterRule() set include regex %q, expected ^a", filter.IncludeRegex)
+	}
+
+	known, err = FeedSetTextFilterRule(&filter, "exclude-regex", "(")
+	if !known || err == nil || filter.ExcludeRegex != "" {
+		t.Fatalf("FeedSetTextFilterRule() accepted an invalid regex")
+	}
+	if known, _ = FeedSetTextFilterRule(&filter, "min-score", "5"); known {
+		t.Fatalf("FeedSetTextFilterRule() accepted an unknown rule")
+	}
+
+	// empty values remove the rule
+	FeedSetTextFilterRule(&filter, "include", "")
+	FeedSetTextFilterRule(&filter, "include-regex", "")
+	if len(filter.IncludeKeywords) > 0 || filter.IncludeRegex != "" {
+		t.Fatalf("FeedSetTextFilterRule() didn't remove the rules with an empty value")
+	}
+	if len(filter.ExcludeKeywords) != 1 {
+		t.Fatalf("FeedSetTextFilterRule() changed other rules")
+	}
+}
@@ -0,0 +1,411 @@
+package helpers
+
+import (
+	"errors"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	feedMaxBackoff = 1 * time.Hour
+)
+
+var (
+	feedSources     []FeedSource
+	feedSourcesLock sync.RWMutex
+)
+
+// FeedSource is implemented by all feed plugins (Twitter, Reddit, …)
+// a target is what the source fetches from (an account, a subreddit, …), it can be posted to many feeds
+type FeedSource interface {
+	// Name is used for the posted items and in _feeds list
+	Name() string
+	// Interval is the minimum time between two checks of the same target
+	Interval() time.Duration
+	// Workers is the amount of targets checked at the same time
+	Workers() int
+	// Targets returns all targets that should be checked in this round
+	Targets() (targets []string, err error)
+	// Check fetches the new items of the target and posts them to its feeds
+	// targets returning an error are retried with an exponential backoff
+	Check(target string) (err error)
+	// GuildFeeds returns all feeds of the source on the guild
+	GuildFeeds(guildID string) (feeds []FeedInfo, err error)
+}
+
+// FeedInfo describes a feed for _feeds list
+type FeedInfo struct {
+	ID            string // the ID used by the commands of the source
+	Name          string
+	ChannelID     string
+	MentionRoleID string
+	Settings      FeedSettings
+}
+
+// FeedSettingsSource is implemented by sources whose feeds can be customized with FeedSettings
+type FeedSettingsSource interface {
+	FeedSource
+	// SetFeedSettings stores the settings of the feed with the ID, as in FeedInfo
+	SetFeedSettings(feedID string, settings FeedSettings) (err error)
+}
+
+// FeedSettings customize how the items of a feed are posted
+type FeedSettings struct {
+	EmbedTemplate string // embed code or text rendered by FeedRenderTemplate, empty for the default post
+	PostAsWebhook bool   // post through a webhook with the FeedIdentity of the item
+}
+
+// FeedIdentity is the name and avatar a feed posts with when it posts as a webhook
+type FeedIdentity struct {
+	Name      string
+	AvatarURL string
+}
+
+// FeedTemplateItem holds the values for the placeholders of feed templates
+type FeedTemplateItem struct {
+	Title       string
+	URL         string
+	Author      string
+	Thumbnail   string
+	Description string
+}
+
+type feedScheduler struct {
+	source      FeedSource
+	refreshTime *expvar.Float
+	failures    map[string]int       // [target]failed checks in a row
+	nextCheck   map[string]time.Time // [target]time of the next check after a failure
+	lock        sync.Mutex
+}
+
+// RegisterFeedSource adds the source to the feed scheduler and starts checking its targets
+// refreshTime is optional and set to the duration of every round
+func RegisterFeedSource(source FeedSource, refreshTime *expvar.Float) {
+	feedSourcesLock.Lock()
+	feedSources = append(feedSources, source)
+	feedSourcesLock.Unlock()
+
+	scheduler := &feedScheduler{
+		source:      source,
+		refreshTime: refreshTime,
+		failures:    make(map[string]int),
+		nextCheck:   make(map[string]time.Time),
+	}
+	go scheduler.run()
+	scheduler.logger().Infof("started feed scheduler (%s)", source.Interval().String())
+}
+
+// GetFeedSources returns all registered feed sources
+func GetFeedSources() (sources []FeedSource) {
+	feedSourcesLock.RLock()
+	defer feedSourcesLock.RUnlock()
+
+	return append(sources, feedSources...)
+}
+
+func (s *feedScheduler) run() {
+	defer Recover()
+	defer func() {
+		go func() {
+			s.logger().Error("the feed scheduler died. Please investigate! Will be restarted in 60 seconds")
+			time.Sleep(60 * time.Second)
+			s.run()
+		}()
+	}()
+
+	for {
+		start := time.Now()
+
+		checked, total := s.checkTargets()
+
+		elapsed := time.Since(start)
+		if checked > 0 {
+			s.logger().Infof("checked %d of %d targets, took %s", checked, total, elapsed.String())
+		}
+		if s.refreshTime != nil {
+			s.refreshTime.Set(elapsed.Seconds())
+		}
+
+		if elapsed < s.source.Interval() {
+			time.Sleep(s.source.Interval() - elapsed)
+		}
+	}
+}
+
+// checkTargets checks all targets which are not backing off with the workers of the source
+func (s *feedScheduler) checkTargets() (checked, total int) {
+	targets, err := s.source.Targets()
+	if err != nil {
+		s.logger().WithError(err).Error("failed to get targets")
+		return 0, 0
+	}
+
+	workers := s.source.Workers()
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				s.checkTarget(target)
+			}
+		}()
+	}
+
+	for _, target := range targets {
+		if !s.isDue(target) {
+			continue
+		}
+		jobs <- target
+		checked++
+	}
+	close(jobs)
+	wg.Wait()
+
+	s.forgetTargets(targets)
+
+	return checked, len(targets)
+}
+
+func (s *feedScheduler) checkTarget(target string) {
+	err := errors.New("check panicked")
+	defer func() {
+		s.finishCheck(target, err)
+	}()
+	defer Recover()
+
+	err = s.source.Check(target)
+}
+
+func (s *feedScheduler) isDue(target string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return !time.Now().Before(s.nextCheck[target])
+}
+
+// finishCheck resets the backoff of the target, or extends it if the check failed
+func (s *feedScheduler) finishCheck(target string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err == nil {
+		delete(s.failures, target)
+		delete(s.nextCheck, target)
+		return
+	}
+
+	s.failures[target]++
+	backoff := getFeedBackoff(s.source.Interval(), s.failures[target])
+	s.nextCheck[target] = time.Now().Add(backoff)
+
+	s.logger().WithField("target", target).WithError(err).Warnf(
+		"check failed %d times in a row, retrying in %s", s.failures[target], backoff.String())
+}
+
+// forgetTargets removes the backoff of targets which have been removed
+func (s *feedScheduler) forgetTargets(targets []string) {
+	current := make(map[string]bool, len(targets))
+	for _, target := range targets {
+		current[target] = true
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for target := range s.failures {
+		if !current[target] {
+			delete(s.failures, target)
+			delete(s.nextCheck, target)
+		}
+	}
+}
+
+func (s *feedScheduler) logger() *logrus.Entry {
+	return cache.GetLogger().WithField("module", "feeds").WithField("source", s.source.Name())
+}
+
+// getFeedBackoff returns the interval doubled for every failure, up to feedMaxBackoff
+func getFeedBackoff(interval time.Duration, failures int) (backoff time.Duration) {
+	backoff = interval
+	for i := 0; i < failures; i++ {
+		backoff *= 2
+		if backoff >= feedMaxBackoff {
+			return feedMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// FeedClaimItem marks the item as posted to the feed, and refreshes when the item has been seen last
+// returns false if the item has been posted before
+func FeedClaimItem(source, feedID, itemID string) (claimed bool, err error) {
+	changes, err := MdbCollection(models.FeedPostedItemsTable).Upsert(
+		bson.M{"source": source, "feedid": feedID, "itemid": itemID},
+		bson.M{"$setOnInsert": bson.M{"postedat": time.Now()}, "$set": bson.M{"seenat": time.Now()}},
+	)
+	if err != nil {
+		if mgo.IsDup(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return changes.UpsertedId != nil, nil
+}
+
+// FeedReleaseItem removes the claim of the item, so it will be posted again with the next check
+func FeedReleaseItem(source, feedID, itemID string) (err error) {
+	err = MdbCollection(models.FeedPostedItemsTable).Remove(
+		bson.M{"source": source, "feedid": feedID, "itemid": itemID},
+	)
+	if IsMdbNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// FeedDeleteItems removes all posted items of the feed, should be called after deleting a feed
+func FeedDeleteItems(source, feedID string) (err error) {
+	_, err = MdbCollection(models.FeedPostedItemsTable).RemoveAll(
+		bson.M{"source": source, "feedid": feedID},
+	)
+	return err
+}
+
+// FeedCanPost checks if the bot can send messages to the channel, and embed links if embedLinks is true
+func FeedCanPost(channelID string, embedLinks bool) bool {
+	channel, err := GetChannelWithoutApi(channelID)
+	if err != nil || channel == nil || channel.ID == "" {
+		return false
+	}
+
+	session := cache.GetSession().SessionForGuildS(channel.GuildID)
+	channelPermission, err := session.State.UserChannelPermissions(session.State.User.ID, channel.ID)
+	if err != nil {
+		return false
+	}
+
+	if channelPermission&discordgo.PermissionSendMessages != discordgo.PermissionSendMessages {
+		return false
+	}
+	if embedLinks && channelPermission&discordgo.PermissionEmbedLinks != discordgo.PermissionEmbedLinks {
+		return false
+	}
+	return true
+}
+
+// FeedPost sends the item to the channel of the feed, and mentions the role if set
+// missing permissions and deleted channels are not returned as errors
+func FeedPost(channelID, mentionRoleID string, data *discordgo.MessageSend) (err error) {
+	if mentionRoleID != "" {
+		data.Content = "<@&" + mentionRoleID + ">\n" + data.Content
+	}
+
+	_, err = SendComplex(channelID, data)
+	if err != nil {
+		if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+			(errD.Message.Code == discordgo.ErrCodeMissingPermissions ||
+				errD.Message.Code == discordgo.ErrCodeMissingAccess ||
+				errD.Message.Code == discordgo.ErrCodeUnknownChannel) {
+			return nil
+		}
+	}
+	return err
+}
+
+// FeedPostWithSettings sends the item to the channel of the feed like FeedPost, with the template and webhook of the settings
+// defaultMessage is sent if the feed has no template
+func FeedPostWithSettings(channelID, mentionRoleID string, settings FeedSettings, identity FeedIdentity,
+	item FeedTemplateItem, defaultMessage *discordgo.MessageSend) (err error) {
+	data := defaultMessage
+	if settings.EmbedTemplate != "" {
+		data = FeedRenderTemplate(settings.EmbedTemplate, item)
+	}
+
+	if !settings.PostAsWebhook {
+		return FeedPost(channelID, mentionRoleID, data)
+	}
+
+	if mentionRoleID != "" {
+		data.Content = "<@&" + mentionRoleID + ">\n" + data.Content
+	}
+
+	// without the Manage Webhooks permission the item is posted as Robyul
+	channel, err := GetChannelWithoutApi(channelID)
+	if err != nil {
+		return FeedPost(channelID, "", data)
+	}
+	webhook, err := GetWebhook(channel.GuildID, channel.ID)
+	if err != nil {
+		return FeedPost(channelID, "", data)
+	}
+
+	params := &discordgo.WebhookParams{
+		Content:   data.Content,
+		Username:  identity.Name,
+		AvatarURL: identity.AvatarURL,
+	}
+	if runes := []rune(params.Username); len(runes) > 80 {
+		params.Username = string(runes[:80])
+	}
+	if data.Embed != nil {
+		params.Embeds = []*discordgo.MessageEmbed{TruncateEmbed(data.Embed)}
+	}
+
+	_, err = WebhookExecuteWithResult(webhook.ID, webhook.Token, params)
+	if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+		errD.Message.Code == discordgo.ErrCodeUnknownWebhook {
+		// the cached webhook has been deleted
+		return FeedPost(channelID, "", data)
+	}
+	return err
+}
+
+// FeedRenderTemplate renders the template of a feed, an embed code or a text, with the values of the item
+// placeholders: {TITLE}, {URL}, {AUTHOR}, {THUMBNAIL}, {DESCRIPTION}
+func FeedRenderTemplate(template string, item FeedTemplateItem) (message *discordgo.MessageSend) {
+	message = &discordgo.MessageSend{
+		Content: template,
+	}
+	if IsEmbedCode(template) {
+		ptext, embed, err := ParseEmbedCode(template)
+		if err == nil {
+			message.Content = ptext
+			message.Embed = embed
+		}
+	}
+
+	message = ReplaceMessageSend(message, []*ReplaceValues{
+		{Before: "{TITLE}", After: item.Title},
+		{Before: "{URL}", After: item.URL},
+		{Before: "{AUTHOR}", After: item.Author},
+		{Before: "{THUMBNAIL}", After: item.Thumbnail},
+		{Before: "{DESCRIPTION}", After: item.Description},
+	})
+
+	// Discord rejects embeds with empty image URLs
+	if message.Embed != nil {
+		if message.Embed.Image != nil && message.Embed.Image.URL == "" {
+			message.Embed.Image = nil
+		}
+		if message.Embed.Thumbnail != nil && message.Embed.Thumbnail.URL == "" {
+			message.Embed.Thumbnail = nil
+		}
+	}
+
+	return message
+}
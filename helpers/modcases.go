@@ -0,0 +1,148 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	// the eventlog option key the case number is stored as
+	eventlogCaseNumberKey = "case_number"
+)
+
+// isModCaseEvent returns true if the eventlog action is a moderation action that gets a case number
+func isModCaseEvent(actionType string, options []models.ElasticEventlogOption) bool {
+	switch actionType {
+	case models.EventlogTypeBanAdd,
+		models.EventlogTypeBanRemove,
+		models.EventlogTypeRobyulMute,
+		models.EventlogTypeRobyulUnmute,
+		models.EventlogTypeRobyulWarn:
+		return true
+	case models.EventlogTypeMemberLeave:
+		for _, option := range options {
+			if option.Key == "member_leave_type" && option.Value == "kick" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getEventlogCaseNumber returns the case number stored in the eventlog options, or an empty string
+func getEventlogCaseNumber(options []models.ElasticEventlogOption) string {
+	for _, option := range options {
+		if option.Key == eventlogCaseNumberKey {
+			return option.Value
+		}
+	}
+	return ""
+}
+
+// nextModCaseNumber atomically increments and returns the case counter of the guild
+func nextModCaseNumber(guildID string) (caseNumber int, err error) {
+	var counter models.ModCaseCounterEntry
+	_, err = MdbCollection(models.ModCaseCountersTable).Find(bson.M{"guildid": guildID}).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"lastcasenumber": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, &counter)
+	return counter.LastCaseNumber, err
+}
+
+// createModCase assigns the next case number of the guild to an eventlog entry
+// the eventlog ID can be empty and set later, see setModCaseEventlogID
+func createModCase(createdAt time.Time, guildID, eventlogID, actionType, targetID string) (caseEntry models.ModCaseEntry, err error) {
+	caseEntry = models.ModCaseEntry{
+		GuildID:    guildID,
+		EventlogID: eventlogID,
+		ActionType: actionType,
+		TargetID:   targetID,
+		CreatedAt:  createdAt,
+	}
+
+	caseEntry.CaseNumber, err = nextModCaseNumber(guildID)
+	if err != nil {
+		return caseEntry, err
+	}
+
+	caseEntry.ID, err = MDbInsert(models.ModCasesTable, caseEntry)
+	return caseEntry, err
+}
+
+func setModCaseEventlogID(caseEntry models.ModCaseEntry, eventlogID string) (err error) {
+	caseEntry.EventlogID = eventlogID
+	return MDbUpdate(models.ModCasesTable, caseEntry.ID, caseEntry)
+}
+
+// GetModCase returns the case with the given number on the guild
+func GetModCase(guildID string, caseNumber int) (caseEntry models.ModCaseEntry, err error) {
+	err = MdbOne(
+		MdbCollection(models.ModCasesTable).Find(bson.M{"guildid": guildID, "casenumber": caseNumber}),
+		&caseEntry,
+	)
+	return caseEntry, err
+}
+
+// GetModCaseEmbed returns the eventlog embed of the case
+func GetModCaseEmbed(caseEntry models.ModCaseEntry) (embed *discordgo.MessageEmbed, err error) {
+	eventlogItem, err := ElasticGetEventlog(caseEntry.EventlogID)
+	if err != nil {
+		return nil, err
+	}
+
+	return getEventlogEmbed(caseEntry.EventlogID, eventlogItem.CreatedAt, eventlogItem.GuildID, eventlogItem.TargetID,
+		eventlogItem.TargetType, eventlogItem.UserID, eventlogItem.ActionType, eventlogItem.Reason,
+		eventlogItem.Changes, eventlogItem.Options, eventlogItem.WaitingFor.AuditLogBackfill), nil
+}
+
+// SetModCaseReason replaces the reason of the eventlog entry of the case, and edits the eventlog messages
+func SetModCaseReason(caseEntry models.ModCaseEntry, userID, reason string) (oldReason string, err error) {
+	if !cache.HasElastic() {
+		return "", errors.New("no elastic client")
+	}
+
+	eventlogItem, err := ElasticGetEventlog(caseEntry.EventlogID)
+	if err != nil {
+		return "", err
+	}
+	oldReason = eventlogItem.Reason
+	eventlogItem.Reason = reason
+
+	_, err = cache.GetElastic().Update().Index(models.ElasticIndexEventlogs).Type("doc").Id(caseEntry.EventlogID).
+		Doc(map[string]interface{}{"Reason": reason}).
+		Do(context.Background())
+	if err != nil {
+		return oldReason, err
+	}
+
+	editEventlogMessages(caseEntry.EventlogID, eventlogItem)
+
+	_, err = EventlogLog(time.Now(), caseEntry.GuildID, caseEntry.TargetID,
+		models.EventlogTargetTypeUser, userID,
+		models.EventlogTypeRobyulCaseReasonUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "case_reason",
+				OldValue: oldReason,
+				NewValue: reason,
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "updated_" + eventlogCaseNumberKey,
+				Value: strconv.Itoa(caseEntry.CaseNumber),
+			},
+		}, false)
+	RelaxLog(err)
+
+	return oldReason, nil
+}
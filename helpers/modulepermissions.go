@@ -73,6 +73,11 @@ const (
 	ModulePermEventlog  // eventlog/
 	ModulePermCrypto    // crypto.go
 	ModulePermImgur     // imgur.go
+	ModulePermAutomod   // automod/
+	ModulePermModmail   // modmail/
+	ModulePermRoleMenus // rolemenus.go
+	ModulePermFeeds     // feeds.go
+	ModulePermRSS       // rss/
 
 	ModulePermAll = ModulePermStats | ModulePermTranslator | ModulePermUrban | ModulePermWeather | ModulePermVLive |
 		ModulePermInstagram | ModulePermFacebook | ModulePermWolframAlpha | ModulePermLastFm | ModulePermTwitter |
@@ -84,7 +89,8 @@ const (
 		ModulePermAutoRole | ModulePermBias | ModulePermDiscordmoney | ModulePermGallery |
 		ModulePermGuildAnnouncements | ModulePermMirror | ModulePermMirror | ModulePermMod | ModulePermNotifications |
 		ModulePermNuke | ModulePermPersistency | ModulePermPing | ModulePermTroublemaker | ModulePermVanityInvite |
-		ModulePerm8ball | ModulePermFeedback | ModulePermEmbedPost | ModulePermEventlog | ModulePermCrypto | ModulePermImgur
+		ModulePerm8ball | ModulePermFeedback | ModulePermEmbedPost | ModulePermEventlog | ModulePermCrypto | ModulePermImgur |
+		ModulePermAutomod | ModulePermModmail | ModulePermRoleMenus | ModulePermFeeds | ModulePermRSS
 )
 
 var (
@@ -143,6 +149,11 @@ var (
 		{Names: []string{"eventlog"}, Permission: ModulePermEventlog},
 		{Names: []string{"crypto"}, Permission: ModulePermCrypto},
 		{Names: []string{"imgur"}, Permission: ModulePermImgur},
+		{Names: []string{"automod"}, Permission: ModulePermAutomod},
+		{Names: []string{"modmail"}, Permission: ModulePermModmail},
+		{Names: []string{"rolemenus", "rolemenu"}, Permission: ModulePermRoleMenus},
+		{Names: []string{"feeds"}, Permission: ModulePermFeeds},
+		{Names: []string{"rss"}, Permission: ModulePermRSS},
 	}
 )
 
@@ -19,6 +19,9 @@ var DefaultClient = &http.Client{
 	Timeout: time.Duration(15 * time.Second),
 }
 
+// ErrNotModified is returned by NetGetUAWithErrorConditional if the resource hasn't changed
+var ErrNotModified = errors.New("not modified")
+
 // NetGet executes a GET request to url with the Karen/Discord-Bot user-agent
 func NetGet(url string) []byte {
 	return NetGetUA(url, DEFAULT_UA)
@@ -90,6 +93,52 @@ func NetGetUAWithError(url string, useragent string) ([]byte, error) {
 	return []byte{}, errors.New("internal error")
 }
 
+// NetGetUAWithErrorConditional performs a conditional GET request with the ETag and Last-Modified of the previous response
+// returns ErrNotModified if the server responded with 304, headers contains the new ETag and Last-Modified
+func NetGetUAWithErrorConditional(url string, useragent string, etag string, lastModified string) (result []byte, headers http.Header, err error) {
+	// Prepare request
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return []byte{}, nil, err
+	}
+
+	// Set custom UA
+	request.Header.Set("User-Agent", useragent)
+	if etag != "" {
+		request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		request.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	// Do request
+	response, err := DefaultClient.Do(request)
+	if err != nil {
+		return []byte{}, nil, err
+	}
+
+	if response != nil && response.Body != nil {
+		defer response.Body.Close()
+	}
+
+	if response.StatusCode == http.StatusNotModified {
+		return []byte{}, response.Header, ErrNotModified
+	}
+
+	// Only continue if code was 200
+	if response.StatusCode != 200 {
+		return []byte{}, response.Header, errors.New("expected status 200; got " + strconv.Itoa(response.StatusCode))
+	}
+
+	buf := bytes.NewBuffer(nil)
+	_, err = io.Copy(buf, response.Body)
+	if err != nil {
+		return []byte{}, response.Header, err
+	}
+
+	return buf.Bytes(), response.Header, nil
+}
+
 func NetGetUAWithErrorAndTransport(url string, useragent string, transport http.Transport) ([]byte, error) {
 	// Allocate client
 	client := &http.Client{
@@ -19,6 +19,8 @@ import (
 
 var isValidVanityName = regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString
 
+var ErrVanityInvitePaused = errors.New("vanity invite is paused")
+
 func UpdateOrInsertVanityUrl(vanityName, guildID, channelID, userID string) (err error) {
 	if !isValidVanityName(vanityName) {
 		return errors.New("invalid vanity name")
@@ -362,6 +364,39 @@ func UpdateVanityUrl(vanityInviteEntry models.VanityInviteEntry) error {
 	return errors.New("empty vanityName submitted")
 }
 
+// SetVanityUrlPaused pauses or resumes a vanity invite
+// when pausing the current discord invite gets deleted, so it can't be used to join anymore
+func SetVanityUrlPaused(vanityInviteEntry models.VanityInviteEntry, paused bool) (err error) {
+	vanityInviteEntry.Paused = paused
+	err = UpdateVanityUrl(vanityInviteEntry)
+	if err != nil || !paused {
+		return err
+	}
+
+	redisClient := cache.GetRedisClient()
+	key := fmt.Sprintf(models.VanityInvitesInviteRedisKey, vanityInviteEntry.GuildID)
+
+	cacheResult, err := redisClient.Get(key).Bytes()
+	if err != nil {
+		// no discord invite cached
+		return nil
+	}
+
+	err = redisClient.Del(key).Err()
+	if err != nil {
+		return err
+	}
+
+	var vanityInviteRedis models.VanityInviteRedisEntry
+	err = json.Unmarshal(cacheResult, &vanityInviteRedis)
+	if err != nil || vanityInviteRedis.InviteCode == "" {
+		return nil
+	}
+
+	_, err = cache.GetSession().SessionForGuildS(vanityInviteEntry.GuildID).InviteDelete(vanityInviteRedis.InviteCode)
+	return err
+}
+
 func ResetCachedDiscordInviteByVanityInvite(vanityInviteEntry models.VanityInviteEntry) (err error) {
 	cacheCodec := cache.GetRedisCacheCodec()
 	key := fmt.Sprintf(models.VanityInvitesInviteRedisKey, vanityInviteEntry.GuildID)
@@ -378,6 +413,10 @@ func ResetCachedDiscordInviteByVanityInvite(vanityInviteEntry models.VanityInvit
 }
 
 func GetDiscordInviteByVanityInvite(vanityInviteEntry models.VanityInviteEntry) (code string, err error) {
+	if vanityInviteEntry.Paused {
+		return "", ErrVanityInvitePaused
+	}
+
 	redisClient := cache.GetRedisClient()
 	key := fmt.Sprintf(models.VanityInvitesInviteRedisKey, vanityInviteEntry.GuildID)
 
@@ -395,9 +395,12 @@ func main() {
 	}
 	log.WithField("module", "launcher").Info("started machinery server, default queue: robyul_tasks")
 	machineryServer.RegisterTasks(map[string]interface{}{
-		"unmute_user":    helpers.UnmuteUserMachinery,
-		"apply_autorole": plugins.AutoroleApply,
-		"log_error":      helpers.LogMachineryError,
+		"unmute_user":        helpers.UnmuteUserMachinery,
+		"unban_user":         helpers.UnbanUserMachinery,
+		"apply_autorole":     plugins.AutoroleApply,
+		"add_role":           helpers.AddRoleMachinery,
+		"close_reactionpoll": plugins.ReactionPollClose,
+		"log_error":          helpers.LogMachineryError,
 	})
 	cache.SetMachineryServer(machineryServer)
 	worker := machineryServer.NewWorker("robyul_worker_1", 1)
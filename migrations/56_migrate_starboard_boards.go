@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo/bson"
+)
+
+// m56_migrate_starboard_boards moves the single starboard of a guild into Config.StarboardBoards
+// and assigns the existing starboard entries to the new board
+func m56_migrate_starboard_boards() {
+	var configs []models.Config
+	err := helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.GuildConfigTable).Find(
+		bson.M{"starboardchannelid": bson.M{"$exists": true, "$ne": ""}},
+	)).All(&configs)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, config := range configs {
+		board := models.StarboardBoard{
+			ID:        bson.NewObjectId().Hex(),
+			Name:      "starboard",
+			ChannelID: config.StarboardChannelID,
+			Minimum:   config.StarboardMinimum,
+			Emoji:     config.StarboardEmoji,
+		}
+		config.StarboardBoards = append(config.StarboardBoards, board)
+		config.StarboardChannelID = ""
+		config.StarboardMinimum = 0
+		config.StarboardEmoji = nil
+
+		err = helpers.MDbUpdateWithoutLogging(models.GuildConfigTable, config.ID, config)
+		if err != nil {
+			panic(err)
+		}
+
+		_, err = helpers.MdbCollection(models.StarboardEntriesTable).UpdateAll(
+			bson.M{"guildid": config.GuildID, "boardid": bson.M{"$in": []interface{}{nil, ""}}},
+			bson.M{"$set": bson.M{"boardid": board.ID}},
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		cache.GetLogger().WithField("module", "migrations").Infof("migrated starboard #%s on #%s", board.ChannelID, config.GuildID)
+	}
+}
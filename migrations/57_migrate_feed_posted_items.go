@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// m57_migrate_feed_posted_items moves the posted tweets, videos, and VLive items of all feeds into FeedPostedItemsTable
+func m57_migrate_feed_posted_items() {
+	err := helpers.MdbCollection(models.FeedPostedItemsTable).EnsureIndex(mgo.Index{
+		Key:    []string{"source", "feedid", "itemid"},
+		Unique: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	var twitterEntries []models.TwitterEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitterTable).Find(
+		bson.M{"postedtweets.0": bson.M{"$exists": true}},
+	).Select(bson.M{"postedtweets": 1})).All(&twitterEntries)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range twitterEntries {
+		for _, tweet := range entry.PostedTweets {
+			migrateFeedPostedItem(models.FeedSourceTwitter, entry.ID, tweet.ID)
+		}
+		unsetFeedPostedItems(models.TwitterTable, entry.ID, "postedtweets")
+	}
+
+	var youtubeEntries []models.YoutubeChannelEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(
+		bson.M{"youtubepostedvideos.0": bson.M{"$exists": true}},
+	).Select(bson.M{"youtubepostedvideos": 1})).All(&youtubeEntries)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range youtubeEntries {
+		for _, videoID := range entry.YoutubePostedVideos {
+			migrateFeedPostedItem(models.FeedSourceYoutube, entry.ID, videoID)
+		}
+		unsetFeedPostedItems(models.YoutubeChannelTable, entry.ID, "youtubepostedvideos")
+	}
+
+	var vliveEntries []models.VliveEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.VliveTable).Find(
+		bson.M{"$or": []bson.M{
+			{"postedvod.0": bson.M{"$exists": true}},
+			{"postedupcoming.0": bson.M{"$exists": true}},
+			{"postedlive.0": bson.M{"$exists": true}},
+			{"postednotices.0": bson.M{"$exists": true}},
+			{"postedcelebs.0": bson.M{"$exists": true}},
+		}},
+	).Select(bson.M{"postedvod": 1, "postedupcoming": 1, "postedlive": 1, "postednotices": 1, "postedcelebs": 1})).All(&vliveEntries)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, entry := range vliveEntries {
+		for _, vod := range entry.PostedVOD {
+			migrateFeedPostedItem(models.FeedSourceVlive, entry.ID, fmt.Sprintf(models.VliveItemVOD, vod.Seq))
+		}
+		for _, upcoming := range entry.PostedUpcoming {
+			migrateFeedPostedItem(models.FeedSourceVlive, entry.ID, fmt.Sprintf(models.VliveItemUpcoming, upcoming.Seq))
+		}
+		for _, live := range entry.PostedLive {
+			migrateFeedPostedItem(models.FeedSourceVlive, entry.ID, fmt.Sprintf(models.VliveItemLive, live.Seq))
+		}
+		for _, notice := range entry.PostedNotices {
+			migrateFeedPostedItem(models.FeedSourceVlive, entry.ID, fmt.Sprintf(models.VliveItemNotice, notice.Number))
+		}
+		for _, celeb := range entry.PostedCelebs {
+			migrateFeedPostedItem(models.FeedSourceVlive, entry.ID, fmt.Sprintf(models.VliveItemCeleb, celeb.ID))
+		}
+		unsetFeedPostedItems(models.VliveTable, entry.ID,
+			"postedvod", "postedupcoming", "postedlive", "postednotices", "postedcelebs")
+	}
+
+	if len(twitterEntries)+len(youtubeEntries)+len(vliveEntries) > 0 {
+		cache.GetLogger().WithField("module", "migrations").Infof(
+			"migrated posted items of %d twitter, %d youtube, and %d vlive feeds",
+			len(twitterEntries), len(youtubeEntries), len(vliveEntries))
+	}
+}
+
+func migrateFeedPostedItem(source string, feedID bson.ObjectId, itemID string) {
+	_, err := helpers.FeedClaimItem(source, feedID.Hex(), itemID)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func unsetFeedPostedItems(collection models.MongoDbCollection, id bson.ObjectId, fields ...string) {
+	unset := bson.M{}
+	for _, field := range fields {
+		unset[field] = ""
+	}
+
+	err := helpers.MdbCollection(collection).UpdateId(id, bson.M{"$unset": unset})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo"
+)
+
+// m58_create_youtube_websub_index makes sure there is only one push subscription per YouTube channel,
+// and indexes the tokens push notifications are checked against
+func m58_create_youtube_websub_index() {
+	err := helpers.MdbCollection(models.YoutubeWebSubTable).EnsureIndex(mgo.Index{
+		Key:    []string{"youtubechannelid"},
+		Unique: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = helpers.MdbCollection(models.YoutubeWebSubTable).EnsureIndex(mgo.Index{
+		Key: []string{"token"},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// m59_create_feed_posted_items_ttl_index removes posted items of feeds once they haven't been in their feed for a while,
+// items still in a feed are seen with every check, and are never removed
+func m59_create_feed_posted_items_ttl_index() {
+	// items claimed before the TTL index existed have never been seen
+	_, err := helpers.MdbCollection(models.FeedPostedItemsTable).UpdateAll(
+		bson.M{"seenat": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"seenat": time.Now()}},
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	err = helpers.MdbCollection(models.FeedPostedItemsTable).EnsureIndex(mgo.Index{
+		Key:         []string{"seenat"},
+		ExpireAfter: 90 * 24 * time.Hour,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import (
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo"
+)
+
+// m60_create_mod_lockdowns_index makes sure there is only one active lockdown per guild
+func m60_create_mod_lockdowns_index() {
+	err := helpers.MdbCollection(models.ModLockdownsTable).EnsureIndex(mgo.Index{
+		Key:    []string{"guildid"},
+		Unique: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+}
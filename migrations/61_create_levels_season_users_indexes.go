@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/globalsign/mgo"
+)
+
+// m61_create_levels_season_users_indexes indexes the EXP of users per season, which is upserted for every message,
+// and the season leaderboards
+func m61_create_levels_season_users_indexes() {
+	err := helpers.MdbCollection(models.LevelsSeasonUsersTable).EnsureIndex(mgo.Index{
+		Key:    []string{"seasonid", "userid"},
+		Unique: true,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = helpers.MdbCollection(models.LevelsSeasonUsersTable).EnsureIndex(mgo.Index{
+		Key: []string{"seasonid", "-exp"},
+	})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -20,6 +20,12 @@ var migrations = []helpers.Callback{
 	m51_reindex_elasticv5_to_v6,
 	m52_create_elastic_index_voice_sessions,
 	m55_create_elastic_index_eventlogs,
+	m56_migrate_starboard_boards,
+	m57_migrate_feed_posted_items,
+	m58_create_youtube_websub_index,
+	m59_create_feed_posted_items_ttl_index,
+	m60_create_mod_lockdowns_index,
+	m61_create_levels_season_users_indexes,
 }
 
 // Run executes all registered migrations
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	AutomodRulesTable MongoDbCollection = "automod_rules"
+)
+
+type AutomodRuleEntry struct {
+	ID              bson.ObjectId `bson:"_id,omitempty"`
+	GuildID         string
+	CreatedByUserID string
+	CreatedAt       time.Time
+	Type            AutomodRuleType
+	// AutomodRuleTypeMentions: max mentions per message
+	// AutomodRuleTypeDuplicates, AutomodRuleTypeRate: max messages within Period
+	Threshold int
+	Period    time.Duration
+	// AutomodRuleTypeInvites: blocked invite codes, empty blocks all invites
+	// AutomodRuleTypeLinksAllow, AutomodRuleTypeLinksDeny: domains
+	// AutomodRuleTypeRegex: the pattern
+	Values           []string
+	Actions          []AutomodAction
+	MuteDuration     time.Duration // zero mutes permanently
+	ExemptRoleIDs    []string
+	ExemptChannelIDs []string
+}
+
+type AutomodRuleType string
+
+const (
+	AutomodRuleTypeMentions   AutomodRuleType = "mentions"
+	AutomodRuleTypeDuplicates AutomodRuleType = "duplicates"
+	AutomodRuleTypeRate       AutomodRuleType = "rate"
+	AutomodRuleTypeInvites    AutomodRuleType = "invites"
+	AutomodRuleTypeLinksAllow AutomodRuleType = "links-allow"
+	AutomodRuleTypeLinksDeny  AutomodRuleType = "links-deny"
+	AutomodRuleTypeRegex      AutomodRuleType = "regex"
+)
+
+type AutomodAction string
+
+const (
+	AutomodActionDelete AutomodAction = "delete"
+	AutomodActionWarn   AutomodAction = "warn"
+	AutomodActionMute   AutomodAction = "mute"
+	AutomodActionKick   AutomodAction = "kick"
+	AutomodActionBan    AutomodAction = "ban"
+)
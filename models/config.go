@@ -41,6 +41,19 @@ type Config struct {
 	LevelsNotificationCode        string
 	LevelsNotificationDeleteAfter int
 	LevelsMaxBadges               int
+	LevelsExpMin                  int // EXP per message, 0 uses the default range
+	LevelsExpMax                  int
+	LevelsCurveMultiplier         float64 // scales the EXP required for every level, 0 uses the default curve
+	LevelsNoExpRoleIDs            []string
+	LevelsChannelMultipliers      []LevelsMultiplier
+	LevelsRoleMultipliers         []LevelsMultiplier
+	LevelsWeekendMultiplier       float64
+	LevelsEventMultipliers        []LevelsEventMultiplier
+	LevelsVoiceExpEnabled         bool
+	LevelsVoiceExpPerMinute       int // 0 uses the default rate
+	LevelsIgnoredVoiceChannelIDs  []string
+	LevelsSeasonRewardRoleID      string // given to the top $LevelsSeasonRewardTop members when a season ends
+	LevelsSeasonRewardTop         int
 
 	MutedMembers []string // deprecated
 
@@ -50,9 +63,11 @@ type Config struct {
 	AutoRoleIDs      []string
 	DelayedAutoRoles []DelayedAutoRole
 
-	StarboardChannelID string
-	StarboardMinimum   int
-	StarboardEmoji     []string
+	StarboardBoards []StarboardBoard
+
+	StarboardChannelID string   // deprecated, migrated to StarboardBoards
+	StarboardMinimum   int      // deprecated, migrated to StarboardBoards
+	StarboardEmoji     []string // deprecated, migrated to StarboardBoards
 
 	ChatlogDisabled bool
 
@@ -73,6 +88,17 @@ type Config struct {
 
 	AdminRoleIDs []string
 	ModRoleIDs   []string
+
+	RatelimitCommandCosts     []RatelimitCommandCost
+	RatelimitChannelCooldowns []RatelimitChannelCooldown
+
+	ModEscalationRules []ModEscalationRule
+
+	RaidDetection      RaidDetection
+	LockdownChannelIDs []string
+
+	ModmailCategoryID   string
+	ModmailLogChannelID string
 }
 
 type InspectTriggersEnabled struct {
@@ -84,6 +110,49 @@ type InspectTriggersEnabled struct {
 	UserJoins                bool
 }
 
+type RatelimitCommandCost struct {
+	Command string
+	Cost    int
+}
+
+type RatelimitChannelCooldown struct {
+	ChannelID string
+	Cooldown  time.Duration
+}
+
+// RaidDetection triggers if one of the thresholds is reached within $Period, thresholds of 0 are disabled
+type RaidDetection struct {
+	Enabled               bool
+	Period                time.Duration
+	JoinThreshold         int
+	NewAccountThreshold   int // joins of accounts younger than $NewAccountAge
+	NewAccountAge         time.Duration
+	SharedInviteThreshold int // joins using the same invite code
+	AutoLockdown          bool
+}
+
+// ModEscalationRule punishes users that received $Warnings warnings within $Period
+type ModEscalationRule struct {
+	Warnings int
+	Period   time.Duration
+	Action   ModInfractionType // ModInfractionTypeMute, ModInfractionTypeKick or ModInfractionTypeBan
+	Duration time.Duration     // mute duration, zero mutes permanently
+}
+
+// LevelsMultiplier multiplies the EXP gained in a channel (or category), or by members with a role
+type LevelsMultiplier struct {
+	ID         string
+	Multiplier float64
+}
+
+// LevelsEventMultiplier multiplies all EXP gained between $Start and $End
+type LevelsEventMultiplier struct {
+	Name       string
+	Multiplier float64
+	Start      time.Time
+	End        time.Time
+}
+
 type DelayedAutoRole struct {
 	RoleID string
 	Delay  time.Duration
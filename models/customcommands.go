@@ -24,6 +24,22 @@ type CustomCommandsEntry struct {
 	StorageMimeType   string // deprecated
 	StorageHash       string // deprecated
 	StorageFilename   string // deprecated
+	// true if Content is rendered as a template, set when the command is added or edited
+	Template bool
+	// persistent counters used by {counter} in templates, map[counter name]value
+	Counters map[string]int
+	// additional keywords the command can be called with
+	Aliases []string
+	// time until the command can be used again on the server, or by the same user
+	Cooldown     time.Duration
+	UserCooldown time.Duration
+	// if not empty the command can only be used with one of the roles, or in one of the channels
+	AllowedRoleIDs    []string
+	DeniedRoleIDs     []string
+	AllowedChannelIDs []string
+	DeniedChannelIDs  []string
+	// deletes the message calling the command
+	DeleteInvoking bool
 }
 
 func CustomCommandsNewObjectName(guildID, userID string) (objectName string) {
@@ -45,6 +45,11 @@ const (
 	EventlogTypeRobyulLevelsRoleDelete              = "Robyul_Levels_Role_Delete"              // EventlogTargetTypeRole
 	EventlogTypeRobyulLevelsRoleGrant               = "Robyul_Levels_Role_Grant"               // EventlogTargetTypeUser
 	EventlogTypeRobyulLevelsRoleDeny                = "Robyul_Levels_Role_Deny"                // EventlogTargetTypeUser
+	EventlogTypeRobyulLevelsExpUpdate               = "Robyul_Levels_Exp_Update"               // EventlogTargetTypeGuild
+	EventlogTypeRobyulLevelsSeasonStart             = "Robyul_Levels_Season_Start"             // EventlogTargetTypeGuild
+	EventlogTypeRobyulLevelsSeasonEnd               = "Robyul_Levels_Season_End"               // EventlogTargetTypeGuild
+	EventlogTypeRobyulLevelsExpEdit                 = "Robyul_Levels_Exp_Edit"                 // EventlogTargetTypeUser
+	EventlogTypeRobyulLevelsImport                  = "Robyul_Levels_Import"                   // EventlogTargetTypeGuild
 	EventlogTypeRobyulNotificationsChannelIgnore    = "Robyul_Notifications_Channel_Ignore"    // EventlogTargetTypeChannel
 	EventlogTypeRobyulVliveFeedAdd                  = "Robyul_Vlive_Feed_Add"                  // EventlogTargetTypeRobyulVliveFeed
 	EventlogTypeRobyulVliveFeedRemove               = "Robyul_Vlive_Feed_Remove"               // EventlogTargetTypeRobyulVliveFeed
@@ -81,6 +86,8 @@ const (
 	EventlogTypeRobyulGuildAnnouncementsLeaveSet    = "Robyul_GuildAnnouncements_Leave_Set"    // EventlogTargetTypeChannel
 	EventlogTypeRobyulGuildAnnouncementsLeaveRemove = "Robyul_GuildAnnouncements_Leave_Remove" // EventlogTargetTypeChannel
 	EventlogTypeRobyulGuildAnnouncementsBanSet      = "Robyul_GuildAnnouncements_Ban_Set"      // EventlogTargetTypeChannel
+	EventlogTypeRobyulGuildAnnouncementsJoinDMSet   = "Robyul_GuildAnnouncements_JoinDM_Set"   // EventlogTargetTypeGuild
+	EventlogTypeRobyulGuildAnnouncementsCardSet     = "Robyul_GuildAnnouncements_Card_Set"     // EventlogTargetTypeChannel, EventlogTargetTypeGuild
 	EventlogTypeRobyulGalleryAdd                    = "Robyul_Gallery_Add"                     // EventlogTargetTypeRobyulGallery
 	EventlogTypeRobyulGalleryRemove                 = "Robyul_Gallery_Remove"                  // EventlogTargetTypeRobyulGallery
 	EventlogTypeRobyulMirrorCreate                  = "Robyul_Mirror_Create"                   // EventlogTargetTypeRobyulMirror
@@ -116,7 +123,31 @@ const (
 	EventlogTypeRobyulEventlogConfigUpdate          = "Robyul_Module_Eventlog_Config_Update"   // EventlogTargetTypeGuild
 	EventlogTypeRobyulTwitterFeedAdd                = "Robyul_Twitter_Feed_Add"                // EventlogTargetTypeRobyulTwitterFeed
 	EventlogTypeRobyulTwitterFeedRemove             = "Robyul_Twitter_Feed_Remove"             // EventlogTargetTypeRobyulTwitterFeed
+	EventlogTypeRobyulTwitterFeedUpdate             = "Robyul_Twitter_Feed_Update"             // EventlogTargetTypeRobyulTwitterFeed
 	EventlogTypeRobyulActionRevert                  = "Robyul_Action_Revert"                   // EventlogTargetTypeRobyulEventlogItem
+	EventlogTypeRobyulRatelimitUpdate               = "Robyul_Ratelimit_Update"                // EventlogTargetTypeGuild, EventlogTargetTypeChannel
+	EventlogTypeRobyulWarn                          = "Robyul_Warn"                            // EventlogTargetTypeUser
+	EventlogTypeRobyulInfractionRemove              = "Robyul_Infraction_Remove"               // EventlogTargetTypeUser
+	EventlogTypeRobyulEscalationRulesUpdate         = "Robyul_Escalation_Rules_Update"         // EventlogTargetTypeGuild
+	EventlogTypeRobyulEscalation                    = "Robyul_Escalation"                      // EventlogTargetTypeUser
+	EventlogTypeRobyulAutomodRuleAdd                = "Robyul_Automod_Rule_Add"                // EventlogTargetTypeRobyulAutomodRule
+	EventlogTypeRobyulAutomodRuleRemove             = "Robyul_Automod_Rule_Remove"             // EventlogTargetTypeRobyulAutomodRule
+	EventlogTypeRobyulAutomodRuleUpdate             = "Robyul_Automod_Rule_Update"             // EventlogTargetTypeRobyulAutomodRule
+	EventlogTypeRobyulAutomodAction                 = "Robyul_Automod_Action"                  // EventlogTargetTypeUser
+	EventlogTypeRobyulLockdownStart                 = "Robyul_Lockdown_Start"                  // EventlogTargetTypeGuild
+	EventlogTypeRobyulLockdownEnd                   = "Robyul_Lockdown_End"                    // EventlogTargetTypeGuild
+	EventlogTypeRobyulRaidDetectionUpdate           = "Robyul_RaidDetection_Update"            // EventlogTargetTypeGuild
+	EventlogTypeRobyulCaseReasonUpdate              = "Robyul_Case_Reason_Update"              // EventlogTargetTypeUser
+	EventlogTypeRobyulModmailUpdate                 = "Robyul_Modmail_Update"                  // EventlogTargetTypeGuild
+	EventlogTypeRobyulModmailThreadOpen             = "Robyul_Modmail_Thread_Open"             // EventlogTargetTypeUser
+	EventlogTypeRobyulModmailThreadClose            = "Robyul_Modmail_Thread_Close"            // EventlogTargetTypeUser
+	EventlogTypeRobyulRoleMenuCreate                = "Robyul_RoleMenu_Create"                 // EventlogTargetTypeRobyulRoleMenu
+	EventlogTypeRobyulRoleMenuUpdate                = "Robyul_RoleMenu_Update"                 // EventlogTargetTypeRobyulRoleMenu
+	EventlogTypeRobyulRoleMenuDelete                = "Robyul_RoleMenu_Delete"                 // EventlogTargetTypeRobyulRoleMenu
+	EventlogTypeRobyulRSSFeedAdd                    = "Robyul_RSS_Feed_Add"                    // EventlogTargetTypeRobyulRSSFeed
+	EventlogTypeRobyulRSSFeedRemove                 = "Robyul_RSS_Feed_Remove"                 // EventlogTargetTypeRobyulRSSFeed
+	EventlogTypeRobyulRSSFeedUpdate                 = "Robyul_RSS_Feed_Update"                 // EventlogTargetTypeRobyulRSSFeed
+	EventlogTypeRobyulFeedUpdate                    = "Robyul_Feed_Update"                     // EventlogTargetTypeRobyulFeed
 
 	EventlogTargetTypeRobyulBadge               = "robyul-badge"
 	EventlogTargetTypeRobyulVliveFeed           = "robyul-vlive-feed"
@@ -132,6 +163,10 @@ const (
 	EventlogTargetTypeRobyulPublicObject        = "robyul-public-object"
 	EventlogTargetTypeRobyulMirrorType          = "robyul-mirror-type"
 	EventlogTargetTypeRobyulEventlogItem        = "robyul-eventlog-item"
+	EventlogTargetTypeRobyulAutomodRule         = "robyul-automod-rule"
+	EventlogTargetTypeRobyulRoleMenu            = "robyul-rolemenu"
+	EventlogTargetTypeRobyulRSSFeed             = "robyul-rss-feed"
+	EventlogTargetTypeRobyulFeed                = "robyul-feed"
 
 	AuditLogBackfillRedisList = "robyul-discord:eventlog:auditlog-backfills:v2"
 )
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	FeedPostedItemsTable MongoDbCollection = "feed_posted_items"
+
+	FeedSourceTwitter = "twitter"
+	FeedSourceReddit  = "reddit"
+	FeedSourceVlive   = "vlive"
+	FeedSourceTwitch  = "twitch"
+	FeedSourceYoutube = "youtube"
+	FeedSourceRSS     = "rss"
+)
+
+// FeedPostedItemEntry marks an item (tweet, submission, video, …) as posted to a feed
+type FeedPostedItemEntry struct {
+	ID       bson.ObjectId `bson:"_id,omitempty"`
+	Source   string        // name of the FeedSource, e.g. twitter
+	FeedID   string        // ID of the feed entry of the source
+	ItemID   string
+	PostedAt time.Time
+	SeenAt   time.Time // last time the item was in the feed, items expire some time after they left it
+}
+
+// FeedTextFilter only lets items pass whose text matches all rules, empty rules match every item
+type FeedTextFilter struct {
+	IncludeKeywords []string // the text has to contain one of the keywords
+	ExcludeKeywords []string // the text must not contain any of the keywords
+	IncludeRegex    string
+	ExcludeRegex    string
+}
@@ -8,14 +8,17 @@ const (
 	GreeterTypeJoin GreeterType = iota
 	GreeterTypeLeave
 	GreeterTypeBan
+	GreeterTypeJoinDM
 )
 
 type GreeterType int
 
 type GreeterEntry struct {
-	Id        bson.ObjectId `bson:"_id,omitempty"`
-	GuildID   string
-	ChannelID string
-	EmbedCode string
-	Type      GreeterType
+	Id                bson.ObjectId `bson:"_id,omitempty"`
+	GuildID           string
+	ChannelID         string // empty for GreeterTypeJoinDM
+	EmbedCode         string
+	Type              GreeterType
+	Card              bool   // attach a generated welcome card
+	CardBackgroundURL string // optional custom background of the welcome card
 }
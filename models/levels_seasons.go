@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	LevelsSeasonsTable     MongoDbCollection = "levels_seasons"
+	LevelsSeasonUsersTable MongoDbCollection = "levels_season_users"
+)
+
+type LevelsSeasonType string
+
+const (
+	LevelsSeasonTypeManual  LevelsSeasonType = "manual"
+	LevelsSeasonTypeWeekly  LevelsSeasonType = "weekly"
+	LevelsSeasonTypeMonthly LevelsSeasonType = "monthly"
+)
+
+// LevelsSeasonEntry is a time window in which the EXP members gain is ranked separately
+// weekly and monthly seasons end automatically at $EndsAt and start the next season, manual seasons have no $EndsAt
+type LevelsSeasonEntry struct {
+	ID        bson.ObjectId `bson:"_id,omitempty"`
+	GuildID   string
+	Number    int
+	Type      LevelsSeasonType
+	StartedAt time.Time
+	EndsAt    time.Time
+	EndedAt   time.Time
+	Active    bool
+	Winners   []LevelsSeasonWinner // the top members, set when the season ends
+}
+
+type LevelsSeasonWinner struct {
+	UserID string
+	Exp    int64
+}
+
+// LevelsSeasonUserEntry is the EXP a member gained in a season
+type LevelsSeasonUserEntry struct {
+	ID       bson.ObjectId `bson:"_id,omitempty"`
+	SeasonID bson.ObjectId
+	GuildID  string
+	UserID   string
+	Exp      int64
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	ModCasesTable        MongoDbCollection = "mod_cases"
+	ModCaseCountersTable MongoDbCollection = "mod_case_counters"
+)
+
+type ModCaseEntry struct {
+	ID         bson.ObjectId `bson:"_id,omitempty"`
+	GuildID    string
+	CaseNumber int
+	EventlogID string
+	ActionType string
+	TargetID   string
+	CreatedAt  time.Time
+}
+
+type ModCaseCounterEntry struct {
+	ID             bson.ObjectId `bson:"_id,omitempty"`
+	GuildID        string
+	LastCaseNumber int
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	ModInfractionsTable MongoDbCollection = "mod_infractions"
+)
+
+type ModInfractionEntry struct {
+	ID             bson.ObjectId `bson:"_id,omitempty"`
+	GuildID        string
+	UserID         string
+	IssuedByUserID string
+	Type           ModInfractionType
+	Reason         string
+	CreatedAt      time.Time
+	// set for timed mutes and bans, zero if the punishment is permanent
+	Duration time.Duration
+	// true if the infraction has been issued by an escalation rule
+	Automatic bool
+}
+
+type ModInfractionType string
+
+const (
+	ModInfractionTypeWarning ModInfractionType = "warning"
+	ModInfractionTypeMute    ModInfractionType = "mute"
+	ModInfractionTypeKick    ModInfractionType = "kick"
+	ModInfractionTypeBan     ModInfractionType = "ban"
+)
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	ModLockdownsTable MongoDbCollection = "mod_lockdowns"
+)
+
+// ModLockdownEntry stores everything required to restore a guild after a lockdown
+type ModLockdownEntry struct {
+	ID                 bson.ObjectId `bson:"_id,omitempty"`
+	GuildID            string
+	StartedByUserID    string
+	StartedAt          time.Time
+	Reason             string
+	Channels           []ModLockdownChannel
+	VanityInvitePaused bool
+}
+
+// ModLockdownChannel is the @everyone permission overwrite of a channel before the lockdown
+type ModLockdownChannel struct {
+	ChannelID        string
+	OverwriteExisted bool
+	Allow            int
+	Deny             int
+	// overwrites of other roles which allowed sending messages
+	RoleOverwrites []ModLockdownRoleOverwrite
+}
+
+// ModLockdownRoleOverwrite is the permission overwrite of a role in a channel before the lockdown
+type ModLockdownRoleOverwrite struct {
+	RoleID string
+	Allow  int
+	Deny   int
+}
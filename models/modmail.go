@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	ModmailThreadsTable MongoDbCollection = "modmail_threads"
+)
+
+type ModmailThreadEntry struct {
+	ID        bson.ObjectId `bson:"_id,omitempty"`
+	GuildID   string
+	UserID    string
+	ChannelID string
+	CreatedAt time.Time
+	Open      bool
+	Messages  []ModmailMessage
+	// set once the thread has been closed
+	ClosedAt             time.Time
+	ClosedByUserID       string
+	CloseReason          string
+	TranscriptObjectName string
+}
+
+type ModmailMessage struct {
+	AuthorID    string
+	AuthorName  string
+	FromUser    bool
+	Content     string
+	Attachments []string
+	CreatedAt   time.Time
+}
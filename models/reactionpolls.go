@@ -23,4 +23,9 @@ type ReactionpollsEntry struct {
 	MaxAllowedVotes int
 	Reactions       map[string][]string // [emoji][]userIDs
 	Initialised     bool
+	EndsAt          time.Time // zero if the poll doesn't close automatically
+	ClosedAt        time.Time
+	Anonymous       bool     // votes are stored in Reactions, and the reactions are removed
+	SingleChoice    bool     // a new vote replaces the previous vote of the member
+	AllowedRoleIDs  []string // if set, only members with one of the roles can vote
 }
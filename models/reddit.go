@@ -20,4 +20,15 @@ type RedditSubredditEntry struct {
 	AddedAt         time.Time
 	PostDelay       int
 	PostDirectLinks bool
+	EmbedTemplate   string // embed code or text, empty for the default post
+	PostAsWebhook   bool
+	Filter          RedditFilter
+}
+
+// RedditFilter only lets submissions pass which match all rules
+type RedditFilter struct {
+	Text        FeedTextFilter // matched against the title and the text
+	Flairs      []string       // the submission has to have one of the flairs
+	MinScore    int            // checked once the post delay has passed
+	ExcludeNSFW bool
 }
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/globalsign/mgo/bson"
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
 
 const (
 	RemindersTable MongoDbCollection = "reminders"
@@ -10,11 +14,28 @@ type RemindersEntry struct {
 	ID        bson.ObjectId `bson:"_id,omitempty"`
 	UserID    string
 	Reminders []RemindersReminderEntry
+	// one-shot reminders that fired recently, kept around so they can still be snoozed
+	RecentlyFired []RemindersReminderEntry
 }
 
 type RemindersReminderEntry struct {
+	ID        bson.ObjectId `bson:",omitempty"`
 	Message   string
 	ChannelID string
 	GuildID   string
 	Timestamp int64
+	// recurring reminders, the time is interpreted in the timezone of the user when rescheduling
+	RepeatInterval RemindersRepeatInterval
+	RepeatWeekday  time.Weekday
+	RepeatHour     int
+	RepeatMinute   int
+	FiredAt        int64
 }
+
+type RemindersRepeatInterval string
+
+const (
+	RemindersRepeatNone   RemindersRepeatInterval = ""
+	RemindersRepeatDaily  RemindersRepeatInterval = "daily"
+	RemindersRepeatWeekly RemindersRepeatInterval = "weekly"
+)
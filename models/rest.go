@@ -269,3 +269,13 @@ const (
 	Redis_Key_Feature_Levels_Badges  = "robyul2-discord:feature:levels-badges:server:%s"
 	Redis_Key_Feature_RandomPictures = "robyul2-discord:feature:randompictures:server:%s"
 )
+
+type Rest_Command struct {
+	Name        string
+	Aliases     []string
+	Usage       string
+	Description string
+	Permission  string
+	Module      string
+	Subcommands []Rest_Command
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	RoleMenusTable MongoDbCollection = "rolemenus"
+)
+
+type RoleMenuMode string
+
+const (
+	RoleMenuModeToggle RoleMenuMode = "toggle" // reacting adds the role, removing the reaction removes it
+	RoleMenuModeUnique RoleMenuMode = "unique" // members can only have one role of the menu
+	RoleMenuModeVerify RoleMenuMode = "verify" // reacting adds the role, removing the reaction keeps it
+	RoleMenuModeMax    RoleMenuMode = "max"    // members can have up to MaxRoles roles of the menu
+)
+
+type RoleMenuOption struct {
+	Emoji  string // unicode emoji, or name:id for custom emoji
+	RoleID string
+}
+
+type RoleMenuEntry struct {
+	ID              bson.ObjectId `bson:"_id,omitempty"`
+	GuildID         string
+	ChannelID       string
+	MessageID       string
+	CreatedByUserID string
+	CreatedAt       time.Time
+	Title           string
+	Mode            RoleMenuMode
+	MaxRoles        int
+	Options         []RoleMenuOption
+}
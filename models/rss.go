@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	RSSFeedsTable MongoDbCollection = "rss_feeds"
+)
+
+type RSSFeedEntry struct {
+	ID            bson.ObjectId `bson:"_id,omitempty"`
+	GuildID       string
+	ChannelID     string
+	AddedByUserID string
+	AddedAt       time.Time
+	URL           string
+	Title         string
+	MentionRoleID string
+	EmbedTemplate string // embed code or text, empty for the default embed
+	PostAsWebhook bool
+}
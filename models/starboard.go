@@ -10,9 +10,32 @@ const (
 	StarboardEntriesTable MongoDbCollection = "starboard_entries"
 )
 
+// StarboardNSFWMode decides if messages in NSFW channels can be posted on a starboard
+type StarboardNSFWMode string
+
+const (
+	// StarboardNSFWModeAuto posts messages in NSFW channels only if the starboard channel is NSFW too
+	StarboardNSFWModeAuto  StarboardNSFWMode = ""
+	StarboardNSFWModeAllow StarboardNSFWMode = "allow"
+	StarboardNSFWModeDeny  StarboardNSFWMode = "deny"
+)
+
+// StarboardBoard is a starboard on a guild, stored in Config.StarboardBoards
+type StarboardBoard struct {
+	ID                string
+	Name              string
+	ChannelID         string
+	Minimum           int
+	Emoji             []string
+	AllowedChannelIDs []string // if set, only messages in these channels or categories can be starred
+	DeniedChannelIDs  []string
+	NSFW              StarboardNSFWMode
+}
+
 type StarboardEntry struct {
 	ID                        bson.ObjectId `bson:"_id,omitempty"`
 	GuildID                   string
+	BoardID                   string // StarboardBoard.ID, every board has its own entry for a message
 	MessageID                 string
 	ChannelID                 string
 	AuthorID                  string
@@ -14,4 +14,6 @@ type TwitchEntry struct {
 	TwitchUserID      string
 	IsLive            bool
 	MentionRoleID     string
+	EmbedTemplate     string // embed code or text, empty for the default embed
+	PostAsWebhook     bool
 }
@@ -18,11 +18,20 @@ type TwitterEntry struct {
 	ChannelID         string
 	AccountScreenName string
 	AccountID         string
-	PostedTweets      []TwitterTweetEntry
+	PostedTweets      []TwitterTweetEntry // deprecated, migrated to FeedPostedItemsTable
 	MentionRoleID     string
 	PostMode          TwitterPostMode
 	ExcludeRTs        bool
 	ExcludeMentions   bool
+	Filter            TwitterFilter
+	EmbedTemplate     string // embed code or text, empty for the default post
+	PostAsWebhook     bool
+}
+
+// TwitterFilter only lets tweets pass which match all rules
+type TwitterFilter struct {
+	Text      FeedTextFilter
+	MediaOnly bool
 }
 
 type TwitterTweetEntry struct {
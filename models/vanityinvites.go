@@ -22,6 +22,8 @@ type VanityInviteEntry struct {
 	VanityNamePretty string
 	SetByUserID      string
 	SetAt            time.Time
+	// paused vanity invites don't create discord invites, used during lockdowns
+	Paused bool
 }
 
 type VanityInviteRedisEntry struct {
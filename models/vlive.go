@@ -4,6 +4,13 @@ import "github.com/globalsign/mgo/bson"
 
 const (
 	VliveTable MongoDbCollection = "vlive"
+
+	// formats of the item IDs of posted VLive items
+	VliveItemVOD      = "vod:%d"
+	VliveItemUpcoming = "upcoming:%d"
+	VliveItemLive     = "live:%d"
+	VliveItemNotice   = "notice:%d"
+	VliveItemCeleb    = "celeb:%s"
 )
 
 type VliveEntry struct {
@@ -11,12 +18,14 @@ type VliveEntry struct {
 	GuildID        string        // renamed from server ID
 	ChannelID      string
 	VLiveChannel   VliveChannelInfo
-	PostedUpcoming []VliveVideoInfo
-	PostedLive     []VliveVideoInfo
-	PostedVOD      []VliveVideoInfo
-	PostedNotices  []VliveNoticeInfo
-	PostedCelebs   []VliveCelebInfo
+	PostedUpcoming []VliveVideoInfo  // deprecated, migrated to FeedPostedItemsTable
+	PostedLive     []VliveVideoInfo  // deprecated, migrated to FeedPostedItemsTable
+	PostedVOD      []VliveVideoInfo  // deprecated, migrated to FeedPostedItemsTable
+	PostedNotices  []VliveNoticeInfo // deprecated, migrated to FeedPostedItemsTable
+	PostedCelebs   []VliveCelebInfo  // deprecated, migrated to FeedPostedItemsTable
 	MentionRoleID  string
+	EmbedTemplate  string // embed code or text, empty for the default embed
+	PostAsWebhook  bool
 }
 
 type VliveChannelInfo struct {
@@ -1,9 +1,14 @@
 package models
 
-import "github.com/globalsign/mgo/bson"
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
 
 const (
 	YoutubeChannelTable  MongoDbCollection = "youtube_channels"
+	YoutubeWebSubTable   MongoDbCollection = "youtube_websub_subscriptions"
 	YoutubeQuotaRedisKey                   = "robyul2-discord:youtube:quota"
 )
 
@@ -14,10 +19,12 @@ type YoutubeChannelEntry struct {
 	ChannelID               string
 	NextCheckTime           int64
 	LastSuccessfulCheckTime int64
+	EmbedTemplate           string // embed code or text, empty for the default embed
+	PostAsWebhook           bool
 
 	// Youtube channel specific fields.
 	YoutubeChannelID    string
-	YoutubePostedVideos []string
+	YoutubePostedVideos []string // deprecated, migrated to FeedPostedItemsTable
 }
 
 type YoutubeQuota struct {
@@ -25,3 +32,12 @@ type YoutubeQuota struct {
 	Left      int64
 	ResetTime int64
 }
+
+// YoutubeWebSubEntry is the push subscription of a YouTube channel, shared by all feeds of the channel
+type YoutubeWebSubEntry struct {
+	ID               bson.ObjectId `bson:"_id,omitempty"`
+	YoutubeChannelID string        // unique
+	Token            string        // part of the callback URL, authenticates the requests of the hub
+	RequestedAt      time.Time     // time of the pending (un)subscription request, zero once the hub verified it
+	LeaseExpiresAt   time.Time     // zero until the hub verified the subscription
+}
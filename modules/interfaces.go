@@ -1,6 +1,7 @@
 package modules
 
 import (
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 )
@@ -79,3 +80,9 @@ type ExtendedPlugin interface {
 		session *discordgo.Session,
 	)
 }
+
+// RoutedPlugin can be implemented by a Plugin or an ExtendedPlugin to declare its commands with typed parameters
+// Commands returned by Routes are dispatched by the router, Action will no longer be called for them
+type RoutedPlugin interface {
+	Routes() []*router.Command
+}
@@ -0,0 +1,179 @@
+package modules
+
+import (
+	"reflect"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/plugins"
+	"github.com/Seklfreak/Robyul2/modules/plugins/automod"
+	"github.com/Seklfreak/Robyul2/modules/plugins/biasgame"
+	"github.com/Seklfreak/Robyul2/modules/plugins/eventlog"
+	"github.com/Seklfreak/Robyul2/modules/plugins/idols"
+	"github.com/Seklfreak/Robyul2/modules/plugins/levels"
+	"github.com/Seklfreak/Robyul2/modules/plugins/mod"
+	"github.com/Seklfreak/Robyul2/modules/plugins/modmail"
+	"github.com/Seklfreak/Robyul2/modules/plugins/notifications"
+	"github.com/Seklfreak/Robyul2/modules/plugins/nugugame"
+	"github.com/Seklfreak/Robyul2/modules/plugins/rss"
+	"github.com/Seklfreak/Robyul2/modules/plugins/youtube"
+	"github.com/Seklfreak/Robyul2/modules/router"
+)
+
+var (
+	// legacyModules are the modules checked in the Action of plugins, for the commands that are not routed yet
+	legacyModules = map[reflect.Type]models.ModulePermissionsModule{
+		reflect.TypeOf(&notifications.Handler{}):      helpers.ModulePermNotifications,
+		reflect.TypeOf(&plugins.Stats{}):              helpers.ModulePermStats,
+		reflect.TypeOf(&plugins.Uptime{}):             helpers.ModulePermStats,
+		reflect.TypeOf(&plugins.Translator{}):         helpers.ModulePermTranslator,
+		reflect.TypeOf(&plugins.UrbanDict{}):          helpers.ModulePermUrban,
+		reflect.TypeOf(&plugins.Weather{}):            helpers.ModulePermWeather,
+		reflect.TypeOf(&plugins.VLive{}):              helpers.ModulePermVLive,
+		reflect.TypeOf(&plugins.WolframAlpha{}):       helpers.ModulePermWolframAlpha,
+		reflect.TypeOf(&plugins.LastFm{}):             helpers.ModulePermLastFm,
+		reflect.TypeOf(&plugins.Twitch{}):             helpers.ModulePermTwitch,
+		reflect.TypeOf(&plugins.Charts{}):             helpers.ModulePermCharts,
+		reflect.TypeOf(&plugins.Choice{}):             helpers.ModulePermChoice,
+		reflect.TypeOf(&plugins.Osu{}):                helpers.ModulePermOsu,
+		reflect.TypeOf(&plugins.Reminders{}):          helpers.ModulePermReminders,
+		reflect.TypeOf(&plugins.Gfycat{}):             helpers.ModulePermGfycat,
+		reflect.TypeOf(&plugins.RandomPictures{}):     helpers.ModulePermRandomPictures,
+		reflect.TypeOf(&youtube.Handler{}):            helpers.ModulePermYouTube,
+		reflect.TypeOf(&rss.Handler{}):                helpers.ModulePermRSS,
+		reflect.TypeOf(&plugins.Spoiler{}):            helpers.ModulePermSpoiler,
+		reflect.TypeOf(&plugins.RandomCat{}):          helpers.ModulePermAnimals,
+		reflect.TypeOf(&plugins.RPS{}):                helpers.ModulePermGames,
+		reflect.TypeOf(&plugins.Nuke{}):               helpers.ModulePermNuke,
+		reflect.TypeOf(&plugins.Dig{}):                helpers.ModulePermDig,
+		reflect.TypeOf(&plugins.Streamable{}):         helpers.ModulePermStreamable,
+		reflect.TypeOf(&plugins.Lyrics{}):             helpers.ModulePermLyrics,
+		reflect.TypeOf(&plugins.Names{}):              helpers.ModulePermMod,
+		reflect.TypeOf(&plugins.Reddit{}):             helpers.ModulePermReddit,
+		reflect.TypeOf(&plugins.Color{}):              helpers.ModulePermColor,
+		reflect.TypeOf(&plugins.Dog{}):                helpers.ModulePermAnimals,
+		reflect.TypeOf(&plugins.Ping{}):               helpers.ModulePermPing,
+		reflect.TypeOf(&plugins.VanityInvite{}):       helpers.ModulePermVanityInvite,
+		reflect.TypeOf(&plugins.DiscordMoney{}):       helpers.ModulePermDiscordmoney,
+		reflect.TypeOf(&plugins.Whois{}):              helpers.ModulePermWhois,
+		reflect.TypeOf(&plugins.Isup{}):               helpers.ModulePermIsup,
+		reflect.TypeOf(&plugins.M8ball{}):             helpers.ModulePerm8ball,
+		reflect.TypeOf(&plugins.Feedback{}):           helpers.ModulePermFeedback,
+		reflect.TypeOf(&plugins.EmbedPost{}):          helpers.ModulePermEmbedPost,
+		reflect.TypeOf(&plugins.Move{}):               helpers.ModulePermMod,
+		reflect.TypeOf(&plugins.Crypto{}):             helpers.ModulePermCrypto,
+		reflect.TypeOf(&plugins.Imgur{}):              helpers.ModulePermImgur,
+		reflect.TypeOf(&plugins.Steam{}):              helpers.ModulePermSteam,
+		reflect.TypeOf(&plugins.Config{}):             helpers.ModulePermMod,
+		reflect.TypeOf(&plugins.Storage{}):            helpers.ModulePermStats,
+		reflect.TypeOf(&plugins.Mirror{}):             helpers.ModulePermMirror,
+		reflect.TypeOf(&plugins.Feeds{}):              helpers.ModulePermFeeds,
+		reflect.TypeOf(&plugins.Bias{}):               helpers.ModulePermBias,
+		reflect.TypeOf(&plugins.GuildAnnouncements{}): helpers.ModulePermGuildAnnouncements,
+		reflect.TypeOf(&levels.Levels{}):              helpers.ModulePermLevels,
+		reflect.TypeOf(&plugins.Gallery{}):            helpers.ModulePermGallery,
+		reflect.TypeOf(&plugins.CustomCommands{}):     helpers.ModulePermCustomCommands,
+		reflect.TypeOf(&plugins.ReactionPolls{}):      helpers.ModulePermReactionPolls,
+		reflect.TypeOf(&plugins.RoleMenus{}):          helpers.ModulePermRoleMenus,
+		reflect.TypeOf(&mod.Mod{}):                    helpers.ModulePermMod,
+		reflect.TypeOf(&plugins.AutoRoles{}):          helpers.ModulePermAutoRole,
+		reflect.TypeOf(&plugins.Starboard{}):          helpers.ModulePermStarboard,
+		reflect.TypeOf(&plugins.Persistency{}):        helpers.ModulePermPersistency,
+		reflect.TypeOf(&plugins.Twitter{}):            helpers.ModulePermTwitter,
+		reflect.TypeOf(&eventlog.Handler{}):           helpers.ModulePermEventlog,
+		reflect.TypeOf(&biasgame.Module{}):            helpers.ModulePermGames,
+		reflect.TypeOf(&nugugame.Module{}):            helpers.ModulePermGames,
+		reflect.TypeOf(&idols.Module{}):               helpers.ModulePermGames,
+		reflect.TypeOf(&automod.Handler{}):            helpers.ModulePermAutomod,
+		reflect.TypeOf(&modmail.Handler{}):            helpers.ModulePermModmail,
+	}
+
+	// legacyAliases maps commands of plugins that are not routed yet to the command they are an alias of
+	legacyAliases = map[string]string{
+		"t":                 "translator",
+		"translate":         "translator",
+		"ub":                "urban",
+		"lf":                "lastfm",
+		"choice":            "choose",
+		"osu!k":             "osu!mania",
+		"remindme":          "remind",
+		"rms":               "reminders",
+		"rapi":              "randompictures",
+		"rp":                "randompictures",
+		"yt":                "youtube",
+		"colour":            "color",
+		"sinfo":             "serverinfo",
+		"uinfo":             "userinfo",
+		"emojis":            "emotes",
+		"emoji":             "emotes",
+		"members":           "memberlist",
+		"nicknames":         "names",
+		"8":                 "8ball",
+		"suggestion":        "feedback",
+		"suggest":           "feedback",
+		"bug":               "issue",
+		"embed":             "embedpost",
+		"embed-edit":        "edit-embed",
+		"embed-get":         "get-embed",
+		"custom-invite":     "vanity-invite",
+		"cryptocurrency":    "crypto",
+		"mirrors":           "mirror",
+		"gfy":               "gfycat",
+		"announcements":     "guildannouncements",
+		"greet":             "guildannouncements",
+		"greeter":           "guildannouncements",
+		"levels":            "level",
+		"leaderboards":      "leaderboard",
+		"ranking":           "leaderboard",
+		"rankings":          "leaderboard",
+		"customcom":         "customcommands",
+		"commands":          "customcommands",
+		"command":           "customcommands",
+		"reactionpoll":      "reactionpolls",
+		"rolemenus":         "rolemenu",
+		"quickban":          "quick-ban",
+		"quickick":          "quick-kick",
+		"quickkick":         "quick-kick",
+		"autoroles":         "autorole",
+		"sb":                "starboard",
+		"bg":                "biasgame",
+		"ng":                "nugugame",
+		"idols":             "idol",
+		"s-edit":            "sug-edit",
+		"notification":      "notifications",
+		"noti":              "notifications",
+		"notis":             "notifications",
+		"modules":           "module",
+		"modulepermissions": "module",
+	}
+)
+
+// registerLegacyCommands registers the commands of a plugin that are not routed yet with the router,
+// so they show up in the help and the command export
+func registerLegacyCommands(plugin BaseModule, names []string) {
+	module := legacyModules[reflect.TypeOf(plugin)]
+
+	var legacyCommands []*router.Command
+	commandsByName := make(map[string]*router.Command)
+	for _, name := range names {
+		if _, isAlias := legacyAliases[name]; isAlias {
+			continue
+		}
+		command := &router.Command{Name: name, Module: module}
+		legacyCommands = append(legacyCommands, command)
+		commandsByName[name] = command
+	}
+	for _, name := range names {
+		canonical, isAlias := legacyAliases[name]
+		if !isAlias {
+			continue
+		}
+		if command, ok := commandsByName[canonical]; ok {
+			command.Aliases = append(command.Aliases, name)
+			continue
+		}
+		legacyCommands = append(legacyCommands, &router.Command{Name: name, Module: module})
+	}
+
+	router.RegisterLegacy(legacyCommands...)
+}
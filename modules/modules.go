@@ -2,13 +2,16 @@ package modules
 
 import (
 	"github.com/Seklfreak/Robyul2/modules/plugins"
+	"github.com/Seklfreak/Robyul2/modules/plugins/automod"
 	"github.com/Seklfreak/Robyul2/modules/plugins/biasgame"
 	"github.com/Seklfreak/Robyul2/modules/plugins/eventlog"
 	"github.com/Seklfreak/Robyul2/modules/plugins/idols"
 	"github.com/Seklfreak/Robyul2/modules/plugins/levels"
 	"github.com/Seklfreak/Robyul2/modules/plugins/mod"
+	"github.com/Seklfreak/Robyul2/modules/plugins/modmail"
 	"github.com/Seklfreak/Robyul2/modules/plugins/notifications"
 	"github.com/Seklfreak/Robyul2/modules/plugins/nugugame"
+	"github.com/Seklfreak/Robyul2/modules/plugins/rss"
 	"github.com/Seklfreak/Robyul2/modules/plugins/youtube"
 )
 
@@ -38,6 +41,7 @@ var (
 		&plugins.Gfycat{},
 		&plugins.RandomPictures{},
 		&youtube.Handler{},
+		&rss.Handler{},
 		&plugins.Spoiler{},
 		&plugins.RandomCat{},
 		&plugins.RPS{},
@@ -72,6 +76,7 @@ var (
 		&plugins.Config{},
 		&plugins.Storage{},
 		&plugins.Mirror{},
+		&plugins.Feeds{},
 	}
 
 	PluginExtendedList = []ExtendedPlugin{
@@ -81,6 +86,7 @@ var (
 		&plugins.Gallery{},
 		&plugins.CustomCommands{},
 		&plugins.ReactionPolls{},
+		&plugins.RoleMenus{},
 		&mod.Mod{},
 		&plugins.AutoRoles{},
 		&plugins.Starboard{},
@@ -92,5 +98,7 @@ var (
 		&biasgame.Module{},
 		&nugugame.Module{},
 		&idols.Module{},
+		&automod.Handler{},
+		&modmail.Handler{},
 	}
 )
@@ -0,0 +1,141 @@
+package automod
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/plugins/mod"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/bwmarrin/discordgo"
+)
+
+// parseActions parses actions like delete,warn,mute:1h
+func parseActions(text string) (actions []models.AutomodAction, muteDuration time.Duration, err error) {
+	for _, part := range strings.Split(strings.ToLower(text), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if strings.HasPrefix(part, string(models.AutomodActionMute)+":") {
+			muteDuration, err = router.ParseDuration(strings.TrimPrefix(part, string(models.AutomodActionMute)+":"))
+			if err != nil {
+				return nil, 0, err
+			}
+			part = string(models.AutomodActionMute)
+		}
+
+		action := models.AutomodAction(part)
+		switch action {
+		case models.AutomodActionDelete, models.AutomodActionWarn, models.AutomodActionMute,
+			models.AutomodActionKick, models.AutomodActionBan:
+		default:
+			return nil, 0, errors.New("invalid action " + part)
+		}
+
+		var duplicate bool
+		for _, existingAction := range actions {
+			if existingAction == action {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			actions = append(actions, action)
+		}
+	}
+
+	if len(actions) <= 0 {
+		return nil, 0, errors.New("no actions")
+	}
+	return actions, muteDuration, nil
+}
+
+// executeActions runs the actions of the rule against the author of the message and logs them to the eventlog
+func executeActions(rule cachedRule, msg *discordgo.Message, guildID string, violation string) {
+	session := cache.GetSession().SessionForGuildS(guildID)
+	botID := session.State.User.ID
+	reason := "Automod: " + violation
+
+	var err error
+	executedActions := make([]string, 0)
+	for _, action := range rule.Actions {
+		switch action {
+		case models.AutomodActionDelete:
+			err = session.ChannelMessageDelete(msg.ChannelID, msg.ID)
+		case models.AutomodActionWarn:
+			_, _, err = mod.WarnUser(guildID, msg.Author.ID, botID, reason)
+		case models.AutomodActionMute:
+			var unmuteAt time.Time
+			if rule.MuteDuration > 0 {
+				unmuteAt = time.Now().Add(rule.MuteDuration)
+			}
+			err = helpers.MuteUser(guildID, msg.Author.ID, unmuteAt)
+			if err == nil {
+				err = addInfraction(guildID, msg.Author.ID, botID, models.ModInfractionTypeMute, reason, rule.MuteDuration)
+			}
+		case models.AutomodActionKick:
+			err = session.GuildMemberDeleteWithReason(guildID, msg.Author.ID, reason)
+			if err == nil {
+				err = addInfraction(guildID, msg.Author.ID, botID, models.ModInfractionTypeKick, reason, 0)
+			}
+		case models.AutomodActionBan:
+			err = session.GuildBanCreateWithReason(guildID, msg.Author.ID, reason, 0)
+			if err == nil {
+				err = addInfraction(guildID, msg.Author.ID, botID, models.ModInfractionTypeBan, reason, 0)
+			}
+		}
+		if err != nil {
+			logger().Warnf("failed to %s user #%s on guild #%s for rule #%s: %s",
+				action, msg.Author.ID, guildID, helpers.MdbIdToHuman(rule.ID), err.Error())
+			continue
+		}
+		executedActions = append(executedActions, string(action))
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, msg.Author.ID,
+		models.EventlogTargetTypeUser, botID,
+		models.EventlogTypeRobyulAutomodAction, reason,
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "automod_rule_id",
+				Value: helpers.MdbIdToHuman(rule.ID),
+				Type:  models.EventlogTargetTypeRobyulAutomodRule,
+			},
+			{
+				Key:   "automod_rule_type",
+				Value: string(rule.Type),
+			},
+			{
+				Key:   "automod_actions",
+				Value: strings.Join(executedActions, ","),
+			},
+			{
+				Key:   "automod_channelid",
+				Value: msg.ChannelID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "automod_message_content",
+				Value: msg.Content,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+}
+
+func addInfraction(guildID, userID, issuedByUserID string, infractionType models.ModInfractionType, reason string, duration time.Duration) (err error) {
+	_, err = mod.AddInfraction(models.ModInfractionEntry{
+		GuildID:        guildID,
+		UserID:         userID,
+		IssuedByUserID: issuedByUserID,
+		Type:           infractionType,
+		Reason:         reason,
+		Duration:       duration,
+		Automatic:      true,
+	})
+	return err
+}
@@ -0,0 +1,517 @@
+package automod
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/Seklfreak/Robyul2/shardmanager"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type Handler struct{}
+
+func (h *Handler) Commands() []string {
+	return []string{
+		"automod",
+	}
+}
+
+func (h *Handler) Init(session *shardmanager.Manager) {
+	defer helpers.Recover()
+
+	history = make(map[string][]historyEntry)
+	historyResets = make(map[string]time.Time)
+
+	err := refreshRules()
+	helpers.Relax(err)
+
+	go cleanupHistoryLoop()
+	logger().Info("started cleanupHistoryLoop loop (10m)")
+}
+
+func (h *Handler) Uninit(session *shardmanager.Manager) {
+	defer helpers.Recover()
+}
+
+func (h *Handler) Routes() []*router.Command {
+	actionsParam := router.Param{Name: "actions", Type: router.ParamString}
+
+	return []*router.Command{
+		{
+			Name:       "automod",
+			Permission: router.PermissionMod,
+			Module:     helpers.ModulePermAutomod,
+			Subcommands: []*router.Command{
+				{
+					Name:        "list",
+					Description: "lists all automod rules on the server",
+					Handler:     h.actionList,
+				},
+				{
+					Name:        "add",
+					Description: "adds an automod rule, actions is a comma separated list of delete, warn, mute (or mute:<duration>), kick and ban",
+					Permission:  router.PermissionAdmin,
+					Subcommands: []*router.Command{
+						{
+							Name:        string(models.AutomodRuleTypeMentions),
+							Description: "triggers for messages with more than the given amount of mentions",
+							Params: []router.Param{
+								{Name: "max mentions", Type: router.ParamInt},
+								actionsParam,
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeDuplicates),
+							Description: "triggers if an user posts the same message more than the given amount of times within the period",
+							Params: []router.Param{
+								{Name: "max duplicates", Type: router.ParamInt},
+								{Name: "period", Type: router.ParamDuration},
+								actionsParam,
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeRate),
+							Description: "triggers if an user posts more than the given amount of messages within the period",
+							Params: []router.Param{
+								{Name: "max messages", Type: router.ParamInt},
+								{Name: "period", Type: router.ParamDuration},
+								actionsParam,
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeInvites),
+							Description: "triggers for the given invite codes, or for all invites if no codes are given",
+							Params: []router.Param{
+								actionsParam,
+								{Name: "invite codes", Type: router.ParamRest, Optional: true},
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeLinksAllow),
+							Description: "triggers for links to all domains except the given ones",
+							Params: []router.Param{
+								actionsParam,
+								{Name: "domains", Type: router.ParamRest},
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeLinksDeny),
+							Description: "triggers for links to the given domains",
+							Params: []router.Param{
+								actionsParam,
+								{Name: "domains", Type: router.ParamRest},
+							},
+							Handler: h.actionAdd,
+						},
+						{
+							Name:        string(models.AutomodRuleTypeRegex),
+							Description: "triggers for messages matching the regular expression",
+							Params: []router.Param{
+								actionsParam,
+								{Name: "regex", Type: router.ParamRest},
+							},
+							Handler: h.actionAdd,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Aliases:     []string{"delete", "del"},
+					Description: "removes an automod rule",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "rule id", Type: router.ParamString},
+					},
+					Handler: h.actionRemove,
+				},
+				{
+					Name:        "exempt",
+					Description: "exempts a channel or a role from an automod rule, or removes the exemption",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "rule id", Type: router.ParamString},
+						{Name: "channel", Type: router.ParamChannel, Optional: true},
+						{Name: "role", Type: router.ParamRole, Optional: true},
+					},
+					Handler: h.actionExempt,
+				},
+			},
+		},
+	}
+}
+
+// Action is not used, all commands are dispatched by the router, see Routes
+func (h *Handler) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+}
+
+func getRuleText(rule models.AutomodRuleEntry) (text string) {
+	text = fmt.Sprintf("`%s` **%s**", helpers.MdbIdToHuman(rule.ID), rule.Type)
+	switch rule.Type {
+	case models.AutomodRuleTypeMentions:
+		text += fmt.Sprintf(" more than %d mentions", rule.Threshold)
+	case models.AutomodRuleTypeDuplicates, models.AutomodRuleTypeRate:
+		text += fmt.Sprintf(" more than %d messages within %s", rule.Threshold, helpers.HumanizeDuration(rule.Period))
+	case models.AutomodRuleTypeInvites:
+		if len(rule.Values) <= 0 {
+			text += " all invites"
+			break
+		}
+		text += " `" + strings.Join(rule.Values, "`, `") + "`"
+	default:
+		text += " `" + strings.Join(rule.Values, "`, `") + "`"
+	}
+
+	actions := make([]string, 0)
+	for _, action := range rule.Actions {
+		if action == models.AutomodActionMute && rule.MuteDuration > 0 {
+			actions = append(actions, string(action)+" for "+helpers.HumanizeDuration(rule.MuteDuration))
+			continue
+		}
+		actions = append(actions, string(action))
+	}
+	text += ": " + strings.Join(actions, ", ")
+
+	exemptions := make([]string, 0)
+	for _, channelID := range rule.ExemptChannelIDs {
+		exemptions = append(exemptions, "<#"+channelID+">")
+	}
+	for _, roleID := range rule.ExemptRoleIDs {
+		exemptions = append(exemptions, "<@&"+roleID+">")
+	}
+	if len(exemptions) > 0 {
+		text += ", except " + strings.Join(exemptions, ", ")
+	}
+	return text
+}
+
+func getRule(guildID string, ruleID string) (rule models.AutomodRuleEntry, err error) {
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.AutomodRulesTable).Find(bson.M{"guildid": guildID, "_id": helpers.HumanToMdbId(ruleID)}),
+		&rule,
+	)
+	return rule, err
+}
+
+// [p]automod list
+func (h *Handler) actionList(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	rules := getRules(channel.GuildID)
+	if len(rules) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.automod.list-empty"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	resultMessage := ":shield: Automod rules on this server:\n"
+	for _, rule := range rules {
+		resultMessage += getRuleText(rule.AutomodRuleEntry) + "\n"
+	}
+	resultMessage += fmt.Sprintf("Found **%d** Rules in total.", len(rules))
+
+	for _, page := range helpers.Pagify(resultMessage, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, page)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// [p]automod add <type> <parameters> <actions>
+func (h *Handler) actionAdd(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	rule := models.AutomodRuleEntry{
+		GuildID:         channel.GuildID,
+		CreatedByUserID: msg.Author.ID,
+		CreatedAt:       time.Now(),
+		Type:            models.AutomodRuleType(ctx.Command.Name),
+	}
+
+	rule.Actions, rule.MuteDuration, err = parseActions(ctx.String("actions"))
+	if err != nil {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.automod.add-error-actions"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	switch rule.Type {
+	case models.AutomodRuleTypeMentions:
+		rule.Threshold = ctx.Int("max mentions")
+	case models.AutomodRuleTypeDuplicates:
+		rule.Threshold = ctx.Int("max duplicates")
+		rule.Period = ctx.Duration("period")
+	case models.AutomodRuleTypeRate:
+		rule.Threshold = ctx.Int("max messages")
+		rule.Period = ctx.Duration("period")
+	case models.AutomodRuleTypeInvites:
+		for _, code := range strings.Fields(ctx.String("invite codes")) {
+			inviteCodes := helpers.ExtractInviteCodes(code)
+			if len(inviteCodes) > 0 {
+				code = inviteCodes[0]
+			}
+			rule.Values = append(rule.Values, code)
+		}
+	case models.AutomodRuleTypeLinksAllow, models.AutomodRuleTypeLinksDeny:
+		for _, domain := range strings.Fields(strings.Replace(ctx.String("domains"), ",", " ", -1)) {
+			hosts := extractLinkHosts(domain)
+			if len(hosts) > 0 {
+				domain = hosts[0]
+			}
+			rule.Values = append(rule.Values, strings.TrimPrefix(strings.ToLower(domain), "www."))
+		}
+	case models.AutomodRuleTypeRegex:
+		pattern := ctx.String("regex")
+		if _, err = regexp.Compile(pattern); err != nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.automod.add-error-regex", err.Error()))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		rule.Values = []string{pattern}
+	}
+
+	if (rule.Type == models.AutomodRuleTypeMentions && rule.Threshold < 1) ||
+		((rule.Type == models.AutomodRuleTypeDuplicates || rule.Type == models.AutomodRuleTypeRate) &&
+			(rule.Threshold < 1 || rule.Period > historyMaxAge)) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.automod.add-error-threshold",
+			helpers.HumanizeDuration(historyMaxAge)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	rule.ID, err = helpers.MDbInsert(models.AutomodRulesTable, rule)
+	helpers.Relax(err)
+
+	err = refreshRules()
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(rule.ID),
+		models.EventlogTargetTypeRobyulAutomodRule, msg.Author.ID,
+		models.EventlogTypeRobyulAutomodRuleAdd, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "automod_rule_type",
+				Value: string(rule.Type),
+			},
+			{
+				Key:   "automod_rule",
+				Value: getRuleText(rule),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.automod.add-success", getRuleText(rule)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]automod remove <rule id>
+func (h *Handler) actionRemove(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	rule, err := getRule(channel.GuildID, ctx.String("rule id"))
+	if helpers.IsMdbNotFound(err) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.automod.rule-not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	err = helpers.MDbDelete(models.AutomodRulesTable, rule.ID)
+	helpers.Relax(err)
+
+	err = refreshRules()
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(rule.ID),
+		models.EventlogTargetTypeRobyulAutomodRule, msg.Author.ID,
+		models.EventlogTypeRobyulAutomodRuleRemove, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "automod_rule_type",
+				Value: string(rule.Type),
+			},
+			{
+				Key:   "automod_rule",
+				Value: getRuleText(rule),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.automod.remove-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]automod exempt <rule id> <#channel or @role>
+func (h *Handler) actionExempt(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	if !ctx.Has("channel") && !ctx.Has("role") {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few")+"\n"+
+			ctx.Command.UsageText(helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	rule, err := getRule(channel.GuildID, ctx.String("rule id"))
+	if helpers.IsMdbNotFound(err) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.automod.rule-not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	oldRuleText := getRuleText(rule)
+
+	var exempted bool
+	if ctx.Has("channel") {
+		targetChannel := ctx.Channel("channel")
+		if targetChannel.GuildID != channel.GuildID {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		rule.ExemptChannelIDs, exempted = toggleID(rule.ExemptChannelIDs, targetChannel.ID)
+	}
+	if ctx.Has("role") {
+		rule.ExemptRoleIDs, exempted = toggleID(rule.ExemptRoleIDs, ctx.Role("role").ID)
+	}
+
+	err = helpers.MDbUpsertID(models.AutomodRulesTable, rule.ID, rule)
+	helpers.Relax(err)
+
+	err = refreshRules()
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(rule.ID),
+		models.EventlogTargetTypeRobyulAutomodRule, msg.Author.ID,
+		models.EventlogTypeRobyulAutomodRuleUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "automod_rule",
+				OldValue: oldRuleText,
+				NewValue: getRuleText(rule),
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	successText := helpers.GetText("plugins.automod.exempt-remove-success")
+	if exempted {
+		successText = helpers.GetText("plugins.automod.exempt-add-success")
+	}
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// toggleID adds id to the list, or removes it if it is on the list already
+func toggleID(list []string, id string) (newList []string, added bool) {
+	newList = make([]string, 0)
+	for _, item := range list {
+		if item == id {
+			continue
+		}
+		newList = append(newList, item)
+	}
+	if len(newList) == len(list) {
+		return append(newList, id), true
+	}
+	return newList, false
+}
+
+func (h *Handler) OnMessage(content string, msg *discordgo.Message, session *discordgo.Session) {
+	if msg.Author == nil || msg.Author.Bot {
+		return
+	}
+
+	channel, err := helpers.GetChannelWithoutApi(msg.ChannelID)
+	if err != nil || channel.GuildID == "" {
+		return
+	}
+
+	rules := getRules(channel.GuildID)
+	if len(rules) <= 0 {
+		return
+	}
+
+	if !helpers.ModuleIsAllowedSilent(channel.ID, msg.ID, msg.Author.ID, helpers.ModulePermAutomod) {
+		return
+	}
+
+	recent := addToHistory(channel.GuildID, msg)
+
+	member := msg.Member
+	if member == nil {
+		member, _ = helpers.GetGuildMemberWithoutApi(channel.GuildID, msg.Author.ID)
+	}
+
+	for _, rule := range rules {
+		if rule.isExempt(channel.ID, member) {
+			continue
+		}
+
+		matched, violation := rule.match(msg, historyForRule(channel.GuildID, msg.Author.ID, rule, recent))
+		if !matched {
+			continue
+		}
+
+		// mods are never punished, only checked once a rule matched to save requests
+		if helpers.IsModByID(channel.GuildID, msg.Author.ID) {
+			return
+		}
+
+		logger().Infof("rule #%s matched message #%s by #%s on #%s: %s",
+			helpers.MdbIdToHuman(rule.ID), msg.ID, msg.Author.ID, channel.GuildID, violation)
+		executeActions(rule, msg, channel.GuildID, violation)
+		// without a reset the rate and duplicates rules would fire for every further message within their period
+		resetHistory(channel.GuildID, msg.Author.ID, rule)
+		// only apply one rule per message
+		return
+	}
+}
+
+func (h *Handler) OnMessageDelete(msg *discordgo.MessageDelete, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildMemberAdd(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildMemberRemove(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnReactionAdd(reaction *discordgo.MessageReactionAdd, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnReactionRemove(reaction *discordgo.MessageReactionRemove, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildBanAdd(user *discordgo.GuildBanAdd, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildBanRemove(user *discordgo.GuildBanRemove, session *discordgo.Session) {
+
+}
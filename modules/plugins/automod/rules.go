@@ -0,0 +1,279 @@
+package automod
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// the longest period duplicates and rate rules may use, messages are kept in the history for this long
+	historyMaxAge = 10 * time.Minute
+	// the maximum amount of messages kept per user and guild
+	historyMaxLength = 50
+)
+
+var (
+	mentionRegex = regexp.MustCompile(`<@[!&]?\d+>`)
+	linkRegex    = regexp.MustCompile(`(?i)\bhttps?://[^\s<>]+`)
+)
+
+type cachedRule struct {
+	models.AutomodRuleEntry
+	regex *regexp.Regexp
+}
+
+type historyEntry struct {
+	content   string
+	createdAt time.Time
+}
+
+var (
+	rulesCache     map[string][]cachedRule // map[guildID][]cachedRule
+	rulesCacheLock sync.RWMutex
+
+	history     map[string][]historyEntry // map[guildID-userID][]historyEntry
+	historyLock sync.Mutex
+	// messages sent before a rule fired are no longer counted for it, so it only fires again for new messages
+	historyResets map[string]time.Time // map[guildID-userID-ruleID]time.Time
+)
+
+// refreshRules loads all rules from the database into the cache
+func refreshRules() (err error) {
+	var entries []models.AutomodRuleEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.AutomodRulesTable).Find(nil).Sort("createdat")).All(&entries)
+	if err != nil {
+		return err
+	}
+
+	newCache := make(map[string][]cachedRule)
+	for _, entry := range entries {
+		rule := cachedRule{AutomodRuleEntry: entry}
+		if entry.Type == models.AutomodRuleTypeRegex && len(entry.Values) > 0 {
+			rule.regex, err = regexp.Compile(entry.Values[0])
+			if err != nil {
+				logger().Warnf("skipping rule #%s with invalid regex: %s", helpers.MdbIdToHuman(entry.ID), err.Error())
+				continue
+			}
+		}
+		newCache[entry.GuildID] = append(newCache[entry.GuildID], rule)
+	}
+
+	rulesCacheLock.Lock()
+	rulesCache = newCache
+	rulesCacheLock.Unlock()
+	return nil
+}
+
+func getRules(guildID string) []cachedRule {
+	rulesCacheLock.RLock()
+	defer rulesCacheLock.RUnlock()
+
+	return rulesCache[guildID]
+}
+
+// addToHistory adds a message to the history and returns the history of the author within historyMaxAge
+func addToHistory(guildID string, msg *discordgo.Message) []historyEntry {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	key := guildID + "-" + msg.Author.ID
+	entries := append(pruneHistory(history[key]), historyEntry{
+		content:   normalizeContent(msg.Content),
+		createdAt: time.Now(),
+	})
+	if len(entries) > historyMaxLength {
+		entries = entries[len(entries)-historyMaxLength:]
+	}
+	history[key] = entries
+
+	result := make([]historyEntry, len(entries))
+	copy(result, entries)
+	return result
+}
+
+// resetHistory stops counting the current history of the user for the rule, called after the rule fired
+func resetHistory(guildID, userID string, rule cachedRule) {
+	historyLock.Lock()
+	defer historyLock.Unlock()
+
+	historyResets[guildID+"-"+userID+"-"+string(rule.ID)] = time.Now()
+}
+
+// historyForRule returns the entries of the history that are newer than the last time the rule fired for the user
+func historyForRule(guildID, userID string, rule cachedRule, entries []historyEntry) []historyEntry {
+	historyLock.Lock()
+	resetAt, ok := historyResets[guildID+"-"+userID+"-"+string(rule.ID)]
+	historyLock.Unlock()
+	if !ok {
+		return entries
+	}
+
+	result := make([]historyEntry, 0)
+	for _, entry := range entries {
+		if entry.createdAt.After(resetAt) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func pruneHistory(entries []historyEntry) []historyEntry {
+	for i, entry := range entries {
+		if time.Since(entry.createdAt) <= historyMaxAge {
+			return entries[i:]
+		}
+	}
+	return nil
+}
+
+// cleanupHistoryLoop removes users that haven't written anything recently from the history
+func cleanupHistoryLoop() {
+	defer helpers.Recover()
+	defer func() {
+		go func() {
+			logger().Error("The cleanupHistoryLoop died. Please investigate! Will be restarted in 60 seconds")
+			time.Sleep(60 * time.Second)
+			cleanupHistoryLoop()
+		}()
+	}()
+
+	for {
+		time.Sleep(historyMaxAge)
+
+		historyLock.Lock()
+		for key, entries := range history {
+			entries = pruneHistory(entries)
+			if len(entries) <= 0 {
+				delete(history, key)
+				continue
+			}
+			history[key] = entries
+		}
+		for key, resetAt := range historyResets {
+			if time.Since(resetAt) > historyMaxAge {
+				delete(historyResets, key)
+			}
+		}
+		historyLock.Unlock()
+	}
+}
+
+func normalizeContent(content string) string {
+	return strings.ToLower(strings.Join(strings.Fields(content), " "))
+}
+
+// isExempt returns true if the channel or one of the roles of the author are exempt from the rule
+func (r *cachedRule) isExempt(channelID string, member *discordgo.Member) bool {
+	for _, exemptChannelID := range r.ExemptChannelIDs {
+		if exemptChannelID == channelID {
+			return true
+		}
+	}
+	if member != nil {
+		for _, exemptRoleID := range r.ExemptRoleIDs {
+			for _, roleID := range member.Roles {
+				if exemptRoleID == roleID {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// match checks the message against the rule, returns a description of the violation if it matches
+func (r *cachedRule) match(msg *discordgo.Message, recent []historyEntry) (matched bool, violation string) {
+	switch r.Type {
+	case models.AutomodRuleTypeMentions:
+		mentions := len(mentionRegex.FindAllString(msg.Content, -1))
+		if msg.MentionEveryone {
+			mentions++
+		}
+		if mentions > r.Threshold {
+			return true, fmt.Sprintf("%d mentions in one message", mentions)
+		}
+	case models.AutomodRuleTypeDuplicates:
+		if strings.TrimSpace(msg.Content) == "" {
+			return false, ""
+		}
+		content := normalizeContent(msg.Content)
+		var duplicates int
+		for _, entry := range recent {
+			if entry.content == content && time.Since(entry.createdAt) <= r.Period {
+				duplicates++
+			}
+		}
+		if duplicates > r.Threshold {
+			return true, fmt.Sprintf("%d identical messages within %s", duplicates, helpers.HumanizeDuration(r.Period))
+		}
+	case models.AutomodRuleTypeRate:
+		var messages int
+		for _, entry := range recent {
+			if time.Since(entry.createdAt) <= r.Period {
+				messages++
+			}
+		}
+		if messages > r.Threshold {
+			return true, fmt.Sprintf("%d messages within %s", messages, helpers.HumanizeDuration(r.Period))
+		}
+	case models.AutomodRuleTypeInvites:
+		for _, code := range helpers.ExtractInviteCodes(msg.Content) {
+			if len(r.Values) <= 0 {
+				return true, "posted invite " + code
+			}
+			for _, blockedCode := range r.Values {
+				if code == blockedCode {
+					return true, "posted blocked invite " + code
+				}
+			}
+		}
+	case models.AutomodRuleTypeLinksAllow:
+		for _, host := range extractLinkHosts(msg.Content) {
+			if !domainListContains(r.Values, host) {
+				return true, "posted link to " + host
+			}
+		}
+	case models.AutomodRuleTypeLinksDeny:
+		for _, host := range extractLinkHosts(msg.Content) {
+			if domainListContains(r.Values, host) {
+				return true, "posted blocked link to " + host
+			}
+		}
+	case models.AutomodRuleTypeRegex:
+		if r.regex != nil && r.regex.MatchString(msg.Content) {
+			return true, "message matched filter " + helpers.MdbIdToHuman(r.ID)
+		}
+	}
+
+	return false, ""
+}
+
+// extractLinkHosts returns the lowercase hosts of all links in the text, without www.
+func extractLinkHosts(text string) (hosts []string) {
+	for _, link := range linkRegex.FindAllString(text, -1) {
+		parsedLink, err := url.Parse(link)
+		if err != nil || parsedLink.Hostname() == "" {
+			continue
+		}
+		hosts = append(hosts, strings.TrimPrefix(strings.ToLower(parsedLink.Hostname()), "www."))
+	}
+	return hosts
+}
+
+// domainListContains returns true if host is one of the domains, or a subdomain of them
+func domainListContains(domains []string, host string) bool {
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
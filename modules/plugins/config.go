@@ -326,8 +326,11 @@ func (m *Config) actionStatus(args []string, in *discordgo.Message, out **discor
 	}
 
 	starboardText := "Disabled"
-	if guildConfig.StarboardChannelID != "" {
-		starboardText = "Enabled, in <#" + guildConfig.StarboardChannelID + ">"
+	if len(guildConfig.StarboardBoards) > 0 {
+		starboardText = "Enabled, in"
+		for _, starboard := range guildConfig.StarboardBoards {
+			starboardText += " <#" + starboard.ChannelID + ">"
+		}
 	}
 
 	chatlogText := "Enabled"
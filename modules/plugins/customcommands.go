@@ -135,19 +135,12 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 				return
 			}
 
-			var entryBucket models.CustomCommandsEntry
-			err = helpers.MdbOne(
-				helpers.MdbCollection(models.CustomCommandsTable).Find(bson.M{"guildid": channel.GuildID, "keyword": args[1]}),
-				&entryBucket,
-			)
-			if err == nil {
+			keywordInUse, err := cc.isKeywordInUse(channel.GuildID, args[1])
+			helpers.Relax(err)
+			if keywordInUse {
 				_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.customcommands.add-keyword-already-exists"))
 				helpers.Relax(err)
 				return
-			} else {
-				if !helpers.IsMdbNotFound(err) {
-					helpers.Relax(err)
-				}
 			}
 
 			var objectName string
@@ -196,6 +189,7 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 				Keyword:           args[1],
 				StorageObjectName: objectName,
 				Content:           content,
+				Template:          isCustomCommandTemplate(content),
 			}
 			_, err = helpers.MDbInsert(
 				models.CustomCommandsTable,
@@ -523,6 +517,7 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 			entryBucket.CreatedAt = time.Now().UTC()
 			entryBucket.Triggered = 0
 			entryBucket.Content = content
+			entryBucket.Template = isCustomCommandTemplate(content)
 			entryBucket.StorageFilename = ""
 			entryBucket.StorageObjectName = objectName
 			entryBucket.StorageHash = ""
@@ -630,6 +625,9 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 			author, err := helpers.GetUser(entryBucket.CreatedByUserID)
 			helpers.Relax(err)
 
+			guild, err := helpers.GetGuild(channel.GuildID)
+			helpers.Relax(err)
+
 			content, filename, data := cc.getCommandContent(entryBucket)
 			messageSend := &discordgo.MessageSend{
 				Embed: &discordgo.MessageEmbed{
@@ -642,6 +640,7 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 					},
 				},
 			}
+			messageSend.Embed.Fields = append(messageSend.Embed.Fields, cc.getSettingsFields(entryBucket, guild)...)
 			if data != nil && len(data) > 0 {
 				messageSend.Files = []*discordgo.File{
 					{
@@ -657,6 +656,10 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 			_, err = helpers.SendComplex(msg.ChannelID, messageSend)
 			helpers.Relax(err)
 			return
+		case "alias", "cooldown", "user-cooldown", "allow-role", "deny-role", "allow-channel", "deny-channel", "delete-invoking":
+			session.ChannelTyping(msg.ChannelID)
+			cc.actionSettings(args, msg)
+			return
 		case "import-json": // [p]command import-json (with json file attached)
 			helpers.RequireMod(msg, func() {
 				session.ChannelTyping(msg.ChannelID)
@@ -692,31 +695,54 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 				err = helpers.MDbIter(helpers.MdbCollection(models.CustomCommandsTable).Find(bson.M{"guildid": channel.GuildID}).Sort("keyword")).All(&entryBucket)
 				helpers.Relax(err)
 
+				guild, err := helpers.GetGuild(channel.GuildID)
+				helpers.Relax(err)
+
+				usedKeywords := make(map[string]bool)
+				for _, customCommand := range entryBucket {
+					usedKeywords[customCommand.Keyword] = true
+					for _, alias := range customCommand.Aliases {
+						usedKeywords[alias] = true
+					}
+				}
+
 				i := 0
 				for newCustomCommandName, newCustomCommandContent := range commandsContainer {
-					commandExists := false
-					for _, customCommand := range entryBucket {
-						if customCommand.Keyword == newCustomCommandName {
-							commandExists = true
-						}
-					}
-					if commandExists {
+					if usedKeywords[newCustomCommandName] {
 						helpers.SendMessage(msg.ChannelID, fmt.Sprintf("Command with the name `%s` already exists.", newCustomCommandName))
 						continue
 					}
 
-					newCustomCommandContentText := strings.TrimPrefix(strings.TrimSuffix(newCustomCommandContent.String(), "\""), "\"")
+					newEntry, err := cc.parseJsonEntry(newCustomCommandContent)
+					if err != nil {
+						helpers.SendMessage(msg.ChannelID, fmt.Sprintf("Unable to import custom command `%s`: `%s`", newCustomCommandName, err.Error()))
+						continue
+					}
+					usedKeywords[newCustomCommandName] = true
 
+					// skip aliases which are in use already, and roles or channels which are not on this server
+					var aliases []string
+					for _, alias := range newEntry.Aliases {
+						if usedKeywords[alias] || helpers.CommandExists(alias) {
+							continue
+						}
+						usedKeywords[alias] = true
+						aliases = append(aliases, alias)
+					}
+					newEntry.Aliases = aliases
+					newEntry.AllowedRoleIDs = cc.filterGuildRoleIDs(guild, newEntry.AllowedRoleIDs)
+					newEntry.DeniedRoleIDs = cc.filterGuildRoleIDs(guild, newEntry.DeniedRoleIDs)
+					newEntry.AllowedChannelIDs = cc.filterGuildChannelIDs(guild, newEntry.AllowedChannelIDs)
+					newEntry.DeniedChannelIDs = cc.filterGuildChannelIDs(guild, newEntry.DeniedChannelIDs)
+
+					newEntry.GuildID = channel.GuildID
+					newEntry.CreatedByUserID = msg.Author.ID
+					newEntry.CreatedAt = time.Now()
+					newEntry.Keyword = newCustomCommandName
+					newEntry.Template = isCustomCommandTemplate(newEntry.Content)
 					_, err = helpers.MDbInsert(
 						models.CustomCommandsTable,
-						models.CustomCommandsEntry{
-							GuildID:         channel.GuildID,
-							CreatedByUserID: msg.Author.ID,
-							CreatedAt:       time.Now(),
-							Triggered:       0,
-							Keyword:         newCustomCommandName,
-							Content:         newCustomCommandContentText,
-						},
+						newEntry,
 					)
 					helpers.Relax(err)
 
@@ -758,7 +784,7 @@ func (cc *CustomCommands) Action(command string, content string, msg *discordgo.
 
 				jsonObj := gabs.New()
 				for _, command := range entryBucket {
-					jsonObj.Set(command.Content, command.Keyword)
+					jsonObj.Set(cc.getJsonEntry(command), command.Keyword)
 				}
 				jsonObj.StringIndent("", "  ")
 
@@ -843,10 +869,32 @@ func (cc *CustomCommands) OnMessage(content string, msg *discordgo.Message, sess
 	prefix := helpers.GetPrefixForServer(channel.GuildID)
 
 	for i, customCommand := range customCommandsCache {
-		if customCommand.GuildID == channel.GuildID && prefix+customCommand.Keyword == content {
-			session.ChannelTyping(msg.ChannelID)
+		if customCommand.GuildID != channel.GuildID {
+			continue
+		}
+
+		args, matched := matchCustomCommand(customCommand, prefix, content)
+		if !matched {
+			continue
+		}
+
+		if !cc.isAllowedToRun(customCommand, msg, channel) || cc.cooldownRemaining(customCommand, msg.Author.ID) > 0 {
+			return
+		}
+
+		session.ChannelTyping(msg.ChannelID)
+		var messageSend *discordgo.MessageSend
+		if customCommand.Template {
+			messageSend, err = cc.renderCommand(customCommand, args, msg, channel)
+			if err != nil {
+				_, err = helpers.SendMessage(msg.ChannelID,
+					helpers.GetTextF("plugins.customcommands.template-error", err.Error()))
+				helpers.RelaxLog(err)
+				return
+			}
+		} else {
 			content, filename, data := cc.getCommandContent(customCommand)
-			messageSend := &discordgo.MessageSend{
+			messageSend = &discordgo.MessageSend{
 				Content: content,
 			}
 			if data != nil && len(data) > 0 {
@@ -857,32 +905,44 @@ func (cc *CustomCommands) OnMessage(content string, msg *discordgo.Message, sess
 					},
 				}
 			}
-			_, err = helpers.SendComplex(msg.ChannelID, messageSend)
-			if err != nil {
-				if errD, ok := err.(*discordgo.RESTError); ok {
-					if errD.Message.Code == discordgo.ErrCodeMissingPermissions {
-						return
-					}
+		}
+		_, err = helpers.SendComplex(msg.ChannelID, messageSend)
+		if err != nil {
+			if errD, ok := err.(*discordgo.RESTError); ok {
+				if errD.Message.Code == discordgo.ErrCodeMissingPermissions {
+					return
 				}
-				helpers.RelaxLog(err)
-				return
 			}
+			helpers.RelaxLog(err)
+			return
+		}
 
-			customCommandsCacheLock.Lock()
-			if len(customCommandsCache) > i {
-				customCommandsCache[i].Triggered += 1
-			}
-			customCommandsCacheLock.Unlock()
+		cc.startCooldowns(customCommand, msg.Author.ID)
 
-			// increase triggered in DB by one
-			err = helpers.MDbUpdate(models.CustomCommandsTable, customCommand.ID, bson.M{"$inc": bson.M{"triggered": 1}})
-			if err != nil && !helpers.IsMdbNotFound(err) {
-				helpers.RelaxLog(err)
+		if customCommand.DeleteInvoking {
+			err = session.ChannelMessageDelete(msg.ChannelID, msg.ID)
+			if err != nil {
+				if errD, ok := err.(*discordgo.RESTError); !ok || errD.Message == nil ||
+					(errD.Message.Code != discordgo.ErrCodeMissingPermissions && errD.Message.Code != discordgo.ErrCodeUnknownMessage) {
+					helpers.RelaxLog(err)
+				}
 			}
+		}
 
-			metrics.CustomCommandsTriggered.Add(1)
-			return
+		customCommandsCacheLock.Lock()
+		if len(customCommandsCache) > i {
+			customCommandsCache[i].Triggered += 1
+		}
+		customCommandsCacheLock.Unlock()
+
+		// increase triggered in DB by one
+		err = helpers.MDbUpdate(models.CustomCommandsTable, customCommand.ID, bson.M{"$inc": bson.M{"triggered": 1}})
+		if err != nil && !helpers.IsMdbNotFound(err) {
+			helpers.RelaxLog(err)
 		}
+
+		metrics.CustomCommandsTriggered.Add(1)
+		return
 	}
 }
 
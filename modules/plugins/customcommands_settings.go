@@ -0,0 +1,459 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Jeffail/gabs"
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	customCommandsCooldownKey     = "robyul2-discord:customcommands:cooldown:%s"
+	customCommandsUserCooldownKey = "robyul2-discord:customcommands:cooldown:%s:%s"
+)
+
+// customCommandsJsonEntry is a command with settings in import-json and export-json
+// commands without settings are exported as a plain string, like before
+type customCommandsJsonEntry struct {
+	Content           string   `json:"content"`
+	Aliases           []string `json:"aliases,omitempty"`
+	Cooldown          string   `json:"cooldown,omitempty"`
+	UserCooldown      string   `json:"user_cooldown,omitempty"`
+	AllowedRoleIDs    []string `json:"allowed_role_ids,omitempty"`
+	DeniedRoleIDs     []string `json:"denied_role_ids,omitempty"`
+	AllowedChannelIDs []string `json:"allowed_channel_ids,omitempty"`
+	DeniedChannelIDs  []string `json:"denied_channel_ids,omitempty"`
+	DeleteInvoking    bool     `json:"delete_invoking,omitempty"`
+}
+
+// getCustomCommand finds a command on the guild by keyword or alias
+func (cc *CustomCommands) getCustomCommand(guildID, name string) (entry models.CustomCommandsEntry, err error) {
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.CustomCommandsTable).Find(bson.M{"guildid": guildID, "$or": []bson.M{
+			{"keyword": name},
+			{"aliases": name},
+		}}),
+		&entry,
+	)
+	return entry, err
+}
+
+// isKeywordInUse returns true if a command on the guild uses the keyword already, as keyword or as alias
+func (cc *CustomCommands) isKeywordInUse(guildID, keyword string) (inUse bool, err error) {
+	count, err := helpers.MdbCount(models.CustomCommandsTable, bson.M{"guildid": guildID, "$or": []bson.M{
+		{"keyword": keyword},
+		{"aliases": keyword},
+	}})
+	return count > 0, err
+}
+
+// matchCustomCommand checks if the content calls the command by its keyword or one of the aliases
+func matchCustomCommand(customCommand models.CustomCommandsEntry, prefix, content string) (args []string, matched bool) {
+	for _, keyword := range append([]string{customCommand.Keyword}, customCommand.Aliases...) {
+		if prefix+keyword == content {
+			return nil, true
+		}
+		// only templates accept arguments
+		if customCommand.Template && strings.HasPrefix(content, prefix+keyword+" ") {
+			return strings.Fields(strings.TrimPrefix(content, prefix+keyword)), true
+		}
+	}
+	return nil, false
+}
+
+// isAllowedToRun checks the role and channel restrictions of the command
+func (cc *CustomCommands) isAllowedToRun(customCommand models.CustomCommandsEntry, msg *discordgo.Message, channel *discordgo.Channel) bool {
+	if sliceContains(customCommand.DeniedChannelIDs, channel.ID) {
+		return false
+	}
+	if len(customCommand.AllowedChannelIDs) > 0 && !sliceContains(customCommand.AllowedChannelIDs, channel.ID) {
+		return false
+	}
+
+	if len(customCommand.AllowedRoleIDs) <= 0 && len(customCommand.DeniedRoleIDs) <= 0 {
+		return true
+	}
+	member, err := helpers.GetGuildMemberWithoutApi(channel.GuildID, msg.Author.ID)
+	if err != nil {
+		return false
+	}
+	var hasAllowedRole bool
+	for _, roleID := range member.Roles {
+		if sliceContains(customCommand.DeniedRoleIDs, roleID) {
+			return false
+		}
+		if sliceContains(customCommand.AllowedRoleIDs, roleID) {
+			hasAllowedRole = true
+		}
+	}
+	return len(customCommand.AllowedRoleIDs) <= 0 || hasAllowedRole
+}
+
+// cooldownRemaining returns how long the user has to wait until the command can be used again
+func (cc *CustomCommands) cooldownRemaining(customCommand models.CustomCommandsEntry, userID string) (remaining time.Duration) {
+	redis := cache.GetRedisClient()
+	if customCommand.Cooldown > 0 {
+		globalRemaining, err := redis.PTTL(fmt.Sprintf(customCommandsCooldownKey, customCommand.ID.Hex())).Result()
+		if err == nil && globalRemaining > remaining {
+			remaining = globalRemaining
+		}
+	}
+	if customCommand.UserCooldown > 0 {
+		userRemaining, err := redis.PTTL(fmt.Sprintf(customCommandsUserCooldownKey, customCommand.ID.Hex(), userID)).Result()
+		if err == nil && userRemaining > remaining {
+			remaining = userRemaining
+		}
+	}
+	return remaining
+}
+
+// startCooldowns starts the cooldowns of the command after the user used it
+func (cc *CustomCommands) startCooldowns(customCommand models.CustomCommandsEntry, userID string) {
+	redis := cache.GetRedisClient()
+	if customCommand.Cooldown > 0 {
+		err := redis.Set(fmt.Sprintf(customCommandsCooldownKey, customCommand.ID.Hex()), 1, customCommand.Cooldown).Err()
+		helpers.RelaxLog(err)
+	}
+	if customCommand.UserCooldown > 0 {
+		err := redis.Set(fmt.Sprintf(customCommandsUserCooldownKey, customCommand.ID.Hex(), userID), 1, customCommand.UserCooldown).Err()
+		helpers.RelaxLog(err)
+	}
+}
+
+// actionSettings changes the settings of a command
+// [p]commands alias <command name> <alias>
+// [p]commands cooldown|user-cooldown <command name> <cooldown|off>
+// [p]commands allow-role|deny-role <command name> <role>
+// [p]commands allow-channel|deny-channel <command name> <#channel>
+// [p]commands delete-invoking <command name>
+func (cc *CustomCommands) actionSettings(args []string, msg *discordgo.Message) {
+	if len(args) < 2 || (args[0] != "delete-invoking" && len(args) < 3) {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.Relax(err)
+		return
+	}
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	entryBucket, err := cc.getCustomCommand(channel.GuildID, args[1])
+	if helpers.IsMdbNotFound(err) {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.customcommands.edit-not-found"))
+		helpers.Relax(err)
+		return
+	}
+	helpers.Relax(err)
+
+	if !cc.canAddCommand(channel.GuildID, msg.Author.ID, &entryBucket) {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("mod.no_permission"))
+		return
+	}
+
+	var change models.ElasticEventlogChange
+	var successText string
+	switch args[0] {
+	case "alias":
+		alias := args[2]
+		oldAliases := strings.Join(entryBucket.Aliases, ", ")
+		if sliceContains(entryBucket.Aliases, alias) {
+			entryBucket.Aliases = sliceWithout(entryBucket.Aliases, alias)
+			successText = helpers.GetTextF("plugins.customcommands.alias-removed", alias)
+		} else {
+			inUse, err := cc.isKeywordInUse(channel.GuildID, alias)
+			helpers.Relax(err)
+			if inUse || helpers.CommandExists(alias) {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.customcommands.add-keyword-already-exists"))
+				helpers.Relax(err)
+				return
+			}
+			entryBucket.Aliases = append(entryBucket.Aliases, alias)
+			successText = helpers.GetTextF("plugins.customcommands.alias-added", alias)
+		}
+		change = models.ElasticEventlogChange{
+			Key:      "command_aliases",
+			OldValue: oldAliases,
+			NewValue: strings.Join(entryBucket.Aliases, ", "),
+		}
+	case "cooldown", "user-cooldown":
+		var cooldown time.Duration
+		if strings.ToLower(args[2]) != "off" {
+			cooldown, err = router.ParseDuration(args[2])
+			if err != nil || cooldown <= 0 {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.customcommands.cooldown-invalid"))
+				helpers.Relax(err)
+				return
+			}
+		}
+		target := &entryBucket.Cooldown
+		change.Key = "command_cooldown"
+		if args[0] == "user-cooldown" {
+			target = &entryBucket.UserCooldown
+			change.Key = "command_user_cooldown"
+		}
+		change.OldValue = target.String()
+		change.NewValue = cooldown.String()
+		*target = cooldown
+		if cooldown > 0 {
+			successText = helpers.GetTextF("plugins.customcommands."+args[0]+"-set", helpers.HumanizeDuration(cooldown))
+		} else {
+			successText = helpers.GetText("plugins.customcommands." + args[0] + "-removed")
+		}
+	case "allow-role", "deny-role":
+		guild, err := helpers.GetGuild(channel.GuildID)
+		helpers.Relax(err)
+		roleText := strings.TrimSuffix(strings.TrimPrefix(strings.Join(args[2:], " "), "<@&"), ">")
+		var targetRole *discordgo.Role
+		for _, role := range guild.Roles {
+			if role.ID == roleText || strings.ToLower(role.Name) == strings.ToLower(roleText) {
+				targetRole = role
+			}
+		}
+		if targetRole == nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.Relax(err)
+			return
+		}
+		list, other := &entryBucket.AllowedRoleIDs, &entryBucket.DeniedRoleIDs
+		change.Key = "command_allowed_roles"
+		if args[0] == "deny-role" {
+			list, other = other, list
+			change.Key = "command_denied_roles"
+		}
+		change.OldValue = strings.Join(*list, ", ")
+		toggleCustomCommandsSetting(list, other, targetRole.ID)
+		change.NewValue = strings.Join(*list, ", ")
+		successText = helpers.GetTextF("plugins.customcommands."+args[0]+"-updated", cc.getRolesText(guild, *list))
+	case "allow-channel", "deny-channel":
+		targetChannel, err := helpers.GetChannelFromMention(msg, args[2])
+		if err != nil || targetChannel.GuildID != channel.GuildID {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.Relax(err)
+			return
+		}
+		list, other := &entryBucket.AllowedChannelIDs, &entryBucket.DeniedChannelIDs
+		change.Key = "command_allowed_channels"
+		if args[0] == "deny-channel" {
+			list, other = other, list
+			change.Key = "command_denied_channels"
+		}
+		change.OldValue = strings.Join(*list, ", ")
+		toggleCustomCommandsSetting(list, other, targetChannel.ID)
+		change.NewValue = strings.Join(*list, ", ")
+		successText = helpers.GetTextF("plugins.customcommands."+args[0]+"-updated", cc.getChannelsText(*list))
+	case "delete-invoking":
+		entryBucket.DeleteInvoking = !entryBucket.DeleteInvoking
+		change = models.ElasticEventlogChange{
+			Key:      "command_delete_invoking",
+			OldValue: helpers.StoreBoolAsString(!entryBucket.DeleteInvoking),
+			NewValue: helpers.StoreBoolAsString(entryBucket.DeleteInvoking),
+		}
+		if entryBucket.DeleteInvoking {
+			successText = helpers.GetText("plugins.customcommands.delete-invoking-enabled")
+		} else {
+			successText = helpers.GetText("plugins.customcommands.delete-invoking-disabled")
+		}
+	}
+
+	err = helpers.MDbUpdate(models.CustomCommandsTable, entryBucket.ID, entryBucket)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulCommandsUpdate, "",
+		[]models.ElasticEventlogChange{change},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "command_keyword",
+				Value: entryBucket.Keyword,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.Relax(err)
+
+	customCommandsCacheLock.Lock()
+	defer customCommandsCacheLock.Unlock()
+	customCommandsCache, err = cc.getAllCustomCommands()
+	helpers.Relax(err)
+}
+
+// getSettingsFields returns embed fields for all settings of the command which are set
+func (cc *CustomCommands) getSettingsFields(customCommand models.CustomCommandsEntry, guild *discordgo.Guild) (fields []*discordgo.MessageEmbedField) {
+	if len(customCommand.Aliases) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Aliases", Value: "`" + strings.Join(customCommand.Aliases, "`, `") + "`"})
+	}
+	if customCommand.Cooldown > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Cooldown", Value: helpers.HumanizeDuration(customCommand.Cooldown), Inline: true})
+	}
+	if customCommand.UserCooldown > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Cooldown per User", Value: helpers.HumanizeDuration(customCommand.UserCooldown), Inline: true})
+	}
+	if len(customCommand.AllowedRoleIDs) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Allowed Roles", Value: cc.getRolesText(guild, customCommand.AllowedRoleIDs)})
+	}
+	if len(customCommand.DeniedRoleIDs) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Denied Roles", Value: cc.getRolesText(guild, customCommand.DeniedRoleIDs)})
+	}
+	if len(customCommand.AllowedChannelIDs) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Allowed Channels", Value: cc.getChannelsText(customCommand.AllowedChannelIDs)})
+	}
+	if len(customCommand.DeniedChannelIDs) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Denied Channels", Value: cc.getChannelsText(customCommand.DeniedChannelIDs)})
+	}
+	if customCommand.DeleteInvoking {
+		fields = append(fields, &discordgo.MessageEmbedField{Name: "Deletes Invoking Message", Value: "Yes"})
+	}
+	return fields
+}
+
+// getRolesText lists role names, without mentioning them
+func (cc *CustomCommands) getRolesText(guild *discordgo.Guild, roleIDs []string) (text string) {
+	if len(roleIDs) <= 0 {
+		return "None"
+	}
+	names := make([]string, 0, len(roleIDs))
+	for _, roleID := range roleIDs {
+		name := "N/A (#" + roleID + ")"
+		for _, role := range guild.Roles {
+			if role.ID == roleID {
+				name = role.Name
+			}
+		}
+		names = append(names, "`"+name+"`")
+	}
+	return strings.Join(names, ", ")
+}
+
+func (cc *CustomCommands) getChannelsText(channelIDs []string) (text string) {
+	if len(channelIDs) <= 0 {
+		return "None"
+	}
+	return "<#" + strings.Join(channelIDs, ">, <#") + ">"
+}
+
+// getJsonEntry returns the value of the command in export-json
+func (cc *CustomCommands) getJsonEntry(customCommand models.CustomCommandsEntry) (value interface{}) {
+	entry := customCommandsJsonEntry{
+		Content:           customCommand.Content,
+		Aliases:           customCommand.Aliases,
+		AllowedRoleIDs:    customCommand.AllowedRoleIDs,
+		DeniedRoleIDs:     customCommand.DeniedRoleIDs,
+		AllowedChannelIDs: customCommand.AllowedChannelIDs,
+		DeniedChannelIDs:  customCommand.DeniedChannelIDs,
+		DeleteInvoking:    customCommand.DeleteInvoking,
+	}
+	if customCommand.Cooldown > 0 {
+		entry.Cooldown = customCommand.Cooldown.String()
+	}
+	if customCommand.UserCooldown > 0 {
+		entry.UserCooldown = customCommand.UserCooldown.String()
+	}
+	if len(entry.Aliases) <= 0 && entry.Cooldown == "" && entry.UserCooldown == "" &&
+		len(entry.AllowedRoleIDs) <= 0 && len(entry.DeniedRoleIDs) <= 0 &&
+		len(entry.AllowedChannelIDs) <= 0 && len(entry.DeniedChannelIDs) <= 0 && !entry.DeleteInvoking {
+		return customCommand.Content
+	}
+	return entry
+}
+
+// parseJsonEntry reads a command from import-json, values can be plain strings or objects with settings
+func (cc *CustomCommands) parseJsonEntry(value *gabs.Container) (entry models.CustomCommandsEntry, err error) {
+	if content, ok := value.Data().(string); ok {
+		entry.Content = content
+		return entry, nil
+	}
+	if _, ok := value.Data().(map[string]interface{}); !ok {
+		entry.Content = strings.TrimPrefix(strings.TrimSuffix(value.String(), "\""), "\"")
+		return entry, nil
+	}
+
+	var jsonEntry customCommandsJsonEntry
+	err = json.Unmarshal(value.Bytes(), &jsonEntry)
+	if err != nil {
+		return entry, err
+	}
+	entry.Content = jsonEntry.Content
+	entry.Aliases = jsonEntry.Aliases
+	entry.AllowedRoleIDs = jsonEntry.AllowedRoleIDs
+	entry.DeniedRoleIDs = jsonEntry.DeniedRoleIDs
+	entry.AllowedChannelIDs = jsonEntry.AllowedChannelIDs
+	entry.DeniedChannelIDs = jsonEntry.DeniedChannelIDs
+	entry.DeleteInvoking = jsonEntry.DeleteInvoking
+	if jsonEntry.Cooldown != "" {
+		entry.Cooldown, err = time.ParseDuration(jsonEntry.Cooldown)
+		if err != nil {
+			return entry, err
+		}
+	}
+	if jsonEntry.UserCooldown != "" {
+		entry.UserCooldown, err = time.ParseDuration(jsonEntry.UserCooldown)
+		if err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// filterGuildRoleIDs removes all IDs of roles which are not on the guild
+func (cc *CustomCommands) filterGuildRoleIDs(guild *discordgo.Guild, roleIDs []string) (result []string) {
+	for _, roleID := range roleIDs {
+		for _, role := range guild.Roles {
+			if role.ID == roleID {
+				result = append(result, roleID)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// filterGuildChannelIDs removes all IDs of channels which are not on the guild
+func (cc *CustomCommands) filterGuildChannelIDs(guild *discordgo.Guild, channelIDs []string) (result []string) {
+	for _, channelID := range channelIDs {
+		for _, channel := range guild.Channels {
+			if channel.ID == channelID {
+				result = append(result, channelID)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// toggleCustomCommandsSetting adds the id to list, or removes it if it is in the list already
+// the id is removed from other, a role or channel can not be allowed and denied at the same time
+func toggleCustomCommandsSetting(list, other *[]string, id string) {
+	*other = sliceWithout(*other, id)
+	if sliceContains(*list, id) {
+		*list = sliceWithout(*list, id)
+		return
+	}
+	*list = append(*list, id)
+}
+
+func sliceContains(list []string, item string) bool {
+	for _, listItem := range list {
+		if listItem == item {
+			return true
+		}
+	}
+	return false
+}
+
+func sliceWithout(list []string, item string) (result []string) {
+	for _, listItem := range list {
+		if listItem != item {
+			result = append(result, listItem)
+		}
+	}
+	return result
+}
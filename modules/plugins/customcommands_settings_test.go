@@ -0,0 +1,36 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Seklfreak/Robyul2/models"
+)
+
+func TestMatchCustomCommand(t *testing.T) {
+	command := models.CustomCommandsEntry{Keyword: "hi", Aliases: []string{"hello"}}
+	template := command
+	template.Template = true
+
+	for _, testCase := range []struct {
+		command  models.CustomCommandsEntry
+		content  string
+		args     []string
+		expected bool
+	}{
+		{command, "_hi", nil, true},
+		{command, "_hello", nil, true},
+		{command, "_hi there", nil, false},
+		{command, "_hit", nil, false},
+		{command, "hi", nil, false},
+		{template, "_hello there  you", []string{"there", "you"}, true},
+		{template, "_hi", nil, true},
+		{template, "_hit", nil, false},
+	} {
+		args, matched := matchCustomCommand(testCase.command, "_", testCase.content)
+		if matched != testCase.expected || strings.Join(args, " ") != strings.Join(testCase.args, " ") {
+			t.Fatalf("matchCustomCommand(%q) = %q, %v, expected %q, %v",
+				testCase.content, args, matched, testCase.args, testCase.expected)
+		}
+	}
+}
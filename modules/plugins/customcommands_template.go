@@ -0,0 +1,270 @@
+package plugins
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	customCommandsTemplateTimeout   = 250 * time.Millisecond
+	customCommandsTemplateMaxOutput = 4000
+	customCommandsTemplateMaxDepth  = 5
+	customCommandsTemplateEmbedTag  = "{embed}"
+)
+
+var (
+	errCustomCommandsTemplateTimeout   = errors.New("rendering took too long")
+	errCustomCommandsTemplateTooLong   = errors.New("the output is too long")
+	errCustomCommandsTemplateTooDeep   = errors.New("too many nested choose blocks")
+	customCommandsTemplateTagRegex     = regexp.MustCompile(`\{(args|arg[0-9]+|choose:|counter|embed|USER_[A-Z]+|GUILD_[A-Z]+|CHANNEL_[A-Z]+)`)
+	customCommandsTemplateCounterRegex = regexp.MustCompile(`[^a-z0-9_\-]+`)
+)
+
+// customCommandsTemplate renders custom command templates, see isCustomCommandTemplate
+// {args}, {arg1}, {arg2}, …			: the arguments the command has been called with
+// {USER_MENTION}, {GUILD_NAME}, …		: information about the author, the server and the channel
+// {choose:a|b|c}						: one of the options by random, options can contain other tags
+// {counter}, {counter:name}			: increments the persistent counter of the command by one and shows it
+// {embed} at the start					: the output is parsed as embed code, see helpers.ParseEmbedCode
+type customCommandsTemplate struct {
+	args     []string
+	values   map[string]string
+	counter  func(name string) (value int, err error)
+	counters map[string]int
+	deadline time.Time
+	output   int
+}
+
+// isCustomCommandTemplate returns true if the content uses any template tags
+func isCustomCommandTemplate(content string) bool {
+	return customCommandsTemplateTagRegex.MatchString(content)
+}
+
+// render renders the source, returns an error if it exceeds the time or output limits
+func (t *customCommandsTemplate) render(source string) (result string, err error) {
+	t.deadline = time.Now().Add(customCommandsTemplateTimeout)
+	t.counters = make(map[string]int)
+	t.output = 0
+	return t.renderBlock(source, 0)
+}
+
+func (t *customCommandsTemplate) renderBlock(source string, depth int) (result string, err error) {
+	if depth > customCommandsTemplateMaxDepth {
+		return "", errCustomCommandsTemplateTooDeep
+	}
+
+	var output strings.Builder
+	for len(source) > 0 {
+		if time.Now().After(t.deadline) {
+			return "", errCustomCommandsTemplateTimeout
+		}
+
+		start := strings.Index(source, "{")
+		if start < 0 {
+			err = t.write(&output, source, depth)
+			return output.String(), err
+		}
+		err = t.write(&output, source[:start], depth)
+		if err != nil {
+			return "", err
+		}
+
+		end := findClosingBrace(source, start)
+		if end < 0 {
+			// { without a matching } is just text
+			err = t.write(&output, source[start:], depth)
+			return output.String(), err
+		}
+
+		value, err := t.renderTag(source[start+1:end], depth)
+		if err != nil {
+			return "", err
+		}
+		err = t.write(&output, value, depth)
+		if err != nil {
+			return "", err
+		}
+
+		source = source[end+1:]
+	}
+	return output.String(), nil
+}
+
+// renderTag renders the tag between { and }, unknown tags are kept as they are
+func (t *customCommandsTemplate) renderTag(tag string, depth int) (result string, err error) {
+	switch {
+	case tag == "args":
+		return strings.Join(t.args, " "), nil
+	case strings.HasPrefix(tag, "arg"):
+		n, err := strconv.Atoi(strings.TrimPrefix(tag, "arg"))
+		if err != nil || n < 1 {
+			break
+		}
+		if n > len(t.args) {
+			return "", nil
+		}
+		return t.args[n-1], nil
+	case strings.HasPrefix(tag, "choose:"):
+		options := splitTopLevel(strings.TrimPrefix(tag, "choose:"), '|')
+		return t.renderBlock(options[rand.Intn(len(options))], depth+1)
+	case tag == "counter" || strings.HasPrefix(tag, "counter:"):
+		name := customCommandsTemplateCounterRegex.ReplaceAllString(strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(tag, "counter"), ":")), "")
+		if name == "" {
+			name = "default"
+		}
+		// every counter is only incremented once per call
+		if value, ok := t.counters[name]; ok {
+			return strconv.Itoa(value), nil
+		}
+		value, err := t.counter(name)
+		if err != nil {
+			return "", err
+		}
+		t.counters[name] = value
+		return strconv.Itoa(value), nil
+	default:
+		if value, ok := t.values[tag]; ok {
+			return value, nil
+		}
+	}
+
+	// unknown tags are not rendered
+	return "{" + tag + "}", nil
+}
+
+// write adds text to the output of a block, only the top level block counts towards the output limit,
+// the output of nested blocks is counted once it is written to the top level block
+func (t *customCommandsTemplate) write(output *strings.Builder, text string, depth int) (err error) {
+	if depth == 0 {
+		t.output += len(text)
+		if t.output > customCommandsTemplateMaxOutput {
+			return errCustomCommandsTemplateTooLong
+		}
+	}
+	output.WriteString(text)
+	return nil
+}
+
+// findClosingBrace returns the index of the } matching the { at start, or -1
+func findClosingBrace(text string, start int) int {
+	var level int
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			level++
+		case '}':
+			level--
+			if level == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits the text at sep, but not inside of nested {}
+func splitTopLevel(text string, sep byte) (parts []string) {
+	var level, last int
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			level++
+		case '}':
+			level--
+		case sep:
+			if level == 0 {
+				parts = append(parts, text[last:i])
+				last = i + 1
+			}
+		}
+	}
+	return append(parts, text[last:])
+}
+
+// renderCommand renders a template command called with args in the channel
+func (cc *CustomCommands) renderCommand(customCommand models.CustomCommandsEntry, args []string,
+	msg *discordgo.Message, channel *discordgo.Channel) (messageSend *discordgo.MessageSend, err error) {
+	guild, err := helpers.GetGuild(channel.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	template := &customCommandsTemplate{
+		args: args,
+		values: map[string]string{
+			"USER_USERNAME":      msg.Author.Username,
+			"USER_ID":            msg.Author.ID,
+			"USER_DISCRIMINATOR": msg.Author.Discriminator,
+			"USER_MENTION":       msg.Author.Mention(),
+			"USER_AVATARURL":     msg.Author.AvatarURL(""),
+			"GUILD_NAME":         guild.Name,
+			"GUILD_ID":           guild.ID,
+			"GUILD_MEMBERCOUNT":  strconv.Itoa(guild.MemberCount),
+			"CHANNEL_NAME":       channel.Name,
+			"CHANNEL_ID":         channel.ID,
+			"CHANNEL_MENTION":    channel.Mention(),
+		},
+		counter: func(name string) (value int, err error) {
+			return cc.incrementCounter(customCommand, name)
+		},
+	}
+
+	source := customCommand.Content
+	isEmbed := strings.HasPrefix(source, customCommandsTemplateEmbedTag)
+	source = strings.TrimPrefix(source, customCommandsTemplateEmbedTag)
+
+	content, err := template.render(source)
+	if err != nil {
+		return nil, err
+	}
+
+	messageSend = &discordgo.MessageSend{Content: content}
+	if isEmbed {
+		messageSend.Content, messageSend.Embed, err = helpers.ParseEmbedCode(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// attach the uploaded file, if there is one
+	fileCommand := customCommand
+	fileCommand.Content = ""
+	fileContent, filename, data := cc.getCommandContent(fileCommand)
+	if fileContent != "" {
+		messageSend.Content = strings.TrimSpace(messageSend.Content + "\n" + fileContent)
+	}
+	if data != nil && len(data) > 0 {
+		messageSend.Files = []*discordgo.File{
+			{
+				Name:   filename,
+				Reader: bytes.NewReader(data),
+			},
+		}
+	}
+
+	return messageSend, nil
+}
+
+// incrementCounter increments a persistent counter of the command by one, and returns the new value
+func (cc *CustomCommands) incrementCounter(customCommand models.CustomCommandsEntry, name string) (value int, err error) {
+	var entry models.CustomCommandsEntry
+	_, err = helpers.MdbCollection(models.CustomCommandsTable).FindId(customCommand.ID).Apply(mgo.Change{
+		Update:    bson.M{"$inc": bson.M{"counters." + name: 1}},
+		ReturnNew: true,
+	}, &entry)
+	if err != nil {
+		return 0, err
+	}
+	return entry.Counters[name], nil
+}
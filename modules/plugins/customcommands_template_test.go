@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestTemplate(args ...string) *customCommandsTemplate {
+	return &customCommandsTemplate{
+		args:   args,
+		values: map[string]string{"USER_USERNAME": "robyul"},
+		counter: func(name string) (value int, err error) {
+			return len(name), nil
+		},
+	}
+}
+
+func TestCustomCommandsTemplateRender(t *testing.T) {
+	for source, expected := range map[string]string{
+		"hello {USER_USERNAME}":                    "hello robyul",
+		"{arg1} and {arg2}, {arg3}!":               "first and second, !",
+		"all: {args}":                              "all: first second",
+		"{counter} {counter:abc} {counter}":        "7 3 7",
+		"{choose:a}{choose:{choose:b}}":            "ab",
+		"{unknown} {arg0} {":                       "{unknown} {arg0} {",
+		"{choose:{USER_USERNAME}|{USER_USERNAME}}": "robyul",
+	} {
+		result, err := newTestTemplate("first", "second").render(source)
+		if err != nil || result != expected {
+			t.Fatalf("customCommandsTemplate.render(%q) = %q, %v, expected %q", source, result, err, expected)
+		}
+	}
+
+	_, err := newTestTemplate().render("{choose:{choose:{choose:{choose:{choose:{choose:a}}}}}}")
+	if err != errCustomCommandsTemplateTooDeep {
+		t.Fatalf("customCommandsTemplate.render() accepted too many nested choose blocks")
+	}
+
+	_, err = newTestTemplate().render(strings.Repeat("a", customCommandsTemplateMaxOutput+1))
+	if err != errCustomCommandsTemplateTooLong {
+		t.Fatalf("customCommandsTemplate.render() accepted a too long output")
+	}
+
+	// output of nested blocks is only counted once
+	nested := strings.Repeat("a", customCommandsTemplateMaxOutput/2)
+	result, err := newTestTemplate().render("{choose:{choose:" + nested + "}}")
+	if err != nil || result != nested {
+		t.Fatalf("customCommandsTemplate.render() counted the output of nested blocks more than once: %v", err)
+	}
+}
+
+func TestFindClosingBrace(t *testing.T) {
+	for text, expected := range map[string]int{
+		"{a}":         2,
+		"{a{b}c}d":    6,
+		"{a{b}":       -1,
+		"x{}":         2,
+		"{choose:{}}": 10,
+	} {
+		if end := findClosingBrace(text, strings.Index(text, "{")); end != expected {
+			t.Fatalf("findClosingBrace(%q) returned %d, expected %d", text, end, expected)
+		}
+	}
+}
+
+func TestSplitTopLevel(t *testing.T) {
+	for text, expected := range map[string][]string{
+		"a|b|c":          {"a", "b", "c"},
+		"a|{choose:b|c}": {"a", "{choose:b|c}"},
+		"":               {""},
+		"a||":            {"a", "", ""},
+	} {
+		parts := splitTopLevel(text, '|')
+		if strings.Join(parts, "\n") != strings.Join(expected, "\n") || len(parts) != len(expected) {
+			t.Fatalf("splitTopLevel(%q) returned %q, expected %q", text, parts, expected)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/modules/plugins/modmail"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 	"github.com/sirupsen/logrus"
@@ -182,6 +183,10 @@ func (dm *DM) OnMessage(session *discordgo.Session, message *discordgo.MessageCr
 	}
 
 	response := dm.DmResponse(message.Message)
+	if modmail.OnDirectMessage(message.Message, response != nil) {
+		return
+	}
+
 	if response != nil {
 		helpers.SendComplex(message.ChannelID, response)
 	}
@@ -0,0 +1,250 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/shardmanager"
+	"github.com/bwmarrin/discordgo"
+)
+
+type Feeds struct{}
+
+func (f *Feeds) Commands() []string {
+	return []string{
+		"feeds",
+	}
+}
+
+func (f *Feeds) Init(session *shardmanager.Manager) {
+}
+
+func (f *Feeds) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+	if !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, helpers.ModulePermFeeds) {
+		return
+	}
+
+	args := strings.Fields(content)
+	if len(args) <= 0 {
+		f.actionList(msg, session)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "list": // [p]feeds list
+		f.actionList(msg, session)
+	case "template": // [p]feeds template <id> [<embed code>]
+		helpers.RequireMod(msg, func() {
+			f.actionTemplate(args, content, msg)
+		})
+	case "webhook": // [p]feeds webhook <id>
+		helpers.RequireMod(msg, func() {
+			f.actionWebhook(args, msg)
+		})
+	default:
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+func (f *Feeds) actionList(msg *discordgo.Message, session *discordgo.Session) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var resultMessage string
+	var total int
+	for _, source := range helpers.GetFeedSources() {
+		feeds, err := source.GuildFeeds(channel.GuildID)
+		if err != nil {
+			helpers.RelaxLog(err)
+			resultMessage += helpers.GetTextF("plugins.feeds.list-source-error", source.Name()) + "\n"
+			continue
+		}
+		if len(feeds) <= 0 {
+			continue
+		}
+
+		resultMessage += fmt.Sprintf("**%s**\n", source.Name())
+		for _, feed := range feeds {
+			var specialText string
+			if feed.MentionRoleID != "" {
+				role, err := session.State.Role(channel.GuildID, feed.MentionRoleID)
+				if err == nil {
+					specialText += fmt.Sprintf(" mentioning `@%s`", role.Name)
+				} else {
+					specialText += " mentioning N/A"
+				}
+			}
+			if feed.Settings.EmbedTemplate != "" {
+				specialText += " with a custom template"
+			}
+			if feed.Settings.PostAsWebhook {
+				specialText += " as webhook"
+			}
+			resultMessage += fmt.Sprintf("`%s`: `%s` posting to <#%s>%s\n",
+				feed.ID, feed.Name, feed.ChannelID, specialText)
+		}
+		total += len(feeds)
+	}
+
+	if total <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.feeds.list-none"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	resultMessage += helpers.GetTextF("plugins.feeds.list-sum", total)
+	for _, resultPage := range helpers.Pagify(resultMessage, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, resultPage)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// actionTemplate sets the template of the feed, or resets it to the default message if none is given
+func (f *Feeds) actionTemplate(args []string, content string, msg *discordgo.Message) {
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	source, feed, ok := f.findFeed(msg, args[1])
+	if !ok {
+		return
+	}
+
+	template := strings.TrimSpace(strings.Replace(content, strings.Join(args[:2], " "), "", 1))
+	if helpers.IsEmbedCode(template) {
+		_, _, err := helpers.ParseEmbedCode(template)
+		if err != nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.feeds.template-invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	settings := feed.Settings
+	settings.EmbedTemplate = template
+	f.setSettings(msg, source, feed, settings)
+
+	if template == "" {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.template-reset-success", feed.Name))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.template-set-success", feed.Name))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// actionWebhook toggles posting the feed through a webhook
+func (f *Feeds) actionWebhook(args []string, msg *discordgo.Message) {
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	source, feed, ok := f.findFeed(msg, args[1])
+	if !ok {
+		return
+	}
+
+	settings := feed.Settings
+	settings.PostAsWebhook = !settings.PostAsWebhook
+
+	if settings.PostAsWebhook {
+		channel, err := helpers.GetChannel(feed.ChannelID)
+		if err == nil {
+			_, err = helpers.GetWebhook(channel.GuildID, channel.ID)
+		}
+		if err != nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.webhook-no-permission", feed.ChannelID))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	f.setSettings(msg, source, feed, settings)
+
+	if settings.PostAsWebhook {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.webhook-enabled", feed.Name))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.webhook-disabled", feed.Name))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// findFeed returns the feed with the ID on the current guild, and sends an error message if it doesn't exist or can't be customized
+func (f *Feeds) findFeed(msg *discordgo.Message, feedID string) (source helpers.FeedSettingsSource, feed helpers.FeedInfo, ok bool) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	for _, feedSource := range helpers.GetFeedSources() {
+		feeds, err := feedSource.GuildFeeds(channel.GuildID)
+		helpers.Relax(err)
+
+		for _, guildFeed := range feeds {
+			if guildFeed.ID != feedID {
+				continue
+			}
+
+			source, ok = feedSource.(helpers.FeedSettingsSource)
+			if !ok {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.settings-not-supported", feedSource.Name()))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return nil, guildFeed, false
+			}
+			return source, guildFeed, true
+		}
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.feeds.not-found"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	return nil, feed, false
+}
+
+func (f *Feeds) setSettings(msg *discordgo.Message, source helpers.FeedSettingsSource, feed helpers.FeedInfo, settings helpers.FeedSettings) {
+	err := source.SetFeedSettings(feed.ID, settings)
+	helpers.Relax(err)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, feed.ID,
+		models.EventlogTargetTypeRobyulFeed, msg.Author.ID,
+		models.EventlogTypeRobyulFeedUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "feed_embedtemplate",
+				OldValue: feed.Settings.EmbedTemplate,
+				NewValue: settings.EmbedTemplate,
+			},
+			{
+				Key:      "feed_postaswebhook",
+				OldValue: helpers.StoreBoolAsString(feed.Settings.PostAsWebhook),
+				NewValue: helpers.StoreBoolAsString(settings.PostAsWebhook),
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "feed_source",
+				Value: source.Name(),
+			},
+			{
+				Key:   "feed_name",
+				Value: feed.Name,
+			},
+			{
+				Key:   "feed_channelid",
+				Value: feed.ChannelID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+}
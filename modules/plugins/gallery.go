@@ -11,6 +11,7 @@ import (
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/metrics"
 	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 	"github.com/globalsign/mgo/bson"
@@ -46,158 +47,180 @@ func (g *Gallery) Uninit(session *shardmanager.Manager) {
 
 }
 
-func (g *Gallery) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
-	if !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, helpers.ModulePermGallery) {
-		return
+func (g *Gallery) Routes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:   "gallery",
+			Module: helpers.ModulePermGallery,
+			Subcommands: []*router.Command{
+				{
+					Name:        "add",
+					Description: "reposts all links and attachments posted in the source channel to the target channel",
+					Permission:  router.PermissionMod,
+					Params: []router.Param{
+						{Name: "source channel", Type: router.ParamChannel},
+						{Name: "target channel", Type: router.ParamChannel},
+					},
+					Handler: g.actionAdd,
+				},
+				{
+					Name:        "list",
+					Description: "lists all galleries on the server",
+					Handler:     g.actionList,
+				},
+				{
+					Name:        "delete",
+					Aliases:     []string{"del", "remove"},
+					Description: "deletes a gallery",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "gallery id", Type: router.ParamString},
+					},
+					Handler: g.actionDelete,
+				},
+				{
+					Name:        "refresh",
+					Description: "reloads all galleries from the database",
+					Permission:  router.PermissionBotAdmin,
+					Handler:     g.actionRefresh,
+				},
+			},
+		},
 	}
+}
 
-	args := strings.Fields(content)
-	if len(args) >= 1 {
-		switch args[0] {
-		case "add": // [p]gallery add <source channel> <target channel>
-			helpers.RequireMod(msg, func() {
-				if len(args) < 3 {
-					helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
-					return
-				}
+// Action is not used, all commands are dispatched by the router, see Routes
+func (g *Gallery) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+}
 
-				session.ChannelTyping(msg.ChannelID)
+// [p]gallery add <source channel> <target channel>
+func (g *Gallery) actionAdd(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
 
-				channel, err := helpers.GetChannel(msg.ChannelID)
-				helpers.Relax(err)
-				guild, err := helpers.GetGuild(channel.GuildID)
-				helpers.Relax(err)
-				sourceChannel, err := helpers.GetChannelFromMention(msg, args[1])
-				if err != nil || sourceChannel.ID == "" || sourceChannel.GuildID != channel.GuildID {
-					helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
-					return
-				}
-				targetChannel, err := helpers.GetChannelFromMention(msg, args[2])
-				if err != nil || targetChannel.ID == "" || targetChannel.GuildID != channel.GuildID {
-					helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
-					return
-				}
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+	sourceChannel := ctx.Channel("source channel")
+	targetChannel := ctx.Channel("target channel")
+
+	newID, err := helpers.MDbInsert(models.GalleryTable, models.GalleryEntry{
+		SourceChannelID: sourceChannel.ID,
+		TargetChannelID: targetChannel.ID,
+		GuildID:         channel.GuildID,
+		AddedByUserID:   msg.Author.ID,
+	})
+	helpers.Relax(err)
 
-				newID, err := helpers.MDbInsert(models.GalleryTable, models.GalleryEntry{
-					SourceChannelID: sourceChannel.ID,
-					TargetChannelID: targetChannel.ID,
-					GuildID:         channel.GuildID,
-					AddedByUserID:   msg.Author.ID,
-				})
-				helpers.Relax(err)
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(newID),
+		models.EventlogTargetTypeRobyulGallery, msg.Author.ID,
+		models.EventlogTypeRobyulGalleryAdd, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "gallery_sourcechannelid",
+				Value: sourceChannel.ID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "gallery_targetchannelid",
+				Value: targetChannel.ID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	cache.GetLogger().WithField("module", "galleries").Info(fmt.Sprintf("Added Gallery on Server %s (%s) posting from #%s (%s) to #%s (%s)",
+		guild.Name, guild.ID, sourceChannel.Name, sourceChannel.ID, targetChannel.Name, targetChannel.ID))
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.add-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 
-				_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(newID),
-					models.EventlogTargetTypeRobyulGallery, msg.Author.ID,
-					models.EventlogTypeRobyulGalleryAdd, "",
-					nil,
-					[]models.ElasticEventlogOption{
-						{
-							Key:   "gallery_sourcechannelid",
-							Value: sourceChannel.ID,
-							Type:  models.EventlogTargetTypeChannel,
-						},
-						{
-							Key:   "gallery_targetchannelid",
-							Value: targetChannel.ID,
-							Type:  models.EventlogTargetTypeChannel,
-						},
-					}, false)
-				helpers.RelaxLog(err)
-
-				cache.GetLogger().WithField("module", "galleries").Info(fmt.Sprintf("Added Gallery on Server %s (%s) posting from #%s (%s) to #%s (%s)",
-					guild.Name, guild.ID, sourceChannel.Name, sourceChannel.ID, targetChannel.Name, targetChannel.ID))
-				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.add-success"))
-				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
-
-				galleries, err = g.GetGalleries()
-				helpers.RelaxLog(err)
-				return
-			})
-		case "list": // [p]gallery list
-			session.ChannelTyping(msg.ChannelID)
-			channel, err := helpers.GetChannel(msg.ChannelID)
-			helpers.Relax(err)
-			var entryBucket []models.GalleryEntry
-			err = helpers.MDbIter(helpers.MdbCollection(models.GalleryTable).Find(bson.M{"guildid": channel.GuildID})).All(&entryBucket)
-			helpers.Relax(err)
-
-			if entryBucket == nil || len(entryBucket) <= 0 {
-				helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.list-empty"))
-				return
-			}
+	galleries, err = g.GetGalleries()
+	helpers.RelaxLog(err)
+}
 
-			resultMessage := ":frame_photo: Galleries on this server:\n"
-			for _, entry := range entryBucket {
-				resultMessage += fmt.Sprintf("`%s`: posting from <#%s> to <#%s>\n",
-					helpers.MdbIdToHuman(entry.ID), entry.SourceChannelID, entry.TargetChannelID)
-			}
-			resultMessage += fmt.Sprintf("Found **%d** Galleries in total.", len(entryBucket))
-
-			_, err = helpers.SendMessage(msg.ChannelID, resultMessage)
-			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
-			return
-		case "delete", "del", "remove": // [p]gallery delete <gallery id>
-			helpers.RequireAdmin(msg, func() {
-				session.ChannelTyping(msg.ChannelID)
-				if len(args) < 2 {
-					_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
-					helpers.Relax(err)
-					return
-				}
+// [p]gallery list
+func (g *Gallery) actionList(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	var entryBucket []models.GalleryEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.GalleryTable).Find(bson.M{"guildid": channel.GuildID})).All(&entryBucket)
+	helpers.Relax(err)
 
-				channel, err := helpers.GetChannel(msg.ChannelID)
-				helpers.Relax(err)
+	if entryBucket == nil || len(entryBucket) <= 0 {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.list-empty"))
+		return
+	}
 
-				var entryBucket models.GalleryEntry
-				err = helpers.MdbOne(
-					helpers.MdbCollection(models.GalleryTable).Find(bson.M{"guildid": channel.GuildID, "_id": helpers.HumanToMdbId(args[1])}),
-					&entryBucket,
-				)
-				if helpers.IsMdbNotFound(err) {
-					helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.delete-not-found"))
-					return
-				}
-				helpers.Relax(err)
+	resultMessage := ":frame_photo: Galleries on this server:\n"
+	for _, entry := range entryBucket {
+		resultMessage += fmt.Sprintf("`%s`: posting from <#%s> to <#%s>\n",
+			helpers.MdbIdToHuman(entry.ID), entry.SourceChannelID, entry.TargetChannelID)
+	}
+	resultMessage += fmt.Sprintf("Found **%d** Galleries in total.", len(entryBucket))
 
-				err = helpers.MDbDelete(models.GalleryTable, entryBucket.ID)
-				helpers.Relax(err)
+	_, err = helpers.SendMessage(msg.ChannelID, resultMessage)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
 
-				_, err = helpers.EventlogLog(time.Now(), entryBucket.GuildID, helpers.MdbIdToHuman(entryBucket.ID),
-					models.EventlogTargetTypeRobyulGallery, msg.Author.ID,
-					models.EventlogTypeRobyulGalleryRemove, "",
-					nil,
-					[]models.ElasticEventlogOption{
-						{
-							Key:   "gallery_sourcechannelid",
-							Value: entryBucket.SourceChannelID,
-						},
-						{
-							Key:   "gallery_targetchannelid",
-							Value: entryBucket.TargetChannelID,
-						},
-					}, false)
-				helpers.RelaxLog(err)
-
-				cache.GetLogger().WithField("module", "galleries").Info(fmt.Sprintf("Deleted Gallery on Server #%s posting from #%s to #%s",
-					channel.GuildID, entryBucket.SourceChannelID, entryBucket.TargetChannelID))
-				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.delete-success"))
-				helpers.Relax(err)
+// [p]gallery delete <gallery id>
+func (g *Gallery) actionDelete(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
 
-				galleries, err = g.GetGalleries()
-				helpers.RelaxLog(err)
-				return
-			})
-		case "refresh": // [p]gallery refresh
-			helpers.RequireBotAdmin(msg, func() {
-				session.ChannelTyping(msg.ChannelID)
-				var err error
-				galleries, err = g.GetGalleries()
-				helpers.RelaxLog(err)
-				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.refreshed-config"))
-				helpers.Relax(err)
-			})
-		}
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var entryBucket models.GalleryEntry
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.GalleryTable).Find(bson.M{"guildid": channel.GuildID, "_id": helpers.HumanToMdbId(ctx.String("gallery id"))}),
+		&entryBucket,
+	)
+	if helpers.IsMdbNotFound(err) {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.delete-not-found"))
+		return
 	}
+	helpers.Relax(err)
+
+	err = helpers.MDbDelete(models.GalleryTable, entryBucket.ID)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), entryBucket.GuildID, helpers.MdbIdToHuman(entryBucket.ID),
+		models.EventlogTargetTypeRobyulGallery, msg.Author.ID,
+		models.EventlogTypeRobyulGalleryRemove, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "gallery_sourcechannelid",
+				Value: entryBucket.SourceChannelID,
+			},
+			{
+				Key:   "gallery_targetchannelid",
+				Value: entryBucket.TargetChannelID,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	cache.GetLogger().WithField("module", "galleries").Info(fmt.Sprintf("Deleted Gallery on Server #%s posting from #%s to #%s",
+		channel.GuildID, entryBucket.SourceChannelID, entryBucket.TargetChannelID))
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.gallery.delete-success"))
+	helpers.Relax(err)
+
+	galleries, err = g.GetGalleries()
+	helpers.RelaxLog(err)
+}
+
+// [p]gallery refresh
+func (g *Gallery) actionRefresh(ctx *router.Context) {
+	ctx.Session.ChannelTyping(ctx.Msg.ChannelID)
+	var err error
+	galleries, err = g.GetGalleries()
+	helpers.RelaxLog(err)
+	_, err = helpers.SendMessage(ctx.Msg.ChannelID, helpers.GetText("plugins.gallery.refreshed-config"))
+	helpers.Relax(err)
 }
 
 func (g *Gallery) OnMessage(content string, msg *discordgo.Message, session *discordgo.Session) {
@@ -2,6 +2,7 @@ package plugins
 
 import (
 	"fmt"
+	"io/ioutil"
 	"strconv"
 	"strings"
 
@@ -27,7 +28,9 @@ func (m *GuildAnnouncements) Commands() []string {
 }
 
 func (m *GuildAnnouncements) Init(session *shardmanager.Manager) {
-
+	cardTemplate, err := ioutil.ReadFile(helpers.GetConfig().Path("assets_folder").Data().(string) + "welcome.html")
+	helpers.Relax(err)
+	greeterCardTemplateString = string(cardTemplate)
 }
 
 func (m *GuildAnnouncements) Uninit(session *shardmanager.Manager) {
@@ -86,12 +89,13 @@ func (m *GuildAnnouncements) Action(command string, content string, msg *discord
 			err = helpers.MDbUpsert(
 				models.GreeterTable,
 				bson.M{"type": models.GreeterTypeJoin, "guildid": targetChannel.GuildID, "channelid": targetChannel.ID},
-				models.GreeterEntry{
-					GuildID:   targetChannel.GuildID,
-					ChannelID: targetChannel.ID,
-					Type:      models.GreeterTypeJoin,
-					EmbedCode: embedCode,
-				},
+				// $set keeps the welcome card settings of the greeting
+				bson.M{"$set": bson.M{
+					"guildid":   targetChannel.GuildID,
+					"channelid": targetChannel.ID,
+					"type":      models.GreeterTypeJoin,
+					"embedcode": embedCode,
+				}},
 			)
 			helpers.Relax(err)
 
@@ -150,12 +154,12 @@ func (m *GuildAnnouncements) Action(command string, content string, msg *discord
 			err = helpers.MDbUpsert(
 				models.GreeterTable,
 				bson.M{"type": models.GreeterTypeLeave, "guildid": targetChannel.GuildID, "channelid": targetChannel.ID},
-				models.GreeterEntry{
-					GuildID:   targetChannel.GuildID,
-					ChannelID: targetChannel.ID,
-					Type:      models.GreeterTypeLeave,
-					EmbedCode: embedCode,
-				},
+				bson.M{"$set": bson.M{
+					"guildid":   targetChannel.GuildID,
+					"channelid": targetChannel.ID,
+					"type":      models.GreeterTypeLeave,
+					"embedcode": embedCode,
+				}},
 			)
 			helpers.Relax(err)
 
@@ -213,12 +217,12 @@ func (m *GuildAnnouncements) Action(command string, content string, msg *discord
 			err = helpers.MDbUpsert(
 				models.GreeterTable,
 				bson.M{"type": models.GreeterTypeBan, "guildid": targetChannel.GuildID, "channelid": targetChannel.ID},
-				models.GreeterEntry{
-					GuildID:   targetChannel.GuildID,
-					ChannelID: targetChannel.ID,
-					Type:      models.GreeterTypeBan,
-					EmbedCode: embedCode,
-				},
+				bson.M{"$set": bson.M{
+					"guildid":   targetChannel.GuildID,
+					"channelid": targetChannel.ID,
+					"type":      models.GreeterTypeBan,
+					"embedcode": embedCode,
+				}},
 			)
 			helpers.Relax(err)
 
@@ -237,6 +241,181 @@ func (m *GuildAnnouncements) Action(command string, content string, msg *discord
 			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.message-edited"))
 			helpers.Relax(err)
 		})
+	case "dm": // [p]greeter dm <embed code>
+		helpers.RequireAdmin(msg, func() {
+			channel, err := helpers.GetChannel(msg.ChannelID)
+			helpers.Relax(err)
+
+			var embedCode string
+
+			if len(args) >= 2 {
+				embedCode = strings.TrimSpace(strings.Replace(content, args[0], "", 1))
+			}
+
+			if embedCode == "" {
+				_, err = helpers.MdbCollection(models.GreeterTable).RemoveAll(bson.M{
+					"type": models.GreeterTypeJoinDM, "guildid": channel.GuildID,
+				})
+				helpers.Relax(err)
+
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.message-disabled"))
+				helpers.Relax(err)
+				return
+			}
+
+			err = helpers.MDbUpsert(
+				models.GreeterTable,
+				bson.M{"type": models.GreeterTypeJoinDM, "guildid": channel.GuildID},
+				bson.M{"$set": bson.M{
+					"guildid":   channel.GuildID,
+					"type":      models.GreeterTypeJoinDM,
+					"embedcode": embedCode,
+				}},
+			)
+			helpers.Relax(err)
+
+			_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+				models.EventlogTargetTypeGuild, msg.Author.ID,
+				models.EventlogTypeRobyulGuildAnnouncementsJoinDMSet, "",
+				nil,
+				[]models.ElasticEventlogOption{
+					{
+						Key:   "join_dm_text",
+						Value: embedCode,
+					},
+				}, false)
+			helpers.RelaxLog(err)
+
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.message-edited"))
+			helpers.Relax(err)
+		})
+	case "card": // [p]greeter card <#channel or channel id|dm> <on [<background url>]|off>
+		helpers.RequireAdmin(msg, func() {
+			if len(args) < 3 {
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				return
+			}
+
+			channel, err := helpers.GetChannel(msg.ChannelID)
+			helpers.Relax(err)
+
+			selector := bson.M{"type": models.GreeterTypeJoinDM, "guildid": channel.GuildID}
+			targetID := channel.GuildID
+			targetType := models.EventlogTargetTypeGuild
+			if args[1] != "dm" {
+				targetChannel, err := helpers.GetChannelFromMention(msg, args[1])
+				if err != nil || targetChannel.ID == "" {
+					helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+					return
+				}
+				selector = bson.M{"type": models.GreeterTypeJoin, "guildid": targetChannel.GuildID, "channelid": targetChannel.ID}
+				targetID = targetChannel.ID
+				targetType = models.EventlogTargetTypeChannel
+			}
+
+			var greeting models.GreeterEntry
+			err = helpers.MdbOne(helpers.MdbCollection(models.GreeterTable).Find(selector), &greeting)
+			if helpers.IsMdbNotFound(err) {
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.card-no-greeting"))
+				return
+			}
+			helpers.Relax(err)
+
+			switch args[2] {
+			case "on":
+				greeting.Card = true
+				greeting.CardBackgroundURL = ""
+				if len(args) >= 4 {
+					if !m.isValidCardBackgroundURL(args[3]) {
+						helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.card-invalid-background"))
+						return
+					}
+					greeting.CardBackgroundURL = args[3]
+				}
+			case "off":
+				greeting.Card = false
+				greeting.CardBackgroundURL = ""
+			default:
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+				return
+			}
+
+			err = helpers.MDbUpdate(models.GreeterTable, greeting.Id, greeting)
+			helpers.Relax(err)
+
+			_, err = helpers.EventlogLog(time.Now(), channel.GuildID, targetID,
+				targetType, msg.Author.ID,
+				models.EventlogTypeRobyulGuildAnnouncementsCardSet, "",
+				nil,
+				[]models.ElasticEventlogOption{
+					{
+						Key:   "card_enabled",
+						Value: helpers.StoreBoolAsString(greeting.Card),
+					},
+					{
+						Key:   "card_background_url",
+						Value: greeting.CardBackgroundURL,
+					},
+				}, false)
+			helpers.RelaxLog(err)
+
+			if greeting.Card {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.card-enabled"))
+			} else {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.card-disabled"))
+			}
+			helpers.Relax(err)
+		})
+	case "test": // [p]greeter test <join|leave|ban|dm>
+		helpers.RequireMod(msg, func() {
+			if len(args) < 2 {
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				return
+			}
+
+			var greeterType models.GreeterType
+			switch args[1] {
+			case "join":
+				greeterType = models.GreeterTypeJoin
+			case "leave":
+				greeterType = models.GreeterTypeLeave
+			case "ban":
+				greeterType = models.GreeterTypeBan
+			case "dm":
+				greeterType = models.GreeterTypeJoinDM
+			default:
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+				return
+			}
+
+			session.ChannelTyping(msg.ChannelID)
+
+			channel, err := helpers.GetChannel(msg.ChannelID)
+			helpers.Relax(err)
+
+			member, err := helpers.GetGuildMember(channel.GuildID, msg.Author.ID)
+			helpers.Relax(err)
+
+			var entryBucket []models.GreeterEntry
+			err = helpers.MDbIter(helpers.MdbCollection(models.GreeterTable).
+				Find(bson.M{"guildid": channel.GuildID, "type": greeterType})).All(&entryBucket)
+			helpers.Relax(err)
+
+			if len(entryBucket) <= 0 {
+				helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.guildannouncements.test-none"))
+				return
+			}
+
+			// the greetings are posted in the current channel instead of their configured channels
+			for _, greeting := range entryBucket {
+				messageSend := m.getGreetingMessage(greeting, member)
+				if messageSend == nil {
+					continue
+				}
+				_, err = helpers.SendComplex(msg.ChannelID, messageSend)
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			}
+		})
 	case "list":
 		helpers.RequireMod(msg, func() {
 			session.ChannelTyping(msg.ChannelID)
@@ -265,6 +444,16 @@ func (m *GuildAnnouncements) Action(command string, content string, msg *discord
 				case models.GreeterTypeBan:
 					message += "on ban in <#" + greeting.ChannelID + ">: `" + greeting.EmbedCode + "`\n"
 					break
+				case models.GreeterTypeJoinDM:
+					message += "on join as DM: `" + greeting.EmbedCode + "`\n"
+					break
+				}
+				if greeting.Card {
+					message += "    with welcome card"
+					if greeting.CardBackgroundURL != "" {
+						message += " (background: <" + greeting.CardBackgroundURL + ">)"
+					}
+					message += "\n"
 				}
 			}
 			message += fmt.Sprintf("_found %d greeter configs in total_\n_To change a config just set a new config for the specific channel, it will replace the old config._", len(entryBucket))
@@ -291,7 +480,9 @@ func (m *GuildAnnouncements) OnGuildMemberAdd(member *discordgo.Member, session
 
 		var entryBucket []models.GreeterEntry
 		err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.GreeterTable).
-			Find(bson.M{"guildid": member.GuildID, "type": models.GreeterTypeJoin})).All(&entryBucket)
+			Find(bson.M{"guildid": member.GuildID, "type": bson.M{"$in": []models.GreeterType{
+				models.GreeterTypeJoin, models.GreeterTypeJoinDM,
+			}}})).All(&entryBucket)
 		helpers.Relax(err)
 
 		if entryBucket == nil || len(entryBucket) <= 0 {
@@ -302,20 +493,14 @@ func (m *GuildAnnouncements) OnGuildMemberAdd(member *discordgo.Member, session
 			ourSetting := guildAnnouncementSetting
 			go func() {
 				defer helpers.Recover()
-				messageSend := &discordgo.MessageSend{
-					Content: ourSetting.EmbedCode,
-				}
-				if helpers.IsEmbedCode(ourSetting.EmbedCode) {
-					ptext, embed, err := helpers.ParseEmbedCode(ourSetting.EmbedCode)
-					if err == nil {
-						messageSend.Content = ptext
-						messageSend.Embed = embed
-					}
-				}
-				messageSend = m.ReplaceMemberText(messageSend, member)
+				messageSend := m.getGreetingMessage(ourSetting, member)
 				if messageSend == nil {
 					return
 				}
+				if ourSetting.Type == models.GreeterTypeJoinDM {
+					m.sendGreetingDM(member, messageSend, session)
+					return
+				}
 				helpers.SendComplex(ourSetting.ChannelID, messageSend)
 			}()
 		}
@@ -349,17 +534,7 @@ func (m *GuildAnnouncements) OnGuildMemberRemove(member *discordgo.Member, sessi
 			go func() {
 				defer helpers.Recover()
 
-				messageSend := &discordgo.MessageSend{
-					Content: ourSetting.EmbedCode,
-				}
-				if helpers.IsEmbedCode(ourSetting.EmbedCode) {
-					ptext, embed, err := helpers.ParseEmbedCode(ourSetting.EmbedCode)
-					if err == nil {
-						messageSend.Content = ptext
-						messageSend.Embed = embed
-					}
-				}
-				messageSend = m.ReplaceMemberText(messageSend, member)
+				messageSend := m.getGreetingMessage(ourSetting, member)
 				if messageSend == nil {
 					return
 				}
@@ -382,15 +557,7 @@ func (m *GuildAnnouncements) ReplaceMemberText(message *discordgo.MessageSend, m
 		helpers.Relax(err)
 	}
 
-	userNumber := -1
-	if guild != nil {
-		if guild.Members != nil {
-			userNumber = len(guild.Members)
-		}
-		if guild.MemberCount > userNumber {
-			userNumber = guild.MemberCount
-		}
-	}
+	userNumber := m.getMemberNumber(guild)
 
 	return helpers.ReplaceMessageSend(
 		message,
@@ -407,6 +574,59 @@ func (m *GuildAnnouncements) ReplaceMemberText(message *discordgo.MessageSend, m
 	)
 }
 
+// getMemberNumber returns the number of members of the guild, or -1 if it is unknown
+func (m *GuildAnnouncements) getMemberNumber(guild *discordgo.Guild) (userNumber int) {
+	userNumber = -1
+	if guild != nil {
+		if guild.Members != nil {
+			userNumber = len(guild.Members)
+		}
+		if guild.MemberCount > userNumber {
+			userNumber = guild.MemberCount
+		}
+	}
+	return userNumber
+}
+
+// getGreetingMessage builds the message of the greeting for the member, returns nil if it shouldn't be sent
+func (m *GuildAnnouncements) getGreetingMessage(greeting models.GreeterEntry, member *discordgo.Member) *discordgo.MessageSend {
+	messageSend := &discordgo.MessageSend{
+		Content: greeting.EmbedCode,
+	}
+	if helpers.IsEmbedCode(greeting.EmbedCode) {
+		ptext, embed, err := helpers.ParseEmbedCode(greeting.EmbedCode)
+		if err == nil {
+			messageSend.Content = ptext
+			messageSend.Embed = embed
+		}
+	}
+	messageSend = m.ReplaceMemberText(messageSend, member)
+	if messageSend == nil || !greeting.Card {
+		return messageSend
+	}
+
+	m.attachWelcomeCard(messageSend, greeting, member)
+	return messageSend
+}
+
+func (m *GuildAnnouncements) sendGreetingDM(member *discordgo.Member, messageSend *discordgo.MessageSend, session *discordgo.Session) {
+	if member.User.Bot {
+		return
+	}
+
+	dmChannel, err := session.UserChannelCreate(member.User.ID)
+	if err != nil {
+		return
+	}
+
+	_, err = helpers.SendComplex(dmChannel.ID, messageSend)
+	if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+		errD.Message.Code == discordgo.ErrCodeCannotSendMessagesToThisUser {
+		return
+	}
+	helpers.RelaxLog(err)
+}
+
 func (m *GuildAnnouncements) OnGuildBanAdd(user *discordgo.GuildBanAdd, session *discordgo.Session) {
 	go func() {
 		defer helpers.Recover()
@@ -430,17 +650,7 @@ func (m *GuildAnnouncements) OnGuildBanAdd(user *discordgo.GuildBanAdd, session
 				member := new(discordgo.Member)
 				member.User = user.User
 				member.GuildID = user.GuildID
-				messageSend := &discordgo.MessageSend{
-					Content: ourSetting.EmbedCode,
-				}
-				if helpers.IsEmbedCode(ourSetting.EmbedCode) {
-					ptext, embed, err := helpers.ParseEmbedCode(ourSetting.EmbedCode)
-					if err == nil {
-						messageSend.Content = ptext
-						messageSend.Embed = embed
-					}
-				}
-				messageSend = m.ReplaceMemberText(messageSend, member)
+				messageSend := m.getGreetingMessage(ourSetting, member)
 				if messageSend == nil {
 					return
 				}
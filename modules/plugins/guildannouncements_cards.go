@@ -0,0 +1,85 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	humanize "github.com/dustin/go-humanize"
+)
+
+const (
+	greeterCardFilename          = "welcome.png"
+	greeterCardWidth             = 600
+	greeterCardHeight            = 200
+	greeterCardDefaultBackground = "linear-gradient(135deg, #0fadED 0%, #2f3136 100%)"
+)
+
+var (
+	greeterCardTemplateString string
+)
+
+// attachWelcomeCard renders the welcome card of the member, and attaches it to the message
+// if the message has an embed without an image, the card is shown as the image of the embed
+func (m *GuildAnnouncements) attachWelcomeCard(messageSend *discordgo.MessageSend, greeting models.GreeterEntry, member *discordgo.Member) {
+	guild, err := helpers.GetGuild(member.GuildID)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return
+	}
+
+	start := time.Now()
+	card, err := m.getWelcomeCard(member, guild, greeting.CardBackgroundURL)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return
+	}
+	cache.GetLogger().WithField("module", "guildannouncements").Info(
+		fmt.Sprintf("took screenshot of welcome card in %s", time.Since(start).String()))
+
+	messageSend.Files = append(messageSend.Files, &discordgo.File{
+		Name:   greeterCardFilename,
+		Reader: bytes.NewReader(card),
+	})
+	if messageSend.Embed != nil && messageSend.Embed.Image == nil {
+		messageSend.Embed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + greeterCardFilename}
+	}
+}
+
+// getWelcomeCard renders the welcome.html template with the same screenshot service used for level profiles
+func (m *GuildAnnouncements) getWelcomeCard(member *discordgo.Member, guild *discordgo.Guild, backgroundURL string) ([]byte, error) {
+	background := greeterCardDefaultBackground
+	if m.isValidCardBackgroundURL(backgroundURL) {
+		background = "url('" + backgroundURL + "')"
+	}
+
+	cardHTML := strings.NewReplacer(
+		"{CARD_BACKGROUND}", background,
+		"{USER_AVATAR_URL}", html.EscapeString(member.User.AvatarURL("256")),
+		"{USER_USERNAME}", html.EscapeString(member.User.Username),
+		"{USER_DISCRIMINATOR}", html.EscapeString(member.User.Discriminator),
+		"{USER_NUMBER}", humanize.Comma(int64(m.getMemberNumber(guild))),
+		"{GUILD_NAME}", html.EscapeString(guild.Name),
+	).Replace(greeterCardTemplateString)
+
+	return helpers.TakeHTMLScreenshot(cardHTML, greeterCardWidth, greeterCardHeight)
+}
+
+// isValidCardBackgroundURL checks if the URL is a http(s) URL which is safe to use in the CSS of the template
+func (m *GuildAnnouncements) isValidCardBackgroundURL(backgroundURL string) bool {
+	if backgroundURL == "" || strings.ContainsAny(backgroundURL, "'\"()\\<> \t\n") {
+		return false
+	}
+	parsedURL, err := url.Parse(backgroundURL)
+	if err != nil {
+		return false
+	}
+	return (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && parsedURL.Host != ""
+}
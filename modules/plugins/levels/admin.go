@@ -0,0 +1,482 @@
+package levels
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	maxAdminExp          = 1000000000
+	maxImportFileSize    = 5e+6
+	maxImportEntries     = 100000
+	importPreviewEntries = 10
+)
+
+var (
+	errImportEmpty        = errors.New("no entries found")
+	errImportTooMany      = errors.New("too many entries")
+	errImportInvalidValue = errors.New("invalid value")
+)
+
+// levelsImportEntry is a member parsed from an import file
+type levelsImportEntry struct {
+	UserID string
+	Exp    int64
+}
+
+// levelsExpChange is a pending change to the EXP of a member
+type levelsExpChange struct {
+	UserID    string
+	ExpBefore int64
+	ExpAfter  int64
+}
+
+// actionExpAdmin edits the EXP of a single member
+// [p]levels give <user> <exp>
+// [p]levels take <user> <exp>
+// [p]levels set <user> level|exp <n>
+func (m *Levels) actionExpAdmin(args []string, msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	if len(args) < 3 || (args[0] == "set" && len(args) < 4) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	targetUser, err := helpers.GetUserFromMention(args[1])
+	if err != nil || targetUser == nil || targetUser.ID == "" {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	curve := GetCurve(channel.GuildID)
+
+	valueText := args[2]
+	if args[0] == "set" {
+		valueText = args[3]
+	}
+	value, err := strconv.ParseInt(strings.Replace(valueText, ",", "", -1), 10, 64)
+	if err != nil || value < 0 || value > maxAdminExp ||
+		(args[0] == "set" && args[2] == "level" && value > int64(curve.LevelFromExp(maxAdminExp))) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.exp-admin-invalid", humanize.Comma(maxAdminExp)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	levelsServerUser, err := m.getLevelsServerUserOrCreateNew(channel.GuildID, targetUser.ID)
+	helpers.Relax(err)
+
+	change := levelsExpChange{UserID: targetUser.ID, ExpBefore: levelsServerUser.Exp}
+	switch args[0] {
+	case "give":
+		change.ExpAfter = change.ExpBefore + value
+	case "take":
+		change.ExpAfter = change.ExpBefore - value
+	case "set":
+		switch args[2] {
+		case "level":
+			change.ExpAfter = curve.ExpForLevel(int(value))
+		case "exp":
+			change.ExpAfter = value
+		default:
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+	if change.ExpAfter < 0 {
+		change.ExpAfter = 0
+	}
+	if change.ExpAfter > maxAdminExp {
+		change.ExpAfter = maxAdminExp
+	}
+
+	levelsServerUser.Exp = change.ExpAfter
+	err = helpers.MDbUpdate(models.LevelsServerusersTable, levelsServerUser.ID, levelsServerUser)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, targetUser.ID,
+		models.EventlogTargetTypeUser, msg.Author.ID,
+		models.EventlogTypeRobyulLevelsExpEdit, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "levels_exp",
+				OldValue: strconv.FormatInt(change.ExpBefore, 10),
+				NewValue: strconv.FormatInt(change.ExpAfter, 10),
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	applyLevelsRolesForChanges(channel.GuildID, curve, []levelsExpChange{change})
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.exp-admin-success",
+		targetUser.Username, humanize.Comma(change.ExpAfter), curve.LevelFromExp(change.ExpAfter)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// actionImport imports EXP from an attached CSV or JSON file, add adds the imported EXP instead of replacing it
+// [p]levels import [add] [dry-run] + FILE
+func (m *Levels) actionImport(args []string, msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var add, dryRun bool
+	for _, arg := range args[1:] {
+		switch strings.ToLower(arg) {
+		case "add":
+			add = true
+		case "dry-run", "dryrun", "preview":
+			dryRun = true
+		}
+	}
+
+	if len(msg.Attachments) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.import-no-file"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	if msg.Attachments[0].Size > maxImportFileSize {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.import-file-too-big"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	data, err := helpers.NetGetUAWithErrorAndTimeout(msg.Attachments[0].URL, helpers.DEFAULT_UA, time.Second*30)
+	helpers.Relax(err)
+
+	curve := GetCurve(channel.GuildID)
+
+	entries, invalid, err := parseLevelsImport(data, curve)
+	if err != nil {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.import-invalid", err.Error()))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	changes := make([]levelsExpChange, 0, len(entries))
+	for _, entry := range entries {
+		var levelsServerUser models.LevelsServerusersEntry
+		err = helpers.MdbOneWithoutLogging(
+			helpers.MdbCollection(models.LevelsServerusersTable).Find(bson.M{"userid": entry.UserID, "guildid": channel.GuildID}),
+			&levelsServerUser,
+		)
+		if err != nil && !helpers.IsMdbNotFound(err) {
+			helpers.Relax(err)
+		}
+
+		change := levelsExpChange{UserID: entry.UserID, ExpBefore: levelsServerUser.Exp, ExpAfter: entry.Exp}
+		if add {
+			change.ExpAfter += change.ExpBefore
+		}
+		if change.ExpAfter > maxAdminExp {
+			change.ExpAfter = maxAdminExp
+		}
+		changes = append(changes, change)
+	}
+
+	preview := getImportPreviewText(channel.GuildID, curve, changes, invalid)
+
+	if dryRun {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.import-dry-run", preview))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	if !helpers.ConfirmEmbed(channel.GuildID, msg.ChannelID, msg.Author,
+		helpers.GetTextF("plugins.levels.import-confirm", preview), "✅", "🚫") {
+		return
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.import-start"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+
+	for _, change := range changes {
+		levelsServerUser, err := getLevelsServerUserOrCreateNewWithoutLogging(channel.GuildID, change.UserID)
+		helpers.Relax(err)
+		levelsServerUser.Exp = change.ExpAfter
+		err = helpers.MDbUpdateWithoutLogging(models.LevelsServerusersTable, levelsServerUser.ID, levelsServerUser)
+		helpers.Relax(err)
+	}
+
+	mode := "replace"
+	if add {
+		mode = "add"
+	}
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulLevelsImport, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "levels_import_filename",
+				Value: msg.Attachments[0].Filename,
+			},
+			{
+				Key:   "levels_import_mode",
+				Value: mode,
+			},
+			{
+				Key:   "levels_import_entries",
+				Value: strconv.Itoa(len(changes)),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	success, failed := applyLevelsRolesForChanges(channel.GuildID, curve, changes)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.import-result",
+		msg.Author.ID, len(changes), success, failed))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// applyLevelsRolesForChanges applies level roles to all members whose level changed, members not on the server are skipped
+func applyLevelsRolesForChanges(guildID string, curve Curve, changes []levelsExpChange) (success, failed int) {
+	for _, change := range getLevelChanges(curve, changes) {
+		if !helpers.GetIsInGuild(guildID, change.UserID) {
+			continue
+		}
+		err := applyLevelsRoles(guildID, change.UserID, curve.LevelFromExp(change.ExpAfter))
+		if err != nil {
+			failed++
+			continue
+		}
+		success++
+	}
+	return success, failed
+}
+
+// getLevelChanges returns all changes which change the level of the member
+func getLevelChanges(curve Curve, changes []levelsExpChange) (levelChanges []levelsExpChange) {
+	for _, change := range changes {
+		if curve.LevelFromExp(change.ExpBefore) != curve.LevelFromExp(change.ExpAfter) {
+			levelChanges = append(levelChanges, change)
+		}
+	}
+	return levelChanges
+}
+
+func getImportPreviewText(guildID string, curve Curve, changes []levelsExpChange, invalid int) (text string) {
+	var onServer, rolesToApply int
+	levelChanges := getLevelChanges(curve, changes)
+	for _, change := range changes {
+		if helpers.GetIsInGuild(guildID, change.UserID) {
+			onServer++
+		}
+	}
+	for _, change := range levelChanges {
+		if helpers.GetIsInGuild(guildID, change.UserID) {
+			rolesToApply++
+		}
+	}
+
+	text = fmt.Sprintf("**Entries:** %s (%s on the server, %s invalid lines skipped)\n**Level changes:** %s (level roles will be applied to %s members)\n",
+		humanize.Comma(int64(len(changes))), humanize.Comma(int64(onServer)), humanize.Comma(int64(invalid)),
+		humanize.Comma(int64(len(levelChanges))), humanize.Comma(int64(rolesToApply)))
+	for i, change := range changes {
+		if i >= importPreviewEntries {
+			text += fmt.Sprintf("... and %s more\n", humanize.Comma(int64(len(changes)-importPreviewEntries)))
+			break
+		}
+		text += fmt.Sprintf("<@%s>: %s EXP (Level %d) ➡ %s EXP (Level %d)\n", change.UserID,
+			humanize.Comma(change.ExpBefore), curve.LevelFromExp(change.ExpBefore),
+			humanize.Comma(change.ExpAfter), curve.LevelFromExp(change.ExpAfter))
+	}
+	return text
+}
+
+// parseLevelsImport parses a JSON or CSV file of user IDs and EXP or levels
+// JSON can either be an object of user IDs to EXP, or an array of objects with user_id, and exp or level
+// CSV rows are user ID and EXP, a header row can name the columns, for example user_id,level
+// returns the entries, and the number of invalid lines skipped, later entries for the same user replace earlier ones
+func parseLevelsImport(data []byte, curve Curve) (entries []levelsImportEntry, invalid int, err error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	var rawEntries []levelsImportEntry
+	switch {
+	case bytes.HasPrefix(data, []byte("{")), bytes.HasPrefix(data, []byte("[")):
+		rawEntries, invalid, err = parseLevelsImportJSON(data, curve)
+	default:
+		rawEntries, invalid, err = parseLevelsImportCSV(data, curve)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	positions := make(map[string]int)
+	for _, entry := range rawEntries {
+		if position, ok := positions[entry.UserID]; ok {
+			entries[position] = entry
+			continue
+		}
+		positions[entry.UserID] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	if len(entries) <= 0 {
+		return nil, invalid, errImportEmpty
+	}
+	if len(entries) > maxImportEntries {
+		return nil, invalid, errImportTooMany
+	}
+	return entries, invalid, nil
+}
+
+func parseLevelsImportJSON(data []byte, curve Curve) (entries []levelsImportEntry, invalid int, err error) {
+	if bytes.HasPrefix(data, []byte("{")) {
+		var expByUserID map[string]json.Number
+		err = json.Unmarshal(data, &expByUserID)
+		if err != nil {
+			return nil, 0, err
+		}
+		for userID, expNumber := range expByUserID {
+			entry, err := newLevelsImportEntry(userID, expNumber.String(), false, curve)
+			if err != nil {
+				invalid++
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		return entries, invalid, nil
+	}
+
+	var items []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // keeps user IDs exact
+	err = decoder.Decode(&items)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, item := range items {
+		userIDColumn, expColumn, isLevel := getImportColumns(item)
+		entry, err := newLevelsImportEntry(fmt.Sprint(item[userIDColumn]), fmt.Sprint(item[expColumn]), isLevel, curve)
+		if err != nil {
+			invalid++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, invalid, nil
+}
+
+func parseLevelsImportCSV(data []byte, curve Curve) (entries []levelsImportEntry, invalid int, err error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	userIDIndex, expIndex, isLevel := 0, 1, false
+	first := true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if first {
+			first = false
+			if len(record) > 0 && !isDiscordID(record[0]) {
+				columns := make(map[string]interface{})
+				for i, column := range record {
+					columns[strings.ToLower(strings.TrimSpace(column))] = i
+				}
+				userIDColumn, expColumn, columnIsLevel := getImportColumns(columns)
+				if index, ok := columns[userIDColumn].(int); ok {
+					userIDIndex = index
+				}
+				if index, ok := columns[expColumn].(int); ok {
+					expIndex = index
+				}
+				isLevel = columnIsLevel
+				continue
+			}
+		}
+
+		if len(record) <= userIDIndex || len(record) <= expIndex {
+			invalid++
+			continue
+		}
+		entry, err := newLevelsImportEntry(record[userIDIndex], record[expIndex], isLevel, curve)
+		if err != nil {
+			invalid++
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, invalid, nil
+}
+
+// getImportColumns finds the user ID and the EXP or level column names
+func getImportColumns(columns map[string]interface{}) (userIDColumn, expColumn string, isLevel bool) {
+	for _, name := range []string{"user_id", "userid", "user", "id"} {
+		if _, ok := columns[name]; ok {
+			userIDColumn = name
+			break
+		}
+	}
+	for _, name := range []string{"exp", "xp", "experience"} {
+		if _, ok := columns[name]; ok {
+			return userIDColumn, name, false
+		}
+	}
+	for _, name := range []string{"level", "lvl"} {
+		if _, ok := columns[name]; ok {
+			return userIDColumn, name, true
+		}
+	}
+	return userIDColumn, "", false
+}
+
+func newLevelsImportEntry(userID, value string, isLevel bool, curve Curve) (entry levelsImportEntry, err error) {
+	userID = strings.TrimSpace(userID)
+	if !isDiscordID(userID) {
+		return entry, errImportInvalidValue
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	// NaN, Inf and huge values would overflow the conversion to int64
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) || number < 0 || number > maxAdminExp {
+		return entry, errImportInvalidValue
+	}
+
+	entry.UserID = userID
+	if isLevel {
+		if number > float64(curve.LevelFromExp(maxAdminExp)) {
+			return entry, errImportInvalidValue
+		}
+		entry.Exp = curve.ExpForLevel(int(number))
+	} else {
+		entry.Exp = int64(number)
+	}
+	if entry.Exp > maxAdminExp {
+		return entry, errImportInvalidValue
+	}
+	return entry, nil
+}
+
+func isDiscordID(text string) bool {
+	if len(text) < 15 || len(text) > 21 {
+		return false
+	}
+	_, err := strconv.ParseUint(text, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,104 @@
+package levels
+
+import (
+	"testing"
+)
+
+const (
+	testUserA = "116620585638821891"
+	testUserB = "273639623324991489"
+)
+
+func TestParseLevelsImport(t *testing.T) {
+	curve := Curve{Multiplier: 2}
+
+	for _, testCase := range []struct {
+		name     string
+		data     string
+		expected map[string]int64
+		invalid  int
+		err      error
+	}{
+		{
+			name:     "json object",
+			data:     `{"` + testUserA + `": 1500, "` + testUserB + `": 20}`,
+			expected: map[string]int64{testUserA: 1500, testUserB: 20},
+		},
+		{
+			name:     "json array with levels",
+			data:     `[{"user_id": ` + testUserA + `, "level": 3}, {"id": "` + testUserB + `", "lvl": "1"}]`,
+			expected: map[string]int64{testUserA: curve.ExpForLevel(3), testUserB: curve.ExpForLevel(1)},
+		},
+		{
+			name:     "json array with invalid items",
+			data:     `[{"user_id": "` + testUserA + `", "exp": 10}, {"user_id": "abc", "exp": 1}, {"user_id": "` + testUserB + `", "exp": -5}]`,
+			expected: map[string]int64{testUserA: 10},
+			invalid:  2,
+		},
+		{
+			name:     "csv without header and BOM",
+			data:     "\xef\xbb\xbf" + testUserA + ",100\n" + testUserB + ", 200.7\n",
+			expected: map[string]int64{testUserA: 100, testUserB: 200},
+		},
+		{
+			name:     "csv with header",
+			data:     "level,name,userid\n5,a," + testUserA + "\n2,b," + testUserB,
+			expected: map[string]int64{testUserA: curve.ExpForLevel(5), testUserB: curve.ExpForLevel(2)},
+		},
+		{
+			name:     "csv with malformed rows",
+			data:     testUserA + ",100\n" + testUserB + "\nnot an id,5\n" + testUserB + ",many\n" + testUserB + ",2000000000",
+			expected: map[string]int64{testUserA: 100},
+			invalid:  4,
+		},
+		{
+			name:     "duplicate users",
+			data:     testUserA + ",100\n" + testUserB + ",5\n" + testUserA + ",300",
+			expected: map[string]int64{testUserA: 300, testUserB: 5},
+		},
+		{
+			name: "values that are not finite or out of range",
+			data: testUserA + ",NaN\n" + testUserA + ",Inf\n" + testUserA + ",-Inf\n" + testUserA + ",1e300\n" +
+				testUserB + ",7",
+			expected: map[string]int64{testUserB: 7},
+			invalid:  4,
+		},
+		{
+			name:     "json values that are out of range",
+			data:     `{"` + testUserA + `": 1e300, "` + testUserB + `": 8}`,
+			expected: map[string]int64{testUserB: 8},
+			invalid:  1,
+		},
+		{
+			name:    "levels above the maximum",
+			data:    "user_id,level\n" + testUserA + ",100000\n" + testUserB + ",NaN",
+			invalid: 2,
+			err:     errImportEmpty,
+		},
+		{
+			name: "empty",
+			data: "  \n",
+			err:  errImportEmpty,
+		},
+	} {
+		entries, invalid, err := parseLevelsImport([]byte(testCase.data), curve)
+		if err != testCase.err || invalid != testCase.invalid {
+			t.Fatalf("%s: parseLevelsImport() returned %d invalid lines and error %v, expected %d and %v",
+				testCase.name, invalid, err, testCase.invalid, testCase.err)
+		}
+		if len(entries) != len(testCase.expected) {
+			t.Fatalf("%s: parseLevelsImport() returned %d entries, expected %d", testCase.name, len(entries), len(testCase.expected))
+		}
+		for _, entry := range entries {
+			if exp, ok := testCase.expected[entry.UserID]; !ok || exp != entry.Exp {
+				t.Fatalf("%s: parseLevelsImport() returned %d EXP for %s, expected %d", testCase.name, entry.Exp, entry.UserID, exp)
+			}
+		}
+	}
+
+	for _, data := range []string{`{"` + testUserA + `": }`, `[{"user_id": 1`, "\"unterminated,1\n"} {
+		if _, _, err := parseLevelsImport([]byte(data), curve); err == nil {
+			t.Fatalf("parseLevelsImport(%q) accepted malformed data", data)
+		}
+	}
+}
@@ -3,31 +3,82 @@ package levels
 import (
 	"math"
 	"math/rand"
+
+	"github.com/Seklfreak/Robyul2/helpers"
 )
 
-func GetLevelFromExp(exp int64) int {
-	calculatedLevel := 0.1 * math.Sqrt(float64(exp))
+// Curve calculates levels from EXP, Multiplier scales the EXP required for every level
+type Curve struct {
+	Multiplier float64
+}
+
+// DefaultCurve is used for global levels, and on servers without a custom curve
+var DefaultCurve = Curve{Multiplier: 1}
+
+// GetCurve returns the curve used on $guildID, guildID "global" returns the DefaultCurve
+func GetCurve(guildID string) Curve {
+	if guildID == "" || guildID == "global" {
+		return DefaultCurve
+	}
+
+	multiplier := helpers.GuildSettingsGetCached(guildID).LevelsCurveMultiplier
+	if multiplier <= 0 {
+		return DefaultCurve
+	}
+	return Curve{Multiplier: multiplier}
+}
+
+func (c Curve) multiplier() float64 {
+	if c.Multiplier <= 0 {
+		return 1
+	}
+	return c.Multiplier
+}
+
+func (c Curve) LevelFromExp(exp int64) int {
+	calculatedLevel := 0.1 * math.Sqrt(float64(exp)/c.multiplier())
 
 	return int(math.Floor(calculatedLevel))
 }
 
-func GetExpForLevel(level int) int64 {
+func (c Curve) ExpForLevel(level int) int64 {
 	if level <= 0 {
 		return 0
 	}
 
-	calculatedExp := math.Pow(float64(level)/0.1, 2)
+	calculatedExp := math.Pow(float64(level)/0.1, 2) * c.multiplier()
 	return int64(calculatedExp)
 }
 
-func GetProgressToNextLevelFromExp(exp int64) int {
-	expLevelCurrently := exp - GetExpForLevel(GetLevelFromExp(exp))
-	expLevelNext := GetExpForLevel(GetLevelFromExp(exp)+1) - GetExpForLevel(GetLevelFromExp(exp))
+func (c Curve) ProgressToNextLevelFromExp(exp int64) int {
+	expLevelCurrently := exp - c.ExpForLevel(c.LevelFromExp(exp))
+	expLevelNext := c.ExpForLevel(c.LevelFromExp(exp)+1) - c.ExpForLevel(c.LevelFromExp(exp))
+	// curves with tiny multipliers stored before the minimum was enforced can have levels without any EXP
+	if expLevelNext <= 0 {
+		return 0
+	}
+	if expLevelNext < 100 {
+		return int(expLevelCurrently * 100 / expLevelNext)
+	}
 	return int(expLevelCurrently / (expLevelNext / 100))
 }
 
-func getRandomExpForMessage() int64 {
-	min := 10
-	max := 15
-	return int64(rand.Intn(max-min) + min)
+func GetLevelFromExp(exp int64) int {
+	return DefaultCurve.LevelFromExp(exp)
+}
+
+func GetExpForLevel(level int) int64 {
+	return DefaultCurve.ExpForLevel(level)
+}
+
+func GetProgressToNextLevelFromExp(exp int64) int {
+	return DefaultCurve.ProgressToNextLevelFromExp(exp)
+}
+
+// getRandomExpForMessage returns a random amount of EXP between $min and $max
+func getRandomExpForMessage(min, max int) int64 {
+	if max <= min {
+		return int64(min)
+	}
+	return int64(rand.Intn(max-min+1) + min)
 }
@@ -0,0 +1,59 @@
+package levels
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDefaultCurve(t *testing.T) {
+	for _, exp := range []int64{0, 1, 99, 100, 101, 399, 400, 12345, 250000, 9876543210} {
+		expected := int(math.Floor(0.1 * math.Sqrt(float64(exp))))
+		if level := GetLevelFromExp(exp); level != expected {
+			t.Fatalf("levels.GetLevelFromExp(%d) returned %d, expected %d", exp, level, expected)
+		}
+	}
+	for level := 0; level <= 500; level++ {
+		expected := int64(0)
+		if level > 0 {
+			expected = int64(math.Pow(float64(level)/0.1, 2))
+		}
+		if exp := GetExpForLevel(level); exp != expected {
+			t.Fatalf("levels.GetExpForLevel(%d) returned %d, expected %d", level, exp, expected)
+		}
+	}
+}
+
+func TestCurveMultiplier(t *testing.T) {
+	curve := Curve{Multiplier: 2}
+	for level := 1; level <= 100; level++ {
+		exp := curve.ExpForLevel(level)
+		if exp != 2*GetExpForLevel(level) {
+			t.Fatalf("levels.Curve.ExpForLevel(%d) returned %d, expected %d", level, exp, 2*GetExpForLevel(level))
+		}
+		if curve.LevelFromExp(exp+1) != level {
+			t.Fatalf("levels.Curve.LevelFromExp(%d) returned %d, expected %d", exp+1, curve.LevelFromExp(exp+1), level)
+		}
+	}
+
+	if progress := (Curve{Multiplier: 0.25}).ProgressToNextLevelFromExp(10); progress != 40 {
+		t.Fatalf("levels.Curve.ProgressToNextLevelFromExp(10) returned %d, expected 40", progress)
+	}
+}
+
+func TestCurveTinyMultiplier(t *testing.T) {
+	curve := Curve{Multiplier: 0.001}
+	for _, exp := range []int64{0, 1, 5, 1000} {
+		if progress := curve.ProgressToNextLevelFromExp(exp); progress < 0 || progress > 100 {
+			t.Fatalf("levels.Curve.ProgressToNextLevelFromExp(%d) returned %d", exp, progress)
+		}
+	}
+
+	for _, text := range []string{"0.005", "0", "-1", "NaN", "101"} {
+		if _, err := parseMultiplier(text, maxCurveMultipler); err == nil {
+			t.Fatalf("levels.parseMultiplier(%q) accepted an invalid multiplier", text)
+		}
+	}
+	if multiplier, err := parseMultiplier("0.1x", maxCurveMultipler); err != nil || multiplier != 0.1 {
+		t.Fatalf("levels.parseMultiplier(\"0.1x\") = %v, %v, expected 0.1", multiplier, err)
+	}
+}
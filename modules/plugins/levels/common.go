@@ -150,7 +150,7 @@ func cacheTopLoop() {
 					rankData = Levels_Cache_Ranking_Item{
 						UserID:  level.Key,
 						EXP:     level.Value,
-						Level:   GetLevelFromExp(level.Value),
+						Level:   GetCurve(guildCache.GuildID).LevelFromExp(level.Value),
 						Ranking: i,
 					}
 
@@ -216,19 +216,33 @@ func processExpStackLoop() {
 		metrics.LevelsStackSize.Set(int64(expStack.Size()))
 		if !expStack.Empty() {
 			expItem := expStack.Pop().(ProcessExpInfo)
+			var exp int64
+			if expItem.Voice {
+				exp = getExpForVoice(expItem.GuildID, expItem.ChannelID, expItem.UserID)
+			} else {
+				exp = getExpForMessage(expItem.GuildID, expItem.ChannelID, expItem.UserID)
+			}
+			if exp <= 0 {
+				continue
+			}
+
 			levelsServerUser, err := getLevelsServerUserOrCreateNewWithoutLogging(expItem.GuildID, expItem.UserID)
 			helpers.Relax(err)
 
+			curve := GetCurve(expItem.GuildID)
 			expBefore := levelsServerUser.Exp
-			levelBefore := GetLevelFromExp(levelsServerUser.Exp)
+			levelBefore := curve.LevelFromExp(levelsServerUser.Exp)
 
-			levelsServerUser.Exp += getRandomExpForMessage()
+			levelsServerUser.Exp += exp
 
-			levelAfter := GetLevelFromExp(levelsServerUser.Exp)
+			levelAfter := curve.LevelFromExp(levelsServerUser.Exp)
 
 			err = helpers.MDbUpdateWithoutLogging(models.LevelsServerusersTable, levelsServerUser.ID, levelsServerUser)
 			helpers.Relax(err)
 
+			err = addSeasonExp(expItem.GuildID, expItem.UserID, exp)
+			helpers.RelaxLog(err)
+
 			if expBefore <= 0 || levelBefore != levelAfter {
 				// apply roles
 				err := applyLevelsRoles(expItem.GuildID, expItem.UserID, levelAfter)
@@ -238,8 +252,8 @@ func processExpStackLoop() {
 					helpers.RelaxLog(err)
 				}
 				guildSettings := helpers.GuildSettingsGetCached(expItem.GuildID)
-				// send level notifications
-				if levelAfter > levelBefore && guildSettings.LevelsNotificationCode != "" {
+				// send level notifications, voice channels can't receive messages
+				if levelAfter > levelBefore && guildSettings.LevelsNotificationCode != "" && !expItem.Voice {
 					go func() {
 						defer helpers.Recover()
 
@@ -50,6 +50,7 @@ type ProcessExpInfo struct {
 	GuildID   string
 	ChannelID string
 	UserID    string
+	Voice     bool // EXP for a minute in a voice channel, see processVoiceExpLoop
 }
 
 var (
@@ -131,6 +132,12 @@ func (m *Levels) Init(session *shardmanager.Manager) {
 	go cacheTopLoop()
 	log.WithField("module", "levels").Info("Started processCacheTopLoop")
 
+	go processVoiceExpLoop()
+	log.WithField("module", "levels").Info("Started processVoiceExpLoop")
+
+	go processSeasonsLoop()
+	log.WithField("module", "levels").Info("Started processSeasonsLoop")
+
 	activeBadgePickerUserIDs = make(map[string]string, 0)
 
 	go setServerFeaturesLoop()
@@ -1804,6 +1811,10 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 		if len(args) >= 1 && args[0] != "" {
 			switch args[0] {
 			case "leaderboard", "top":
+				if len(args) >= 2 && args[1] == "season" {
+					m.actionSeasonTop(msg) // see actionSeasonTop
+					return
+				}
 				// [p]level top
 				// TODO: use cached top list
 				var levelsServersUsers []models.LevelsServerusersEntry
@@ -1855,7 +1866,7 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 
 					topLevelEmbed.Fields = append(topLevelEmbed.Fields, &discordgo.MessageEmbedField{
 						Name:   fmt.Sprintf("%d. %s", displayRanking, fullUsername),
-						Value:  fmt.Sprintf("Level: %d", GetLevelFromExp(levelsServersUsers[i-offset].Exp)),
+						Value:  fmt.Sprintf("Level: %d", GetCurve(channel.GuildID).LevelFromExp(levelsServersUsers[i-offset].Exp)),
 						Inline: false,
 					})
 					displayRanking++
@@ -1887,7 +1898,7 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 
 					topLevelEmbed.Fields = append(topLevelEmbed.Fields, &discordgo.MessageEmbedField{
 						Name:   "Your Rank: " + serverRank,
-						Value:  fmt.Sprintf("Level: %d", GetLevelFromExp(thislevelUser.Exp)),
+						Value:  fmt.Sprintf("Level: %d", GetCurve(channel.GuildID).LevelFromExp(thislevelUser.Exp)),
 						Inline: false,
 					})
 
@@ -2230,6 +2241,49 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 					}
 				}
 				return
+			case "give", "take", "set": // see actionExpAdmin
+				helpers.RequireAdmin(msg, func() {
+					m.actionExpAdmin(args, msg)
+				})
+				return
+			case "import": // see actionImport
+				helpers.RequireAdmin(msg, func() {
+					m.actionImport(args, msg)
+				})
+				return
+			case "season", "seasons": // see actionSeason
+				if len(args) < 2 || args[1] == "archive" || args[1] == "history" {
+					helpers.RequireMod(msg, func() {
+						m.actionSeason(args, msg)
+					})
+					return
+				}
+				helpers.RequireAdmin(msg, func() {
+					m.actionSeason(args, msg)
+				})
+				return
+			case "voice": // see actionVoiceSettings
+				if len(args) < 2 {
+					helpers.RequireMod(msg, func() {
+						m.actionVoiceSettings(args, msg)
+					})
+					return
+				}
+				helpers.RequireAdmin(msg, func() {
+					m.actionVoiceSettings(args, msg)
+				})
+				return
+			case "exp", "curve", "no-exp-role", "multiplier", "multipliers": // see actionExpSettings
+				if (args[0] == "multiplier" || args[0] == "multipliers") && (len(args) < 2 || args[1] == "list") {
+					helpers.RequireMod(msg, func() {
+						m.actionExpSettings(args, msg)
+					})
+					return
+				}
+				helpers.RequireAdmin(msg, func() {
+					m.actionExpSettings(args, msg)
+				})
+				return
 				// [p]level process-history
 			case "process-history":
 				helpers.RequireBotAdmin(msg, func() {
@@ -2913,7 +2967,8 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 		zeroWidthWhitespace, err := strconv.Unquote(`'\u200b'`)
 		helpers.Relax(err)
 
-		localExpForLevel := GetExpForLevel(GetLevelFromExp(levelThisServerUser.Exp))
+		localCurve := GetCurve(channel.GuildID)
+		localExpForLevel := localCurve.ExpForLevel(localCurve.LevelFromExp(levelThisServerUser.Exp))
 		globalExpForLevel := GetExpForLevel(GetLevelFromExp(totalExp))
 
 		userLevelEmbed := &discordgo.MessageEmbed{
@@ -2926,14 +2981,14 @@ func (m *Levels) Action(command string, content string, msg *discordgo.Message,
 			Fields: []*discordgo.MessageEmbedField{
 				{
 					Name:   "Level",
-					Value:  strconv.Itoa(GetLevelFromExp(levelThisServerUser.Exp)),
+					Value:  strconv.Itoa(localCurve.LevelFromExp(levelThisServerUser.Exp)),
 					Inline: true,
 				},
 				{
 					Name: "Level Progress",
 					Value: fmt.Sprintf("%s/%s EXP (%d %%)",
-						humanize.Comma(levelThisServerUser.Exp-localExpForLevel), humanize.Comma(GetExpForLevel(GetLevelFromExp(levelThisServerUser.Exp)+1)-localExpForLevel),
-						GetProgressToNextLevelFromExp(levelThisServerUser.Exp),
+						humanize.Comma(levelThisServerUser.Exp-localExpForLevel), humanize.Comma(localCurve.ExpForLevel(localCurve.LevelFromExp(levelThisServerUser.Exp)+1)-localExpForLevel),
+						localCurve.ProgressToNextLevelFromExp(levelThisServerUser.Exp),
 					),
 					Inline: true,
 				},
@@ -3264,9 +3319,9 @@ func (m *Levels) GetProfileHTML(member *discordgo.Member, guild *discordgo.Guild
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_AVATAR_URL}", html.EscapeString(avatarUrl), -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_TITLE}", html.EscapeString(title), -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_BIO}", html.EscapeString(bio), -1)
-	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_SERVER_LEVEL}", strconv.Itoa(GetLevelFromExp(levelThisServerUser.Exp)), -1)
+	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_SERVER_LEVEL}", strconv.Itoa(GetCurve(guild.ID).LevelFromExp(levelThisServerUser.Exp)), -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_SERVER_RANK}", serverRank, -1)
-	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_SERVER_LEVEL_PERCENT}", strconv.Itoa(GetProgressToNextLevelFromExp(levelThisServerUser.Exp)), -1)
+	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_SERVER_LEVEL_PERCENT}", strconv.Itoa(GetCurve(guild.ID).ProgressToNextLevelFromExp(levelThisServerUser.Exp)), -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_GLOBAL_LEVEL}", strconv.Itoa(GetLevelFromExp(totalExp)), -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_GLOBAL_RANK}", globalRank, -1)
 	tempTemplateHtml = strings.Replace(tempTemplateHtml, "{USER_BACKGROUND_URL}", m.GetProfileBackgroundUrl(userData), -1)
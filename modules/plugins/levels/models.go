@@ -87,7 +87,7 @@ func getLevelForUser(userID string, guildID string) int {
 	} else {
 		for _, levelsServerUser := range levelsServersUser {
 			if levelsServerUser.GuildID == guildID {
-				return GetLevelFromExp(levelsServerUser.Exp)
+				return GetCurve(guildID).LevelFromExp(levelsServerUser.Exp)
 			}
 		}
 	}
@@ -0,0 +1,446 @@
+package levels
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// the EXP per message on servers without a custom range
+	defaultExpMin = 10
+	defaultExpMax = 14
+
+	maxExpPerMessage  = 1000
+	maxMultiplier     = 10
+	maxCurveMultipler = 100
+	// lower multipliers would make levels of the curve cost almost no EXP
+	minMultiplier = 0.1
+
+	eventTimeFormat = "2006-01-02T15:04"
+)
+
+// getExpRange returns the EXP members gain per message on the server
+func getExpRange(settings models.Config) (min, max int) {
+	if settings.LevelsExpMin <= 0 && settings.LevelsExpMax <= 0 {
+		return defaultExpMin, defaultExpMax
+	}
+	return settings.LevelsExpMin, settings.LevelsExpMax
+}
+
+// getExpForMessage returns the EXP for a message, with all multipliers applied
+// returns 0 if the user has a no EXP role
+func getExpForMessage(guildID, channelID, userID string) int64 {
+	settings := helpers.GuildSettingsGetCached(guildID)
+
+	return applyMultipliers(settings, guildID, channelID, userID, getRandomExpForMessage(getExpRange(settings)))
+}
+
+// getExpForVoice returns the EXP for a minute in the voice channel, with all multipliers applied
+// returns 0 if the user has a no EXP role
+func getExpForVoice(guildID, channelID, userID string) int64 {
+	settings := helpers.GuildSettingsGetCached(guildID)
+
+	return applyMultipliers(settings, guildID, channelID, userID, int64(getVoiceExpPerMinute(settings)))
+}
+
+// applyMultipliers applies the channel, role, and event multipliers to $exp
+func applyMultipliers(settings models.Config, guildID, channelID, userID string, exp int64) int64 {
+	var memberRoleIDs []string
+	member, err := helpers.GetGuildMemberWithoutApi(guildID, userID)
+	if err == nil {
+		memberRoleIDs = member.Roles
+	}
+	for _, roleID := range memberRoleIDs {
+		for _, noExpRoleID := range settings.LevelsNoExpRoleIDs {
+			if roleID == noExpRoleID {
+				return 0
+			}
+		}
+	}
+
+	multiplier := getChannelMultiplier(settings, channelID) *
+		getRoleMultiplier(settings, memberRoleIDs) *
+		getEventMultiplier(settings, time.Now())
+	if multiplier == 1 {
+		return exp
+	}
+	return int64(math.Round(float64(exp) * multiplier))
+}
+
+// getChannelMultiplier returns the multiplier for the channel, or the category of the channel
+func getChannelMultiplier(settings models.Config, channelID string) float64 {
+	var parentID string
+	channel, err := helpers.GetChannelWithoutApi(channelID)
+	if err == nil {
+		parentID = channel.ParentID
+	}
+
+	multiplier := float64(1)
+	for _, channelMultiplier := range settings.LevelsChannelMultipliers {
+		if channelMultiplier.ID == channelID {
+			return channelMultiplier.Multiplier
+		}
+		if parentID != "" && channelMultiplier.ID == parentID {
+			multiplier = channelMultiplier.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// getRoleMultiplier returns the highest multiplier of all roles, multipliers of multiple roles don't stack
+func getRoleMultiplier(settings models.Config, roleIDs []string) float64 {
+	var multiplier float64
+	for _, roleMultiplier := range settings.LevelsRoleMultipliers {
+		for _, roleID := range roleIDs {
+			if roleMultiplier.ID == roleID && roleMultiplier.Multiplier > multiplier {
+				multiplier = roleMultiplier.Multiplier
+			}
+		}
+	}
+	if multiplier <= 0 {
+		return 1
+	}
+	return multiplier
+}
+
+// getEventMultiplier returns the highest multiplier of all active events, including the weekend multiplier
+func getEventMultiplier(settings models.Config, now time.Time) float64 {
+	var multiplier float64
+	if settings.LevelsWeekendMultiplier > 0 {
+		weekday := now.UTC().Weekday()
+		if weekday == time.Saturday || weekday == time.Sunday {
+			multiplier = settings.LevelsWeekendMultiplier
+		}
+	}
+	for _, event := range settings.LevelsEventMultipliers {
+		if now.After(event.Start) && now.Before(event.End) && event.Multiplier > multiplier {
+			multiplier = event.Multiplier
+		}
+	}
+	if multiplier <= 0 {
+		return 1
+	}
+	return multiplier
+}
+
+func parseMultiplier(text string, max float64) (multiplier float64, err error) {
+	multiplier, err = strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(text), "x"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if multiplier < minMultiplier || multiplier > max || math.IsNaN(multiplier) {
+		return 0, fmt.Errorf("multiplier has to be between %s and %s",
+			strconv.FormatFloat(minMultiplier, 'f', -1, 64), strconv.FormatFloat(max, 'f', -1, 64))
+	}
+	return multiplier, nil
+}
+
+func formatMultiplier(multiplier float64) string {
+	return strconv.FormatFloat(multiplier, 'f', -1, 64) + "x"
+}
+
+// setMultiplier sets the multiplier for $id, a multiplier of 0 removes it
+func setMultiplier(multipliers []models.LevelsMultiplier, id string, multiplier float64) (result []models.LevelsMultiplier) {
+	result = make([]models.LevelsMultiplier, 0)
+	for _, item := range multipliers {
+		if item.ID != id {
+			result = append(result, item)
+		}
+	}
+	if multiplier > 0 {
+		result = append(result, models.LevelsMultiplier{ID: id, Multiplier: multiplier})
+	}
+	return result
+}
+
+// actionExpSettings configures how members gain EXP on the server
+// [p]levels exp <min> <max|reset>
+// [p]levels curve <multiplier|reset>
+// [p]levels no-exp-role <role>
+// [p]levels multiplier list
+// [p]levels multiplier channel <#channel or category id> <multiplier|off>
+// [p]levels multiplier role <role> <multiplier|off>
+// [p]levels multiplier weekend <multiplier|off>
+// [p]levels multiplier event <name> <multiplier> <start> <end>, or <name> off
+func (m *Levels) actionExpSettings(args []string, msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+
+	if len(args) < 2 {
+		if args[0] == "multiplier" || args[0] == "multipliers" {
+			args = append(args, "list")
+		} else {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	var change models.ElasticEventlogChange
+	var successText string
+
+	switch args[0] {
+	case "exp":
+		oldMin, oldMax := getExpRange(settings)
+		change = models.ElasticEventlogChange{Key: "levels_exp_range", OldValue: fmt.Sprintf("%d-%d", oldMin, oldMax)}
+		if args[1] == "reset" {
+			settings.LevelsExpMin, settings.LevelsExpMax = 0, 0
+		} else {
+			if len(args) < 3 {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			min, errMin := strconv.Atoi(args[1])
+			max, errMax := strconv.Atoi(args[2])
+			if errMin != nil || errMax != nil || min <= 0 || max < min || max > maxExpPerMessage {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.exp-range-invalid", maxExpPerMessage))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			settings.LevelsExpMin, settings.LevelsExpMax = min, max
+		}
+		newMin, newMax := getExpRange(settings)
+		change.NewValue = fmt.Sprintf("%d-%d", newMin, newMax)
+		successText = helpers.GetTextF("plugins.levels.exp-range-set", newMin, newMax)
+	case "curve":
+		change = models.ElasticEventlogChange{Key: "levels_curve_multiplier", OldValue: formatMultiplier(GetCurve(channel.GuildID).multiplier())}
+		if args[1] == "reset" {
+			settings.LevelsCurveMultiplier = 0
+		} else {
+			settings.LevelsCurveMultiplier, err = parseMultiplier(args[1], maxCurveMultipler)
+			if err != nil {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.multiplier-invalid", maxCurveMultipler))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+		}
+		newCurve := Curve{Multiplier: settings.LevelsCurveMultiplier}
+		change.NewValue = formatMultiplier(newCurve.multiplier())
+		successText = helpers.GetTextF("plugins.levels.curve-set",
+			formatMultiplier(newCurve.multiplier()), newCurve.ExpForLevel(10), newCurve.ExpForLevel(50))
+	case "no-exp-role":
+		role := findRole(guild, strings.Join(args[1:], " "))
+		if role == nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		change = models.ElasticEventlogChange{Key: "levels_noexproleids", OldValue: strings.Join(settings.LevelsNoExpRoleIDs, ";"), Type: models.EventlogTargetTypeRole}
+		newRoleIDs := make([]string, 0)
+		for _, roleID := range settings.LevelsNoExpRoleIDs {
+			if roleID != role.ID {
+				newRoleIDs = append(newRoleIDs, roleID)
+			}
+		}
+		if len(newRoleIDs) == len(settings.LevelsNoExpRoleIDs) {
+			newRoleIDs = append(newRoleIDs, role.ID)
+			successText = helpers.GetTextF("plugins.levels.no-exp-role-added", role.Name)
+		} else {
+			successText = helpers.GetTextF("plugins.levels.no-exp-role-removed", role.Name)
+		}
+		settings.LevelsNoExpRoleIDs = newRoleIDs
+		change.NewValue = strings.Join(settings.LevelsNoExpRoleIDs, ";")
+	case "multiplier", "multipliers":
+		switch args[1] {
+		case "list":
+			_, err = helpers.SendMessage(msg.ChannelID, getMultipliersText(settings, guild))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		case "channel", "role", "weekend":
+			if (args[1] == "weekend" && len(args) < 3) || (args[1] != "weekend" && len(args) < 4) {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			var multiplier float64
+			if strings.ToLower(args[len(args)-1]) != "off" {
+				multiplier, err = parseMultiplier(args[len(args)-1], maxMultiplier)
+				if err != nil {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.multiplier-invalid", maxMultiplier))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+			}
+			switch args[1] {
+			case "channel":
+				targetChannel, err := helpers.GetChannelOrCategoryFromMention(msg, args[2])
+				if err != nil || targetChannel.GuildID != channel.GuildID {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				change = models.ElasticEventlogChange{Key: "levels_channel_multiplier", Type: models.EventlogTargetTypeChannel,
+					OldValue: formatMultiplier(getChannelMultiplier(settings, targetChannel.ID))}
+				settings.LevelsChannelMultipliers = setMultiplier(settings.LevelsChannelMultipliers, targetChannel.ID, multiplier)
+				change.NewValue = formatMultiplier(getChannelMultiplier(settings, targetChannel.ID))
+			case "role":
+				role := findRole(guild, strings.Join(args[2:len(args)-1], " "))
+				if role == nil {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				change = models.ElasticEventlogChange{Key: "levels_role_multiplier", Type: models.EventlogTargetTypeRole,
+					OldValue: formatMultiplier(getRoleMultiplier(settings, []string{role.ID}))}
+				settings.LevelsRoleMultipliers = setMultiplier(settings.LevelsRoleMultipliers, role.ID, multiplier)
+				change.NewValue = formatMultiplier(getRoleMultiplier(settings, []string{role.ID}))
+			case "weekend":
+				change = models.ElasticEventlogChange{Key: "levels_weekend_multiplier", OldValue: formatMultiplier(math.Max(settings.LevelsWeekendMultiplier, 1))}
+				settings.LevelsWeekendMultiplier = multiplier
+				change.NewValue = formatMultiplier(math.Max(settings.LevelsWeekendMultiplier, 1))
+			}
+			successText = helpers.GetText("plugins.levels.multiplier-set")
+		case "event":
+			if len(args) < 4 {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			name := args[2]
+			oldEvents := getEventsText(settings.LevelsEventMultipliers)
+			// remove the event with the same name, and all events which are over
+			newEvents := make([]models.LevelsEventMultiplier, 0)
+			for _, event := range settings.LevelsEventMultipliers {
+				if event.Name != name && event.End.After(time.Now()) {
+					newEvents = append(newEvents, event)
+				}
+			}
+			if strings.ToLower(args[3]) != "off" {
+				if len(args) < 6 {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				multiplier, err := parseMultiplier(args[3], maxMultiplier)
+				if err != nil {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.multiplier-invalid", maxMultiplier))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				start := time.Now()
+				if strings.ToLower(args[4]) != "now" {
+					start, err = time.Parse(eventTimeFormat, args[4])
+				}
+				end, errEnd := time.Parse(eventTimeFormat, args[5])
+				if err != nil || errEnd != nil || !end.After(start) || !end.After(time.Now()) {
+					_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.multiplier-event-invalid-time"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				newEvents = append(newEvents, models.LevelsEventMultiplier{
+					Name:       name,
+					Multiplier: multiplier,
+					Start:      start,
+					End:        end,
+				})
+			}
+			settings.LevelsEventMultipliers = newEvents
+			change = models.ElasticEventlogChange{Key: "levels_event_multipliers", OldValue: oldEvents, NewValue: getEventsText(newEvents)}
+			successText = helpers.GetText("plugins.levels.multiplier-set")
+		default:
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulLevelsExpUpdate, "",
+		[]models.ElasticEventlogChange{change},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// getMultipliersText lists the EXP settings of the server
+func getMultipliersText(settings models.Config, guild *discordgo.Guild) (text string) {
+	min, max := getExpRange(settings)
+	text = fmt.Sprintf("**EXP per Message:** %d - %d\n", min, max)
+	text += fmt.Sprintf("**Curve:** %s\n", formatMultiplier(Curve{Multiplier: settings.LevelsCurveMultiplier}.multiplier()))
+
+	text += "**No EXP Roles:**"
+	if len(settings.LevelsNoExpRoleIDs) <= 0 {
+		text += " None"
+	}
+	for _, roleID := range settings.LevelsNoExpRoleIDs {
+		text += " `" + getRoleName(guild, roleID) + "`"
+	}
+
+	text += "\n**Channel Multipliers:**"
+	if len(settings.LevelsChannelMultipliers) <= 0 {
+		text += " None"
+	}
+	for _, multiplier := range settings.LevelsChannelMultipliers {
+		text += fmt.Sprintf(" <#%s> %s", multiplier.ID, formatMultiplier(multiplier.Multiplier))
+	}
+
+	text += "\n**Role Multipliers:**"
+	if len(settings.LevelsRoleMultipliers) <= 0 {
+		text += " None"
+	}
+	for _, multiplier := range settings.LevelsRoleMultipliers {
+		text += fmt.Sprintf(" `%s` %s", getRoleName(guild, multiplier.ID), formatMultiplier(multiplier.Multiplier))
+	}
+
+	text += "\n**Weekend Multiplier:** "
+	if settings.LevelsWeekendMultiplier > 0 {
+		text += formatMultiplier(settings.LevelsWeekendMultiplier)
+	} else {
+		text += "None"
+	}
+
+	text += "\n**Events:** " + getEventsText(settings.LevelsEventMultipliers)
+	return text
+}
+
+func getEventsText(events []models.LevelsEventMultiplier) (text string) {
+	var eventTexts []string
+	for _, event := range events {
+		if event.End.Before(time.Now()) {
+			continue
+		}
+		eventTexts = append(eventTexts, fmt.Sprintf("%s %s (%s - %s UTC)", event.Name, formatMultiplier(event.Multiplier),
+			event.Start.UTC().Format(eventTimeFormat), event.End.UTC().Format(eventTimeFormat)))
+	}
+	if len(eventTexts) <= 0 {
+		return "None"
+	}
+	return strings.Join(eventTexts, ", ")
+}
+
+// findRole finds a role on the guild by mention, ID, or name
+func findRole(guild *discordgo.Guild, text string) *discordgo.Role {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "<@&"), ">")
+	for _, role := range guild.Roles {
+		if role.ID == text || strings.ToLower(role.Name) == strings.ToLower(text) {
+			return role
+		}
+	}
+	return nil
+}
+
+func getRoleName(guild *discordgo.Guild, roleID string) string {
+	for _, role := range guild.Roles {
+		if role.ID == roleID {
+			return role.Name
+		}
+	}
+	return "N/A (#" + roleID + ")"
+}
@@ -0,0 +1,487 @@
+package levels
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/dustin/go-humanize"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	// how many members are stored as winners of a season, at least, more are stored if the reward role goes to more members
+	seasonWinnersArchived = 10
+
+	maxSeasonRewardTop = 25
+)
+
+var (
+	activeSeasons     = make(map[string]models.LevelsSeasonEntry)
+	activeSeasonsLock sync.RWMutex
+
+	errSeasonActive    = errors.New("there is an active season already")
+	errSeasonNotActive = errors.New("there is no active season")
+)
+
+// loadActiveSeasons caches all active seasons
+func loadActiveSeasons() (err error) {
+	var seasons []models.LevelsSeasonEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.LevelsSeasonsTable).Find(bson.M{"active": true})).All(&seasons)
+	if err != nil {
+		return err
+	}
+
+	activeSeasonsLock.Lock()
+	defer activeSeasonsLock.Unlock()
+	activeSeasons = make(map[string]models.LevelsSeasonEntry)
+	for _, season := range seasons {
+		activeSeasons[season.GuildID] = season
+	}
+	return nil
+}
+
+func getActiveSeason(guildID string) (season models.LevelsSeasonEntry, ok bool) {
+	activeSeasonsLock.RLock()
+	defer activeSeasonsLock.RUnlock()
+	season, ok = activeSeasons[guildID]
+	return season, ok
+}
+
+// addSeasonExp adds EXP to the active season of the guild, if there is one
+func addSeasonExp(guildID, userID string, exp int64) (err error) {
+	season, ok := getActiveSeason(guildID)
+	if !ok {
+		return nil
+	}
+
+	return helpers.MDbUpsertWithoutLogging(models.LevelsSeasonUsersTable,
+		bson.M{"seasonid": season.ID, "userid": userID},
+		bson.M{"$inc": bson.M{"exp": exp}, "$setOnInsert": bson.M{"guildid": guildID}},
+	)
+}
+
+// getSeasonEnd returns when a season started at $start ends, weekly seasons end on mondays, monthly seasons on the first of the month
+// manual seasons don't end automatically
+func getSeasonEnd(seasonType models.LevelsSeasonType, start time.Time) time.Time {
+	start = start.UTC()
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	switch seasonType {
+	case models.LevelsSeasonTypeWeekly:
+		daysUntilMonday := (int(time.Monday) - int(day.Weekday()) + 7) % 7
+		if daysUntilMonday == 0 {
+			daysUntilMonday = 7
+		}
+		return day.AddDate(0, 0, daysUntilMonday)
+	case models.LevelsSeasonTypeMonthly:
+		return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
+}
+
+// startSeason starts a new season on the guild
+func startSeason(guildID string, seasonType models.LevelsSeasonType, userID string) (season models.LevelsSeasonEntry, err error) {
+	if _, ok := getActiveSeason(guildID); ok {
+		return season, errSeasonActive
+	}
+
+	var lastSeason models.LevelsSeasonEntry
+	err = helpers.MdbOne(helpers.MdbCollection(models.LevelsSeasonsTable).Find(bson.M{"guildid": guildID}).Sort("-number"), &lastSeason)
+	if err != nil && !helpers.IsMdbNotFound(err) {
+		return season, err
+	}
+
+	now := time.Now()
+	season = models.LevelsSeasonEntry{
+		GuildID:   guildID,
+		Number:    lastSeason.Number + 1,
+		Type:      seasonType,
+		StartedAt: now,
+		EndsAt:    getSeasonEnd(seasonType, now),
+		Active:    true,
+	}
+	season.ID, err = helpers.MDbInsert(models.LevelsSeasonsTable, season)
+	if err != nil {
+		return season, err
+	}
+
+	activeSeasonsLock.Lock()
+	activeSeasons[guildID] = season
+	activeSeasonsLock.Unlock()
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, guildID,
+		models.EventlogTargetTypeGuild, userID,
+		models.EventlogTypeRobyulLevelsSeasonStart, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "levels_season_number",
+				Value: strconv.Itoa(season.Number),
+			},
+			{
+				Key:   "levels_season_type",
+				Value: string(season.Type),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return season, nil
+}
+
+// endSeason ends the season, stores the winners, and gives the reward roles to the winners
+func endSeason(season models.LevelsSeasonEntry, userID string) (endedSeason models.LevelsSeasonEntry, err error) {
+	// the reward role goes to the winners, so all members getting it have to be stored
+	winnersCount := seasonWinnersArchived
+	if rewardTop := helpers.GuildSettingsGetCached(season.GuildID).LevelsSeasonRewardTop; rewardTop > winnersCount {
+		winnersCount = rewardTop
+	}
+
+	topUsers, err := getSeasonTop(season, winnersCount)
+	if err != nil {
+		return season, err
+	}
+
+	season.Active = false
+	season.EndedAt = time.Now()
+	season.Winners = make([]models.LevelsSeasonWinner, 0)
+	for _, topUser := range topUsers {
+		season.Winners = append(season.Winners, models.LevelsSeasonWinner{UserID: topUser.UserID, Exp: topUser.Exp})
+	}
+	err = helpers.MDbUpdate(models.LevelsSeasonsTable, season.ID, season)
+	if err != nil {
+		return season, err
+	}
+
+	activeSeasonsLock.Lock()
+	if activeSeason, ok := activeSeasons[season.GuildID]; ok && activeSeason.ID == season.ID {
+		delete(activeSeasons, season.GuildID)
+	}
+	activeSeasonsLock.Unlock()
+
+	winnerIDs := make([]string, 0)
+	for _, winner := range season.Winners {
+		winnerIDs = append(winnerIDs, winner.UserID)
+	}
+	_, err = helpers.EventlogLog(time.Now(), season.GuildID, season.GuildID,
+		models.EventlogTargetTypeGuild, userID,
+		models.EventlogTypeRobyulLevelsSeasonEnd, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "levels_season_number",
+				Value: strconv.Itoa(season.Number),
+			},
+			{
+				Key:   "levels_season_winners",
+				Value: strings.Join(winnerIDs, ";"),
+				Type:  models.EventlogTargetTypeUser,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	err = applySeasonRewards(season)
+	helpers.RelaxLog(err)
+
+	return season, nil
+}
+
+// applySeasonRewards removes the reward role from the winners of the previous season, and queues adding it to the new winners
+func applySeasonRewards(season models.LevelsSeasonEntry) (err error) {
+	settings := helpers.GuildSettingsGetCached(season.GuildID)
+	if settings.LevelsSeasonRewardRoleID == "" || settings.LevelsSeasonRewardTop <= 0 {
+		return nil
+	}
+
+	newWinners := make(map[string]bool)
+	for i, winner := range season.Winners {
+		if i >= settings.LevelsSeasonRewardTop {
+			break
+		}
+		newWinners[winner.UserID] = true
+	}
+
+	var previousSeason models.LevelsSeasonEntry
+	err = helpers.MdbOne(helpers.MdbCollection(models.LevelsSeasonsTable).Find(
+		bson.M{"guildid": season.GuildID, "number": bson.M{"$lt": season.Number}, "active": false}).Sort("-number"),
+		&previousSeason,
+	)
+	if err != nil && !helpers.IsMdbNotFound(err) {
+		return err
+	}
+	session := cache.GetSession().SessionForGuildS(season.GuildID)
+	for _, winner := range previousSeason.Winners {
+		if newWinners[winner.UserID] || !helpers.GetIsInGuild(season.GuildID, winner.UserID) {
+			continue
+		}
+		err = session.GuildMemberRoleRemove(season.GuildID, winner.UserID, settings.LevelsSeasonRewardRoleID)
+		if err != nil {
+			cache.GetLogger().WithField("module", "levels").Warnf("failed to remove season reward role: %s", err.Error())
+		}
+	}
+
+	for userID := range newWinners {
+		if !helpers.GetIsInGuild(season.GuildID, userID) {
+			continue
+		}
+		_, err = cache.GetMachineryServer().SendTask(helpers.AddRoleSignature(season.GuildID, userID, settings.LevelsSeasonRewardRoleID))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getSeasonTop returns the members with the most EXP in the season
+func getSeasonTop(season models.LevelsSeasonEntry, limit int) (topUsers []models.LevelsSeasonUserEntry, err error) {
+	err = helpers.MDbIter(helpers.MdbCollection(models.LevelsSeasonUsersTable).Find(
+		bson.M{"seasonid": season.ID, "exp": bson.M{"$gt": 0}}).Sort("-exp").Limit(limit)).All(&topUsers)
+	return topUsers, err
+}
+
+// processSeasonsLoop ends weekly and monthly seasons, and starts the next season
+func processSeasonsLoop() {
+	log := cache.GetLogger()
+
+	defer helpers.Recover()
+	defer func() {
+		go func() {
+			log.WithField("module", "levels").Error("The processSeasonsLoop died. Please investigate! Will be restarted in 60 seconds")
+			time.Sleep(60 * time.Second)
+			processSeasonsLoop()
+		}()
+	}()
+
+	err := loadActiveSeasons()
+	helpers.Relax(err)
+
+	for {
+		activeSeasonsLock.RLock()
+		endingSeasons := make([]models.LevelsSeasonEntry, 0)
+		for _, season := range activeSeasons {
+			if !season.EndsAt.IsZero() && time.Now().After(season.EndsAt) {
+				endingSeasons = append(endingSeasons, season)
+			}
+		}
+		activeSeasonsLock.RUnlock()
+
+		for _, season := range endingSeasons {
+			botID := cache.GetSession().SessionForGuildS(season.GuildID).State.User.ID
+			_, err = endSeason(season, botID)
+			if err != nil {
+				helpers.RelaxLog(err)
+				continue
+			}
+			_, err = startSeason(season.GuildID, season.Type, botID)
+			helpers.RelaxLog(err)
+		}
+
+		time.Sleep(1 * time.Minute)
+	}
+}
+
+// actionSeason manages seasons on the server
+// [p]levels season
+// [p]levels season start <weekly|monthly|manual>
+// [p]levels season end
+// [p]levels season rewards <role> <top n>, or off
+// [p]levels season archive
+func (m *Levels) actionSeason(args []string, msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+
+	if len(args) < 2 {
+		season, ok := getActiveSeason(channel.GuildID)
+		if !ok {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-none", helpers.GetPrefixForServer(channel.GuildID)))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		ends := "when ended by an Admin"
+		if !season.EndsAt.IsZero() {
+			ends = fmt.Sprintf("%s UTC (in %s)", season.EndsAt.UTC().Format(time.ANSIC), helpers.HumanizeDuration(time.Until(season.EndsAt)))
+		}
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-status",
+			season.Number, string(season.Type), season.StartedAt.UTC().Format(time.ANSIC), ends, helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	switch args[1] {
+	case "start":
+		seasonType := models.LevelsSeasonTypeManual
+		if len(args) >= 3 {
+			seasonType = models.LevelsSeasonType(strings.ToLower(args[2]))
+		}
+		if seasonType != models.LevelsSeasonTypeManual && seasonType != models.LevelsSeasonTypeWeekly && seasonType != models.LevelsSeasonTypeMonthly {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		season, err := startSeason(channel.GuildID, seasonType, msg.Author.ID)
+		if err == errSeasonActive {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.season-start-error-active"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		helpers.Relax(err)
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-start-success", season.Number))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	case "end", "stop":
+		season, ok := getActiveSeason(channel.GuildID)
+		if !ok {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-none", helpers.GetPrefixForServer(channel.GuildID)))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		season, err = endSeason(season, msg.Author.ID)
+		helpers.Relax(err)
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-end-success", season.Number))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	case "rewards", "reward":
+		if len(args) < 3 {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		settings := helpers.GuildSettingsGetCached(channel.GuildID)
+		change := models.ElasticEventlogChange{Key: "levels_season_rewards",
+			OldValue: fmt.Sprintf("%s;%d", settings.LevelsSeasonRewardRoleID, settings.LevelsSeasonRewardTop)}
+		var successText string
+		if strings.ToLower(args[2]) == "off" {
+			settings.LevelsSeasonRewardRoleID = ""
+			settings.LevelsSeasonRewardTop = 0
+			successText = helpers.GetText("plugins.levels.season-rewards-disabled")
+		} else {
+			if len(args) < 4 {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			role := findRole(guild, strings.Join(args[2:len(args)-1], " "))
+			top, err := strconv.Atoi(args[len(args)-1])
+			if role == nil || err != nil || top <= 0 || top > maxSeasonRewardTop {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-rewards-invalid", maxSeasonRewardTop))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			settings.LevelsSeasonRewardRoleID = role.ID
+			settings.LevelsSeasonRewardTop = top
+			successText = helpers.GetTextF("plugins.levels.season-rewards-set", role.Name, top)
+		}
+		change.NewValue = fmt.Sprintf("%s;%d", settings.LevelsSeasonRewardRoleID, settings.LevelsSeasonRewardTop)
+
+		err = helpers.GuildSettingsSet(channel.GuildID, settings)
+		helpers.Relax(err)
+
+		_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+			models.EventlogTargetTypeGuild, msg.Author.ID,
+			models.EventlogTypeRobyulLevelsExpUpdate, "",
+			[]models.ElasticEventlogChange{change},
+			nil, false)
+		helpers.RelaxLog(err)
+
+		_, err = helpers.SendMessage(msg.ChannelID, successText)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	case "archive", "history":
+		var seasons []models.LevelsSeasonEntry
+		err = helpers.MDbIter(helpers.MdbCollection(models.LevelsSeasonsTable).Find(
+			bson.M{"guildid": channel.GuildID, "active": false}).Sort("-number").Limit(10)).All(&seasons)
+		helpers.Relax(err)
+		if len(seasons) <= 0 {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.season-archive-empty"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+
+		archiveEmbed := &discordgo.MessageEmbed{
+			Color:  0x0FADED,
+			Title:  helpers.GetTextF("plugins.levels.season-archive-embed-title", guild.Name),
+			Fields: []*discordgo.MessageEmbedField{},
+		}
+		for _, season := range seasons {
+			winnersText := "No winners"
+			for i, winner := range season.Winners {
+				if i == 0 {
+					winnersText = ""
+				}
+				if i >= 3 {
+					break
+				}
+				winnersText += fmt.Sprintf("%d. <@%s> (%s EXP)\n", i+1, winner.UserID, humanize.Comma(winner.Exp))
+			}
+			archiveEmbed.Fields = append(archiveEmbed.Fields, &discordgo.MessageEmbedField{
+				Name: fmt.Sprintf("Season %d (%s - %s)", season.Number,
+					season.StartedAt.UTC().Format("Jan 02, 2006"), season.EndedAt.UTC().Format("Jan 02, 2006")),
+				Value: winnersText,
+			})
+		}
+		_, err = helpers.SendEmbed(msg.ChannelID, archiveEmbed)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	default:
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// actionSeasonTop shows the top members of the active season
+// [p]levels top season
+func (m *Levels) actionSeasonTop(msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+
+	season, ok := getActiveSeason(channel.GuildID)
+	if !ok {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.season-none", helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	topUsers, err := getSeasonTop(season, 10)
+	helpers.Relax(err)
+	if len(topUsers) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.levels.top-server-no-stats"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	topEmbed := &discordgo.MessageEmbed{
+		Color:  0x0FADED,
+		Title:  helpers.GetTextF("plugins.levels.top-season-embed-title", season.Number, guild.Name),
+		Fields: []*discordgo.MessageEmbedField{},
+	}
+	if !season.EndsAt.IsZero() {
+		topEmbed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Season ends in %s", helpers.HumanizeDuration(time.Until(season.EndsAt)))}
+	}
+	for i, topUser := range topUsers {
+		name := "N/A"
+		member, err := helpers.GetGuildMemberWithoutApi(channel.GuildID, topUser.UserID)
+		if err == nil {
+			name = member.User.Username
+			if member.Nick != "" {
+				name += " ~ " + member.Nick
+			}
+		}
+		topEmbed.Fields = append(topEmbed.Fields, &discordgo.MessageEmbedField{
+			Name:   fmt.Sprintf("%d. %s", i+1, name),
+			Value:  fmt.Sprintf("Season EXP: %s", humanize.Comma(topUser.Exp)),
+			Inline: false,
+		})
+	}
+	if guild.Icon != "" {
+		topEmbed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: guild.IconURL()}
+	}
+
+	_, err = helpers.SendEmbed(msg.ChannelID, topEmbed)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
@@ -0,0 +1,272 @@
+package levels
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// the EXP per minute in voice channels on servers without a custom rate
+	defaultVoiceExpPerMinute = 5
+
+	maxVoiceExpPerMinute = 100
+)
+
+// getVoiceExpPerMinute returns the EXP members gain per minute in voice channels on the server
+func getVoiceExpPerMinute(settings models.Config) int {
+	if settings.LevelsVoiceExpPerMinute <= 0 {
+		return defaultVoiceExpPerMinute
+	}
+	return settings.LevelsVoiceExpPerMinute
+}
+
+// processVoiceExpLoop pushes EXP for every member in a voice channel to the exp stack once a minute
+func processVoiceExpLoop() {
+	log := cache.GetLogger()
+
+	defer helpers.Recover()
+	defer func() {
+		go func() {
+			log.WithField("module", "levels").Error("The processVoiceExpLoop died. Please investigate! Will be restarted in 60 seconds")
+			time.Sleep(60 * time.Second)
+			processVoiceExpLoop()
+		}()
+	}()
+
+	for {
+		time.Sleep(1 * time.Minute)
+
+		for _, shard := range cache.GetSession().Sessions {
+			shard.State.RLock()
+			guilds := make([]*discordgo.Guild, len(shard.State.Guilds))
+			copy(guilds, shard.State.Guilds)
+			shard.State.RUnlock()
+
+			for _, guild := range guilds {
+				if !helpers.GuildSettingsGetCached(guild.ID).LevelsVoiceExpEnabled {
+					continue
+				}
+
+				for _, expInfo := range getVoiceExpInfos(shard, guild) {
+					expStack.Push(expInfo)
+				}
+			}
+		}
+	}
+}
+
+// getVoiceExpInfos returns all members of the guild which should receive voice EXP
+// members receive voice EXP if they are not deafened, and at least one other member in the channel is not muted
+func getVoiceExpInfos(shard *discordgo.Session, guild *discordgo.Guild) (expInfos []ProcessExpInfo) {
+	if helpers.IsBlacklistedGuild(guild.ID) || helpers.IsLimitedGuild(guild.ID) {
+		return nil
+	}
+	for _, temporaryIgnoredGuild := range temporaryIgnoredGuilds {
+		if temporaryIgnoredGuild == guild.ID {
+			return nil
+		}
+	}
+
+	settings := helpers.GuildSettingsGetCached(guild.ID)
+
+	shard.State.RLock()
+	voiceStatesByChannel := make(map[string][]discordgo.VoiceState)
+	for _, voiceState := range guild.VoiceStates {
+		if voiceState.ChannelID == "" || voiceState.ChannelID == guild.AfkChannelID {
+			continue
+		}
+		voiceStatesByChannel[voiceState.ChannelID] = append(voiceStatesByChannel[voiceState.ChannelID], *voiceState)
+	}
+	shard.State.RUnlock()
+
+	for channelID, voiceStates := range voiceStatesByChannel {
+		if containsID(settings.LevelsIgnoredVoiceChannelIDs, channelID) {
+			continue
+		}
+
+		var listeners []discordgo.VoiceState
+		var speakers int
+		for _, voiceState := range voiceStates {
+			member, err := helpers.GetGuildMemberWithoutApi(guild.ID, voiceState.UserID)
+			if err != nil || member.User == nil || member.User.Bot {
+				continue
+			}
+			if voiceState.Deaf || voiceState.SelfDeaf {
+				continue
+			}
+			listeners = append(listeners, voiceState)
+			if !voiceState.Mute && !voiceState.SelfMute && !voiceState.Suppress {
+				speakers++
+			}
+		}
+
+		for _, listener := range listeners {
+			// at least one other member has to be unmuted
+			otherSpeakers := speakers
+			if !listener.Mute && !listener.SelfMute && !listener.Suppress {
+				otherSpeakers--
+			}
+			if otherSpeakers <= 0 ||
+				containsID(settings.LevelsIgnoredUserIDs, listener.UserID) || helpers.IsBlacklisted(listener.UserID) {
+				continue
+			}
+
+			expInfos = append(expInfos, ProcessExpInfo{
+				GuildID:   guild.ID,
+				ChannelID: channelID,
+				UserID:    listener.UserID,
+				Voice:     true,
+			})
+		}
+	}
+
+	return expInfos
+}
+
+// actionVoiceSettings configures voice EXP on the server
+// [p]levels voice
+// [p]levels voice enable|disable
+// [p]levels voice rate <exp per minute|reset>
+// [p]levels voice ignore <voice channel id or name>
+func (m *Levels) actionVoiceSettings(args []string, msg *discordgo.Message) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+
+	if len(args) < 2 {
+		_, err = helpers.SendMessage(msg.ChannelID, getVoiceSettingsText(settings))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	var change models.ElasticEventlogChange
+	var successText string
+
+	switch args[1] {
+	case "enable", "disable":
+		change = models.ElasticEventlogChange{Key: "levels_voiceexp_enabled", OldValue: helpers.StoreBoolAsString(settings.LevelsVoiceExpEnabled)}
+		settings.LevelsVoiceExpEnabled = args[1] == "enable"
+		change.NewValue = helpers.StoreBoolAsString(settings.LevelsVoiceExpEnabled)
+		if settings.LevelsVoiceExpEnabled {
+			successText = helpers.GetTextF("plugins.levels.voice-enabled", getVoiceExpPerMinute(settings))
+		} else {
+			successText = helpers.GetText("plugins.levels.voice-disabled")
+		}
+	case "rate":
+		if len(args) < 3 {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		change = models.ElasticEventlogChange{Key: "levels_voiceexp_perminute", OldValue: strconv.Itoa(getVoiceExpPerMinute(settings))}
+		if args[2] == "reset" {
+			settings.LevelsVoiceExpPerMinute = 0
+		} else {
+			rate, err := strconv.Atoi(args[2])
+			if err != nil || rate <= 0 || rate > maxVoiceExpPerMinute {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.levels.voice-rate-invalid", maxVoiceExpPerMinute))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			settings.LevelsVoiceExpPerMinute = rate
+		}
+		change.NewValue = strconv.Itoa(getVoiceExpPerMinute(settings))
+		successText = helpers.GetTextF("plugins.levels.voice-rate-set", getVoiceExpPerMinute(settings))
+	case "ignore":
+		if len(args) < 3 {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		voiceChannel := findVoiceChannel(guild, strings.Join(args[2:], " "))
+		if voiceChannel == nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		change = models.ElasticEventlogChange{Key: "levels_ignoredvoicechannelids", Type: models.EventlogTargetTypeChannel,
+			OldValue: strings.Join(settings.LevelsIgnoredVoiceChannelIDs, ";")}
+		if containsID(settings.LevelsIgnoredVoiceChannelIDs, voiceChannel.ID) {
+			newIgnoredIDs := make([]string, 0)
+			for _, ignoredID := range settings.LevelsIgnoredVoiceChannelIDs {
+				if ignoredID != voiceChannel.ID {
+					newIgnoredIDs = append(newIgnoredIDs, ignoredID)
+				}
+			}
+			settings.LevelsIgnoredVoiceChannelIDs = newIgnoredIDs
+			successText = helpers.GetTextF("plugins.levels.voice-ignore-removed", voiceChannel.Name)
+		} else {
+			settings.LevelsIgnoredVoiceChannelIDs = append(settings.LevelsIgnoredVoiceChannelIDs, voiceChannel.ID)
+			successText = helpers.GetTextF("plugins.levels.voice-ignore-added", voiceChannel.Name)
+		}
+		change.NewValue = strings.Join(settings.LevelsIgnoredVoiceChannelIDs, ";")
+	default:
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulLevelsExpUpdate, "",
+		[]models.ElasticEventlogChange{change},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func getVoiceSettingsText(settings models.Config) (text string) {
+	text = "**Voice EXP:** "
+	if settings.LevelsVoiceExpEnabled {
+		text += "Enabled"
+	} else {
+		text += "Disabled"
+	}
+	text += fmt.Sprintf("\n**EXP per Minute:** %d", getVoiceExpPerMinute(settings))
+	text += "\n**Ignored Voice Channels:**"
+	if len(settings.LevelsIgnoredVoiceChannelIDs) <= 0 {
+		text += " None"
+	}
+	for _, channelID := range settings.LevelsIgnoredVoiceChannelIDs {
+		text += " <#" + channelID + ">"
+	}
+	return text
+}
+
+// findVoiceChannel finds a voice channel on the guild by ID, mention, or name
+func findVoiceChannel(guild *discordgo.Guild, text string) *discordgo.Channel {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "<#"), ">")
+	for _, guildChannel := range guild.Channels {
+		if guildChannel.Type != discordgo.ChannelTypeGuildVoice {
+			continue
+		}
+		if guildChannel.ID == text || strings.ToLower(guildChannel.Name) == strings.ToLower(text) {
+			return guildChannel
+		}
+	}
+	return nil
+}
+
+func containsID(ids []string, id string) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
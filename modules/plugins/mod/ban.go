@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/bwmarrin/discordgo"
 )
 
-// banHandler [p]ban <User> [<Days>] [<Reason>], checks for IsMod and Ban Permissions
+// banHandler [p]ban <User> [<Duration>] [<Days>] [<Reason>], checks for IsMod and Ban Permissions
+// bans with a duration, like 12h or 7d, are lifted automatically
 func banHandler(msg *discordgo.Message, content string, confirmation bool) {
 	if !helpers.IsMod(msg) {
 		helpers.SendMessage(msg.ChannelID, helpers.GetText("mod.no_permission"))
@@ -49,9 +53,21 @@ func banHandler(msg *discordgo.Message, content string, confirmation bool) {
 		return
 	}
 
+	// Duration Argument
+	var duration time.Duration
+	var err error
+
+	if len(args) >= offset+1 && !regexNumberOnly.MatchString(args[offset]) {
+		duration, err = router.ParseDuration(args[offset])
+		if err == nil {
+			offset++
+		} else {
+			duration = 0
+		}
+	}
+
 	// Days Argument
 	days := 0
-	var err error
 
 	if len(args) >= offset+1 {
 		dayArg := args[offset]
@@ -120,9 +136,17 @@ func banHandler(msg *discordgo.Message, content string, confirmation bool) {
 		"Issued by: %s#%s (#%s) | Delete Days: %d | Reason: ",
 		msg.Author.Username, msg.Author.Discriminator, msg.Author.ID, days,
 	)
+	if duration > 0 {
+		reasonText = fmt.Sprintf(
+			"Issued by: %s#%s (#%s) | Delete Days: %d | Duration: %s | Reason: ",
+			msg.Author.Username, msg.Author.Discriminator, msg.Author.ID, days, helpers.HumanizeDuration(duration),
+		)
+	}
 
+	var reason string
 	if len(args) >= offset+1 {
-		reasonText += strings.TrimSpace(strings.Replace(content, strings.Join(args[:offset], " "), "", 1))
+		reason = strings.TrimSpace(strings.Replace(content, strings.Join(args[:offset], " "), "", 1))
+		reasonText += reason
 	}
 
 	if strings.HasSuffix(reasonText, "Reason: ") {
@@ -145,6 +169,11 @@ func banHandler(msg *discordgo.Message, content string, confirmation bool) {
 				days,
 				reasonText,
 			), "✅", "🚫") {
+		var unbanAt time.Time
+		if duration > 0 {
+			unbanAt = time.Now().Add(duration)
+		}
+
 		for _, userToBan := range usersToBan {
 			err = cache.GetSession().SessionForGuildS(msg.GuildID).GuildBanCreateWithReason(guild.ID, userToBan.ID, reasonText, days)
 			if err != nil {
@@ -166,7 +195,25 @@ func banHandler(msg *discordgo.Message, content string, confirmation bool) {
 				"Banned User %s (#%s) on Guild %s (#%s) by %s (#%s)",
 				userToBan.Username, userToBan.ID, guild.Name, guild.ID, msg.Author.Username, msg.Author.ID,
 			))
-			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.user-banned-success", userToBan.Username, userToBan.ID))
+			_, err = AddInfraction(models.ModInfractionEntry{
+				GuildID:        guild.ID,
+				UserID:         userToBan.ID,
+				IssuedByUserID: msg.Author.ID,
+				Type:           models.ModInfractionTypeBan,
+				Reason:         reason,
+				Duration:       duration,
+			})
+			helpers.RelaxLog(err)
+
+			// schedules the unban, or removes previously scheduled unbans for permanent bans
+			err = helpers.CreatePendingUnban(guild.ID, userToBan.ID, unbanAt)
+			helpers.RelaxLog(err)
+
+			successText := helpers.GetTextF("plugins.mod.user-banned-success", userToBan.Username, userToBan.ID)
+			if !unbanAt.IsZero() {
+				successText = helpers.GetTextF("plugins.mod.user-banned-success-timed", userToBan.Username, userToBan.ID, unbanAt.UTC().Format(time.ANSIC)+" UTC")
+			}
+			_, err = helpers.SendMessage(msg.ChannelID, successText)
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 		}
 	}
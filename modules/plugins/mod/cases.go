@@ -0,0 +1,75 @@
+package mod
+
+import (
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/modules/router"
+)
+
+func (m *Mod) caseRoutes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "case",
+			Description: "shows a moderation case, every ban, unban, kick, mute, unmute and warning gets a case number",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermMod,
+			Params: []router.Param{
+				{Name: "case number", Type: router.ParamInt},
+			},
+			Handler: m.actionCase,
+		},
+		{
+			Name:        "reason",
+			Description: "replaces the reason of a moderation case, the eventlog message is updated as well",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermMod,
+			Params: []router.Param{
+				{Name: "case number", Type: router.ParamInt},
+				{Name: "reason", Type: router.ParamRest},
+			},
+			Handler: m.actionCaseReason,
+		},
+	}
+}
+
+// [p]case <case number>
+func (m *Mod) actionCase(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	caseEntry, err := helpers.GetModCase(channel.GuildID, ctx.Int("case number"))
+	if helpers.IsMdbNotFound(err) || (err == nil && caseEntry.EventlogID == "") || !cache.HasElastic() {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.case-not-found", ctx.Int("case number")))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	embed, err := helpers.GetModCaseEmbed(caseEntry)
+	helpers.Relax(err)
+
+	_, err = helpers.SendEmbed(msg.ChannelID, embed)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]reason <case number> <reason>
+func (m *Mod) actionCaseReason(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	caseEntry, err := helpers.GetModCase(channel.GuildID, ctx.Int("case number"))
+	if helpers.IsMdbNotFound(err) || (err == nil && caseEntry.EventlogID == "") || !cache.HasElastic() {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.case-not-found", ctx.Int("case number")))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	_, err = helpers.SetModCaseReason(caseEntry, msg.Author.ID, ctx.String("reason"))
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.case-reason-success", caseEntry.CaseNumber))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
@@ -0,0 +1,503 @@
+package mod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/globalsign/mgo/bson"
+)
+
+func (m *Mod) infractionRoutes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "warn",
+			Description: "warns an user, too many warnings will be punished according to the escalation rules",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermMod,
+			Params: []router.Param{
+				{Name: "user", Type: router.ParamUser},
+				{Name: "reason", Type: router.ParamRest},
+			},
+			Handler: m.actionWarn,
+		},
+		{
+			Name:        "infractions",
+			Aliases:     []string{"infraction"},
+			Description: "lists the warnings, mutes, kicks and bans of an user",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermMod,
+			Params: []router.Param{
+				{Name: "user", Type: router.ParamUser},
+			},
+			Handler: m.actionInfractions,
+			Subcommands: []*router.Command{
+				{
+					Name:        "remove",
+					Aliases:     []string{"delete", "del"},
+					Description: "removes an infraction, removed warnings no longer count towards the escalation rules",
+					Params: []router.Param{
+						{Name: "infraction id", Type: router.ParamString},
+					},
+					Handler: m.actionInfractionRemove,
+				},
+				{
+					Name:        "escalation",
+					Aliases:     []string{"escalations"},
+					Description: "lists the escalation rules of the server",
+					Handler:     m.actionEscalationList,
+					Subcommands: []*router.Command{
+						{
+							Name:        "add",
+							Description: "punishes users that received the given amount of warnings within the period, action can be mute, kick or ban",
+							Permission:  router.PermissionAdmin,
+							Params: []router.Param{
+								{Name: "warnings", Type: router.ParamInt},
+								{Name: "period", Type: router.ParamDuration},
+								{Name: "action", Type: router.ParamString},
+								{Name: "mute duration", Type: router.ParamDuration, Optional: true},
+							},
+							Handler: m.actionEscalationAdd,
+						},
+						{
+							Name:        "remove",
+							Aliases:     []string{"delete", "del"},
+							Description: "removes an escalation rule",
+							Permission:  router.PermissionAdmin,
+							Params: []router.Param{
+								{Name: "rule number", Type: router.ParamInt},
+							},
+							Handler: m.actionEscalationRemove,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// AddInfraction stores an infraction in the database
+func AddInfraction(infraction models.ModInfractionEntry) (models.ModInfractionEntry, error) {
+	if infraction.CreatedAt.IsZero() {
+		infraction.CreatedAt = time.Now()
+	}
+
+	newID, err := helpers.MDbInsert(models.ModInfractionsTable, infraction)
+	if err != nil {
+		return infraction, err
+	}
+	infraction.ID = newID
+
+	return infraction, nil
+}
+
+// WarnUser warns $userID on $guildID and applies the escalation rules of the guild
+// returns the warning, and the punishment if an escalation rule has been triggered
+func WarnUser(guildID, userID, issuedByUserID, reason string) (warning models.ModInfractionEntry, punishment *models.ModInfractionEntry, err error) {
+	warning, err = AddInfraction(models.ModInfractionEntry{
+		GuildID:        guildID,
+		UserID:         userID,
+		IssuedByUserID: issuedByUserID,
+		Type:           models.ModInfractionTypeWarning,
+		Reason:         reason,
+	})
+	if err != nil {
+		return warning, nil, err
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, userID,
+		models.EventlogTargetTypeUser, issuedByUserID,
+		models.EventlogTypeRobyulWarn, reason,
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "infraction_id",
+				Value: helpers.MdbIdToHuman(warning.ID),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	guild, err := helpers.GetGuild(guildID)
+	if err == nil {
+		dmChannel, err := cache.GetSession().SessionForGuildS(guildID).UserChannelCreate(userID)
+		if err == nil {
+			helpers.SendMessage(dmChannel.ID, helpers.GetTextF("plugins.mod.warn-dm", guild.Name, reason))
+		}
+	}
+
+	punishment, err = escalateWarnings(guildID, userID)
+	return warning, punishment, err
+}
+
+// escalateWarnings punishes $userID according to the rule with the highest amount of warnings that matches
+// a rule matches once, when the warning that reaches its amount is given, further warnings don't repeat its punishment
+func escalateWarnings(guildID, userID string) (punishment *models.ModInfractionEntry, err error) {
+	var matchingRule *models.ModEscalationRule
+	var warnings int
+	rules := helpers.GuildSettingsGetCached(guildID).ModEscalationRules
+	for i := range rules {
+		if matchingRule != nil && rules[i].Warnings <= matchingRule.Warnings {
+			continue
+		}
+
+		count, err := helpers.MdbCount(models.ModInfractionsTable, bson.M{
+			"guildid":   guildID,
+			"userid":    userID,
+			"type":      models.ModInfractionTypeWarning,
+			"createdat": bson.M{"$gte": time.Now().Add(-rules[i].Period)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if count == rules[i].Warnings {
+			matchingRule = &rules[i]
+			warnings = count
+		}
+	}
+
+	if matchingRule == nil {
+		return nil, nil
+	}
+
+	session := cache.GetSession().SessionForGuildS(guildID)
+	reason := fmt.Sprintf("Automatic punishment: %d warnings within %s",
+		warnings, helpers.HumanizeDuration(matchingRule.Period))
+
+	switch matchingRule.Action {
+	case models.ModInfractionTypeMute:
+		var unmuteAt time.Time
+		var options []models.ElasticEventlogOption
+		if matchingRule.Duration > 0 {
+			unmuteAt = time.Now().Add(matchingRule.Duration)
+			options = []models.ElasticEventlogOption{
+				{
+					Key:   "mute_until",
+					Value: unmuteAt.Format(models.ISO8601),
+				},
+			}
+		}
+
+		err = helpers.MuteUser(guildID, userID, unmuteAt)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = helpers.EventlogLog(time.Now(), guildID, userID,
+			models.EventlogTargetTypeUser, session.State.User.ID,
+			models.EventlogTypeRobyulMute, reason,
+			nil,
+			options, false)
+		helpers.RelaxLog(err)
+	case models.ModInfractionTypeKick, models.ModInfractionTypeBan:
+		if matchingRule.Action == models.ModInfractionTypeKick {
+			err = session.GuildMemberDeleteWithReason(guildID, userID, reason)
+		} else {
+			err = session.GuildBanCreateWithReason(guildID, userID, reason, 0)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the kick or ban itself is logged with its Discord event, this links it to the escalation
+		_, err = helpers.EventlogLog(time.Now(), guildID, userID,
+			models.EventlogTargetTypeUser, session.State.User.ID,
+			models.EventlogTypeRobyulEscalation, reason,
+			nil,
+			[]models.ElasticEventlogOption{
+				{
+					Key:   "escalation_action",
+					Value: string(matchingRule.Action),
+				},
+			}, false)
+		helpers.RelaxLog(err)
+	default:
+		return nil, nil
+	}
+
+	infraction, err := AddInfraction(models.ModInfractionEntry{
+		GuildID:        guildID,
+		UserID:         userID,
+		IssuedByUserID: session.State.User.ID,
+		Type:           matchingRule.Action,
+		Reason:         reason,
+		Duration:       matchingRule.Duration,
+		Automatic:      true,
+	})
+	return &infraction, err
+}
+
+func getInfractionText(infraction models.ModInfractionEntry) (text string) {
+	text = fmt.Sprintf("`%s` **%s**", helpers.MdbIdToHuman(infraction.ID), infraction.Type)
+	if infraction.Duration > 0 {
+		text += " for " + helpers.HumanizeDuration(infraction.Duration)
+	} else if infraction.Type == models.ModInfractionTypeMute {
+		text += " permanently"
+	}
+	text += fmt.Sprintf(" by <@%s> at %s UTC", infraction.IssuedByUserID, infraction.CreatedAt.UTC().Format(time.ANSIC))
+	if infraction.Automatic {
+		text += " (automatic)"
+	}
+	if infraction.Reason != "" {
+		text += ": " + infraction.Reason
+	}
+	return text
+}
+
+func getEscalationRuleText(rule models.ModEscalationRule) (text string) {
+	text = fmt.Sprintf("**%d** warnings within **%s**: **%s**",
+		rule.Warnings, helpers.HumanizeDuration(rule.Period), rule.Action)
+	if rule.Action == models.ModInfractionTypeMute {
+		if rule.Duration > 0 {
+			text += " for **" + helpers.HumanizeDuration(rule.Duration) + "**"
+		} else {
+			text += " permanently"
+		}
+	}
+	return text
+}
+
+// [p]warn <user> <reason>
+func (m *Mod) actionWarn(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	targetUser := ctx.User("user")
+
+	if targetUser.ID == msg.Author.ID || targetUser.Bot {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.warn-error-invalid-user"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	warning, punishment, err := WarnUser(channel.GuildID, targetUser.ID, msg.Author.ID, ctx.String("reason"))
+	if err != nil && warning.ID.Valid() {
+		// the warning has been stored, only the punishment failed
+		helpers.RelaxLog(err)
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.warn-error-escalation",
+			targetUser.Username, targetUser.ID, helpers.MdbIdToHuman(warning.ID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	successText := helpers.GetTextF("plugins.mod.warn-success",
+		targetUser.Username, targetUser.ID, helpers.MdbIdToHuman(warning.ID))
+	if punishment != nil {
+		successText += "\n" + helpers.GetTextF("plugins.mod.warn-success-escalation", punishment.Reason, punishment.Type)
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]infractions <user>
+func (m *Mod) actionInfractions(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	targetUser := ctx.User("user")
+
+	var infractions []models.ModInfractionEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.ModInfractionsTable).Find(
+		bson.M{"guildid": channel.GuildID, "userid": targetUser.ID},
+	).Sort("createdat")).All(&infractions)
+	helpers.Relax(err)
+
+	if len(infractions) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.infractions-empty",
+			targetUser.Username, targetUser.ID))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	var warnings int
+	resultMessage := fmt.Sprintf(":scales: Infractions of `%s (#%s)`:\n", targetUser.Username, targetUser.ID)
+	for _, infraction := range infractions {
+		resultMessage += getInfractionText(infraction) + "\n"
+		if infraction.Type == models.ModInfractionTypeWarning {
+			warnings++
+		}
+	}
+	resultMessage += fmt.Sprintf("Found **%d** Infractions in total, **%d** of them are warnings.", len(infractions), warnings)
+
+	for _, page := range helpers.Pagify(resultMessage, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, page)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// [p]infraction remove <infraction id>
+func (m *Mod) actionInfractionRemove(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var infraction models.ModInfractionEntry
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModInfractionsTable).Find(bson.M{"guildid": channel.GuildID, "_id": helpers.HumanToMdbId(ctx.String("infraction id"))}),
+		&infraction,
+	)
+	if helpers.IsMdbNotFound(err) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.infraction-not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	err = helpers.MDbDelete(models.ModInfractionsTable, infraction.ID)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, infraction.UserID,
+		models.EventlogTargetTypeUser, msg.Author.ID,
+		models.EventlogTypeRobyulInfractionRemove, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "infraction_id",
+				Value: helpers.MdbIdToHuman(infraction.ID),
+			},
+			{
+				Key:   "infraction_type",
+				Value: string(infraction.Type),
+			},
+			{
+				Key:   "infraction_reason",
+				Value: infraction.Reason,
+			},
+			{
+				Key:   "infraction_issuedby",
+				Value: infraction.IssuedByUserID,
+				Type:  models.EventlogTargetTypeUser,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.infraction-remove-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]infractions escalation
+func (m *Mod) actionEscalationList(ctx *router.Context) {
+	msg := ctx.Msg
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	rules := helpers.GuildSettingsGetCached(channel.GuildID).ModEscalationRules
+	if len(rules) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.escalation-list-empty"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	resultMessage := ":chart_with_upwards_trend: Escalation rules on this server:\n"
+	for i, rule := range rules {
+		resultMessage += fmt.Sprintf("`#%d` %s\n", i+1, getEscalationRuleText(rule))
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, resultMessage)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]infractions escalation add <warnings> <period> <mute|kick|ban> [<mute duration>]
+func (m *Mod) actionEscalationAdd(ctx *router.Context) {
+	msg := ctx.Msg
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	rule := models.ModEscalationRule{
+		Warnings: ctx.Int("warnings"),
+		Period:   ctx.Duration("period"),
+		Action:   models.ModInfractionType(strings.ToLower(ctx.String("action"))),
+		Duration: ctx.Duration("mute duration"),
+	}
+	if rule.Action != models.ModInfractionTypeMute {
+		rule.Duration = 0
+	}
+
+	if rule.Warnings < 1 || rule.Period <= 0 ||
+		(rule.Action != models.ModInfractionTypeMute &&
+			rule.Action != models.ModInfractionTypeKick &&
+			rule.Action != models.ModInfractionTypeBan) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid")+"\n"+
+			ctx.Command.UsageText(helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	settings.ModEscalationRules = append(settings.ModEscalationRules, rule)
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulEscalationRulesUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "escalation_rule",
+				OldValue: "",
+				NewValue: getEscalationRuleText(rule),
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.escalation-add-success", getEscalationRuleText(rule)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]infractions escalation remove <rule number>
+func (m *Mod) actionEscalationRemove(ctx *router.Context) {
+	msg := ctx.Msg
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	number := ctx.Int("rule number")
+	if number < 1 || number > len(settings.ModEscalationRules) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.escalation-not-found", number))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	rule := settings.ModEscalationRules[number-1]
+	newRules := make([]models.ModEscalationRule, 0)
+	for i, oldRule := range settings.ModEscalationRules {
+		if i != number-1 {
+			newRules = append(newRules, oldRule)
+		}
+	}
+	settings.ModEscalationRules = newRules
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulEscalationRulesUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "escalation_rule",
+				OldValue: getEscalationRuleText(rule),
+				NewValue: "",
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.escalation-remove-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
 	"github.com/bwmarrin/discordgo"
 )
 
@@ -98,8 +99,10 @@ func kickHander(msg *discordgo.Message, content string, confirmation bool) {
 		msg.Author.Username, msg.Author.Discriminator, msg.Author.ID,
 	)
 
+	var reason string
 	if len(args) >= offset+1 {
-		reasonText += strings.TrimSpace(strings.Replace(content, strings.Join(args[:offset], " "), "", 1))
+		reason = strings.TrimSpace(strings.Replace(content, strings.Join(args[:offset], " "), "", 1))
+		reasonText += reason
 	}
 
 	if strings.HasSuffix(reasonText, "Reason: ") {
@@ -142,6 +145,14 @@ func kickHander(msg *discordgo.Message, content string, confirmation bool) {
 				"Kicked User %s (#%s) on Guild %s (#%s) by %s (#%s)",
 				userToKick.Username, userToKick.ID, guild.Name, guild.ID, msg.Author.Username, msg.Author.ID,
 			))
+			_, err = AddInfraction(models.ModInfractionEntry{
+				GuildID:        guild.ID,
+				UserID:         userToKick.ID,
+				IssuedByUserID: msg.Author.ID,
+				Type:           models.ModInfractionTypeKick,
+				Reason:         reason,
+			})
+			helpers.RelaxLog(err)
 			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.user-kicked-success", userToKick.Username, userToKick.ID))
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 		}
@@ -17,6 +17,7 @@ import (
 	"github.com/Seklfreak/Robyul2/emojis"
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bradfitz/slice"
 	"github.com/bwmarrin/discordgo"
@@ -65,12 +66,26 @@ func (m *Mod) Commands() []string {
 		"toggle-chatlog",
 		"pending-unmutes",
 		"pending-mutes",
+		"pending-unbans",
+		"pending-bans",
 		"batch-roles",
 		"set-bot-dp",
 		"pin",
+		"warn",
+		"infractions",
+		"infraction",
+		"lockdown",
+		"raid-detection",
+		"case",
+		"reason",
 	}
 }
 
+func (m *Mod) Routes() []*router.Command {
+	routes := append(m.infractionRoutes(), m.raidRoutes()...)
+	return append(routes, m.caseRoutes()...)
+}
+
 type CacheInviteInformation struct {
 	GuildID         string
 	CreatedByUserID string
@@ -484,7 +499,7 @@ func (m *Mod) Action(command string, content string, msg *discordgo.Message, ses
 			}
 		})
 		return
-	case "pending-unmutes", "pending-mutes": // [p]pending-unmutes
+	case "pending-unmutes", "pending-mutes", "pending-unbans", "pending-bans": // [p]pending-unmutes or [p]pending-unbans
 		helpers.RequireMod(msg, func() {
 			session.ChannelTyping(msg.ChannelID)
 
@@ -498,13 +513,22 @@ func (m *Mod) Action(command string, content string, msg *discordgo.Message, ses
 			tasksJson, err := cache.GetMachineryRedisClient().ZRange(key, 0, delayedTasks).Result()
 			helpers.Relax(err)
 
+			taskName := "unmute_user"
+			taskVerb := "Unmuting"
+			taskNoun := "unmutes"
+			if command == "pending-unbans" || command == "pending-bans" {
+				taskName = "unban_user"
+				taskVerb = "Unbanning"
+				taskNoun = "unbans"
+			}
+
 			resultText := ""
 
 			for _, taskJson := range tasksJson {
 				task, err := gabs.ParseJSON([]byte(taskJson))
 				helpers.Relax(err)
 
-				if task.Path("Name").Data().(string) != "unmute_user" {
+				if task.Path("Name").Data().(string) != taskName {
 					continue
 				}
 
@@ -525,13 +549,13 @@ func (m *Mod) Action(command string, content string, msg *discordgo.Message, ses
 					user.ID = userID
 				}
 
-				resultText += fmt.Sprintf("Unmuting %s (`#%s`) at %s UTC\n", user.Username, user.ID, eta.Format(time.ANSIC))
+				resultText += fmt.Sprintf("%s %s (`#%s`) at %s UTC\n", taskVerb, user.Username, user.ID, eta.Format(time.ANSIC))
 			}
 
 			if resultText == "" {
-				resultText = "Found no pending unmutes."
+				resultText = "Found no pending " + taskNoun + "."
 			} else {
-				resultText = "Found the following pending unmutes:\n" + resultText
+				resultText = "Found the following pending " + taskNoun + ":\n" + resultText
 			}
 
 			for _, page := range helpers.Pagify(resultText, "\n") {
@@ -586,6 +610,19 @@ func (m *Mod) Action(command string, content string, msg *discordgo.Message, ses
 					options, false)
 				helpers.RelaxLog(err)
 
+				var muteDuration time.Duration
+				if time.Now().Before(timeToUnmuteAt) {
+					muteDuration = time.Until(timeToUnmuteAt)
+				}
+				_, err = AddInfraction(models.ModInfractionEntry{
+					GuildID:        channel.GuildID,
+					UserID:         targetUser.ID,
+					IssuedByUserID: msg.Author.ID,
+					Type:           models.ModInfractionTypeMute,
+					Duration:       muteDuration,
+				})
+				helpers.RelaxLog(err)
+
 				_, err = helpers.SendMessage(msg.ChannelID, successText)
 				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 				return
@@ -2279,6 +2316,16 @@ func (m *Mod) OnGuildMemberAdd(member *discordgo.Member, session *discordgo.Sess
 			}
 		}
 
+		go func() {
+			defer helpers.Recover()
+
+			if member.User.Bot {
+				return
+			}
+
+			m.checkRaid(member, usedInvite.Code)
+		}()
+
 		go func() {
 			defer helpers.Recover()
 
@@ -2550,6 +2597,10 @@ func (m *Mod) OnGuildBanAdd(user *discordgo.GuildBanAdd, session *discordgo.Sess
 
 func (m *Mod) OnGuildBanRemove(user *discordgo.GuildBanRemove, session *discordgo.Session) {
 	m.removeBanFromCache(user)
+
+	// temporary bans that have been lifted manually don't need to be lifted again
+	err := helpers.RemovePendingUnbans(user.GuildID, user.User.ID)
+	helpers.RelaxLog(err)
 }
 func (m *Mod) OnMessageDelete(msg *discordgo.MessageDelete, session *discordgo.Session) {
 
@@ -0,0 +1,660 @@
+package mod
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	// the maximum amount of joins kept per guild for the raid detection
+	raidJoinsMaxLength = 1000
+)
+
+var (
+	ErrLockdownActive     = errors.New("lockdown is already active")
+	ErrLockdownNotActive  = errors.New("no lockdown active")
+	ErrNoLockdownChannels = errors.New("no lockdown channels configured")
+
+	raidJoins     = make(map[string][]raidJoin) // map[guildID][]raidJoin
+	raidAlertedAt = make(map[string]time.Time)  // map[guildID]time.Time
+	raidJoinsLock sync.Mutex
+
+	raidDetectionDefaults = models.RaidDetection{
+		Period:                time.Minute,
+		JoinThreshold:         10,
+		NewAccountThreshold:   5,
+		NewAccountAge:         24 * time.Hour,
+		SharedInviteThreshold: 8,
+	}
+)
+
+type raidJoin struct {
+	UserID           string
+	JoinedAt         time.Time
+	AccountCreatedAt time.Time
+	InviteCode       string
+}
+
+func (m *Mod) raidRoutes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "lockdown",
+			Description: "shows if the server is in lockdown, and which channels will be locked",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermMod,
+			Handler:     m.actionLockdownStatus,
+			Subcommands: []*router.Command{
+				{
+					Name:        "start",
+					Description: "denies everyone to send messages in the lockdown channels and pauses the vanity invite",
+					Params: []router.Param{
+						{Name: "reason", Type: router.ParamRest, Optional: true},
+					},
+					Handler: m.actionLockdownStart,
+				},
+				{
+					Name:        "end",
+					Aliases:     []string{"stop"},
+					Description: "restores the permissions of the lockdown channels and resumes the vanity invite",
+					Handler:     m.actionLockdownEnd,
+				},
+				{
+					Name:        "channel",
+					Description: "adds a channel to the lockdown channels, or removes it",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "channel", Type: router.ParamChannel},
+					},
+					Handler: m.actionLockdownChannel,
+				},
+			},
+		},
+		{
+			Name:        "raid-detection",
+			Description: "shows the raid detection settings, alerts are sent to the auto inspects channel",
+			Permission:  router.PermissionAdmin,
+			Module:      helpers.ModulePermMod,
+			Handler:     m.actionRaidDetectionStatus,
+			Subcommands: []*router.Command{
+				{
+					Name:        "enable",
+					Description: "enables the raid detection",
+					Handler:     m.actionRaidDetectionToggle,
+				},
+				{
+					Name:        "disable",
+					Description: "disables the raid detection",
+					Handler:     m.actionRaidDetectionToggle,
+				},
+				{
+					Name:        "joins",
+					Description: "triggers if more users join within the period, 0 disables it",
+					Params: []router.Param{
+						{Name: "joins", Type: router.ParamInt},
+						{Name: "period", Type: router.ParamDuration},
+					},
+					Handler: m.actionRaidDetectionSet,
+				},
+				{
+					Name:        "new-accounts",
+					Description: "triggers if more users with accounts younger than the account age join within the period, 0 disables it",
+					Params: []router.Param{
+						{Name: "joins", Type: router.ParamInt},
+						{Name: "account age", Type: router.ParamDuration},
+					},
+					Handler: m.actionRaidDetectionSet,
+				},
+				{
+					Name:        "invites",
+					Description: "triggers if more users join using the same invite within the period, 0 disables it",
+					Params: []router.Param{
+						{Name: "joins", Type: router.ParamInt},
+					},
+					Handler: m.actionRaidDetectionSet,
+				},
+				{
+					Name:        "auto-lockdown",
+					Description: "starts a lockdown automatically when a raid is detected",
+					Params: []router.Param{
+						{Name: "on or off", Type: router.ParamString},
+					},
+					Handler: m.actionRaidDetectionSet,
+				},
+			},
+		},
+	}
+}
+
+// checkRaid adds the join to the raid detection, and alerts the mods and starts a lockdown if a threshold is reached
+func (m *Mod) checkRaid(member *discordgo.Member, inviteCode string) {
+	settings := helpers.GuildSettingsGetCached(member.GuildID)
+	if !settings.RaidDetection.Enabled || settings.RaidDetection.Period <= 0 {
+		return
+	}
+	detection := settings.RaidDetection
+
+	raidJoinsLock.Lock()
+	joins := make([]raidJoin, 0)
+	for _, join := range raidJoins[member.GuildID] {
+		if time.Since(join.JoinedAt) <= detection.Period {
+			joins = append(joins, join)
+		}
+	}
+	joins = append(joins, raidJoin{
+		UserID:           member.User.ID,
+		JoinedAt:         time.Now(),
+		AccountCreatedAt: helpers.GetTimeFromSnowflake(member.User.ID),
+		InviteCode:       inviteCode,
+	})
+	if len(joins) > raidJoinsMaxLength {
+		joins = joins[len(joins)-raidJoinsMaxLength:]
+	}
+	raidJoins[member.GuildID] = joins
+
+	var newAccounts, sharedInvite int
+	for _, join := range joins {
+		if time.Since(join.AccountCreatedAt) < detection.NewAccountAge {
+			newAccounts++
+		}
+		if inviteCode != "" && join.InviteCode == inviteCode {
+			sharedInvite++
+		}
+	}
+
+	triggers := make([]string, 0)
+	if detection.JoinThreshold > 0 && len(joins) >= detection.JoinThreshold {
+		triggers = append(triggers, fmt.Sprintf("**%d** users joined within %s",
+			len(joins), helpers.HumanizeDuration(detection.Period)))
+	}
+	if detection.NewAccountThreshold > 0 && newAccounts >= detection.NewAccountThreshold {
+		triggers = append(triggers, fmt.Sprintf("**%d** users with accounts younger than %s joined within %s",
+			newAccounts, helpers.HumanizeDuration(detection.NewAccountAge), helpers.HumanizeDuration(detection.Period)))
+	}
+	if detection.SharedInviteThreshold > 0 && sharedInvite >= detection.SharedInviteThreshold {
+		triggers = append(triggers, fmt.Sprintf("**%d** users joined using the invite `%s` within %s",
+			sharedInvite, inviteCode, helpers.HumanizeDuration(detection.Period)))
+	}
+
+	// alert only once per period
+	if len(triggers) <= 0 || time.Since(raidAlertedAt[member.GuildID]) <= detection.Period {
+		raidJoinsLock.Unlock()
+		return
+	}
+	raidAlertedAt[member.GuildID] = time.Now()
+	raidJoinsLock.Unlock()
+
+	cache.GetLogger().WithField("module", "mod").Infof("detected possible raid on guild #%s: %s",
+		member.GuildID, strings.Join(triggers, ", "))
+
+	prefix := helpers.GetPrefixForServer(member.GuildID)
+	footerText := helpers.GetTextF("plugins.mod.raid-alert-footer", prefix)
+	if detection.AutoLockdown {
+		_, err := StartLockdown(member.GuildID, cache.GetSession().SessionForGuildS(member.GuildID).State.User.ID,
+			"Raid detected: "+strings.Join(triggers, ", "))
+		switch err {
+		case nil:
+			footerText = helpers.GetTextF("plugins.mod.raid-alert-footer-lockdown", prefix)
+		case ErrLockdownActive:
+			footerText = helpers.GetTextF("plugins.mod.raid-alert-footer-lockdown-active", prefix)
+		default:
+			helpers.RelaxLog(err)
+		}
+	}
+
+	if settings.InspectsChannel == "" {
+		return
+	}
+
+	var joinedUsersText string
+	for i := len(joins) - 1; i >= 0 && i >= len(joins)-15; i-- {
+		joinedUsersText += fmt.Sprintf("<@%s> (`#%s`), created %s\n",
+			joins[i].UserID, joins[i].UserID, helpers.SinceInDaysText(joins[i].AccountCreatedAt))
+	}
+
+	_, err := helpers.SendEmbed(settings.InspectsChannel, &discordgo.MessageEmbed{
+		Title:       helpers.GetText("plugins.mod.raid-alert-title"),
+		Description: strings.Join(triggers, "\n"),
+		Fields: []*discordgo.MessageEmbedField{
+			{Name: "Latest Joins", Value: joinedUsersText, Inline: false},
+		},
+		Footer: &discordgo.MessageEmbedFooter{Text: footerText},
+		Color:  helpers.GetDiscordColorFromHex("#b22222"),
+	})
+	if err != nil {
+		cache.GetLogger().WithField("module", "mod").Warnf("Failed to send raid alert to channel #%s on guild #%s: %s",
+			settings.InspectsChannel, member.GuildID, err.Error())
+	}
+}
+
+// GetLockdown returns the active lockdown of a guild
+func GetLockdown(guildID string) (lockdown models.ModLockdownEntry, err error) {
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModLockdownsTable).Find(bson.M{"guildid": guildID}),
+		&lockdown,
+	)
+	return lockdown, err
+}
+
+// StartLockdown denies @everyone to send messages in the lockdown channels, removes the permission from roles
+// which allow it, and pauses the vanity invite
+// the previous permission overwrites are stored before changing them, so EndLockdown can restore them
+func StartLockdown(guildID, userID, reason string) (lockdown models.ModLockdownEntry, err error) {
+	_, err = GetLockdown(guildID)
+	if err == nil {
+		return lockdown, ErrLockdownActive
+	}
+	if !helpers.IsMdbNotFound(err) {
+		return lockdown, err
+	}
+
+	channelIDs := helpers.GuildSettingsGetCached(guildID).LockdownChannelIDs
+	if len(channelIDs) <= 0 {
+		return lockdown, ErrNoLockdownChannels
+	}
+
+	lockdown = models.ModLockdownEntry{
+		GuildID:         guildID,
+		StartedByUserID: userID,
+		StartedAt:       time.Now(),
+		Reason:          reason,
+		Channels:        make([]models.ModLockdownChannel, 0),
+	}
+
+	for _, channelID := range channelIDs {
+		channel, err := helpers.GetChannel(channelID)
+		if err != nil {
+			helpers.RelaxLog(err)
+			continue
+		}
+
+		lockdownChannel := models.ModLockdownChannel{
+			ChannelID: channel.ID,
+		}
+		for _, overwrite := range channel.PermissionOverwrites {
+			if overwrite.Type != "role" {
+				continue
+			}
+			if overwrite.ID == guildID {
+				lockdownChannel.OverwriteExisted = true
+				lockdownChannel.Allow = overwrite.Allow
+				lockdownChannel.Deny = overwrite.Deny
+				continue
+			}
+			if overwrite.Allow&discordgo.PermissionSendMessages == discordgo.PermissionSendMessages {
+				lockdownChannel.RoleOverwrites = append(lockdownChannel.RoleOverwrites, models.ModLockdownRoleOverwrite{
+					RoleID: overwrite.ID,
+					Allow:  overwrite.Allow,
+					Deny:   overwrite.Deny,
+				})
+			}
+		}
+		lockdown.Channels = append(lockdown.Channels, lockdownChannel)
+	}
+
+	vanityInvite, _ := helpers.GetVanityUrlByGuildID(guildID)
+	lockdown.VanityInvitePaused = vanityInvite.VanityName != "" && !vanityInvite.Paused
+
+	// store the lockdown first, so it can be ended even if we fail halfway
+	// the unique index on the guild makes sure a lockdown started at the same time doesn't overwrite the stored permissions
+	lockdown.ID, err = helpers.MDbInsert(models.ModLockdownsTable, lockdown)
+	if err != nil {
+		if mgo.IsDup(err) {
+			return lockdown, ErrLockdownActive
+		}
+		return lockdown, err
+	}
+
+	session := cache.GetSession().SessionForGuildS(guildID)
+	for _, lockdownChannel := range lockdown.Channels {
+		err = session.ChannelPermissionSet(lockdownChannel.ChannelID, guildID, "role",
+			lockdownChannel.Allow&^discordgo.PermissionSendMessages,
+			lockdownChannel.Deny|discordgo.PermissionSendMessages,
+		)
+		helpers.RelaxLog(err)
+		// without the allow the roles fall back to the deny of @everyone
+		for _, roleOverwrite := range lockdownChannel.RoleOverwrites {
+			err = session.ChannelPermissionSet(lockdownChannel.ChannelID, roleOverwrite.RoleID, "role",
+				roleOverwrite.Allow&^discordgo.PermissionSendMessages, roleOverwrite.Deny)
+			helpers.RelaxLog(err)
+		}
+	}
+
+	if lockdown.VanityInvitePaused {
+		err = helpers.SetVanityUrlPaused(vanityInvite, true)
+		helpers.RelaxLog(err)
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, guildID,
+		models.EventlogTargetTypeGuild, userID,
+		models.EventlogTypeRobyulLockdownStart, reason,
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "lockdown_channelids",
+				Value: strings.Join(lockdownChannelIDs(lockdown), ","),
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "lockdown_vanityinvite_paused",
+				Value: helpers.StoreBoolAsString(lockdown.VanityInvitePaused),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return lockdown, nil
+}
+
+// EndLockdown restores the permission overwrites from before the lockdown, and resumes the vanity invite
+func EndLockdown(guildID, userID string) (err error) {
+	lockdown, err := GetLockdown(guildID)
+	if helpers.IsMdbNotFound(err) {
+		return ErrLockdownNotActive
+	}
+	if err != nil {
+		return err
+	}
+
+	session := cache.GetSession().SessionForGuildS(guildID)
+	for _, lockdownChannel := range lockdown.Channels {
+		if lockdownChannel.OverwriteExisted {
+			err = session.ChannelPermissionSet(lockdownChannel.ChannelID, guildID, "role",
+				lockdownChannel.Allow, lockdownChannel.Deny)
+		} else {
+			err = session.ChannelPermissionDelete(lockdownChannel.ChannelID, guildID)
+		}
+		helpers.RelaxLog(err)
+		for _, roleOverwrite := range lockdownChannel.RoleOverwrites {
+			err = session.ChannelPermissionSet(lockdownChannel.ChannelID, roleOverwrite.RoleID, "role",
+				roleOverwrite.Allow, roleOverwrite.Deny)
+			helpers.RelaxLog(err)
+		}
+	}
+
+	if lockdown.VanityInvitePaused {
+		vanityInvite, err := helpers.GetVanityUrlByGuildID(guildID)
+		if err == nil && vanityInvite.Paused {
+			err = helpers.SetVanityUrlPaused(vanityInvite, false)
+			helpers.RelaxLog(err)
+		}
+	}
+
+	err = helpers.MDbDelete(models.ModLockdownsTable, lockdown.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, guildID,
+		models.EventlogTargetTypeGuild, userID,
+		models.EventlogTypeRobyulLockdownEnd, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "lockdown_channelids",
+				Value: strings.Join(lockdownChannelIDs(lockdown), ","),
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "lockdown_started_at",
+				Value: lockdown.StartedAt.Format(models.ISO8601),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return nil
+}
+
+func lockdownChannelIDs(lockdown models.ModLockdownEntry) (channelIDs []string) {
+	for _, lockdownChannel := range lockdown.Channels {
+		channelIDs = append(channelIDs, lockdownChannel.ChannelID)
+	}
+	return channelIDs
+}
+
+func getRaidDetectionText(detection models.RaidDetection) (text string) {
+	if !detection.Enabled {
+		return "Raid detection is **disabled**."
+	}
+
+	text = fmt.Sprintf("Raid detection is **enabled**, within **%s**:\n", helpers.HumanizeDuration(detection.Period))
+	if detection.JoinThreshold > 0 {
+		text += fmt.Sprintf("- **%d** joins\n", detection.JoinThreshold)
+	}
+	if detection.NewAccountThreshold > 0 {
+		text += fmt.Sprintf("- **%d** joins of accounts younger than **%s**\n",
+			detection.NewAccountThreshold, helpers.HumanizeDuration(detection.NewAccountAge))
+	}
+	if detection.SharedInviteThreshold > 0 {
+		text += fmt.Sprintf("- **%d** joins using the same invite\n", detection.SharedInviteThreshold)
+	}
+	text += "Automatic lockdown: **" + helpers.StoreBoolAsString(detection.AutoLockdown) + "**"
+	return text
+}
+
+// [p]lockdown
+func (m *Mod) actionLockdownStatus(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var resultText string
+	lockdown, err := GetLockdown(channel.GuildID)
+	if err == nil {
+		resultText = helpers.GetTextF("plugins.mod.lockdown-status-active",
+			lockdown.StartedByUserID, lockdown.StartedAt.UTC().Format(time.ANSIC)+" UTC")
+		if lockdown.Reason != "" {
+			resultText += "\nReason: " + lockdown.Reason
+		}
+	} else if helpers.IsMdbNotFound(err) {
+		resultText = helpers.GetText("plugins.mod.lockdown-status-inactive")
+	} else {
+		helpers.Relax(err)
+	}
+
+	channelIDs := helpers.GuildSettingsGetCached(channel.GuildID).LockdownChannelIDs
+	if len(channelIDs) > 0 {
+		resultText += "\nLockdown channels: <#" + strings.Join(channelIDs, ">, <#") + ">"
+	} else {
+		resultText += "\n" + helpers.GetTextF("plugins.mod.lockdown-no-channels", helpers.GetPrefixForServer(channel.GuildID))
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, resultText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]lockdown start [<reason>]
+func (m *Mod) actionLockdownStart(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	lockdown, err := StartLockdown(channel.GuildID, msg.Author.ID, ctx.String("reason"))
+	switch err {
+	case ErrLockdownActive:
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.lockdown-start-error-active"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	case ErrNoLockdownChannels:
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.lockdown-no-channels",
+			helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.mod.lockdown-start-success",
+		len(lockdown.Channels), helpers.GetPrefixForServer(channel.GuildID)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]lockdown end
+func (m *Mod) actionLockdownEnd(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	err = EndLockdown(channel.GuildID, msg.Author.ID)
+	if err == ErrLockdownNotActive {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.lockdown-status-inactive"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.mod.lockdown-end-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]lockdown channel <#channel>
+func (m *Mod) actionLockdownChannel(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	targetChannel := ctx.Channel("channel")
+
+	if targetChannel.GuildID != channel.GuildID {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	newChannelIDs := make([]string, 0)
+	for _, channelID := range settings.LockdownChannelIDs {
+		if channelID != targetChannel.ID {
+			newChannelIDs = append(newChannelIDs, channelID)
+		}
+	}
+	added := len(newChannelIDs) == len(settings.LockdownChannelIDs)
+	if added {
+		newChannelIDs = append(newChannelIDs, targetChannel.ID)
+	}
+	oldChannelIDs := settings.LockdownChannelIDs
+
+	settings.LockdownChannelIDs = newChannelIDs
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulRaidDetectionUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "lockdown_channelids",
+				OldValue: strings.Join(oldChannelIDs, ","),
+				NewValue: strings.Join(newChannelIDs, ","),
+				Type:     models.EventlogTargetTypeChannel,
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	successText := helpers.GetTextF("plugins.mod.lockdown-channel-remove-success", targetChannel.ID)
+	if added {
+		successText = helpers.GetTextF("plugins.mod.lockdown-channel-add-success", targetChannel.ID)
+	}
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]raid-detection
+func (m *Mod) actionRaidDetectionStatus(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID,
+		getRaidDetectionText(helpers.GuildSettingsGetCached(channel.GuildID).RaidDetection))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]raid-detection enable|disable
+func (m *Mod) actionRaidDetectionToggle(ctx *router.Context) {
+	m.updateRaidDetection(ctx, func(detection *models.RaidDetection) bool {
+		detection.Enabled = ctx.Command.Name == "enable"
+		if detection.Enabled && detection.Period <= 0 {
+			autoLockdown := detection.AutoLockdown
+			*detection = raidDetectionDefaults
+			detection.Enabled = true
+			detection.AutoLockdown = autoLockdown
+		}
+		return true
+	})
+}
+
+// [p]raid-detection joins|new-accounts|invites|auto-lockdown <values>
+func (m *Mod) actionRaidDetectionSet(ctx *router.Context) {
+	m.updateRaidDetection(ctx, func(detection *models.RaidDetection) bool {
+		if ctx.Command.Name != "auto-lockdown" && ctx.Int("joins") < 0 {
+			return false
+		}
+
+		switch ctx.Command.Name {
+		case "joins":
+			detection.JoinThreshold = ctx.Int("joins")
+			detection.Period = ctx.Duration("period")
+		case "new-accounts":
+			detection.NewAccountThreshold = ctx.Int("joins")
+			detection.NewAccountAge = ctx.Duration("account age")
+		case "invites":
+			detection.SharedInviteThreshold = ctx.Int("joins")
+		case "auto-lockdown":
+			switch strings.ToLower(ctx.String("on or off")) {
+			case "on", "yes", "enable":
+				detection.AutoLockdown = true
+			case "off", "no", "disable":
+				detection.AutoLockdown = false
+			default:
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// updateRaidDetection applies update to the raid detection settings of the guild, and logs the change
+func (m *Mod) updateRaidDetection(ctx *router.Context, update func(detection *models.RaidDetection) (valid bool)) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	oldDetection := settings.RaidDetection
+	if !update(&settings.RaidDetection) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid")+"\n"+
+			ctx.Command.UsageText(helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulRaidDetectionUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "raiddetection",
+				OldValue: getRaidDetectionText(oldDetection),
+				NewValue: getRaidDetectionText(settings.RaidDetection),
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, getRaidDetectionText(settings.RaidDetection))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
@@ -0,0 +1,367 @@
+package modmail
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	"github.com/Seklfreak/Robyul2/shardmanager"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type Handler struct{}
+
+func (h *Handler) Commands() []string {
+	return []string{
+		"modmail",
+		"close",
+	}
+}
+
+func (h *Handler) Init(session *shardmanager.Manager) {
+	defer helpers.Recover()
+}
+
+func (h *Handler) Uninit(session *shardmanager.Manager) {
+	defer helpers.Recover()
+}
+
+func (h *Handler) Routes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "modmail",
+			Description: "shows the modmail settings, users can open a modmail thread by sending Robyul a DM",
+			Permission:  router.PermissionAdmin,
+			Module:      helpers.ModulePermModmail,
+			Handler:     h.actionStatus,
+			Subcommands: []*router.Command{
+				{
+					Name:        "category",
+					Description: "sets the category new threads will be created in, only mods should be able to see it",
+					Params: []router.Param{
+						{Name: "category id", Type: router.ParamString},
+					},
+					Handler: h.actionCategory,
+				},
+				{
+					Name:        "log",
+					Description: "sets the channel transcripts of closed threads will be posted in",
+					Params: []router.Param{
+						{Name: "channel", Type: router.ParamChannel},
+					},
+					Handler: h.actionLog,
+				},
+				{
+					Name:        "disable",
+					Description: "disables modmail, open threads stay open until they are closed",
+					Handler:     h.actionDisable,
+				},
+				{
+					Name:        "threads",
+					Description: "lists the modmail threads of an user",
+					Permission:  router.PermissionMod,
+					Params: []router.Param{
+						{Name: "user", Type: router.ParamUser},
+					},
+					Handler: h.actionThreads,
+				},
+				{
+					Name:        "transcript",
+					Description: "posts the transcript of a closed thread",
+					Permission:  router.PermissionMod,
+					Params: []router.Param{
+						{Name: "thread id", Type: router.ParamString},
+					},
+					Handler: h.actionTranscript,
+				},
+			},
+		},
+		{
+			Name:        "close",
+			Description: "closes the modmail thread in the current channel, and stores the transcript",
+			Permission:  router.PermissionMod,
+			Module:      helpers.ModulePermModmail,
+			Params: []router.Param{
+				{Name: "reason", Type: router.ParamRest, Optional: true},
+			},
+			Handler: h.actionClose,
+		},
+	}
+}
+
+// Action is not used, all commands are dispatched by the router, see Routes
+func (h *Handler) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+}
+
+// [p]modmail
+func (h *Handler) actionStatus(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	if settings.ModmailCategoryID == "" {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.modmail.status-disabled",
+			helpers.GetPrefixForServer(channel.GuildID)))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	var categoryName string
+	category, err := helpers.GetChannel(settings.ModmailCategoryID)
+	if err == nil {
+		categoryName = category.Name
+	}
+	logChannelText := "_/_"
+	if settings.ModmailLogChannelID != "" {
+		logChannelText = "<#" + settings.ModmailLogChannelID + ">"
+	}
+
+	openThreads, err := helpers.MdbCount(models.ModmailThreadsTable, bson.M{"guildid": channel.GuildID, "open": true})
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.modmail.status-enabled",
+		categoryName, settings.ModmailCategoryID, logChannelText, openThreads))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]modmail category <category id>
+func (h *Handler) actionCategory(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	category, err := helpers.GetChannel(strings.Trim(ctx.String("category id"), "<#>"))
+	if err != nil || category.GuildID != channel.GuildID || category.Type != discordgo.ChannelTypeGuildCategory {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.category-error-invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	h.updateSettings(ctx, channel.GuildID, func(settings *models.Config) {
+		settings.ModmailCategoryID = category.ID
+	})
+}
+
+// [p]modmail log <#channel>
+func (h *Handler) actionLog(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	targetChannel := ctx.Channel("channel")
+	if targetChannel.GuildID != channel.GuildID {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	h.updateSettings(ctx, channel.GuildID, func(settings *models.Config) {
+		settings.ModmailLogChannelID = targetChannel.ID
+	})
+}
+
+// [p]modmail disable
+func (h *Handler) actionDisable(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	h.updateSettings(ctx, channel.GuildID, func(settings *models.Config) {
+		settings.ModmailCategoryID = ""
+	})
+}
+
+// updateSettings applies update to the guild settings, logs the change and shows the new settings
+func (h *Handler) updateSettings(ctx *router.Context, guildID string, update func(settings *models.Config)) {
+	msg := ctx.Msg
+
+	settings := helpers.GuildSettingsGetCached(guildID)
+	oldCategoryID, oldLogChannelID := settings.ModmailCategoryID, settings.ModmailLogChannelID
+	update(&settings)
+
+	err := helpers.GuildSettingsSet(guildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, guildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulModmailUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "modmail_categoryid",
+				OldValue: oldCategoryID,
+				NewValue: settings.ModmailCategoryID,
+				Type:     models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:      "modmail_log_channelid",
+				OldValue: oldLogChannelID,
+				NewValue: settings.ModmailLogChannelID,
+				Type:     models.EventlogTargetTypeChannel,
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	h.actionStatus(ctx)
+}
+
+// [p]modmail threads <user>
+func (h *Handler) actionThreads(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	targetUser := ctx.User("user")
+
+	var threads []models.ModmailThreadEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.ModmailThreadsTable).Find(
+		bson.M{"guildid": channel.GuildID, "userid": targetUser.ID},
+	).Sort("-createdat")).All(&threads)
+	helpers.Relax(err)
+
+	if len(threads) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.threads-empty"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	resultText := fmt.Sprintf(":incoming_envelope: Modmail threads of `%s#%s`:\n", targetUser.Username, targetUser.Discriminator)
+	for _, thread := range threads {
+		resultText += fmt.Sprintf("`%s` opened %s, %d messages",
+			helpers.MdbIdToHuman(thread.ID), thread.CreatedAt.UTC().Format(time.ANSIC)+" UTC", len(thread.Messages))
+		if thread.Open {
+			resultText += ", open in <#" + thread.ChannelID + ">"
+		} else if thread.CloseReason != "" {
+			resultText += ", closed: " + thread.CloseReason
+		}
+		resultText += "\n"
+	}
+	resultText += fmt.Sprintf("Found **%d** Threads in total.", len(threads))
+
+	for _, page := range helpers.Pagify(resultText, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, page)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// [p]modmail transcript <thread id>
+func (h *Handler) actionTranscript(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var thread models.ModmailThreadEntry
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModmailThreadsTable).Find(
+			bson.M{"guildid": channel.GuildID, "_id": helpers.HumanToMdbId(ctx.String("thread id"))},
+		),
+		&thread,
+	)
+	if helpers.IsMdbNotFound(err) || (err == nil && thread.TranscriptObjectName == "") {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.thread-not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	transcript, err := helpers.RetrieveFile(thread.TranscriptObjectName)
+	helpers.Relax(err)
+
+	_, err = helpers.SendFile(msg.ChannelID, getTranscriptFilename(thread), bytes.NewReader(transcript), "")
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]close [<reason>]
+func (h *Handler) actionClose(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+
+	thread, err := getOpenThreadByChannel(msg.ChannelID)
+	if helpers.IsMdbNotFound(err) {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.close-error-no-thread"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	helpers.Relax(err)
+
+	_, err = closeThread(thread, msg.Author.ID, ctx.String("reason"))
+	helpers.Relax(err)
+}
+
+func (h *Handler) OnMessage(content string, msg *discordgo.Message, session *discordgo.Session) {
+	if msg.Author == nil || msg.Author.Bot {
+		return
+	}
+
+	channel, err := helpers.GetChannelWithoutApi(msg.ChannelID)
+	if err != nil || channel.GuildID == "" || channel.ParentID == "" {
+		return
+	}
+
+	// commands and notes starting with the prefix stay in the thread channel
+	if strings.HasPrefix(msg.Content, helpers.GetPrefixForServer(channel.GuildID)) {
+		return
+	}
+
+	if channel.ParentID != helpers.GuildSettingsGetCached(channel.GuildID).ModmailCategoryID {
+		return
+	}
+
+	thread, err := getOpenThreadByChannel(channel.ID)
+	if err != nil {
+		return
+	}
+
+	if !helpers.IsModByID(channel.GuildID, msg.Author.ID) ||
+		!helpers.ModuleIsAllowedSilent(channel.ID, msg.ID, msg.Author.ID, helpers.ModulePermModmail) {
+		return
+	}
+
+	err = relayModMessage(thread, msg)
+	if err != nil {
+		if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil &&
+			errD.Message.Code == discordgo.ErrCodeCannotSendMessagesToThisUser {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.reply-error-cannot-dm"))
+			helpers.RelaxLog(err)
+			return
+		}
+		helpers.RelaxLog(err)
+		return
+	}
+
+	err = session.MessageReactionAdd(msg.ChannelID, msg.ID, "📨")
+	helpers.RelaxLog(err)
+}
+
+func (h *Handler) OnMessageDelete(msg *discordgo.MessageDelete, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildMemberAdd(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildMemberRemove(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnReactionAdd(reaction *discordgo.MessageReactionAdd, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnReactionRemove(reaction *discordgo.MessageReactionRemove, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildBanAdd(user *discordgo.GuildBanAdd, session *discordgo.Session) {
+
+}
+
+func (h *Handler) OnGuildBanRemove(user *discordgo.GuildBanRemove, session *discordgo.Session) {
+
+}
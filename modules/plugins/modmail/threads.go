@@ -0,0 +1,461 @@
+package modmail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	// how long users have to pick a server after DMing Robyul
+	pendingThreadTimeout = 10 * time.Minute
+)
+
+var (
+	channelNameRegex = regexp.MustCompile(`[^a-z0-9\-_]+`)
+)
+
+// pendingThread is the first message of an user that has yet to pick a server
+type pendingThread struct {
+	message   *discordgo.Message
+	guildIDs  []string
+	createdAt time.Time
+}
+
+var (
+	pendingThreads     = make(map[string]pendingThread) // map[userID]pendingThread
+	pendingThreadsLock sync.Mutex
+)
+
+// OnDirectMessage handles DMs to Robyul, called by the DM plugin before it responds
+// returns true if the message belongs to a modmail thread and should not be handled any further
+// botResponds should be true if the DM plugin will respond to the message itself (help, invite, ...)
+func OnDirectMessage(msg *discordgo.Message, botResponds bool) (handled bool) {
+	if msg.Author == nil || msg.Author.Bot || helpers.IsBlacklisted(msg.Author.ID) {
+		return false
+	}
+
+	thread, err := getOpenThreadByUser(msg.Author.ID)
+	if err == nil {
+		err = relayUserMessage(thread, msg)
+		if err == nil {
+			return true
+		}
+		if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil && errD.Message.Code == discordgo.ErrCodeUnknownChannel {
+			// the thread channel has been deleted manually
+			_, err = closeThread(thread, "", "Thread channel deleted")
+			helpers.RelaxLog(err)
+		} else {
+			helpers.RelaxLog(err)
+			return true
+		}
+	} else if !helpers.IsMdbNotFound(err) {
+		helpers.RelaxLog(err)
+		return false
+	}
+
+	pendingThreadsLock.Lock()
+	pending, isPending := pendingThreads[msg.Author.ID]
+	if isPending && time.Since(pending.createdAt) > pendingThreadTimeout {
+		isPending = false
+	}
+	if isPending {
+		if choice, err := strconv.Atoi(strings.TrimSpace(msg.Content)); err == nil {
+			delete(pendingThreads, msg.Author.ID)
+			pendingThreadsLock.Unlock()
+
+			if choice < 1 || choice > len(pending.guildIDs) {
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.pick-error-invalid"))
+				helpers.RelaxLog(err)
+				return true
+			}
+
+			thread, err = openThread(pending.guildIDs[choice-1], pending.message)
+			if err != nil {
+				helpers.RelaxLog(err)
+				_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.modmail.open-error"))
+				helpers.RelaxLog(err)
+				return true
+			}
+
+			guildName := thread.GuildID
+			if guild, err := helpers.GetGuild(thread.GuildID); err == nil {
+				guildName = guild.Name
+			}
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.modmail.open-success", guildName))
+			helpers.RelaxLog(err)
+			return true
+		}
+	}
+	pendingThreadsLock.Unlock()
+
+	if botResponds {
+		return false
+	}
+
+	guildIDs := getModmailGuildIDs(msg.Author.ID)
+	if len(guildIDs) <= 0 {
+		return false
+	}
+
+	pickText := helpers.GetText("plugins.modmail.pick-server") + "\n"
+	for i, guildID := range guildIDs {
+		guild, err := helpers.GetGuild(guildID)
+		if err != nil {
+			continue
+		}
+		pickText += fmt.Sprintf("`%d` %s\n", i+1, guild.Name)
+	}
+	_, err = helpers.SendMessage(msg.ChannelID, pickText)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return false
+	}
+
+	pendingThreadsLock.Lock()
+	pendingThreads[msg.Author.ID] = pendingThread{
+		message:   msg,
+		guildIDs:  guildIDs,
+		createdAt: time.Now(),
+	}
+	pendingThreadsLock.Unlock()
+
+	// not handled, so the DM is still forwarded to the bot staff
+	return false
+}
+
+// getModmailGuildIDs returns all servers of the user that have modmail set up, and allow the user to use it
+func getModmailGuildIDs(userID string) (guildIDs []string) {
+	for _, shard := range cache.GetSession().Sessions {
+		for _, guild := range shard.State.Guilds {
+			categoryID := helpers.GuildSettingsGetCached(guild.ID).ModmailCategoryID
+			if categoryID == "" {
+				continue
+			}
+
+			if helpers.IsBlacklistedGuild(guild.ID) || helpers.IsLimitedGuild(guild.ID) {
+				continue
+			}
+
+			if !helpers.GetIsInGuild(guild.ID, userID) {
+				continue
+			}
+
+			if !helpers.ModuleIsAllowedSilent(categoryID, "", userID, helpers.ModulePermModmail) {
+				continue
+			}
+
+			guildIDs = append(guildIDs, guild.ID)
+		}
+	}
+	return guildIDs
+}
+
+func getOpenThreadByUser(userID string) (thread models.ModmailThreadEntry, err error) {
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModmailThreadsTable).Find(bson.M{"userid": userID, "open": true}),
+		&thread,
+	)
+	return thread, err
+}
+
+func getOpenThreadByChannel(channelID string) (thread models.ModmailThreadEntry, err error) {
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModmailThreadsTable).Find(bson.M{"channelid": channelID, "open": true}),
+		&thread,
+	)
+	return thread, err
+}
+
+// openThread creates the thread channel in the modmail category and relays the first message
+func openThread(guildID string, firstMessage *discordgo.Message) (thread models.ModmailThreadEntry, err error) {
+	settings := helpers.GuildSettingsGetCached(guildID)
+	if settings.ModmailCategoryID == "" {
+		return thread, errors.New("modmail is not set up on this server")
+	}
+
+	author := firstMessage.Author
+	channelName := channelNameRegex.ReplaceAllString(strings.ToLower(author.Username), "")
+	if channelName == "" {
+		channelName = "user"
+	}
+
+	session := cache.GetSession().SessionForGuildS(guildID)
+	channel, err := session.GuildChannelCreateComplex(guildID, discordgo.GuildChannelCreateData{
+		Name:     "modmail-" + channelName + "-" + author.Discriminator,
+		Type:     discordgo.ChannelTypeGuildText,
+		Topic:    fmt.Sprintf("Modmail thread with %s#%s (#%s)", author.Username, author.Discriminator, author.ID),
+		ParentID: settings.ModmailCategoryID,
+	})
+	if err != nil {
+		return thread, err
+	}
+
+	thread = models.ModmailThreadEntry{
+		GuildID:   guildID,
+		UserID:    author.ID,
+		ChannelID: channel.ID,
+		CreatedAt: time.Now(),
+		Open:      true,
+		Messages:  make([]models.ModmailMessage, 0),
+	}
+	thread.ID, err = helpers.MDbInsert(models.ModmailThreadsTable, thread)
+	if err != nil {
+		return thread, err
+	}
+
+	infoText := fmt.Sprintf("<@%s> `%s#%s` (`#%s`)\nAccount created %s.",
+		author.ID, author.Username, author.Discriminator, author.ID,
+		helpers.SinceInDaysText(helpers.GetTimeFromSnowflake(author.ID)))
+	member, err := helpers.GetGuildMemberWithoutApi(guildID, author.ID)
+	if err == nil {
+		joinedAt, err := discordgo.Timestamp(member.JoinedAt).Parse()
+		if err == nil {
+			infoText += fmt.Sprintf("\nJoined the server %s.", helpers.SinceInDaysText(joinedAt))
+		}
+	}
+	_, err = helpers.SendEmbed(channel.ID, &discordgo.MessageEmbed{
+		Title:       helpers.GetText("plugins.modmail.thread-header-title"),
+		Description: infoText,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: helpers.GetTextF("plugins.modmail.thread-header-footer", helpers.GetPrefixForServer(guildID)),
+		},
+		Color: helpers.GetDiscordColorFromHex("#0faded"),
+	})
+	helpers.RelaxLog(err)
+
+	err = relayUserMessage(thread, firstMessage)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, author.ID,
+		models.EventlogTargetTypeUser, author.ID,
+		models.EventlogTypeRobyulModmailThreadOpen, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "modmail_channelid",
+				Value: channel.ID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return thread, nil
+}
+
+// relayUserMessage posts a DM of the user in the thread channel
+func relayUserMessage(thread models.ModmailThreadEntry, msg *discordgo.Message) (err error) {
+	entry := newThreadMessage(msg, true)
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: fmt.Sprintf("%s#%s", msg.Author.Username, msg.Author.Discriminator),
+		},
+		Description: entry.Content,
+		Footer:      &discordgo.MessageEmbedFooter{Text: "User ID: " + msg.Author.ID},
+		Color:       helpers.GetDiscordColorFromHex("#0faded"),
+	}
+	if msg.Author.Avatar != "" {
+		embed.Author.IconURL = msg.Author.AvatarURL("128")
+	}
+	if len(entry.Attachments) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments",
+			Value: strings.Join(entry.Attachments, "\n"),
+		})
+	}
+
+	_, err = helpers.SendEmbed(thread.ChannelID, embed)
+	if err != nil {
+		return err
+	}
+
+	return addThreadMessage(thread, entry)
+}
+
+// relayModMessage sends a message of a moderator in the thread channel to the user
+func relayModMessage(thread models.ModmailThreadEntry, msg *discordgo.Message) (err error) {
+	entry := newThreadMessage(msg, false)
+
+	guild, err := helpers.GetGuild(thread.GuildID)
+	if err != nil {
+		return err
+	}
+
+	dmChannel, err := cache.GetSession().SessionForGuildS(thread.GuildID).UserChannelCreate(thread.UserID)
+	if err != nil {
+		return err
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: helpers.GetTextF("plugins.modmail.reply-author", guild.Name),
+		},
+		Description: entry.Content,
+		Footer:      &discordgo.MessageEmbedFooter{Text: entry.AuthorName},
+		Color:       helpers.GetDiscordColorFromHex("#73d016"),
+	}
+	if guild.Icon != "" {
+		embed.Author.IconURL = guild.IconURL()
+	}
+	if len(entry.Attachments) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Attachments",
+			Value: strings.Join(entry.Attachments, "\n"),
+		})
+	}
+
+	_, err = helpers.SendEmbed(dmChannel.ID, embed)
+	if err != nil {
+		return err
+	}
+
+	return addThreadMessage(thread, entry)
+}
+
+func newThreadMessage(msg *discordgo.Message, fromUser bool) (entry models.ModmailMessage) {
+	entry = models.ModmailMessage{
+		AuthorID:   msg.Author.ID,
+		AuthorName: msg.Author.Username + "#" + msg.Author.Discriminator,
+		FromUser:   fromUser,
+		Content:    msg.Content,
+		CreatedAt:  time.Now(),
+	}
+	for _, attachment := range msg.Attachments {
+		entry.Attachments = append(entry.Attachments, attachment.URL)
+	}
+	return entry
+}
+
+func addThreadMessage(thread models.ModmailThreadEntry, entry models.ModmailMessage) (err error) {
+	return helpers.MDbUpdateQuery(models.ModmailThreadsTable,
+		bson.M{"_id": thread.ID},
+		bson.M{"$push": bson.M{"messages": entry}},
+	)
+}
+
+// closeThread stores the transcript of the thread, deletes the thread channel and notifies the user
+func closeThread(thread models.ModmailThreadEntry, closedByUserID, reason string) (transcriptObjectName string, err error) {
+	// reload thread to get all messages
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ModmailThreadsTable).Find(bson.M{"_id": thread.ID}),
+		&thread,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	thread.Open = false
+	thread.ClosedAt = time.Now()
+	thread.ClosedByUserID = closedByUserID
+	thread.CloseReason = reason
+
+	transcript := getTranscript(thread)
+	thread.TranscriptObjectName, err = helpers.AddFile("", transcript, helpers.AddFileMetadata{
+		Filename: getTranscriptFilename(thread),
+		GuildID:  thread.GuildID,
+	}, "modmail", false)
+	if err != nil {
+		return "", err
+	}
+
+	err = helpers.MDbUpdate(models.ModmailThreadsTable, thread.ID, thread)
+	if err != nil {
+		return thread.TranscriptObjectName, err
+	}
+
+	session := cache.GetSession().SessionForGuildS(thread.GuildID)
+	_, err = session.ChannelDelete(thread.ChannelID)
+	if err != nil {
+		logger().Warnf("failed to delete thread channel #%s on guild #%s: %s",
+			thread.ChannelID, thread.GuildID, err.Error())
+	}
+
+	logChannelID := helpers.GuildSettingsGetCached(thread.GuildID).ModmailLogChannelID
+	if logChannelID != "" {
+		_, err = helpers.SendFile(logChannelID, getTranscriptFilename(thread), bytes.NewReader(transcript),
+			helpers.GetTextF("plugins.modmail.log-closed", thread.UserID, closedByUserID, helpers.MdbIdToHuman(thread.ID)))
+		helpers.RelaxLog(err)
+	}
+
+	guild, err := helpers.GetGuild(thread.GuildID)
+	if err == nil && closedByUserID != "" {
+		dmChannel, err := session.UserChannelCreate(thread.UserID)
+		if err == nil {
+			_, err = helpers.SendMessage(dmChannel.ID, helpers.GetTextF("plugins.modmail.closed-dm", guild.Name))
+		}
+		if err != nil {
+			logger().Warnf("failed to notify user #%s about closed thread #%s: %s",
+				thread.UserID, helpers.MdbIdToHuman(thread.ID), err.Error())
+		}
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), thread.GuildID, thread.UserID,
+		models.EventlogTargetTypeUser, closedByUserID,
+		models.EventlogTypeRobyulModmailThreadClose, reason,
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "modmail_thread_id",
+				Value: helpers.MdbIdToHuman(thread.ID),
+			},
+			{
+				Key:   "modmail_messages",
+				Value: strconv.Itoa(len(thread.Messages)),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return thread.TranscriptObjectName, nil
+}
+
+func getTranscriptFilename(thread models.ModmailThreadEntry) string {
+	return "modmail-" + thread.UserID + "-" + thread.CreatedAt.UTC().Format("2006-01-02") + ".txt"
+}
+
+// getTranscript returns the whole thread as plain text
+func getTranscript(thread models.ModmailThreadEntry) []byte {
+	var transcript bytes.Buffer
+
+	guildName := "N/A"
+	guild, err := helpers.GetGuild(thread.GuildID)
+	if err == nil {
+		guildName = guild.Name
+	}
+
+	fmt.Fprintf(&transcript, "Modmail thread %s with user #%s on %s (#%s)\n",
+		helpers.MdbIdToHuman(thread.ID), thread.UserID, guildName, thread.GuildID)
+	fmt.Fprintf(&transcript, "Opened at %s\n", thread.CreatedAt.UTC().Format(time.ANSIC)+" UTC")
+	if !thread.ClosedAt.IsZero() {
+		fmt.Fprintf(&transcript, "Closed at %s by #%s\n", thread.ClosedAt.UTC().Format(time.ANSIC)+" UTC", thread.ClosedByUserID)
+	}
+	if thread.CloseReason != "" {
+		fmt.Fprintf(&transcript, "Reason: %s\n", thread.CloseReason)
+	}
+	transcript.WriteString("\n")
+
+	for _, message := range thread.Messages {
+		role := "Moderator"
+		if message.FromUser {
+			role = "User"
+		}
+		fmt.Fprintf(&transcript, "[%s] %s %s (#%s): %s\n",
+			message.CreatedAt.UTC().Format("2006-01-02 15:04:05"), role, message.AuthorName, message.AuthorID, message.Content)
+		for _, attachment := range message.Attachments {
+			fmt.Fprintf(&transcript, "    Attachment: %s\n", attachment)
+		}
+	}
+
+	return transcript.Bytes()
+}
@@ -1,9 +1,14 @@
 package plugins
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/ratelimits"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
@@ -14,6 +19,7 @@ type Ratelimit struct{}
 func (r *Ratelimit) Commands() []string {
 	return []string{
 		"limits",
+		"ratelimit",
 	}
 }
 
@@ -21,9 +27,247 @@ func (r *Ratelimit) Init(session *shardmanager.Manager) {
 
 }
 
+func (r *Ratelimit) Routes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "limits",
+			Description: "shows how many commands you have left",
+			Handler:     r.actionLimits,
+		},
+		{
+			Name:    "ratelimit",
+			Aliases: []string{"ratelimits"},
+			Subcommands: []*router.Command{
+				{
+					Name:        "status",
+					Description: "shows the keys, chill zone and cooldown of an user",
+					Permission:  router.PermissionMod,
+					Params: []router.Param{
+						{Name: "user", Type: router.ParamUser},
+					},
+					Handler: r.actionStatus,
+				},
+				{
+					Name:        "list",
+					Description: "lists the command costs and channel cooldowns set up on the server",
+					Permission:  router.PermissionMod,
+					Handler:     r.actionList,
+				},
+				{
+					Name:        "cost",
+					Description: "sets the keys a command costs on the server, use default to reset it",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "command", Type: router.ParamString},
+						{Name: "cost", Type: router.ParamString},
+					},
+					Handler: r.actionCost,
+				},
+				{
+					Name:        "cooldown",
+					Description: "sets a cooldown between commands of the same user in a channel, use off to remove it",
+					Permission:  router.PermissionAdmin,
+					Params: []router.Param{
+						{Name: "channel", Type: router.ParamChannel},
+						{Name: "cooldown", Type: router.ParamString},
+					},
+					Handler: r.actionCooldown,
+				},
+			},
+		},
+	}
+}
+
+// Action is not used, all commands are dispatched by the router, see Routes
 func (r *Ratelimit) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
-	helpers.SendMessage(
-		msg.ChannelID,
-		"You've still got "+strconv.Itoa(int(ratelimits.Container.Get(msg.Author.ID)))+" commands left",
+}
+
+// [p]limits
+func (r *Ratelimit) actionLimits(ctx *router.Context) {
+	_, err := helpers.SendMessage(
+		ctx.Msg.ChannelID,
+		"You've still got "+strconv.Itoa(ratelimits.Container.Get(ctx.Msg.Author.ID))+" keys left",
 	)
+	helpers.RelaxMessage(err, ctx.Msg.ChannelID, ctx.Msg.ID)
+}
+
+// [p]ratelimit status <user>
+func (r *Ratelimit) actionStatus(ctx *router.Context) {
+	msg := ctx.Msg
+	targetUser := ctx.User("user")
+
+	chillText := "no"
+	if remaining := ratelimits.Container.ChillRemaining(targetUser.ID); remaining > 0 {
+		chillText = helpers.HumanizeDuration(remaining + time.Second)
+	}
+	cooldownText := "no"
+	if remaining := ratelimits.Container.CooldownRemaining(msg.ChannelID, targetUser.ID); remaining > 0 {
+		cooldownText = helpers.HumanizeDuration(remaining + time.Second)
+	}
+
+	_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.ratelimit.status",
+		targetUser.Username, targetUser.ID,
+		ratelimits.Container.Get(targetUser.ID), ratelimits.BUCKET_UPPER_BOUND,
+		chillText, cooldownText))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]ratelimit list
+func (r *Ratelimit) actionList(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+
+	if len(settings.RatelimitCommandCosts) <= 0 && len(settings.RatelimitChannelCooldowns) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.ratelimit.list-empty"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	resultMessage := ":stopwatch: Ratelimits on this server:\n"
+	for _, override := range settings.RatelimitCommandCosts {
+		resultMessage += fmt.Sprintf("`%s%s` costs **%d** keys\n",
+			helpers.GetPrefixForServer(channel.GuildID), override.Command, override.Cost)
+	}
+	for _, cooldown := range settings.RatelimitChannelCooldowns {
+		resultMessage += fmt.Sprintf("<#%s> has a cooldown of **%s**\n",
+			cooldown.ChannelID, helpers.HumanizeDuration(cooldown.Cooldown))
+	}
+
+	for _, page := range helpers.Pagify(resultMessage, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, page)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// [p]ratelimit cost <command> <cost|default>
+func (r *Ratelimit) actionCost(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	// overrides are stored by the name of the command, so they apply to all of its aliases
+	command, known := router.Resolve(
+		strings.ToLower(strings.TrimPrefix(ctx.String("command"), helpers.GetPrefixForServer(channel.GuildID))))
+	if !known {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.ratelimit.cost-unknown-command"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	oldCost := ratelimits.GetCommandCost(channel.GuildID, command)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	newOverrides := make([]models.RatelimitCommandCost, 0)
+	for _, override := range settings.RatelimitCommandCosts {
+		if ratelimits.ResolveCommand(override.Command) != command {
+			newOverrides = append(newOverrides, override)
+		}
+	}
+
+	var successText string
+	if strings.ToLower(ctx.String("cost")) == "default" {
+		successText = helpers.GetTextF("plugins.ratelimit.cost-reset-success", command)
+	} else {
+		cost, err := strconv.Atoi(ctx.String("cost"))
+		if err != nil || cost < 0 || cost > ratelimits.MAX_COMMAND_COST {
+			_, err = helpers.SendMessage(msg.ChannelID,
+				helpers.GetTextF("plugins.ratelimit.cost-invalid", ratelimits.MAX_COMMAND_COST))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		newOverrides = append(newOverrides, models.RatelimitCommandCost{
+			Command: command,
+			Cost:    cost,
+		})
+		successText = helpers.GetTextF("plugins.ratelimit.cost-set-success", command, cost)
+	}
+
+	settings.RatelimitCommandCosts = newOverrides
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, channel.GuildID,
+		models.EventlogTargetTypeGuild, msg.Author.ID,
+		models.EventlogTypeRobyulRatelimitUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "ratelimit_command_cost",
+				OldValue: strconv.Itoa(oldCost),
+				NewValue: strconv.Itoa(ratelimits.GetCommandCost(channel.GuildID, command)),
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "ratelimit_command",
+				Value: command,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]ratelimit cooldown <#channel> <cooldown|off>
+func (r *Ratelimit) actionCooldown(ctx *router.Context) {
+	msg := ctx.Msg
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+	targetChannel := ctx.Channel("channel")
+
+	if targetChannel.GuildID != channel.GuildID {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	oldCooldown := ratelimits.GetChannelCooldown(channel.GuildID, targetChannel.ID)
+
+	settings := helpers.GuildSettingsGetCached(channel.GuildID)
+	newCooldowns := make([]models.RatelimitChannelCooldown, 0)
+	for _, cooldown := range settings.RatelimitChannelCooldowns {
+		if cooldown.ChannelID != targetChannel.ID {
+			newCooldowns = append(newCooldowns, cooldown)
+		}
+	}
+
+	var newCooldown time.Duration
+	var successText string
+	if strings.ToLower(ctx.String("cooldown")) == "off" {
+		successText = helpers.GetTextF("plugins.ratelimit.cooldown-remove-success", targetChannel.ID)
+	} else {
+		newCooldown, err = router.ParseDuration(ctx.String("cooldown"))
+		if err != nil || newCooldown <= 0 {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.ratelimit.cooldown-invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		newCooldowns = append(newCooldowns, models.RatelimitChannelCooldown{
+			ChannelID: targetChannel.ID,
+			Cooldown:  newCooldown,
+		})
+		successText = helpers.GetTextF("plugins.ratelimit.cooldown-set-success",
+			targetChannel.ID, helpers.HumanizeDuration(newCooldown))
+	}
+
+	settings.RatelimitChannelCooldowns = newCooldowns
+	err = helpers.GuildSettingsSet(channel.GuildID, settings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, targetChannel.ID,
+		models.EventlogTargetTypeChannel, msg.Author.ID,
+		models.EventlogTypeRobyulRatelimitUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "ratelimit_channel_cooldown",
+				OldValue: oldCooldown.String(),
+				NewValue: newCooldown.String(),
+			},
+		},
+		nil, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, successText)
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 }
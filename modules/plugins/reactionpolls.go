@@ -11,6 +11,7 @@ import (
 	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 	humanize "github.com/dustin/go-humanize"
@@ -39,7 +40,7 @@ var (
 // @TODO: add metrics
 func (rp *ReactionPolls) Init(session *shardmanager.Manager) {
 	var err error
-	reactionPollIDsCache, err = rp.getAllActiveReactionPollIDs()
+	reactionPollIDsCache, err = rp.getReactionPollIDs()
 	helpers.Relax(err)
 }
 
@@ -56,7 +57,7 @@ func (rp *ReactionPolls) Action(command string, content string, msg *discordgo.M
 	helpers.Relax(err)
 
 	switch args[0] {
-	case "create": // [p]reactionpolls create "<poll text>" <max number of votes> <allowed emotes>
+	case "create": // [p]reactionpolls create "<poll text>" <max number of votes|single> [<duration>] [anonymous] [<@role>] <allowed emotes>
 		session.ChannelTyping(msg.ChannelID)
 		if len(args) < 4 {
 			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
@@ -69,8 +70,12 @@ func (rp *ReactionPolls) Action(command string, content string, msg *discordgo.M
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 			return
 		}
+		var pollSingleChoice bool
 		pollMaxVotes, err := strconv.Atoi(args[2])
-		if err != nil {
+		if strings.ToLower(args[2]) == "single" {
+			pollSingleChoice = true
+			pollMaxVotes = 1
+		} else if err != nil {
 			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
 			return
@@ -79,12 +84,43 @@ func (rp *ReactionPolls) Action(command string, content string, msg *discordgo.M
 		helpers.Relax(err)
 		guild, err := helpers.GetGuild(channel.GuildID)
 		helpers.Relax(err)
+		var pollEndsAt time.Time
+		var pollAnonymous bool
+		pollAllowedRoleIDs := make([]string, 0)
 		allowedEmotes := make([]string, 0)
-		for _, allowedEmote := range args[3:] {
+		for _, arg := range args[3:] {
+			if strings.ToLower(arg) == "anonymous" || strings.ToLower(arg) == "anon" {
+				pollAnonymous = true
+				continue
+			}
+			if strings.HasPrefix(arg, "<@&") {
+				role, err := session.State.Role(guild.ID, strings.TrimSuffix(strings.TrimPrefix(arg, "<@&"), ">"))
+				if err != nil {
+					_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				pollAllowedRoleIDs = append(pollAllowedRoleIDs, role.ID)
+				continue
+			}
+			if duration, err := router.ParseDuration(arg); err == nil {
+				if duration < reactionPollMinDuration || duration > reactionPollMaxDuration {
+					_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reactionpolls.create-invalid-duration"))
+					helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+					return
+				}
+				pollEndsAt = time.Now().UTC().Add(duration)
+				continue
+			}
 			allowedEmotes = append(allowedEmotes,
-				strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(allowedEmote, "<a:"), "<:"), ">"),
+				strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(arg, "<a:"), "<:"), ">"),
 			)
 		}
+		if len(allowedEmotes) <= 0 {
+			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
 		if len(allowedEmotes) > 20 {
 			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reactionpolls.create-too-many-reactions"))
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
@@ -129,31 +165,73 @@ func (rp *ReactionPolls) Action(command string, content string, msg *discordgo.M
 			MaxAllowedVotes: pollMaxVotes,
 			Reactions:       nil,
 			Initialised:     true,
+			EndsAt:          pollEndsAt,
+			Anonymous:       pollAnonymous,
+			SingleChoice:    pollSingleChoice,
+			AllowedRoleIDs:  pollAllowedRoleIDs,
 		}
 
-		_, err = helpers.MDbInsert(
+		newEntry.ID, err = helpers.MDbInsert(
 			models.ReactionpollsTable,
 			newEntry,
 		)
 		helpers.Relax(err)
 
+		if !newEntry.EndsAt.IsZero() {
+			_, err = cache.GetMachineryServer().SendTask(ReactionPollCloseSignature(newEntry.ID, newEntry.EndsAt))
+			helpers.Relax(err)
+		}
+
 		for _, allowedEmote := range allowedEmotes {
 			err = session.MessageReactionAdd(pollPostedMessage.ChannelID, pollPostedMessage.ID, allowedEmote)
 			helpers.Relax(err)
 		}
 
-		reactionPollIDsCache, err = rp.getAllActiveReactionPollIDs()
+		reactionPollIDsCache, err = rp.getReactionPollIDs()
 		helpers.Relax(err)
 
 		pollEmbed = rp.getEmbedForPoll(newEntry, 0)
 		_, err = helpers.EditEmbed(pollPostedMessage.ChannelID, pollPostedMessage.ID, pollEmbed)
 		helpers.Relax(err)
 		return
+	case "close", "end": // [p]reactionpolls close <poll id>
+		session.ChannelTyping(msg.ChannelID)
+		if len(args) < 2 {
+			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		var reactionPoll models.ReactionpollsEntry
+		err = helpers.MdbOne(
+			helpers.MdbCollection(models.ReactionpollsTable).Find(bson.M{"_id": helpers.HumanToMdbId(args[1]), "guildid": msg.GuildID}),
+			&reactionPoll,
+		)
+		if err != nil {
+			if helpers.IsMdbNotFound(err) {
+				_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reactionpolls.close-not-found"))
+				helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+				return
+			}
+			helpers.Relax(err)
+		}
+		if reactionPoll.CreatedByUserID != msg.Author.ID && !helpers.IsMod(msg) {
+			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("mod.no_permission"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		if !reactionPoll.Active {
+			_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reactionpolls.close-not-active"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		err = ReactionPollClose(helpers.MdbIdToHuman(reactionPoll.ID))
+		helpers.Relax(err)
+		return
 	case "refresh": // [p]reactionpolls refresh
 		helpers.RequireBotAdmin(msg, func() {
 			session.ChannelTyping(msg.ChannelID)
 			var err error
-			reactionPollIDsCache, err = rp.getAllActiveReactionPollIDs()
+			reactionPollIDsCache, err = rp.getReactionPollIDs()
 			helpers.Relax(err)
 			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reactionpolls.refreshed-polls"))
 			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
@@ -176,6 +254,36 @@ func (rp *ReactionPolls) getEmbedForPoll(poll models.ReactionpollsEntry, totalVo
 			IconURL: pollAuthor.AvatarURL("64"),
 		},
 	}
+
+	options := make([]string, 0)
+	if poll.SingleChoice {
+		options = append(options, "Single choice")
+	}
+	if poll.Anonymous {
+		options = append(options, "Anonymous")
+	}
+	if len(poll.AllowedRoleIDs) > 0 {
+		rolesText := "Only for"
+		for _, roleID := range poll.AllowedRoleIDs {
+			rolesText += " <@&" + roleID + ">"
+		}
+		options = append(options, rolesText)
+	}
+	if len(options) > 0 {
+		pollEmbed.Description += "\n\n_" + strings.Join(options, " · ") + "_"
+	}
+
+	switch {
+	case !poll.Active:
+		pollEmbed.Color = 0x808080
+		pollEmbed.Footer.Text += " | Closed"
+		if !poll.ClosedAt.IsZero() {
+			pollEmbed.Timestamp = poll.ClosedAt.Format(time.RFC3339)
+		}
+	case !poll.EndsAt.IsZero():
+		pollEmbed.Footer.Text += " | Closes"
+		pollEmbed.Timestamp = poll.EndsAt.Format(time.RFC3339)
+	}
 	return pollEmbed
 }
 
@@ -221,23 +329,61 @@ func (rp *ReactionPolls) OnReactionAdd(reaction *discordgo.MessageReactionAdd, s
 				helpers.Relax(err)
 			}
 		}
-		if message.Author.ID != session.State.User.ID {
+		if message == nil || message.Author.ID != session.State.User.ID {
 			return
 		}
-		// update entry
-		if reactionPoll.Reactions[reaction.Emoji.APIName()] == nil {
-			reactionPoll.Reactions[reaction.Emoji.APIName()] = make([]string, 0)
+		// stop if the poll has been closed
+		if !reactionPoll.Active {
+			session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+			return
 		}
-		reactionPoll.Reactions[reaction.Emoji.APIName()] = append(reactionPoll.Reactions[reaction.Emoji.APIName()], reaction.UserID)
-		err = helpers.MDbUpdateWithoutLogging(models.ReactionpollsTable, reactionPoll.ID, reactionPoll)
-		helpers.Relax(err)
-		// check if user is allowed to add another vote
-		if reactionPoll.MaxAllowedVotes > -1 {
-			if rp.getTotalVotes(reactionPoll, reaction.UserID) > reactionPoll.MaxAllowedVotes {
-				session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+		// check if user has one of the required roles
+		if !rp.canVote(reactionPoll, reaction.UserID) {
+			session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+			return
+		}
+		if reactionPoll.Reactions == nil {
+			reactionPoll.Reactions = make(map[string][]string, 0)
+		}
+		// anonymous votes are stored, and the reaction is removed, reacting again removes the vote
+		if reactionPoll.Anonymous {
+			session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+			if sliceContains(reactionPoll.Reactions[reaction.Emoji.APIName()], reaction.UserID) {
+				reactionPoll.Reactions[reaction.Emoji.APIName()] = sliceWithout(reactionPoll.Reactions[reaction.Emoji.APIName()], reaction.UserID)
+				err = helpers.MDbUpdateWithoutLogging(models.ReactionpollsTable, reactionPoll.ID, reactionPoll)
+				helpers.Relax(err)
+				pollEmbed := rp.getEmbedForPoll(reactionPoll, rp.getTotalVotes(reactionPoll, ""))
+				_, err = helpers.EditEmbed(reactionPoll.ChannelID, reactionPoll.MessageID, pollEmbed)
+				helpers.RelaxLog(err)
 				return
 			}
 		}
+		// single choice polls replace the previous vote
+		if reactionPoll.SingleChoice {
+			for emoji, userIDs := range reactionPoll.Reactions {
+				if emoji == reaction.Emoji.APIName() || !sliceContains(userIDs, reaction.UserID) {
+					continue
+				}
+				reactionPoll.Reactions[emoji] = sliceWithout(userIDs, reaction.UserID)
+				if !reactionPoll.Anonymous {
+					session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, emoji, reaction.UserID)
+				}
+			}
+		} else if reactionPoll.MaxAllowedVotes > -1 {
+			// check if user is allowed to add another vote
+			if rp.getTotalVotes(reactionPoll, reaction.UserID) >= reactionPoll.MaxAllowedVotes {
+				if !reactionPoll.Anonymous {
+					session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+				}
+				return
+			}
+		}
+		// update entry
+		if !sliceContains(reactionPoll.Reactions[reaction.Emoji.APIName()], reaction.UserID) {
+			reactionPoll.Reactions[reaction.Emoji.APIName()] = append(reactionPoll.Reactions[reaction.Emoji.APIName()], reaction.UserID)
+		}
+		err = helpers.MDbUpdateWithoutLogging(models.ReactionpollsTable, reactionPoll.ID, reactionPoll)
+		helpers.Relax(err)
 		// update embed
 		pollEmbed := rp.getEmbedForPoll(reactionPoll, rp.getTotalVotes(reactionPoll, ""))
 		_, err = helpers.EditEmbed(reactionPoll.ChannelID, reactionPoll.MessageID, pollEmbed)
@@ -273,8 +419,8 @@ func (rp *ReactionPolls) OnReactionRemove(reaction *discordgo.MessageReactionRem
 				break
 			}
 		}
-		// skip embed update if emote is not allowed
-		if !isAllowed {
+		// skip embed update if emote is not allowed, reactions on anonymous or closed polls are removed by the bot
+		if !isAllowed || reactionPoll.Anonymous || !reactionPoll.Active {
 			return
 		}
 		// count total votes for the message
@@ -287,7 +433,7 @@ func (rp *ReactionPolls) OnReactionRemove(reaction *discordgo.MessageReactionRem
 				helpers.Relax(err)
 			}
 		}
-		if message.Author.ID != session.State.User.ID {
+		if message == nil || message.Author.ID != session.State.User.ID {
 			return
 		}
 		// update entry
@@ -311,6 +457,23 @@ func (rp *ReactionPolls) OnReactionRemove(reaction *discordgo.MessageReactionRem
 	}
 }
 
+// canVote checks if the member has one of the roles required to vote
+func (rp *ReactionPolls) canVote(reactionPoll models.ReactionpollsEntry, userID string) bool {
+	if len(reactionPoll.AllowedRoleIDs) <= 0 {
+		return true
+	}
+	member, err := helpers.GetGuildMemberWithoutApi(reactionPoll.GuildID, userID)
+	if err != nil {
+		return false
+	}
+	for _, roleID := range member.Roles {
+		if sliceContains(reactionPoll.AllowedRoleIDs, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
 func (rp *ReactionPolls) getTotalVotes(reactionPoll models.ReactionpollsEntry, userID string) (count int) {
 	if reactionPoll.Reactions == nil {
 		reactionPoll.Reactions = make(map[string][]string, 0)
@@ -387,11 +550,16 @@ func (rp *ReactionPolls) OnGuildMemberRemove(member *discordgo.Member, session *
 
 }
 
-func (rp *ReactionPolls) getAllActiveReactionPollIDs() (ids []ReactionPollCacheEntry, err error) {
+// getReactionPollIDs returns all active polls, and the polls closed recently,
+// reactions on closed polls are removed as long as they are cached
+func (rp *ReactionPolls) getReactionPollIDs() (ids []ReactionPollCacheEntry, err error) {
 	var entryBucket []models.ReactionpollsEntry
 	err = helpers.MDbIter(
 		helpers.MdbCollection(models.ReactionpollsTable).
-			Find(bson.M{"active": true}).
+			Find(bson.M{"$or": []bson.M{
+				{"active": true},
+				{"closedat": bson.M{"$gte": time.Now().Add(-reactionPollClosedCacheDuration)}},
+			}}).
 			Select(bson.M{"_id": 1, "messageid": 1}),
 	).All(&entryBucket)
 	if err != nil {
@@ -0,0 +1,210 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/RichardKnop/machinery/v1/tasks"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/globalsign/mgo/bson"
+)
+
+const (
+	reactionPollMinDuration = time.Minute
+	reactionPollMaxDuration = 30 * 24 * time.Hour
+	// how long closed polls stay cached
+	reactionPollClosedCacheDuration = 7 * 24 * time.Hour
+
+	reactionPollChartFilename  = "reactionpoll-results.png"
+	reactionPollChartWidth     = 600
+	reactionPollChartBarHeight = 24
+	reactionPollChartPadding   = 8
+)
+
+var (
+	reactionPollChartBackground = color.RGBA{0x2f, 0x31, 0x36, 0xff}
+	reactionPollChartTrack      = color.RGBA{0x40, 0x44, 0x4b, 0xff}
+	reactionPollChartColors     = []color.RGBA{
+		{0x0f, 0xad, 0xed, 0xff},
+		{0xff, 0xd7, 0x00, 0xff},
+		{0x43, 0xb5, 0x81, 0xff},
+		{0xf0, 0x47, 0x47, 0xff},
+		{0x9b, 0x59, 0xb6, 0xff},
+		{0xe6, 0x7e, 0x22, 0xff},
+	}
+)
+
+type reactionPollResult struct {
+	Emote string
+	Votes int
+}
+
+// ReactionPollClose is called by machinery to close a poll when it ends, and posts the results
+func ReactionPollClose(pollID string) (err error) {
+	if !bson.IsObjectIdHex(pollID) {
+		return nil
+	}
+
+	rp := &ReactionPolls{}
+	id := bson.ObjectIdHex(pollID)
+	rp.lockEntry(id)
+	defer rp.unlockEntry(id)
+
+	var reactionPoll models.ReactionpollsEntry
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.ReactionpollsTable).Find(bson.M{"_id": id}),
+		&reactionPoll,
+	)
+	if err != nil {
+		if helpers.IsMdbNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	// the poll has been closed already
+	if !reactionPoll.Active {
+		return nil
+	}
+
+	reactionPoll.Active = false
+	reactionPoll.ClosedAt = time.Now().UTC()
+	err = helpers.MDbUpdate(models.ReactionpollsTable, reactionPoll.ID, reactionPoll)
+	if err != nil {
+		return err
+	}
+
+	// the poll stays cached, so reactions added to it are removed
+	_, err = helpers.EditEmbed(reactionPoll.ChannelID, reactionPoll.MessageID,
+		rp.getEmbedForPoll(reactionPoll, rp.getTotalVotes(reactionPoll, "")))
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendComplex(reactionPoll.ChannelID, rp.getResultsMessage(reactionPoll))
+	if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil {
+		if errD.Message.Code == discordgo.ErrCodeUnknownChannel ||
+			errD.Message.Code == discordgo.ErrCodeMissingAccess ||
+			errD.Message.Code == discordgo.ErrCodeMissingPermissions {
+			return nil
+		}
+	}
+	return err
+}
+
+func ReactionPollCloseSignature(pollID bson.ObjectId, closeAt time.Time) (signature *tasks.Signature) {
+	signature = &tasks.Signature{
+		Name: "close_reactionpoll",
+		Args: []tasks.Arg{
+			{
+				Type:  "string",
+				Value: helpers.MdbIdToHuman(pollID),
+			},
+		},
+	}
+	signature.ETA = &closeAt
+	signature.RetryCount = 3
+	signature.OnError = []*tasks.Signature{{Name: "log_error"}}
+	return signature
+}
+
+// getResults returns the votes for every allowed emote, in the order of the emotes
+func (rp *ReactionPolls) getResults(reactionPoll models.ReactionpollsEntry) (results []reactionPollResult, totalVotes int) {
+	for _, allowedEmote := range reactionPoll.AllowedEmotes {
+		votes := len(reactionPoll.Reactions[allowedEmote])
+		results = append(results, reactionPollResult{Emote: allowedEmote, Votes: votes})
+		totalVotes += votes
+	}
+	return results, totalVotes
+}
+
+func (rp *ReactionPolls) getResultsMessage(reactionPoll models.ReactionpollsEntry) *discordgo.MessageSend {
+	results, totalVotes := rp.getResults(reactionPoll)
+
+	resultsEmbed := &discordgo.MessageEmbed{
+		Title:       helpers.GetText("plugins.reactionpolls.results-embed-title"),
+		Description: reactionPoll.Text,
+		Color:       0x0FADED,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Total Votes %s | Poll #%s",
+				humanize.Comma(int64(totalVotes)), helpers.MdbIdToHuman(reactionPoll.ID)),
+		},
+	}
+	for i, result := range results {
+		percentage := getReactionPollPercentage(result.Votes, totalVotes)
+		resultsEmbed.Fields = append(resultsEmbed.Fields, &discordgo.MessageEmbedField{
+			Name: fmt.Sprintf("%d. %s", i+1, getReactionPollEmoteText(result.Emote)),
+			Value: fmt.Sprintf("`%s` %s votes (%.1f%%)",
+				getReactionPollTextBar(percentage, 10), humanize.Comma(int64(result.Votes)), percentage),
+		})
+	}
+
+	message := &discordgo.MessageSend{Embed: resultsEmbed}
+
+	chart, err := drawReactionPollChart(results, totalVotes)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return message
+	}
+	resultsEmbed.Image = &discordgo.MessageEmbedImage{URL: "attachment://" + reactionPollChartFilename}
+	message.Files = []*discordgo.File{
+		{
+			Name:   reactionPollChartFilename,
+			Reader: bytes.NewReader(chart),
+		},
+	}
+	return message
+}
+
+// drawReactionPollChart draws one horizontal bar for every result, in the order of the results
+func drawReactionPollChart(results []reactionPollResult, totalVotes int) ([]byte, error) {
+	height := len(results)*(reactionPollChartBarHeight+reactionPollChartPadding) + reactionPollChartPadding
+	chart := image.NewRGBA(image.Rect(0, 0, reactionPollChartWidth, height))
+	draw.Draw(chart, chart.Bounds(), &image.Uniform{reactionPollChartBackground}, image.ZP, draw.Src)
+
+	maxBarWidth := reactionPollChartWidth - 2*reactionPollChartPadding
+	for i, result := range results {
+		top := reactionPollChartPadding + i*(reactionPollChartBarHeight+reactionPollChartPadding)
+		track := image.Rect(reactionPollChartPadding, top, reactionPollChartPadding+maxBarWidth, top+reactionPollChartBarHeight)
+		draw.Draw(chart, track, &image.Uniform{reactionPollChartTrack}, image.ZP, draw.Src)
+
+		barWidth := int(float64(maxBarWidth) * getReactionPollPercentage(result.Votes, totalVotes) / 100)
+		if barWidth <= 0 {
+			continue
+		}
+		bar := image.Rect(reactionPollChartPadding, top, reactionPollChartPadding+barWidth, top+reactionPollChartBarHeight)
+		barColor := reactionPollChartColors[i%len(reactionPollChartColors)]
+		draw.Draw(chart, bar, &image.Uniform{barColor}, image.ZP, draw.Src)
+	}
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, chart)
+	return buffer.Bytes(), err
+}
+
+func getReactionPollPercentage(votes, totalVotes int) float64 {
+	if totalVotes <= 0 {
+		return 0
+	}
+	return float64(votes) * 100 / float64(totalVotes)
+}
+
+func getReactionPollTextBar(percentage float64, length int) string {
+	filled := int(percentage/100*float64(length) + 0.5)
+	return strings.Repeat("█", filled) + strings.Repeat("░", length-filled)
+}
+
+// getReactionPollEmoteText returns custom emotes, stored as name:id, in their message format
+func getReactionPollEmoteText(emote string) string {
+	if strings.Contains(emote, ":") {
+		return "<:" + emote + ">"
+	}
+	return emote
+}
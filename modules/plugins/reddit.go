@@ -3,6 +3,7 @@ package plugins
 import (
 	"net/http"
 	"strings"
+	"sync"
 
 	"fmt"
 
@@ -31,8 +32,10 @@ type Reddit struct {
 }
 
 var (
-	redditSession   *geddit.OAuthSession
-	RedditUserAgent = "geddit:Robyul:" + version.BOT_VERSION + " by /u/Seklfreak"
+	redditSession            *geddit.OAuthSession
+	RedditUserAgent          = "geddit:Robyul:" + version.BOT_VERSION + " by /u/Seklfreak"
+	redditBundledEntries     map[string][]models.RedditSubredditEntry // [subreddit]entries
+	redditBundledEntriesLock sync.RWMutex
 )
 
 const (
@@ -68,119 +71,205 @@ func (r *Reddit) Init(session *shardmanager.Manager) {
 		return
 	}
 	r.redditLoggedIn = true
-	go r.checkSubredditLoop()
-	r.logger().Info("Started checkSubredditLoop loop (0s)")
+	helpers.RegisterFeedSource(r, nil)
 }
 
-func (r *Reddit) checkSubredditLoop() {
-	defer helpers.Recover()
-	defer func() {
-		go func() {
-			r.logger().Error("The checkSubredditLoop died. Please investigate! Will be restarted in 60 seconds")
-			time.Sleep(60 * time.Second)
-			r.checkSubredditLoop()
-		}()
-	}()
+func (r *Reddit) Name() string {
+	return models.FeedSourceReddit
+}
+
+func (r *Reddit) Interval() time.Duration {
+	return 60 * time.Second
+}
+
+func (r *Reddit) Workers() int {
+	return 1
+}
 
+func (r *Reddit) Targets() (targets []string, err error) {
 	var entries []models.RedditSubredditEntry
-	var bundledEntries map[string][]models.RedditSubredditEntry
-	var newPost bool
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.RedditSubredditsTable).Find(nil)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	bundledEntries := make(map[string][]models.RedditSubredditEntry)
+	for _, entry := range entries {
+		embedLinks := !entry.PostDirectLinks
+		if entry.EmbedTemplate != "" {
+			embedLinks = helpers.IsEmbedCode(entry.EmbedTemplate)
+		}
+		if !helpers.FeedCanPost(entry.ChannelID, embedLinks) {
+			continue
+		}
+
+		bundledEntries[entry.SubredditName] = append(bundledEntries[entry.SubredditName], entry)
+	}
+
+	redditBundledEntriesLock.Lock()
+	redditBundledEntries = bundledEntries
+	redditBundledEntriesLock.Unlock()
+
+	for subredditName := range bundledEntries {
+		targets = append(targets, subredditName)
+	}
+	return targets, nil
+}
+
+// Check posts all submissions of the subreddit between the last check and the post delay of each feed
+func (r *Reddit) Check(target string) (err error) {
+	// stay below the rate limit of the Reddit API
+	defer time.Sleep(2 * time.Second)
+
+	redditBundledEntriesLock.RLock()
+	entries := redditBundledEntries[target]
+	redditBundledEntriesLock.RUnlock()
+
+	newSubmissions, err := redditSession.SubredditSubmissions(target, geddit.NewSubmissions, geddit.ListingOptions{
+		Limit: 30,
+	})
+	if err != nil && strings.Contains(err.Error(), "oauth2: token expired and refresh token is not set") {
+		// login when token expired
+		err = redditSession.LoginAuth(
+			helpers.GetConfig().Path("reddit.username").Data().(string),
+			helpers.GetConfig().Path("reddit.password").Data().(string),
+		)
+		if err != nil {
+			return err
+		}
+		r.logger().Warn("logged in again after token expired")
 
-	for {
-		err := helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.RedditSubredditsTable).Find(nil)).All(&entries)
-		helpers.Relax(err)
+		newSubmissions, err = redditSession.SubredditSubmissions(target, geddit.NewSubmissions, geddit.ListingOptions{
+			Limit: 30,
+		})
+	}
+	if err != nil {
+		return err
+	}
 
-		bundledEntries = make(map[string][]models.RedditSubredditEntry, 0)
+	// a failed post doesn't stop the other submissions from being posted, the last error is returned
+	var postErr error
+	for _, entry := range entries {
+		newPost := false
+		postFailed := false
+		hasToBeBefore := time.Now().Add(-(time.Duration(entry.PostDelay) * time.Minute))
+		hasToBeAfter := entry.LastChecked
 
-		for _, entry := range entries {
-			channel, err := helpers.GetChannelWithoutApi(entry.ChannelID)
-			if err != nil || channel == nil || channel.ID == "" {
-				//r.logger().Warn(fmt.Sprintf("skipped subreddit r/%s for Channel #%s on Guild #%s: channel not found!",
-				//	entry.SubredditName, entry.ChannelID, entry.GuildID))
+		for _, submission := range newSubmissions {
+			submissionTime := time.Unix(int64(submission.DateCreated), 0)
+			if !submissionTime.Before(hasToBeBefore) || !submissionTime.After(hasToBeAfter) {
 				continue
 			}
+			newPost = true
 
-			if _, ok := bundledEntries[entry.SubredditName]; ok {
-				bundledEntries[entry.SubredditName] = append(bundledEntries[entry.SubredditName], entry)
-			} else {
-				bundledEntries[entry.SubredditName] = []models.RedditSubredditEntry{entry}
+			if !r.matchesFilter(entry.Filter, submission) {
+				continue
 			}
-		}
-
-		r.logger().Infof("checking %d subreddits for %d feeds", len(bundledEntries), len(entries))
 
-		for subredditName, entries := range bundledEntries {
-		BundleStart:
-			// r.logger().Info(fmt.Sprintf("checking subreddit r/%s for %d channels", subredditName, len(entries)))
-			newSubmissions, err := redditSession.SubredditSubmissions(subredditName, geddit.NewSubmissions, geddit.ListingOptions{
-				Limit: 30,
-			})
+			claimed, err := helpers.FeedClaimItem(models.FeedSourceReddit, entry.ID.Hex(), submission.ID)
 			if err != nil {
-				if strings.Contains(err.Error(), "oauth2: token expired and refresh token is not set") {
-					// login when token expired
-					err = redditSession.LoginAuth(
-						helpers.GetConfig().Path("reddit.username").Data().(string),
-						helpers.GetConfig().Path("reddit.password").Data().(string),
-					)
-					helpers.Relax(err)
-					r.logger().Warn("logged in again after token expired")
-					goto BundleStart
-				}
-				r.logger().Warnf("updating subreddit r/%s failed: %s", subredditName, err.Error())
-				time.Sleep(2 * time.Second)
+				helpers.RelaxLog(err)
+				postErr = err
+				postFailed = true
 				continue
 			}
-			for _, entry := range entries {
-				newPost = false
-				hasToBeBefore := time.Now().Add(-(time.Duration(entry.PostDelay) * time.Minute))
-				hasToBeAfter := entry.LastChecked
-
-				for _, submission := range newSubmissions {
-					submissionTime := time.Unix(int64(submission.DateCreated), 0)
-					if !submissionTime.Before(hasToBeBefore) || !submissionTime.After(hasToBeAfter) {
-						continue
-					}
-					newPost = true
-
-					postSubmission := submission
-					postChannelID := entry.ChannelID
-					go func() {
-						defer helpers.Recover()
-
-						r.logger().Info(fmt.Sprintf("posting submission: #%s (%s) on r/%s (%s) to #%s",
-							postSubmission.ID, submissionTime.Format(time.ANSIC), subredditName,
-							RedditBaseUrl+"/r/"+subredditName+"/comments/"+postSubmission.ID+"/", entry.ChannelID))
-
-						err = r.postSubmission(postChannelID, postSubmission, entry.PostDirectLinks)
-						if err != nil {
-							if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil {
-								if errD.Message.Code != discordgo.ErrCodeMissingPermissions &&
-									errD.Message.Code != discordgo.ErrCodeUnknownChannel &&
-									errD.Message.Code != discordgo.ErrCodeMissingAccess {
-									helpers.Relax(err)
-								}
-							} else {
-								helpers.Relax(err)
-							}
-						}
-					}()
-				}
-				if newPost {
-					entry.LastChecked = hasToBeBefore
-					err = helpers.MDbUpdateWithoutLogging(models.RedditSubredditsTable, entry.ID, entry)
-					helpers.Relax(err)
-				}
+			if !claimed {
+				continue
+			}
+
+			r.logger().Info(fmt.Sprintf("posting submission: #%s (%s) on r/%s (%s) to #%s",
+				submission.ID, submissionTime.Format(time.ANSIC), target,
+				RedditBaseUrl+"/r/"+target+"/comments/"+submission.ID+"/", entry.ChannelID))
+
+			err = r.postSubmission(entry, submission)
+			if err != nil {
+				helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceReddit, entry.ID.Hex(), submission.ID))
+				helpers.RelaxLog(err)
+				postErr = err
+				postFailed = true
 			}
-			time.Sleep(2 * time.Second)
 		}
 
-		if len(entries) <= 10 {
-			time.Sleep(time.Second * 60)
+		// the failed submissions are tried again with the next check
+		if newPost && !postFailed {
+			err = helpers.MDbUpdateQueryWithoutLogging(models.RedditSubredditsTable,
+				bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{"lastchecked": hasToBeBefore}})
+			if err != nil {
+				helpers.RelaxLog(err)
+				postErr = err
+			}
 		}
 	}
+
+	return postErr
 }
 
-func (r *Reddit) postSubmission(channelID string, submission *geddit.Submission, postDirectLinks bool) (err error) {
+func (r *Reddit) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.RedditSubredditEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.RedditSubredditsTable).Find(bson.M{"guildid": guildID})).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:        helpers.MdbIdToHuman(entry.ID),
+			Name:      "r/" + entry.SubredditName,
+			ChannelID: entry.ChannelID,
+			Settings:  helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		})
+	}
+	return feeds, nil
+}
+
+func (r *Reddit) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	return helpers.MDbUpdateQuery(models.RedditSubredditsTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
+}
+
+// matchesFilter returns true if the submission passes the filter of the feed
+func (r *Reddit) matchesFilter(filter models.RedditFilter, submission *geddit.Submission) bool {
+	if filter.ExcludeNSFW && submission.IsNSFW {
+		return false
+	}
+
+	if filter.MinScore > 0 && submission.Score < filter.MinScore {
+		return false
+	}
+
+	if len(filter.Flairs) > 0 {
+		var found bool
+		for _, flair := range filter.Flairs {
+			if strings.ToLower(flair) == strings.ToLower(submission.LinkFlairText) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return helpers.FeedTextFilterMatches(filter.Text,
+		html.UnescapeString(submission.Title), html.UnescapeString(submission.Selftext))
+}
+
+// describeFilter returns one line for every rule of the filter
+func (r *Reddit) describeFilter(filter models.RedditFilter) (lines []string) {
+	lines = helpers.FeedDescribeTextFilter(filter.Text)
+	if len(filter.Flairs) > 0 {
+		lines = append(lines, "flairs: `"+strings.Join(filter.Flairs, "`, `")+"`")
+	}
+	if filter.MinScore > 0 {
+		lines = append(lines, fmt.Sprintf("minimum score: `%d`", filter.MinScore))
+	}
+	if filter.ExcludeNSFW {
+		lines = append(lines, "exclude NSFW submissions")
+	}
+	return lines
+}
+
+func (r *Reddit) postSubmission(entry models.RedditSubredditEntry, submission *geddit.Submission) (err error) {
 	data := &discordgo.MessageSend{}
 
 	data.Content = "<" + RedditBaseUrl + submission.Permalink + ">"
@@ -230,7 +319,17 @@ func (r *Reddit) postSubmission(channelID string, submission *geddit.Submission,
 		data.Embed.Image = &discordgo.MessageEmbedImage{URL: submission.ThumbnailURL}
 	}
 
-	if postDirectLinks {
+	item := helpers.FeedTemplateItem{
+		Title:       html.UnescapeString(submission.Title),
+		URL:         RedditBaseUrl + submission.Permalink,
+		Author:      "/u/" + submission.Author,
+		Description: textModeSelftext,
+	}
+	if data.Embed.Image != nil {
+		item.Thumbnail = data.Embed.Image.URL
+	}
+
+	if entry.PostDirectLinks {
 		content += textModeTitle + " _" + helpers.GetText("plugins.reddit.embed-footer") + "_\n"
 		content += "<" + RedditBaseUrl + submission.Permalink + "> by `/u/" + submission.Author + "`\n"
 		if textModeSelftext != "" {
@@ -243,8 +342,10 @@ func (r *Reddit) postSubmission(channelID string, submission *geddit.Submission,
 		data.Embed = nil
 	}
 
-	_, err = helpers.SendComplex(channelID, data)
-	return err
+	return helpers.FeedPostWithSettings(entry.ChannelID, "",
+		helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		helpers.FeedIdentity{Name: "r/" + submission.Subreddit, AvatarURL: helpers.GetText("plugins.reddit.embed-footer-imageurl")},
+		item, data)
 }
 
 func (r *Reddit) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
@@ -283,6 +384,8 @@ func (r *Reddit) actionStart(args []string, in *discordgo.Message, out **discord
 		return r.actionList
 	case "toggle-direct-link", "toggle-direct-links":
 		return r.actionToggleDirectLinks
+	case "filter", "filters":
+		return r.actionFilter
 	default:
 		return r.actionInfo
 	}
@@ -401,6 +504,9 @@ func (r *Reddit) actionList(args []string, in *discordgo.Message, out **discordg
 		if subredditEntry.PostDirectLinks {
 			directLinkModeText = ", direct link mode"
 		}
+		if len(r.describeFilter(subredditEntry.Filter)) > 0 {
+			directLinkModeText += ", filtered"
+		}
 
 		subredditListText += fmt.Sprintf("`%s`: Subreddit `r/%s` posting to <#%s> (Delay: %d minutes%s)\n",
 			helpers.MdbIdToHuman(subredditEntry.ID), subredditEntry.SubredditName, subredditEntry.ChannelID,
@@ -440,6 +546,9 @@ func (r *Reddit) actionRemove(args []string, in *discordgo.Message, out **discor
 	err = helpers.MDbDelete(models.RedditSubredditsTable, subredditEntry.ID)
 	helpers.Relax(err)
 
+	err = helpers.FeedDeleteItems(models.FeedSourceReddit, subredditEntry.ID.Hex())
+	helpers.RelaxLog(err)
+
 	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(subredditEntry.ID),
 		models.EventlogTargetTypeRobyulRedditFeed, in.Author.ID,
 		models.EventlogTypeRobyulRedditFeedRemove, "",
@@ -557,6 +666,115 @@ func (r *Reddit) actionToggleDirectLinks(args []string, in *discordgo.Message, o
 	return r.actionFinish
 }
 
+// [p]reddit filter <id> [<rule> [<value>]]
+func (r *Reddit) actionFilter(args []string, in *discordgo.Message, out **discordgo.MessageSend) redditAction {
+	if !helpers.IsMod(in) {
+		*out = r.newMsg(helpers.GetText("mod.no_permission"))
+		return r.actionFinish
+	}
+
+	if len(args) < 2 {
+		*out = r.newMsg("bot.arguments.too-few")
+		return r.actionFinish
+	}
+
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	var subredditEntry models.RedditSubredditEntry
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.RedditSubredditsTable).Find(bson.M{"guildid": channel.GuildID, "_id": helpers.HumanToMdbId(args[1])}),
+		&subredditEntry,
+	)
+	if helpers.IsMdbNotFound(err) {
+		*out = r.newMsg("plugins.reddit.filter-subreddit-not-found")
+		return r.actionFinish
+	}
+	helpers.Relax(err)
+
+	if len(args) < 3 {
+		filterLines := r.describeFilter(subredditEntry.Filter)
+		if len(filterLines) <= 0 {
+			*out = r.newMsg("plugins.reddit.filter-none", subredditEntry.SubredditName)
+			return r.actionFinish
+		}
+		*out = r.newMsg("plugins.reddit.filter-list", subredditEntry.SubredditName, strings.Join(filterLines, "\n"))
+		return r.actionFinish
+	}
+
+	beforeFilter := subredditEntry.Filter
+	rule := strings.ToLower(args[2])
+	value := strings.Join(args[3:], " ")
+
+	switch rule {
+	case "flairs", "flair":
+		subredditEntry.Filter.Flairs = helpers.FeedSplitFilterList(value)
+	case "min-score", "score":
+		subredditEntry.Filter.MinScore = 0
+		if value != "" {
+			subredditEntry.Filter.MinScore, err = strconv.Atoi(value)
+			if err != nil {
+				*out = r.newMsg("bot.arguments.invalid")
+				return r.actionFinish
+			}
+		}
+		// the score is only checked once, when the submission leaves the post delay
+		if subredditEntry.Filter.MinScore > 0 && subredditEntry.PostDelay <= 0 {
+			*out = r.newMsg("plugins.reddit.filter-min-score-without-delay", subredditEntry.SubredditName)
+			return r.actionFinish
+		}
+	case "exclude-nsfw", "nsfw":
+		subredditEntry.Filter.ExcludeNSFW = !subredditEntry.Filter.ExcludeNSFW
+	case "reset":
+		subredditEntry.Filter = models.RedditFilter{}
+	default:
+		known, err := helpers.FeedSetTextFilterRule(&subredditEntry.Filter.Text, rule, value)
+		if !known {
+			*out = r.newMsg("bot.arguments.invalid")
+			return r.actionFinish
+		}
+		if err != nil {
+			*out = r.newMsg("plugins.reddit.filter-invalid-regex", err.Error())
+			return r.actionFinish
+		}
+	}
+
+	err = helpers.MDbUpdate(models.RedditSubredditsTable, subredditEntry.ID, subredditEntry)
+	helpers.Relax(err)
+
+	filterLines := r.describeFilter(subredditEntry.Filter)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(subredditEntry.ID),
+		models.EventlogTargetTypeRobyulRedditFeed, in.Author.ID,
+		models.EventlogTypeRobyulRedditFeedUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "reddit_filter",
+				OldValue: strings.Join(r.describeFilter(beforeFilter), "; "),
+				NewValue: strings.Join(filterLines, "; "),
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "reddit_channelid",
+				Value: subredditEntry.ChannelID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "reddit_subredditname",
+				Value: subredditEntry.SubredditName,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	if len(filterLines) <= 0 {
+		*out = r.newMsg("plugins.reddit.filter-none", subredditEntry.SubredditName)
+		return r.actionFinish
+	}
+	*out = r.newMsg("plugins.reddit.filter-updated", subredditEntry.SubredditName, strings.Join(filterLines, "\n"))
+	return r.actionFinish
+}
+
 func (r *Reddit) getSubredditInfo(subreddit string) (data *discordgo.MessageSend) {
 	subredditData, err := redditSession.AboutSubreddit(subreddit)
 	if err != nil {
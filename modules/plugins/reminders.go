@@ -1,6 +1,8 @@
 package plugins
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -9,6 +11,7 @@ import (
 	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 	"github.com/globalsign/mgo/bson"
@@ -21,8 +24,27 @@ type Reminders struct {
 	parser *when.Parser
 }
 
-// maps guildid => custom message
-var customReminderMsgMap map[string]string
+const (
+	// how long fired one-shot reminders can still be snoozed
+	remindersSnoozeWindow = 24 * time.Hour
+)
+
+var (
+	// maps guildid => custom message
+	customReminderMsgMap map[string]string
+
+	// every day at 9am <message>, every monday at 18:30 <message>, every week <message>
+	reminderRecurringRegex = regexp.MustCompile(`(?is)^every\s+(day|week|monday|tuesday|wednesday|thursday|friday|saturday|sunday)s?(?:\s+at\s+(\d{1,2})(?:[:.](\d{2}))?\s*(am|pm)?)?(?:\s+(.*))?$`)
+	reminderWeekdays       = map[string]time.Weekday{
+		"sunday":    time.Sunday,
+		"monday":    time.Monday,
+		"tuesday":   time.Tuesday,
+		"wednesday": time.Wednesday,
+		"thursday":  time.Thursday,
+		"friday":    time.Friday,
+		"saturday":  time.Saturday,
+	}
+)
 
 func (r *Reminders) Commands() []string {
 	return []string{
@@ -52,7 +74,7 @@ func (r *Reminders) Init(session *shardmanager.Manager) {
 			}
 
 			for _, reminders := range reminderBucket {
-				changes := false
+				changes := setMissingReminderIDs(&reminders)
 
 				// Downward loop for in-loop element removal
 				for idx := len(reminders.Reminders) - 1; idx >= 0; idx-- {
@@ -65,6 +87,8 @@ func (r *Reminders) Init(session *shardmanager.Manager) {
 							if reminder.Message == "" {
 								content = ":alarm_clock: You wanted me to remind you about something, but you didn't tell me about what. <:blobthinking:317028940885524490>"
 							}
+							content += "\n" + helpers.GetTextF("plugins.reminders.fired-footer",
+								helpers.MdbIdToHuman(reminder.ID), helpers.GetPrefixForServer(reminder.GuildID), helpers.MdbIdToHuman(reminder.ID))
 
 							helpers.SendMessage(
 								dmChannel.ID,
@@ -72,7 +96,23 @@ func (r *Reminders) Init(session *shardmanager.Manager) {
 							)
 						}
 
-						reminders.Reminders = append(reminders.Reminders[:idx], reminders.Reminders[idx+1:]...)
+						if reminder.RepeatInterval != models.RemindersRepeatNone {
+							// reschedule recurring reminders instead of removing them
+							reminder.Timestamp = getNextReminderOccurrence(reminder, time.Now(), getReminderLocation(reminders.UserID)).Unix()
+							reminders.Reminders[idx] = reminder
+						} else {
+							reminder.FiredAt = time.Now().Unix()
+							reminders.RecentlyFired = append(reminders.RecentlyFired, reminder)
+							reminders.Reminders = append(reminders.Reminders[:idx], reminders.Reminders[idx+1:]...)
+						}
+						changes = true
+					}
+				}
+
+				// forget about fired reminders that can no longer be snoozed
+				for idx := len(reminders.RecentlyFired) - 1; idx >= 0; idx-- {
+					if time.Since(time.Unix(reminders.RecentlyFired[idx].FiredAt, 0)) > remindersSnoozeWindow {
+						reminders.RecentlyFired = append(reminders.RecentlyFired[:idx], reminders.RecentlyFired[idx+1:]...)
 						changes = true
 					}
 				}
@@ -102,39 +142,90 @@ func (r *Reminders) Init(session *shardmanager.Manager) {
 	cache.GetLogger().WithField("module", "reminders").Info("Started reminder loop (10s)")
 }
 
-func (r *Reminders) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
-	if !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, helpers.ModulePermReminders) {
-		return
+func (r *Reminders) Routes() []*router.Command {
+	return []*router.Command{
+		{
+			Name:        "remindme",
+			Aliases:     []string{"remind", "rm"},
+			Description: "reminds you about something, for example `in 2 hours check the oven` or `every monday at 9am take out the trash`",
+			Module:      helpers.ModulePermReminders,
+			Params: []router.Param{
+				{Name: "time and message", Type: router.ParamRest},
+			},
+			Handler: r.actionRemind,
+		},
+		{
+			Name:        "reminders",
+			Aliases:     []string{"rms"},
+			Description: "lists your pending reminders",
+			Module:      helpers.ModulePermReminders,
+			Handler:     r.actionList,
+			Subcommands: []*router.Command{
+				{
+					Name:        "delete",
+					Aliases:     []string{"del", "remove"},
+					Description: "deletes one of your reminders",
+					Params: []router.Param{
+						{Name: "reminder id", Type: router.ParamString},
+					},
+					Handler: r.actionDelete,
+				},
+				{
+					Name:        "edit",
+					Description: "changes the message of one of your reminders",
+					Params: []router.Param{
+						{Name: "reminder id", Type: router.ParamString},
+						{Name: "new message", Type: router.ParamRest},
+					},
+					Handler: r.actionEdit,
+				},
+				{
+					Name:        "snooze",
+					Description: "delays a pending reminder, or one that fired in the last 24 hours, for example by `10m` or `2 hours`",
+					Params: []router.Param{
+						{Name: "reminder id", Type: router.ParamString},
+						{Name: "duration", Type: router.ParamRest},
+					},
+					Handler: r.actionSnooze,
+				},
+			},
+		},
 	}
+}
+
+// Action is not used, all commands are dispatched by the router, see Routes
+func (r *Reminders) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+}
 
-	switch command {
-	case "rm", "remind", "remindme":
-		session.ChannelTyping(msg.ChannelID)
+// [p]remindme <time> <message>, [p]remindme every <day|week|weekday> [at <time>] <message>
+func (r *Reminders) actionRemind(ctx *router.Context) {
+	msg := ctx.Msg
+	content := ctx.String("time and message")
+	ctx.Session.ChannelTyping(msg.ChannelID)
 
-		channel, err := helpers.GetChannel(msg.ChannelID)
-		helpers.Relax(err)
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
 
-		parts := strings.Fields(content)
+	parts := strings.Fields(content)
 
-		if len(parts) < 3 {
-			helpers.SendMessage(msg.ChannelID, ":x: Please check if the format is correct")
-			return
-		}
+	if len(parts) < 2 {
+		helpers.SendMessage(msg.ChannelID, ":x: Please check if the format is correct")
+		return
+	}
 
-		r, err := r.parser.Parse(content, time.Now())
-		helpers.Relax(err)
-		if r == nil {
+	userLocation := getReminderLocation(msg.Author.ID)
+
+	if reminderRecurringRegex.MatchString(content) {
+		reminder, ok := parseRecurringReminder(content, time.Now(), userLocation)
+		if !ok {
 			helpers.SendMessage(msg.ChannelID, ":x: Please check if the format is correct")
 			return
 		}
+		reminder.ChannelID = channel.ID
+		reminder.GuildID = channel.GuildID
 
 		reminders := getReminders(msg.Author.ID)
-		reminders.Reminders = append(reminders.Reminders, models.RemindersReminderEntry{
-			Message:   strings.Replace(content, r.Text, "", 1),
-			ChannelID: channel.ID,
-			GuildID:   channel.GuildID,
-			Timestamp: r.Time.Unix(),
-		})
+		reminders.Reminders = append(reminders.Reminders, reminder)
 
 		err = helpers.MDbUpsertID(
 			models.RemindersTable,
@@ -143,60 +234,326 @@ func (r *Reminders) Action(command string, content string, msg *discordgo.Messag
 		)
 		helpers.Relax(err)
 
-		var userLocation *time.Location
-		userData, err := helpers.GetUserUserdata(msg.Author.ID)
-		if err == nil {
-			userLocation, _ = time.LoadLocation(userData.Timezone)
+		helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.reminders.recurring-success",
+			getReminderRepeatText(reminder), time.Unix(reminder.Timestamp, 0).In(userLocation).Format(time.UnixDate)))
+		return
+	}
+
+	if len(parts) < 3 {
+		helpers.SendMessage(msg.ChannelID, ":x: Please check if the format is correct")
+		return
+	}
+
+	result, err := r.parser.Parse(content, time.Now())
+	helpers.Relax(err)
+	if result == nil {
+		helpers.SendMessage(msg.ChannelID, ":x: Please check if the format is correct")
+		return
+	}
+
+	reminders := getReminders(msg.Author.ID)
+	reminders.Reminders = append(reminders.Reminders, models.RemindersReminderEntry{
+		ID:        bson.NewObjectId(),
+		Message:   strings.Replace(content, result.Text, "", 1),
+		ChannelID: channel.ID,
+		GuildID:   channel.GuildID,
+		Timestamp: result.Time.Unix(),
+	})
+
+	err = helpers.MDbUpsertID(
+		models.RemindersTable,
+		reminders.ID,
+		reminders,
+	)
+	helpers.Relax(err)
+
+	// Check if guild has a custom message set
+	if customMsg, ok := customReminderMsgMap[channel.GuildID]; ok {
+		helpers.SendMessage(msg.ChannelID, fmt.Sprintf(customMsg, result.Time.In(userLocation).Format(time.UnixDate)))
+	} else {
+		helpers.SendMessage(msg.ChannelID, "Ok I'll remind you at `"+result.Time.In(userLocation).Format(time.UnixDate)+" ` <:blobokhand:317032017164238848>")
+	}
+}
+
+// [p]reminders
+func (r *Reminders) actionList(ctx *router.Context) {
+	msg := ctx.Msg
+	ctx.Session.ChannelTyping(msg.ChannelID)
+
+	reminders := getReminders(msg.Author.ID)
+	var embedFields []*discordgo.MessageEmbedField
+
+	userLocation := getReminderLocation(msg.Author.ID)
+
+	for _, reminder := range reminders.Reminders {
+		ts := time.Unix(reminder.Timestamp, 0)
+
+		name := "At " + ts.In(userLocation).Format(time.UnixDate)
+		if reminder.RepeatInterval != models.RemindersRepeatNone {
+			name += ", repeats " + getReminderRepeatText(reminder)
 		}
-		if userLocation == nil {
-			userLocation, _ = time.LoadLocation("UTC")
+		value := reminder.Message
+		if value == "" {
+			value = "N/A"
 		}
 
-		// Check if guild has a custom message set
-		if customMsg, ok := customReminderMsgMap[channel.GuildID]; ok {
-			helpers.SendMessage(msg.ChannelID, fmt.Sprintf(customMsg, r.Time.In(userLocation).Format(time.UnixDate)))
-		} else {
-			helpers.SendMessage(msg.ChannelID, "Ok I'll remind you at `"+r.Time.In(userLocation).Format(time.UnixDate)+" ` <:blobokhand:317032017164238848>")
+		embedFields = append(embedFields, &discordgo.MessageEmbedField{
+			Inline: false,
+			Name:   name,
+			Value:  value + "\nID: `" + helpers.MdbIdToHuman(reminder.ID) + "`",
+		})
+	}
+
+	if len(embedFields) == 0 {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.empty"))
+		return
+	}
+
+	err := helpers.SendPagedMessage(msg, &discordgo.MessageEmbed{
+		Title:  "Pending reminders",
+		Fields: embedFields,
+		Color:  0x0FADED,
+	}, 10)
+	helpers.RelaxLog(err)
+}
+
+// [p]reminders delete <reminder id>
+func (r *Reminders) actionDelete(ctx *router.Context) {
+	msg := ctx.Msg
+
+	reminders := getReminders(msg.Author.ID)
+	idx := findReminder(reminders.Reminders, ctx.String("reminder id"))
+	if idx < 0 {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.not-found"))
+		return
+	}
+	reminders.Reminders = append(reminders.Reminders[:idx], reminders.Reminders[idx+1:]...)
+
+	err := helpers.MDbUpsertID(
+		models.RemindersTable,
+		reminders.ID,
+		reminders,
+	)
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.delete-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]reminders edit <reminder id> <new message>
+func (r *Reminders) actionEdit(ctx *router.Context) {
+	msg := ctx.Msg
+
+	reminders := getReminders(msg.Author.ID)
+	idx := findReminder(reminders.Reminders, ctx.String("reminder id"))
+	if idx < 0 {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.not-found"))
+		return
+	}
+	reminders.Reminders[idx].Message = ctx.String("new message")
+
+	err := helpers.MDbUpsertID(
+		models.RemindersTable,
+		reminders.ID,
+		reminders,
+	)
+	helpers.Relax(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.edit-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// [p]reminders snooze <reminder id> <duration>
+func (r *Reminders) actionSnooze(ctx *router.Context) {
+	msg := ctx.Msg
+
+	snoozeUntil, ok := r.parseSnoozeTime(ctx.String("duration"), time.Now())
+	if !ok {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		return
+	}
+
+	reminders := getReminders(msg.Author.ID)
+	idx := findReminder(reminders.Reminders, ctx.String("reminder id"))
+	if idx >= 0 {
+		// snoozing a pending reminder only delays its next occurrence
+		reminders.Reminders[idx].Timestamp = snoozeUntil.Unix()
+	} else {
+		firedIdx := findReminder(reminders.RecentlyFired, ctx.String("reminder id"))
+		if firedIdx < 0 {
+			helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.not-found"))
+			return
 		}
-		break
+		reminder := reminders.RecentlyFired[firedIdx]
+		reminder.Timestamp = snoozeUntil.Unix()
+		reminder.FiredAt = 0
+		reminders.Reminders = append(reminders.Reminders, reminder)
+		reminders.RecentlyFired = append(reminders.RecentlyFired[:firedIdx], reminders.RecentlyFired[firedIdx+1:]...)
+	}
 
-	case "rms", "reminders": // TODO: better interface
-		session.ChannelTyping(msg.ChannelID)
+	err := helpers.MDbUpsertID(
+		models.RemindersTable,
+		reminders.ID,
+		reminders,
+	)
+	helpers.Relax(err)
 
-		reminders := getReminders(msg.Author.ID)
-		var embedFields []*discordgo.MessageEmbedField
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.reminders.snooze-success",
+		snoozeUntil.In(getReminderLocation(msg.Author.ID)).Format(time.UnixDate)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
 
-		var userLocation *time.Location
-		userData, err := helpers.GetUserUserdata(msg.Author.ID)
-		if err == nil {
-			userLocation, _ = time.LoadLocation(userData.Timezone)
+// parseSnoozeTime parses durations like 1h30m, or texts like 10 minutes
+func (r *Reminders) parseSnoozeTime(text string, now time.Time) (snoozeUntil time.Time, ok bool) {
+	duration, err := time.ParseDuration(text)
+	if err == nil {
+		if duration <= 0 {
+			return snoozeUntil, false
+		}
+		return now.Add(duration), true
+	}
+
+	if !strings.HasPrefix(strings.ToLower(text), "in ") {
+		text = "in " + text
+	}
+	result, err := r.parser.Parse(text, now)
+	if err != nil || result == nil || !result.Time.After(now) {
+		return snoozeUntil, false
+	}
+	return result.Time, true
+}
+
+// parseRecurringReminder parses a recurring reminder, the time of day defaults to the current time
+func parseRecurringReminder(content string, now time.Time, location *time.Location) (reminder models.RemindersReminderEntry, ok bool) {
+	matches := reminderRecurringRegex.FindStringSubmatch(strings.TrimSpace(content))
+	if len(matches) < 6 {
+		return reminder, false
+	}
+
+	localNow := now.In(location)
+	reminder.ID = bson.NewObjectId()
+	reminder.RepeatHour = localNow.Hour()
+	reminder.RepeatMinute = localNow.Minute()
+	reminder.Message = strings.TrimSpace(matches[5])
+
+	unit := strings.ToLower(matches[1])
+	switch unit {
+	case "day":
+		reminder.RepeatInterval = models.RemindersRepeatDaily
+	case "week":
+		reminder.RepeatInterval = models.RemindersRepeatWeekly
+		reminder.RepeatWeekday = localNow.Weekday()
+	default:
+		reminder.RepeatInterval = models.RemindersRepeatWeekly
+		reminder.RepeatWeekday = reminderWeekdays[unit]
+	}
+
+	if matches[2] != "" {
+		hour, err := strconv.Atoi(matches[2])
+		if err != nil {
+			return reminder, false
+		}
+		minute := 0
+		if matches[3] != "" {
+			minute, err = strconv.Atoi(matches[3])
+			if err != nil {
+				return reminder, false
+			}
+		}
+		switch strings.ToLower(matches[4]) {
+		case "am":
+			if hour < 1 || hour > 12 {
+				return reminder, false
+			}
+			if hour == 12 {
+				hour = 0
+			}
+		case "pm":
+			if hour < 1 || hour > 12 {
+				return reminder, false
+			}
+			if hour != 12 {
+				hour += 12
+			}
 		}
-		if userLocation == nil {
-			userLocation, _ = time.LoadLocation("UTC")
+		if hour > 23 || minute > 59 {
+			return reminder, false
 		}
+		reminder.RepeatHour = hour
+		reminder.RepeatMinute = minute
+	}
+
+	reminder.Timestamp = getNextReminderOccurrence(reminder, now, location).Unix()
+	return reminder, true
+}
+
+// getNextReminderOccurrence returns the next time a recurring reminder should fire after the given time
+func getNextReminderOccurrence(reminder models.RemindersReminderEntry, after time.Time, location *time.Location) time.Time {
+	localAfter := after.In(location)
+	next := time.Date(localAfter.Year(), localAfter.Month(), localAfter.Day(),
+		reminder.RepeatHour, reminder.RepeatMinute, 0, 0, location)
 
-		for _, reminder := range reminders.Reminders {
-			ts := time.Unix(reminder.Timestamp, 0)
+	if reminder.RepeatInterval == models.RemindersRepeatWeekly {
+		next = next.AddDate(0, 0, (int(reminder.RepeatWeekday)-int(next.Weekday())+7)%7)
+	}
 
-			embedFields = append(embedFields, &discordgo.MessageEmbedField{
-				Inline: false,
-				Name:   "At " + ts.In(userLocation).Format(time.UnixDate),
-				Value:  reminder.Message,
-			})
+	for !next.After(after) {
+		if reminder.RepeatInterval == models.RemindersRepeatWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
 		}
+	}
 
-		if len(embedFields) == 0 {
-			helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.reminders.empty"))
-			return
+	return next
+}
+
+func getReminderRepeatText(reminder models.RemindersReminderEntry) (text string) {
+	switch reminder.RepeatInterval {
+	case models.RemindersRepeatDaily:
+		text = "every day"
+	case models.RemindersRepeatWeekly:
+		text = "every " + reminder.RepeatWeekday.String()
+	default:
+		return ""
+	}
+	return text + fmt.Sprintf(" at %02d:%02d", reminder.RepeatHour, reminder.RepeatMinute)
+}
+
+// getReminderLocation returns the timezone set in the profile of the user, or UTC
+func getReminderLocation(userID string) (userLocation *time.Location) {
+	userData, err := helpers.GetUserUserdata(userID)
+	if err == nil {
+		userLocation, _ = time.LoadLocation(userData.Timezone)
+	}
+	if userLocation == nil {
+		userLocation = time.UTC
+	}
+	return userLocation
+}
+
+func findReminder(reminders []models.RemindersReminderEntry, id string) int {
+	mdbID := helpers.HumanToMdbId(id)
+	if mdbID == "" {
+		return -1
+	}
+	for i, reminder := range reminders {
+		if reminder.ID == mdbID {
+			return i
 		}
+	}
+	return -1
+}
 
-		helpers.SendEmbed(msg.ChannelID, &discordgo.MessageEmbed{
-			Title:  "Pending reminders",
-			Fields: embedFields,
-			Color:  0x0FADED,
-		})
-		break
+// setMissingReminderIDs assigns IDs to reminders created before reminders had IDs
+func setMissingReminderIDs(reminders *models.RemindersEntry) (changes bool) {
+	for i := range reminders.Reminders {
+		if reminders.Reminders[i].ID == "" {
+			reminders.Reminders[i].ID = bson.NewObjectId()
+			changes = true
+		}
 	}
+	return changes
 }
 
 func getReminders(userID string) (reminder models.RemindersEntry) {
@@ -225,5 +582,14 @@ func getReminders(userID string) (reminder models.RemindersEntry) {
 		panic(err)
 	}
 
+	if setMissingReminderIDs(&reminder) {
+		err = helpers.MDbUpsertID(
+			models.RemindersTable,
+			reminder.ID,
+			reminder,
+		)
+		helpers.RelaxLog(err)
+	}
+
 	return reminder
 }
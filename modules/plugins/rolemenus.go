@@ -0,0 +1,918 @@
+package plugins
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/shardmanager"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type RoleMenus struct{}
+
+const (
+	roleMenuMaxOptions = 20
+)
+
+var (
+	roleMenuMessageIDs      = make(map[string]bson.ObjectId) // [messageID]menuID
+	roleMenuMessageIDsLock  sync.RWMutex
+	roleMenuMemberLocks     = make(map[string]*sync.Mutex) // [guildID:userID]
+	roleMenuMemberLocksLock sync.Mutex
+)
+
+func (rm *RoleMenus) Commands() []string {
+	return []string{
+		"rolemenu",
+		"rolemenus",
+	}
+}
+
+func (rm *RoleMenus) Init(session *shardmanager.Manager) {
+	err := rm.refreshCache()
+	helpers.Relax(err)
+}
+
+func (rm *RoleMenus) Uninit(session *shardmanager.Manager) {
+
+}
+
+func (rm *RoleMenus) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+	if !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, helpers.ModulePermRoleMenus) {
+		return
+	}
+
+	args, err := helpers.ToArgv(content)
+	helpers.Relax(err)
+
+	if len(args) < 1 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "create": // [p]rolemenu create [<#channel>] <toggle|unique|verify|max roles> "<title>" <emoji> <@role> [<emoji> <@role> ...]
+		helpers.RequireAdmin(msg, func() {
+			rm.actionCreate(args, msg, session)
+		})
+		return
+	case "add": // [p]rolemenu add <message id> <emoji> <@role>
+		helpers.RequireAdmin(msg, func() {
+			rm.actionAdd(args, msg, session)
+		})
+		return
+	case "remove": // [p]rolemenu remove <message id> <emoji|@role>
+		helpers.RequireAdmin(msg, func() {
+			rm.actionRemove(args, msg, session)
+		})
+		return
+	case "mode": // [p]rolemenu mode <message id> <toggle|unique|verify|max roles>
+		helpers.RequireAdmin(msg, func() {
+			rm.actionMode(args, msg, session)
+		})
+		return
+	case "title": // [p]rolemenu title <message id> "<title>"
+		helpers.RequireAdmin(msg, func() {
+			rm.actionTitle(args, msg, session)
+		})
+		return
+	case "refresh": // [p]rolemenu refresh <message id>
+		helpers.RequireAdmin(msg, func() {
+			rm.actionRefresh(args, msg, session)
+		})
+		return
+	case "delete": // [p]rolemenu delete <message id>
+		helpers.RequireAdmin(msg, func() {
+			rm.actionDelete(args, msg, session)
+		})
+		return
+	case "list": // [p]rolemenu list
+		helpers.RequireMod(msg, func() {
+			rm.actionList(msg, session)
+		})
+		return
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionCreate(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	i := 1
+	if len(args) > i && strings.HasPrefix(args[i], "<#") {
+		channel, err = helpers.GetChannelFromMention(msg, args[i])
+		if err != nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		i++
+	}
+	// mode, title, and at least one emoji and role
+	if len(args) < i+4 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	mode, maxRoles, ok := rm.parseMode(args[i])
+	if !ok {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.invalid-mode"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	title := strings.TrimSpace(args[i+1])
+	optionArgs := args[i+2:]
+	if title == "" || len(optionArgs)%2 != 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	if len(optionArgs)/2 > roleMenuMaxOptions {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.too-many-options", roleMenuMaxOptions))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	guild, err := helpers.GetGuild(channel.GuildID)
+	helpers.Relax(err)
+
+	options := make([]models.RoleMenuOption, 0)
+	for j := 0; j < len(optionArgs); j += 2 {
+		option, errText := rm.parseOption(guild, msg.Author.ID, options, optionArgs[j], optionArgs[j+1])
+		if errText != "" {
+			_, err = helpers.SendMessage(msg.ChannelID, errText)
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+		options = append(options, option)
+	}
+
+	menuPostedMessages, err := helpers.SendEmbed(channel.ID, &discordgo.MessageEmbed{
+		Color:       0x0FADED,
+		Description: "**Role menu is being created...** :construction_site:",
+	})
+	helpers.RelaxEmbed(err, msg.ChannelID, msg.ID)
+	if len(menuPostedMessages) <= 0 {
+		helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.errors.generic-nomessage"))
+		return
+	}
+
+	menu := models.RoleMenuEntry{
+		GuildID:         guild.ID,
+		ChannelID:       channel.ID,
+		MessageID:       menuPostedMessages[0].ID,
+		CreatedByUserID: msg.Author.ID,
+		CreatedAt:       time.Now().UTC(),
+		Title:           title,
+		Mode:            mode,
+		MaxRoles:        maxRoles,
+		Options:         options,
+	}
+	menu.ID, err = helpers.MDbInsert(models.RoleMenusTable, menu)
+	helpers.Relax(err)
+
+	rm.cacheMenu(menu)
+
+	rm.updateMessage(menu, session)
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuCreate, nil)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.create-success", menu.MessageID, menu.ChannelID))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionAdd(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 4 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+	if len(menu.Options) >= roleMenuMaxOptions {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.too-many-options", roleMenuMaxOptions))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	guild, err := helpers.GetGuild(menu.GuildID)
+	helpers.Relax(err)
+
+	option, errText := rm.parseOption(guild, msg.Author.ID, menu.Options, args[2], args[3])
+	if errText != "" {
+		_, err = helpers.SendMessage(msg.ChannelID, errText)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	beforeMenu := menu
+	menu.Options = append(menu.Options, option)
+	err = helpers.MDbUpdate(models.RoleMenusTable, menu.ID, menu)
+	helpers.Relax(err)
+
+	rm.updateMessage(menu, session)
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuUpdate, &beforeMenu)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.add-success",
+		getReactionPollEmoteText(option.Emoji), guild.Roles[rm.getRoleIndex(guild, option.RoleID)].Name))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionRemove(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 3 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+
+	// the option can be given by its emoji or its role
+	emoji := rm.parseEmoji(args[2])
+	roleID := strings.TrimSuffix(strings.TrimPrefix(args[2], "<@&"), ">")
+	options := make([]models.RoleMenuOption, 0)
+	var removedOption models.RoleMenuOption
+	for _, option := range menu.Options {
+		if option.Emoji == emoji || option.RoleID == roleID {
+			removedOption = option
+			continue
+		}
+		options = append(options, option)
+	}
+	if removedOption.RoleID == "" {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.option-not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+	if len(options) <= 0 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.remove-last-option"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	beforeMenu := menu
+	menu.Options = options
+	err := helpers.MDbUpdate(models.RoleMenusTable, menu.ID, menu)
+	helpers.Relax(err)
+
+	// reactions of members stay on the message, but aren't mapped to a role anymore
+	err = session.MessageReactionRemove(menu.ChannelID, menu.MessageID, removedOption.Emoji, "@me")
+	helpers.RelaxLog(err)
+
+	rm.updateMessage(menu, session)
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuUpdate, &beforeMenu)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.remove-success",
+		getReactionPollEmoteText(removedOption.Emoji)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionMode(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 3 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+
+	mode, maxRoles, ok := rm.parseMode(args[2])
+	if !ok {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.invalid-mode"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	beforeMenu := menu
+	menu.Mode = mode
+	menu.MaxRoles = maxRoles
+	err := helpers.MDbUpdate(models.RoleMenusTable, menu.ID, menu)
+	helpers.Relax(err)
+
+	rm.updateMessage(menu, session)
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuUpdate, &beforeMenu)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rolemenus.mode-success", rm.getModeText(menu)))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionTitle(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 3 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+
+	title := strings.TrimSpace(strings.Join(args[2:], " "))
+	if title == "" {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	beforeMenu := menu
+	menu.Title = title
+	err := helpers.MDbUpdate(models.RoleMenusTable, menu.ID, menu)
+	helpers.Relax(err)
+
+	rm.updateMessage(menu, session)
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuUpdate, &beforeMenu)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.title-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// actionRefresh renders the menu again, and adds missing reactions, e.g. after the message has been edited or the reactions have been cleared
+func (rm *RoleMenus) actionRefresh(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+
+	rm.cacheMenu(menu)
+
+	rm.updateMessage(menu, session)
+
+	_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.refresh-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionDelete(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	menu, ok := rm.getMenuForMessage(msg, args[1])
+	if !ok {
+		return
+	}
+
+	rm.deleteMenu(menu)
+
+	err := session.ChannelMessageDelete(menu.ChannelID, menu.MessageID)
+	if errD, ok := err.(*discordgo.RESTError); !ok || errD.Message == nil ||
+		(errD.Message.Code != discordgo.ErrCodeUnknownMessage && errD.Message.Code != discordgo.ErrCodeUnknownChannel) {
+		helpers.RelaxLog(err)
+	}
+
+	rm.logMenuChange(menu, msg.Author.ID, models.EventlogTypeRobyulRoleMenuDelete, nil)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.delete-success"))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (rm *RoleMenus) actionList(msg *discordgo.Message, session *discordgo.Session) {
+	session.ChannelTyping(msg.ChannelID)
+
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var menus []models.RoleMenuEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.RoleMenusTable).Find(bson.M{"guildid": channel.GuildID}).Sort("createdat")).All(&menus)
+	helpers.Relax(err)
+
+	if len(menus) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.list-none"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	persistentRoles := (&Persistency{}).GetPersistentRoles(channel.GuildID)
+
+	var listText string
+	for _, menu := range menus {
+		listText += helpers.GetTextF("plugins.rolemenus.list-entry",
+			menu.MessageID, menu.ChannelID, menu.Title, rm.getModeText(menu)) + "\n"
+		for _, option := range menu.Options {
+			listText += fmt.Sprintf("    %s <@&%s>", getReactionPollEmoteText(option.Emoji), option.RoleID)
+			for _, persistentRole := range persistentRoles {
+				if persistentRole.ID == option.RoleID {
+					listText += " _(persistent)_"
+					break
+				}
+			}
+			listText += "\n"
+		}
+	}
+
+	for _, page := range helpers.Pagify(listText, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, page)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// parseMode parses toggle, unique, verify, or the maximum number of roles members can pick
+func (rm *RoleMenus) parseMode(text string) (mode models.RoleMenuMode, maxRoles int, ok bool) {
+	switch strings.ToLower(text) {
+	case "toggle":
+		return models.RoleMenuModeToggle, 0, true
+	case "unique", "single":
+		return models.RoleMenuModeUnique, 0, true
+	case "verify":
+		return models.RoleMenuModeVerify, 0, true
+	}
+	maxRoles, err := strconv.Atoi(text)
+	if err != nil || maxRoles < 1 {
+		return mode, 0, false
+	}
+	return models.RoleMenuModeMax, maxRoles, true
+}
+
+// parseEmoji returns custom emoji in the name:id format used by reactions
+func (rm *RoleMenus) parseEmoji(text string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(text, "<a:"), "<:"), ">")
+}
+
+// parseOption parses an emoji and a role, and returns an error text if they can't be used on the menu
+func (rm *RoleMenus) parseOption(guild *discordgo.Guild, userID string, options []models.RoleMenuOption, emojiText, roleText string) (option models.RoleMenuOption, errText string) {
+	option.Emoji = rm.parseEmoji(emojiText)
+	if emojiParts := strings.Split(option.Emoji, ":"); len(emojiParts) >= 2 {
+		if rm.getEmojiIndex(guild, emojiParts[1]) < 0 {
+			return option, helpers.GetText("plugins.rolemenus.invalid-emoji")
+		}
+	}
+
+	roleID := strings.TrimSuffix(strings.TrimPrefix(roleText, "<@&"), ">")
+	roleIndex := rm.getRoleIndex(guild, roleID)
+	if roleIndex < 0 {
+		for i, role := range guild.Roles {
+			if strings.ToLower(role.Name) == strings.ToLower(roleText) {
+				roleIndex = i
+				break
+			}
+		}
+	}
+	if roleIndex < 0 || guild.Roles[roleIndex].ID == guild.ID || guild.Roles[roleIndex].Managed {
+		return option, helpers.GetTextF("plugins.rolemenus.invalid-role", roleText)
+	}
+	role := guild.Roles[roleIndex]
+	option.RoleID = role.ID
+
+	if rm.isPersistencyManagedRole(guild.ID, role.ID) {
+		return option, helpers.GetTextF("plugins.rolemenus.role-persistency-managed", role.Name)
+	}
+	if !rm.canManageRole(guild, userID, role) {
+		return option, helpers.GetTextF("plugins.rolemenus.role-not-assignable", role.Name)
+	}
+
+	for _, existingOption := range options {
+		if existingOption.Emoji == option.Emoji || existingOption.RoleID == option.RoleID {
+			return option, helpers.GetText("plugins.rolemenus.option-duplicate")
+		}
+	}
+	return option, ""
+}
+
+func (rm *RoleMenus) getRoleIndex(guild *discordgo.Guild, roleID string) int {
+	for i, role := range guild.Roles {
+		if role.ID == roleID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (rm *RoleMenus) getEmojiIndex(guild *discordgo.Guild, emojiID string) int {
+	for i, emoji := range guild.Emojis {
+		if emoji.ID == emojiID {
+			return i
+		}
+	}
+	return -1
+}
+
+// getHighestRolePosition returns the position of the highest role of the member
+func (rm *RoleMenus) getHighestRolePosition(guild *discordgo.Guild, userID string) (position int) {
+	member, err := helpers.GetGuildMember(guild.ID, userID)
+	if err != nil {
+		return 0
+	}
+	for _, roleID := range member.Roles {
+		if roleIndex := rm.getRoleIndex(guild, roleID); roleIndex >= 0 && guild.Roles[roleIndex].Position > position {
+			position = guild.Roles[roleIndex].Position
+		}
+	}
+	return position
+}
+
+// canManageRole checks if the role is below the highest role of the bot, and below the highest role of the user unless they own the server
+func (rm *RoleMenus) canManageRole(guild *discordgo.Guild, userID string, role *discordgo.Role) bool {
+	if role.Position >= rm.getHighestRolePosition(guild, cache.GetSession().SessionForGuildS(guild.ID).State.User.ID) {
+		return false
+	}
+	if guild.OwnerID == userID {
+		return true
+	}
+	return role.Position < rm.getHighestRolePosition(guild, userID)
+}
+
+// isPersistencyManagedRole checks if Persistency manages the role, for example the mute role, members should never be able to toggle these roles themselves
+func (rm *RoleMenus) isPersistencyManagedRole(guildID, roleID string) bool {
+	for _, managedRole := range (&Persistency{}).GetPersistentManagedRoles(guildID) {
+		if managedRole.ID == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+func (rm *RoleMenus) getModeText(menu models.RoleMenuEntry) string {
+	switch menu.Mode {
+	case models.RoleMenuModeUnique:
+		return helpers.GetText("plugins.rolemenus.mode-unique")
+	case models.RoleMenuModeVerify:
+		return helpers.GetText("plugins.rolemenus.mode-verify")
+	case models.RoleMenuModeMax:
+		return helpers.GetTextF("plugins.rolemenus.mode-max", menu.MaxRoles)
+	}
+	return helpers.GetText("plugins.rolemenus.mode-toggle")
+}
+
+func (rm *RoleMenus) getEmbed(menu models.RoleMenuEntry) *discordgo.MessageEmbed {
+	var description string
+	for _, option := range menu.Options {
+		description += fmt.Sprintf("%s <@&%s>\n", getReactionPollEmoteText(option.Emoji), option.RoleID)
+	}
+	return &discordgo.MessageEmbed{
+		Title:       menu.Title,
+		Description: description,
+		Color:       0x0FADED,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: rm.getModeText(menu),
+		},
+	}
+}
+
+// updateMessage renders the menu on its message, and adds the reactions of all options
+// the menu is stored by its message ID, so the message can be edited without breaking the menu
+func (rm *RoleMenus) updateMessage(menu models.RoleMenuEntry, session *discordgo.Session) {
+	_, err := helpers.EditEmbed(menu.ChannelID, menu.MessageID, rm.getEmbed(menu))
+	helpers.RelaxLog(err)
+
+	for _, option := range menu.Options {
+		err = session.MessageReactionAdd(menu.ChannelID, menu.MessageID, option.Emoji)
+		helpers.RelaxLog(err)
+	}
+}
+
+// getMenuForMessage returns the menu on the message of the current server, and sends an error if it doesn't exist
+func (rm *RoleMenus) getMenuForMessage(msg *discordgo.Message, messageID string) (menu models.RoleMenuEntry, ok bool) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.RoleMenusTable).Find(bson.M{"guildid": channel.GuildID, "messageid": messageID}),
+		&menu,
+	)
+	if err != nil {
+		if helpers.IsMdbNotFound(err) {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rolemenus.not-found"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return menu, false
+		}
+		helpers.Relax(err)
+	}
+	return menu, true
+}
+
+func (rm *RoleMenus) deleteMenu(menu models.RoleMenuEntry) {
+	err := helpers.MDbDelete(models.RoleMenusTable, menu.ID)
+	helpers.Relax(err)
+
+	roleMenuMessageIDsLock.Lock()
+	delete(roleMenuMessageIDs, menu.MessageID)
+	roleMenuMessageIDsLock.Unlock()
+}
+
+func (rm *RoleMenus) logMenuChange(menu models.RoleMenuEntry, userID string, eventlogType string, beforeMenu *models.RoleMenuEntry) {
+	var changes []models.ElasticEventlogChange
+	if beforeMenu != nil {
+		changes = []models.ElasticEventlogChange{
+			{
+				Key:      "rolemenu_title",
+				OldValue: beforeMenu.Title,
+				NewValue: menu.Title,
+			},
+			{
+				Key:      "rolemenu_mode",
+				OldValue: rm.getModeText(*beforeMenu),
+				NewValue: rm.getModeText(menu),
+			},
+			{
+				Key:      "rolemenu_roleids",
+				OldValue: strings.Join(rm.getRoleIDs(*beforeMenu), ";"),
+				NewValue: strings.Join(rm.getRoleIDs(menu), ";"),
+				Type:     models.EventlogTargetTypeRole,
+			},
+		}
+	}
+
+	_, err := helpers.EventlogLog(time.Now(), menu.GuildID, menu.MessageID,
+		models.EventlogTargetTypeRobyulRoleMenu, userID,
+		eventlogType, "",
+		changes,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "rolemenu_channelid",
+				Value: menu.ChannelID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "rolemenu_title",
+				Value: menu.Title,
+			},
+			{
+				Key:   "rolemenu_mode",
+				Value: rm.getModeText(menu),
+			},
+			{
+				Key:   "rolemenu_roleids",
+				Value: strings.Join(rm.getRoleIDs(menu), ";"),
+				Type:  models.EventlogTargetTypeRole,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+}
+
+func (rm *RoleMenus) getRoleIDs(menu models.RoleMenuEntry) (roleIDs []string) {
+	for _, option := range menu.Options {
+		roleIDs = append(roleIDs, option.RoleID)
+	}
+	return roleIDs
+}
+
+func (rm *RoleMenus) getOption(menu models.RoleMenuEntry, emoji string) (option models.RoleMenuOption, ok bool) {
+	for _, option := range menu.Options {
+		if option.Emoji == emoji {
+			return option, true
+		}
+	}
+	return option, false
+}
+
+func (rm *RoleMenus) refreshCache() (err error) {
+	var menus []models.RoleMenuEntry
+	err = helpers.MDbIter(
+		helpers.MdbCollection(models.RoleMenusTable).Find(nil).Select(bson.M{"_id": 1, "messageid": 1}),
+	).All(&menus)
+	if err != nil {
+		return err
+	}
+
+	messageIDs := make(map[string]bson.ObjectId)
+	for _, menu := range menus {
+		messageIDs[menu.MessageID] = menu.ID
+	}
+
+	roleMenuMessageIDsLock.Lock()
+	roleMenuMessageIDs = messageIDs
+	roleMenuMessageIDsLock.Unlock()
+	return nil
+}
+
+func (rm *RoleMenus) cacheMenu(menu models.RoleMenuEntry) {
+	roleMenuMessageIDsLock.Lock()
+	roleMenuMessageIDs[menu.MessageID] = menu.ID
+	roleMenuMessageIDsLock.Unlock()
+}
+
+func (rm *RoleMenus) getCachedMenuID(messageID string) (menuID bson.ObjectId, ok bool) {
+	roleMenuMessageIDsLock.RLock()
+	defer roleMenuMessageIDsLock.RUnlock()
+	menuID, ok = roleMenuMessageIDs[messageID]
+	return menuID, ok
+}
+
+// getMenuForReaction returns the menu and the option for a reaction by a member
+func (rm *RoleMenus) getMenuForReaction(menuID bson.ObjectId, emoji string) (menu models.RoleMenuEntry, option models.RoleMenuOption, ok bool) {
+	err := helpers.MdbOneWithoutLogging(
+		helpers.MdbCollection(models.RoleMenusTable).Find(bson.M{"_id": menuID}),
+		&menu,
+	)
+	if err != nil {
+		if !helpers.IsMdbNotFound(err) {
+			helpers.RelaxLog(err)
+		}
+		return menu, option, false
+	}
+	option, ok = rm.getOption(menu, emoji)
+	return menu, option, ok
+}
+
+// lockMember makes sure reactions of a member are handled one after another, so unique and max modes can't be bypassed by reacting quickly
+func (rm *RoleMenus) lockMember(guildID, userID string) {
+	roleMenuMemberLocksLock.Lock()
+	lock, ok := roleMenuMemberLocks[guildID+":"+userID]
+	if !ok {
+		lock = new(sync.Mutex)
+		roleMenuMemberLocks[guildID+":"+userID] = lock
+	}
+	roleMenuMemberLocksLock.Unlock()
+
+	lock.Lock()
+}
+
+func (rm *RoleMenus) unlockMember(guildID, userID string) {
+	roleMenuMemberLocksLock.Lock()
+	lock, ok := roleMenuMemberLocks[guildID+":"+userID]
+	roleMenuMemberLocksLock.Unlock()
+
+	if ok {
+		lock.Unlock()
+	}
+}
+
+// relaxRoleChange ignores errors caused by missing permissions or deleted roles and members
+func (rm *RoleMenus) relaxRoleChange(err error) {
+	if errD, ok := err.(*discordgo.RESTError); ok && errD.Message != nil {
+		if errD.Message.Code == discordgo.ErrCodeMissingPermissions ||
+			errD.Message.Code == discordgo.ErrCodeMissingAccess ||
+			errD.Message.Code == discordgo.ErrCodeUnknownRole ||
+			errD.Message.Code == discordgo.ErrCodeUnknownMember {
+			return
+		}
+	}
+	helpers.RelaxLog(err)
+}
+
+func (rm *RoleMenus) OnReactionAdd(reaction *discordgo.MessageReactionAdd, session *discordgo.Session) {
+	// skip reactions by the bot
+	if reaction.UserID == session.State.User.ID {
+		return
+	}
+	menuID, ok := rm.getCachedMenuID(reaction.MessageID)
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer helpers.Recover()
+
+		menu, option, ok := rm.getMenuForReaction(menuID, reaction.Emoji.APIName())
+		if !ok {
+			session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+			return
+		}
+
+		rm.lockMember(menu.GuildID, reaction.UserID)
+		defer rm.unlockMember(menu.GuildID, reaction.UserID)
+
+		// the state is only updated by the gateway events of earlier role changes, which can still be on their way
+		member, err := session.GuildMember(menu.GuildID, reaction.UserID)
+		if err != nil || member.User == nil || member.User.Bot || rm.isPersistencyManagedRole(menu.GuildID, option.RoleID) {
+			session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+			return
+		}
+		if sliceContains(member.Roles, option.RoleID) {
+			return
+		}
+
+		switch menu.Mode {
+		case models.RoleMenuModeUnique:
+			// replace the previous role of the menu
+			for _, otherOption := range menu.Options {
+				if otherOption.RoleID == option.RoleID {
+					continue
+				}
+				if !sliceContains(member.Roles, otherOption.RoleID) {
+					continue
+				}
+				err = session.GuildMemberRoleRemove(menu.GuildID, reaction.UserID, otherOption.RoleID)
+				rm.relaxRoleChange(err)
+				session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, otherOption.Emoji, reaction.UserID)
+			}
+		case models.RoleMenuModeMax:
+			var memberRoles int
+			for _, otherOption := range menu.Options {
+				if sliceContains(member.Roles, otherOption.RoleID) {
+					memberRoles++
+				}
+			}
+			if memberRoles >= menu.MaxRoles {
+				session.MessageReactionRemove(reaction.ChannelID, reaction.MessageID, reaction.Emoji.APIName(), reaction.UserID)
+				return
+			}
+		}
+
+		err = session.GuildMemberRoleAdd(menu.GuildID, reaction.UserID, option.RoleID)
+		rm.relaxRoleChange(err)
+	}()
+}
+
+func (rm *RoleMenus) OnReactionRemove(reaction *discordgo.MessageReactionRemove, session *discordgo.Session) {
+	// skip reactions by the bot
+	if reaction.UserID == session.State.User.ID {
+		return
+	}
+	menuID, ok := rm.getCachedMenuID(reaction.MessageID)
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer helpers.Recover()
+
+		menu, option, ok := rm.getMenuForReaction(menuID, reaction.Emoji.APIName())
+		// verify menus never remove roles
+		if !ok || menu.Mode == models.RoleMenuModeVerify || rm.isPersistencyManagedRole(menu.GuildID, option.RoleID) {
+			return
+		}
+
+		rm.lockMember(menu.GuildID, reaction.UserID)
+		defer rm.unlockMember(menu.GuildID, reaction.UserID)
+
+		// the state is only updated by the gateway events of earlier role changes, which can still be on their way
+		member, err := session.GuildMember(menu.GuildID, reaction.UserID)
+		if err != nil || !sliceContains(member.Roles, option.RoleID) {
+			return
+		}
+
+		err = session.GuildMemberRoleRemove(menu.GuildID, reaction.UserID, option.RoleID)
+		rm.relaxRoleChange(err)
+	}()
+}
+
+func (rm *RoleMenus) OnMessageDelete(msg *discordgo.MessageDelete, session *discordgo.Session) {
+	menuID, ok := rm.getCachedMenuID(msg.ID)
+	if !ok {
+		return
+	}
+
+	go func() {
+		defer helpers.Recover()
+
+		rm.deleteMenu(models.RoleMenuEntry{ID: menuID, MessageID: msg.ID})
+	}()
+}
+
+func (rm *RoleMenus) OnMessage(content string, msg *discordgo.Message, session *discordgo.Session) {
+
+}
+
+func (rm *RoleMenus) OnGuildMemberAdd(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (rm *RoleMenus) OnGuildMemberRemove(member *discordgo.Member, session *discordgo.Session) {
+
+}
+
+func (rm *RoleMenus) OnGuildBanAdd(user *discordgo.GuildBanAdd, session *discordgo.Session) {
+
+}
+
+func (rm *RoleMenus) OnGuildBanRemove(user *discordgo.GuildBanRemove, session *discordgo.Session) {
+
+}
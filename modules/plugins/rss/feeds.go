@@ -0,0 +1,276 @@
+package rss
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// a feed which changed the GUIDs of all of its items shouldn't flood the channel,
+	// only the newest items of the feed are posted
+	maxPostsPerCheck  = 5
+	descriptionLength = 300
+)
+
+type feeds struct {
+	bundledEntries     map[string][]models.RSSFeedEntry // [url]entries
+	bundledEntriesLock sync.RWMutex
+	headers            map[string]conditionalHeaders // [url]headers of the last successful check
+	headersLock        sync.Mutex
+}
+
+// conditionalHeaders are sent with the next request of the URL, to skip unchanged feeds
+type conditionalHeaders struct {
+	ETag         string
+	LastModified string
+}
+
+func (f *feeds) Init() {
+	f.headers = make(map[string]conditionalHeaders)
+	helpers.RegisterFeedSource(f, nil)
+}
+
+func (f *feeds) Name() string {
+	return models.FeedSourceRSS
+}
+
+func (f *feeds) Interval() time.Duration {
+	return 5 * time.Minute
+}
+
+func (f *feeds) Workers() int {
+	return 5
+}
+
+func (f *feeds) Targets() (targets []string, err error) {
+	var entries []models.RSSFeedEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.RSSFeedsTable).Find(nil)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	bundledEntries := make(map[string][]models.RSSFeedEntry)
+	for _, entry := range entries {
+		if !helpers.FeedCanPost(entry.ChannelID, entry.EmbedTemplate == "" || helpers.IsEmbedCode(entry.EmbedTemplate)) {
+			continue
+		}
+
+		bundledEntries[entry.URL] = append(bundledEntries[entry.URL], entry)
+	}
+
+	f.bundledEntriesLock.Lock()
+	f.bundledEntries = bundledEntries
+	f.bundledEntriesLock.Unlock()
+
+	for url := range bundledEntries {
+		targets = append(targets, url)
+	}
+	return targets, nil
+}
+
+// Check fetches the feed at the URL and posts its new items to all feeds of the URL
+func (f *feeds) Check(target string) (err error) {
+	f.bundledEntriesLock.RLock()
+	entries := f.bundledEntries[target]
+	f.bundledEntriesLock.RUnlock()
+
+	if len(entries) <= 0 {
+		return nil
+	}
+
+	f.headersLock.Lock()
+	previousHeaders := f.headers[target]
+	f.headersLock.Unlock()
+
+	parsedFeed, headers, err := fetchFeed(target, previousHeaders)
+	if err != nil {
+		if err == helpers.ErrNotModified {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		err = f.postItems(entry, parsedFeed)
+		if err != nil {
+			return err
+		}
+	}
+
+	// only stored after all entries have been checked, if posting an item failed the feed is fetched
+	// again with the next check, instead of being skipped as not modified
+	f.headersLock.Lock()
+	f.headers[target] = headers
+	f.headersLock.Unlock()
+	return nil
+}
+
+func (f *feeds) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.RSSFeedEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.RSSFeedsTable).Find(
+		bson.M{"guildid": guildID},
+	)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:            helpers.MdbIdToHuman(entry.ID),
+			Name:          entry.URL,
+			ChannelID:     entry.ChannelID,
+			MentionRoleID: entry.MentionRoleID,
+			Settings:      helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		})
+	}
+	return feeds, nil
+}
+
+func (f *feeds) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	return helpers.MDbUpdateQuery(models.RSSFeedsTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
+}
+
+// postItems posts all items of the feed which haven't been posted to the entry before, oldest first,
+// only the newest unposted items are posted, older ones are claimed without posting them
+func (f *feeds) postItems(entry models.RSSFeedEntry, parsedFeed *feed) (err error) {
+	var items []feedItem
+	for _, item := range parsedFeed.Items {
+		if item.ID != "" {
+			items = append(items, item)
+		}
+	}
+	sortItemsNewestFirst(items)
+
+	var unpostedItems []feedItem
+	for _, item := range items {
+		claimed, err := helpers.FeedClaimItem(models.FeedSourceRSS, entry.ID.Hex(), item.ID)
+		if err != nil {
+			return err
+		}
+		if claimed {
+			unpostedItems = append(unpostedItems, item)
+		}
+	}
+	if len(unpostedItems) > maxPostsPerCheck {
+		unpostedItems = unpostedItems[:maxPostsPerCheck]
+	}
+
+	for i := len(unpostedItems) - 1; i >= 0; i-- {
+		item := unpostedItems[i]
+
+		templateItem, defaultMessage := getItemMessage(entry, parsedFeed, item)
+		err = helpers.FeedPostWithSettings(entry.ChannelID, entry.MentionRoleID,
+			helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+			helpers.FeedIdentity{Name: getFeedTitle(entry, parsedFeed)},
+			templateItem, defaultMessage)
+		if err != nil {
+			// the item and all newer ones are posted with the next check
+			for _, unpostedItem := range unpostedItems[:i+1] {
+				helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceRSS, entry.ID.Hex(), unpostedItem.ID))
+			}
+			return err
+		}
+
+		logger().WithFields(logrus.Fields{
+			"title":   item.Title,
+			"url":     entry.URL,
+			"channel": entry.ChannelID,
+		}).Info("posting item")
+	}
+
+	return nil
+}
+
+// sortItemsNewestFirst sorts the items by their publishing date if all of them have one,
+// otherwise the items are expected to be in the newest first order of the feed
+func sortItemsNewestFirst(items []feedItem) {
+	for _, item := range items {
+		if item.Published.IsZero() {
+			return
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Published.After(items[j].Published)
+	})
+}
+
+// getItemMessage returns the values for the template of the entry, and the default embed of the item
+func getItemMessage(entry models.RSSFeedEntry, parsedFeed *feed, item feedItem) (templateItem helpers.FeedTemplateItem, message *discordgo.MessageSend) {
+	description := item.Description
+	if runes := []rune(description); len(runes) > descriptionLength {
+		description = strings.TrimSpace(string(runes[:descriptionLength])) + "…"
+	}
+
+	templateItem = helpers.FeedTemplateItem{
+		Title:       item.Title,
+		URL:         item.URL,
+		Author:      item.Author,
+		Thumbnail:   item.Image,
+		Description: description,
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Author: &discordgo.MessageEmbedAuthor{
+			Name: getFeedTitle(entry, parsedFeed),
+			URL:  parsedFeed.URL,
+		},
+		Title:       item.Title,
+		URL:         item.URL,
+		Description: description,
+		Color:       helpers.GetDiscordColorFromHex(rssColor),
+	}
+	if item.Image != "" {
+		embed.Image = &discordgo.MessageEmbedImage{URL: item.Image}
+	}
+	if item.Author != "" {
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: item.Author}
+	}
+	if !item.Published.IsZero() {
+		embed.Timestamp = item.Published.Format(time.RFC3339)
+	}
+
+	message = &discordgo.MessageSend{
+		Embed: embed,
+	}
+	if item.URL != "" {
+		message.Content = "<" + item.URL + ">"
+	}
+	return templateItem, message
+}
+
+// getFeedTitle returns the current title of the feed, or the title it had when it was added
+func getFeedTitle(entry models.RSSFeedEntry, parsedFeed *feed) string {
+	if parsedFeed.Title != "" {
+		return parsedFeed.Title
+	}
+	return entry.Title
+}
+
+// fetchFeed downloads and parses the feed, returns helpers.ErrNotModified if it didn't change since the previous request
+func fetchFeed(url string, previousHeaders conditionalHeaders) (result *feed, headers conditionalHeaders, err error) {
+	data, responseHeaders, err := helpers.NetGetUAWithErrorConditional(
+		url, helpers.DEFAULT_UA, previousHeaders.ETag, previousHeaders.LastModified)
+	if err != nil {
+		return nil, previousHeaders, err
+	}
+
+	result, err = parseFeed(data)
+	if err != nil {
+		return nil, previousHeaders, err
+	}
+
+	return result, conditionalHeaders{
+		ETag:         responseHeaders.Get("ETag"),
+		LastModified: responseHeaders.Get("Last-Modified"),
+	}, nil
+}
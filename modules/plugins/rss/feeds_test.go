@@ -0,0 +1,27 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSortItemsNewestFirst(t *testing.T) {
+	day := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	items := []feedItem{
+		{ID: "b", Published: day.Add(time.Hour)},
+		{ID: "a", Published: day},
+		{ID: "c", Published: day.Add(2 * time.Hour)},
+	}
+	sortItemsNewestFirst(items)
+	if items[0].ID != "c" || items[1].ID != "b" || items[2].ID != "a" {
+		t.Fatalf("sortItemsNewestFirst() returned %+v, expected c, b, a", items)
+	}
+
+	// the order of the feed is kept if an item has no publishing date
+	items = []feedItem{{ID: "a", Published: day}, {ID: "b"}, {ID: "c", Published: day.Add(time.Hour)}}
+	sortItemsNewestFirst(items)
+	if items[0].ID != "a" || items[1].ID != "b" || items[2].ID != "c" {
+		t.Fatalf("sortItemsNewestFirst() reordered items without a publishing date: %+v", items)
+	}
+}
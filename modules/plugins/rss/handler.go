@@ -0,0 +1,367 @@
+package rss
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/shardmanager"
+	"github.com/bwmarrin/discordgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	rssColor = "F26522"
+)
+
+type Handler struct {
+	feedsLoop feeds
+}
+
+func (h *Handler) Commands() []string {
+	return []string{
+		"rss",
+	}
+}
+
+func (h *Handler) Init(session *shardmanager.Manager) {
+	defer helpers.Recover()
+
+	h.feedsLoop.Init()
+}
+
+func (h *Handler) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
+	if !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, helpers.ModulePermRSS) {
+		return
+	}
+
+	args := strings.Fields(content)
+	if len(args) <= 0 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "add": // [p]rss add <url> <#channel> [<role>]
+		helpers.RequireMod(msg, func() {
+			h.actionAdd(args, msg, session)
+		})
+	case "delete", "del", "remove": // [p]rss delete <id>
+		helpers.RequireMod(msg, func() {
+			h.actionDelete(args, msg)
+		})
+	case "template": // [p]rss template <id> [<embed code>], same as [p]feeds template
+		helpers.RequireMod(msg, func() {
+			h.actionTemplate(args, content, msg)
+		})
+	case "list": // [p]rss list
+		h.actionList(msg, session)
+	default:
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+func (h *Handler) actionAdd(args []string, msg *discordgo.Message, session *discordgo.Session) {
+	if len(args) < 3 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	session.ChannelTyping(msg.ChannelID)
+
+	feedURL := strings.Trim(args[1], "<>")
+	if !strings.HasPrefix(feedURL, "http://") && !strings.HasPrefix(feedURL, "https://") {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rss.add-invalid-url"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	targetChannel, err := helpers.GetChannelFromMention(msg, args[2])
+	if err != nil {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	var mentionRoleID string
+	if len(args) >= 4 {
+		mentionRoleID = h.findMentionableRole(targetChannel.GuildID, strings.Join(args[3:], " "))
+		if mentionRoleID == "" {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rss.add-role-not-found"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	count, err := helpers.MdbCollection(models.RSSFeedsTable).Find(
+		bson.M{"channelid": targetChannel.ID, "url": feedURL},
+	).Count()
+	helpers.Relax(err)
+	if count > 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rss.add-already", targetChannel.ID))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	parsedFeed, _, err := fetchFeed(feedURL, conditionalHeaders{})
+	if err != nil {
+		logger().WithError(err).WithField("url", feedURL).Info("failed to add feed")
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rss.add-invalid-feed"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	feedTitle := parsedFeed.Title
+	if feedTitle == "" {
+		feedTitle = feedURL
+	}
+
+	newID, err := helpers.MDbInsert(
+		models.RSSFeedsTable,
+		models.RSSFeedEntry{
+			GuildID:       targetChannel.GuildID,
+			ChannelID:     targetChannel.ID,
+			AddedByUserID: msg.Author.ID,
+			AddedAt:       time.Now(),
+			URL:           feedURL,
+			Title:         feedTitle,
+			MentionRoleID: mentionRoleID,
+		},
+	)
+	helpers.Relax(err)
+
+	// don't post the current items
+	for _, item := range parsedFeed.Items {
+		if item.ID == "" {
+			continue
+		}
+		_, err = helpers.FeedClaimItem(models.FeedSourceRSS, newID.Hex(), item.ID)
+		helpers.Relax(err)
+	}
+
+	_, err = helpers.EventlogLog(time.Now(), targetChannel.GuildID, helpers.MdbIdToHuman(newID),
+		models.EventlogTargetTypeRobyulRSSFeed, msg.Author.ID,
+		models.EventlogTypeRobyulRSSFeedAdd, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "rss_channelid",
+				Value: targetChannel.ID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "rss_url",
+				Value: feedURL,
+			},
+			{
+				Key:   "rss_title",
+				Value: feedTitle,
+			},
+			{
+				Key:   "rss_mentionroleid",
+				Value: mentionRoleID,
+				Type:  models.EventlogTargetTypeRole,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rss.add-success", feedTitle, targetChannel.ID))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+
+	logger().WithFields(logrus.Fields{
+		"url":     feedURL,
+		"channel": targetChannel.ID,
+		"guild":   targetChannel.GuildID,
+	}).Info("added feed")
+}
+
+func (h *Handler) actionDelete(args []string, msg *discordgo.Message) {
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	entry, ok := h.findEntry(msg, args[1])
+	if !ok {
+		return
+	}
+
+	err := helpers.MDbDelete(models.RSSFeedsTable, entry.ID)
+	helpers.Relax(err)
+
+	err = helpers.FeedDeleteItems(models.FeedSourceRSS, entry.ID.Hex())
+	helpers.RelaxLog(err)
+
+	_, err = helpers.EventlogLog(time.Now(), entry.GuildID, helpers.MdbIdToHuman(entry.ID),
+		models.EventlogTargetTypeRobyulRSSFeed, msg.Author.ID,
+		models.EventlogTypeRobyulRSSFeedRemove, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "rss_channelid",
+				Value: entry.ChannelID,
+				Type:  models.EventlogTargetTypeChannel,
+			},
+			{
+				Key:   "rss_url",
+				Value: entry.URL,
+			},
+			{
+				Key:   "rss_title",
+				Value: entry.Title,
+			},
+			{
+				Key:   "rss_mentionroleid",
+				Value: entry.MentionRoleID,
+				Type:  models.EventlogTargetTypeRole,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.rss.delete-success", entry.Title))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+// actionTemplate sets the template of the feed, or resets it to the default embed if none is given
+func (h *Handler) actionTemplate(args []string, content string, msg *discordgo.Message) {
+	if len(args) < 2 {
+		_, err := helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	entry, ok := h.findEntry(msg, args[1])
+	if !ok {
+		return
+	}
+
+	template := strings.TrimSpace(strings.Replace(content, strings.Join(args[:2], " "), "", 1))
+	if helpers.IsEmbedCode(template) {
+		_, _, err := helpers.ParseEmbedCode(template)
+		if err != nil {
+			_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.feeds.template-invalid"))
+			helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+			return
+		}
+	}
+
+	err := h.feedsLoop.SetFeedSettings(helpers.MdbIdToHuman(entry.ID),
+		helpers.FeedSettings{EmbedTemplate: template, PostAsWebhook: entry.PostAsWebhook})
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), entry.GuildID, helpers.MdbIdToHuman(entry.ID),
+		models.EventlogTargetTypeRobyulRSSFeed, msg.Author.ID,
+		models.EventlogTypeRobyulRSSFeedUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "rss_embedtemplate",
+				OldValue: entry.EmbedTemplate,
+				NewValue: template,
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "rss_url",
+				Value: entry.URL,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	if template == "" {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.template-reset-success", entry.URL))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	_, err = helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.feeds.template-set-success", entry.URL))
+	helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+}
+
+func (h *Handler) actionList(msg *discordgo.Message, session *discordgo.Session) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	var entries []models.RSSFeedEntry
+	err = helpers.MDbIter(helpers.MdbCollection(models.RSSFeedsTable).Find(
+		bson.M{"guildid": channel.GuildID},
+	)).All(&entries)
+	helpers.Relax(err)
+
+	if len(entries) <= 0 {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rss.list-none"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return
+	}
+
+	var resultMessage string
+	for _, entry := range entries {
+		var specialText string
+		if entry.MentionRoleID != "" {
+			role, err := session.State.Role(channel.GuildID, entry.MentionRoleID)
+			if err == nil {
+				specialText += fmt.Sprintf(" mentioning `@%s`", role.Name)
+			} else {
+				specialText += " mentioning N/A"
+			}
+		}
+		if entry.EmbedTemplate != "" {
+			specialText += " with a custom template"
+		}
+		resultMessage += fmt.Sprintf("`%s`: `%s` (<%s>) posting to <#%s>%s\n",
+			helpers.MdbIdToHuman(entry.ID), entry.Title, entry.URL, entry.ChannelID, specialText)
+	}
+	resultMessage += helpers.GetTextF("plugins.rss.list-sum", len(entries))
+
+	for _, resultPage := range helpers.Pagify(resultMessage, "\n") {
+		_, err = helpers.SendMessage(msg.ChannelID, resultPage)
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+	}
+}
+
+// findEntry returns the feed with the ID on the current guild, and sends an error message if it doesn't exist
+func (h *Handler) findEntry(msg *discordgo.Message, id string) (entry models.RSSFeedEntry, ok bool) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	helpers.Relax(err)
+
+	err = helpers.MdbOne(
+		helpers.MdbCollection(models.RSSFeedsTable).Find(bson.M{"_id": helpers.HumanToMdbId(id), "guildid": channel.GuildID}),
+		&entry,
+	)
+	if helpers.IsMdbNotFound(err) || entry.ID == "" {
+		_, err = helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.rss.not-found"))
+		helpers.RelaxMessage(err, msg.ChannelID, msg.ID)
+		return entry, false
+	}
+	helpers.Relax(err)
+
+	return entry, true
+}
+
+// findMentionableRole returns the ID of the mentionable role matching the mention, ID, or name
+func (h *Handler) findMentionableRole(guildID, roleText string) (roleID string) {
+	guild, err := helpers.GetGuild(guildID)
+	if err != nil {
+		return ""
+	}
+
+	roleText = strings.TrimSuffix(strings.TrimPrefix(roleText, "<@&"), ">")
+	for _, role := range guild.Roles {
+		if role.Mentionable &&
+			(role.ID == roleText || strings.ToLower(role.Name) == strings.ToLower(roleText)) {
+			return role.ID
+		}
+	}
+	return ""
+}
+
+func logger() *logrus.Entry {
+	return cache.GetLogger().WithField("module", "rss")
+}
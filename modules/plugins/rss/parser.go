@@ -0,0 +1,420 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	errUnknownFormat = errors.New("unknown feed format")
+
+	dateLayouts = []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		time.RFC3339,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"2 Jan 2006 15:04:05 -0700",
+		"2006-01-02T15:04:05",
+	}
+)
+
+// feed is the parsed RSS, Atom, or JSON Feed
+type feed struct {
+	Title string
+	URL   string
+	Items []feedItem // newest first, as in the document
+}
+
+type feedItem struct {
+	ID          string // GUID, falls back to the link or the title
+	Title       string
+	URL         string
+	Author      string
+	Description string // plain text
+	Image       string
+	Published   time.Time
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Links []string  `xml:"link"` // also matches atom:link, which has no text
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"` // RSS 1.0 has the items next to the channel
+}
+
+type rssItem struct {
+	GUID        string   `xml:"guid"`
+	Title       string   `xml:"title"`
+	Links       []string `xml:"link"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Enclosure   struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	MediaContent struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+type atomDocument struct {
+	Title   string      `xml:"title"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   string     `xml:"summary"`
+	Content   string     `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Author    struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+	MediaThumbnail struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup struct {
+		Thumbnail struct {
+			URL string `xml:"url,attr"`
+		} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type jsonFeedDocument struct {
+	Version     string `json:"version"`
+	Title       string `json:"title"`
+	HomePageURL string `json:"home_page_url"`
+	Author      struct {
+		Name string `json:"name"`
+	} `json:"author"`
+	Items []struct {
+		ID            json.RawMessage `json:"id"` // string in 1.1, sometimes a number in the wild
+		URL           string          `json:"url"`
+		Title         string          `json:"title"`
+		ContentText   string          `json:"content_text"`
+		ContentHTML   string          `json:"content_html"`
+		Summary       string          `json:"summary"`
+		Image         string          `json:"image"`
+		BannerImage   string          `json:"banner_image"`
+		DatePublished string          `json:"date_published"`
+		Author        struct {
+			Name string `json:"name"`
+		} `json:"author"`
+		Authors []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+	} `json:"items"`
+}
+
+// parseFeed detects the format of the document and parses it
+func parseFeed(data []byte) (result *feed, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) <= 0 {
+		return nil, errUnknownFormat
+	}
+
+	if data[0] == '{' {
+		return parseJSONFeed(data)
+	}
+
+	root, err := getXMLRoot(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(root) {
+	case "rss", "rdf":
+		return parseRSS(data)
+	case "feed":
+		return parseAtom(data)
+	}
+	return nil, errUnknownFormat
+}
+
+func newXMLDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+// charsetReader converts Latin-1 feeds to UTF-8, other charsets are read as they are
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(label) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		data, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 0, len(data))
+		for _, b := range data {
+			buf = append(buf, string(rune(b))...)
+		}
+		return bytes.NewReader(buf), nil
+	}
+	return input, nil
+}
+
+func getXMLRoot(data []byte) (name string, err error) {
+	decoder := newXMLDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return "", errUnknownFormat
+			}
+			return "", err
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			return element.Name.Local, nil
+		}
+	}
+}
+
+func parseRSS(data []byte) (result *feed, err error) {
+	var document rssDocument
+	err = newXMLDecoder(data).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &feed{
+		Title: strings.TrimSpace(document.Channel.Title),
+		URL:   getFirst(document.Channel.Links),
+	}
+
+	for _, item := range append(document.Channel.Items, document.Items...) {
+		description, descriptionImage := parseHTML(item.Description)
+		if description == "" {
+			description, descriptionImage = parseHTML(item.Content)
+		}
+
+		newItem := feedItem{
+			ID:          strings.TrimSpace(item.GUID),
+			Title:       strings.TrimSpace(item.Title),
+			URL:         getFirst(item.Links),
+			Author:      strings.TrimSpace(item.Creator),
+			Description: description,
+			Published:   parseDate(item.PubDate, item.Date),
+		}
+		if newItem.Author == "" {
+			newItem.Author = strings.TrimSpace(item.Author)
+		}
+
+		switch {
+		case item.MediaThumbnail.URL != "":
+			newItem.Image = item.MediaThumbnail.URL
+		case item.MediaContent.URL != "":
+			newItem.Image = item.MediaContent.URL
+		case item.Enclosure.URL != "" && strings.HasPrefix(item.Enclosure.Type, "image/"):
+			newItem.Image = item.Enclosure.URL
+		default:
+			newItem.Image = descriptionImage
+		}
+
+		result.Items = append(result.Items, completeItem(newItem))
+	}
+
+	return result, nil
+}
+
+func parseAtom(data []byte) (result *feed, err error) {
+	var document atomDocument
+	err = newXMLDecoder(data).Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &feed{
+		Title: strings.TrimSpace(document.Title),
+		URL:   getAtomLink(document.Links),
+	}
+
+	for _, entry := range document.Entries {
+		description, descriptionImage := parseHTML(entry.Summary)
+		if description == "" {
+			description, descriptionImage = parseHTML(entry.Content)
+		}
+
+		newItem := feedItem{
+			ID:          strings.TrimSpace(entry.ID),
+			Title:       strings.TrimSpace(entry.Title),
+			URL:         getAtomLink(entry.Links),
+			Author:      strings.TrimSpace(entry.Author.Name),
+			Description: description,
+			Image:       entry.MediaThumbnail.URL,
+			Published:   parseDate(entry.Published, entry.Updated),
+		}
+		if newItem.Image == "" {
+			newItem.Image = entry.MediaGroup.Thumbnail.URL
+		}
+		if newItem.Image == "" {
+			newItem.Image = descriptionImage
+		}
+
+		result.Items = append(result.Items, completeItem(newItem))
+	}
+
+	return result, nil
+}
+
+// getFirst returns the first value which isn't empty
+func getFirst(values []string) string {
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// getAtomLink returns the alternate link, or the first link without a rel
+func getAtomLink(links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	for _, link := range links {
+		if link.Rel == "" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+func parseJSONFeed(data []byte) (result *feed, err error) {
+	var document jsonFeedDocument
+	err = json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(document.Version, "jsonfeed.org") {
+		return nil, errUnknownFormat
+	}
+
+	result = &feed{
+		Title: strings.TrimSpace(document.Title),
+		URL:   strings.TrimSpace(document.HomePageURL),
+	}
+
+	for _, item := range document.Items {
+		description := strings.TrimSpace(item.Summary)
+		descriptionImage := ""
+		if description == "" {
+			description = strings.TrimSpace(item.ContentText)
+		}
+		if description == "" {
+			description, descriptionImage = parseHTML(item.ContentHTML)
+		}
+
+		newItem := feedItem{
+			ID:          strings.Trim(string(item.ID), "\" "),
+			Title:       strings.TrimSpace(item.Title),
+			URL:         strings.TrimSpace(item.URL),
+			Author:      strings.TrimSpace(item.Author.Name),
+			Description: description,
+			Image:       item.Image,
+			Published:   parseDate(item.DatePublished),
+		}
+		if newItem.Author == "" && len(item.Authors) > 0 {
+			newItem.Author = strings.TrimSpace(item.Authors[0].Name)
+		}
+		if newItem.Author == "" {
+			newItem.Author = strings.TrimSpace(document.Author.Name)
+		}
+		if newItem.Image == "" {
+			newItem.Image = item.BannerImage
+		}
+		if newItem.Image == "" {
+			newItem.Image = descriptionImage
+		}
+
+		result.Items = append(result.Items, completeItem(newItem))
+	}
+
+	return result, nil
+}
+
+// completeItem sets the ID of items without GUID, and the title of items without title
+func completeItem(item feedItem) feedItem {
+	if item.ID == "" {
+		item.ID = item.URL
+	}
+	if item.ID == "" {
+		item.ID = item.Title
+	}
+	if item.Title == "" {
+		item.Title = item.URL
+	}
+	return item
+}
+
+// parseHTML returns the text and the first image of the HTML
+func parseHTML(text string) (plain, image string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ""
+	}
+	if !strings.Contains(text, "<") {
+		return text, ""
+	}
+
+	document, err := goquery.NewDocumentFromReader(strings.NewReader(text))
+	if err != nil {
+		return text, ""
+	}
+	image, _ = document.Find("img").First().Attr("src")
+	document.Find("br").ReplaceWithHtml("\n")
+	document.Find("p").Each(func(i int, selection *goquery.Selection) {
+		selection.AppendHtml("\n")
+	})
+
+	return strings.TrimSpace(document.Text()), image
+}
+
+// parseDate returns the first date which can be parsed
+func parseDate(dates ...string) time.Time {
+	for _, date := range dates {
+		date = strings.TrimSpace(date)
+		if date == "" {
+			continue
+		}
+		for _, layout := range dateLayouts {
+			parsed, err := time.Parse(layout, date)
+			if err == nil {
+				return parsed
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,167 @@
+package rss
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+)
+
+const (
+	testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel>
+	<title>Agency News</title>
+	<atom:link href="https://example.com/rss" rel="self" type="application/rss+xml" />
+	<link>https://example.com/</link>
+	<item>
+		<title>Second</title>
+		<link>https://example.com/2</link>
+		<guid isPermaLink="false">news-2</guid>
+		<dc:creator>Staff</dc:creator>
+		<description><![CDATA[<p>Comeback <b>soon</b></p><img src="https://example.com/2.jpg">]]></description>
+		<pubDate>Tue, 02 Jan 2018 15:04:05 +0000</pubDate>
+	</item>
+	<item>
+		<title>First</title>
+		<link>https://example.com/1</link>
+	</item>
+</channel>
+</rss>`
+	testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+	<title>Fan Blog</title>
+	<link href="https://blog.example.com/atom.xml" rel="self"/>
+	<link href="https://blog.example.com/" rel="alternate"/>
+	<entry>
+		<id>tag:blog.example.com,2018:1</id>
+		<title>Hello</title>
+		<link href="https://blog.example.com/hello"/>
+		<author><name>Writer</name></author>
+		<summary>Hello World</summary>
+		<updated>2018-01-02T15:04:05Z</updated>
+	</entry>
+</feed>`
+	testJSONFeed = `{
+	"version": "https://jsonfeed.org/version/1",
+	"title": "Charts",
+	"home_page_url": "https://charts.example.com/",
+	"items": [
+		{
+			"id": "1",
+			"url": "https://charts.example.com/1",
+			"title": "Weekly Chart",
+			"content_html": "<p>Number one</p>",
+			"image": "https://charts.example.com/1.png",
+			"authors": [{"name": "Charts Team"}]
+		}
+	]
+}`
+)
+
+func TestParseFeed(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		title    string
+		url      string
+		items    []feedItem
+	}{
+		{
+			name:     "rss",
+			document: testRSS,
+			title:    "Agency News",
+			url:      "https://example.com/",
+			items: []feedItem{
+				{ID: "news-2", Title: "Second", URL: "https://example.com/2", Author: "Staff",
+					Description: "Comeback soon", Image: "https://example.com/2.jpg"},
+				{ID: "https://example.com/1", Title: "First", URL: "https://example.com/1"},
+			},
+		},
+		{
+			name:     "atom",
+			document: testAtom,
+			title:    "Fan Blog",
+			url:      "https://blog.example.com/",
+			items: []feedItem{
+				{ID: "tag:blog.example.com,2018:1", Title: "Hello", URL: "https://blog.example.com/hello",
+					Author: "Writer", Description: "Hello World"},
+			},
+		},
+		{
+			name:     "json feed",
+			document: testJSONFeed,
+			title:    "Charts",
+			url:      "https://charts.example.com/",
+			items: []feedItem{
+				{ID: "1", Title: "Weekly Chart", URL: "https://charts.example.com/1", Author: "Charts Team",
+					Description: "Number one", Image: "https://charts.example.com/1.png"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := parseFeed([]byte(test.document))
+		if err != nil {
+			t.Fatalf("%s: parseFeed() returned error: %s", test.name, err.Error())
+		}
+		if result.Title != test.title || result.URL != test.url {
+			t.Errorf("%s: got feed %q (%s), expected %q (%s)", test.name, result.Title, result.URL, test.title, test.url)
+		}
+		if len(result.Items) != len(test.items) {
+			t.Fatalf("%s: got %d items, expected %d", test.name, len(result.Items), len(test.items))
+		}
+		for i, expected := range test.items {
+			got := result.Items[i]
+			got.Published = expected.Published
+			if got != expected {
+				t.Errorf("%s: got item %+v, expected %+v", test.name, got, expected)
+			}
+		}
+	}
+
+	result, err := parseFeed([]byte(testRSS))
+	if err == nil && result.Items[0].Published.IsZero() {
+		t.Errorf("rss: pubDate hasn't been parsed")
+	}
+
+	_, err = parseFeed([]byte("<html><body>not a feed</body></html>"))
+	if err != errUnknownFormat {
+		t.Errorf("html: got error %v, expected %v", err, errUnknownFormat)
+	}
+}
+
+func TestFetchFeedConditional(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Tue, 02 Jan 2018 15:04:05 GMT")
+		w.Write([]byte(testAtom))
+	}))
+	defer server.Close()
+
+	result, headers, err := fetchFeed(server.URL, conditionalHeaders{})
+	if err != nil {
+		t.Fatalf("fetchFeed() returned error: %s", err.Error())
+	}
+	if len(result.Items) != 1 {
+		t.Errorf("got %d items, expected 1", len(result.Items))
+	}
+	if headers.ETag != `"v1"` || headers.LastModified != "Tue, 02 Jan 2018 15:04:05 GMT" {
+		t.Errorf("got headers %+v", headers)
+	}
+
+	_, _, err = fetchFeed(server.URL, headers)
+	if err != helpers.ErrNotModified {
+		t.Errorf("got error %v, expected %v", err, helpers.ErrNotModified)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, expected 2", requests)
+	}
+}
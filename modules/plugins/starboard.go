@@ -36,6 +36,11 @@ func (s *Starboard) Commands() []string {
 	}
 }
 
+const (
+	starboardDefaultName = "starboard"
+	starboardMaxBoards   = 10
+)
+
 var (
 	// one lock for every guild ID
 	starboardStarLocks = make(map[string]*sync.Mutex, 0)
@@ -78,25 +83,39 @@ func (s *Starboard) actionStart(args []string, in *discordgo.Message, out **disc
 		return s.actionStarrers
 	case "top":
 		return s.actionTop
-	case "status":
+	case "status", "list":
 		return s.actionStatus
+	case "create", "add":
+		return s.actionCreate
+	case "delete", "remove":
+		return s.actionDelete
 	case "set":
 		return s.actionSet
 	case "minimum":
 		return s.actionMinimum
 	case "emoji", "emojis":
 		return s.actionEmoji
+	case "channel", "channels":
+		return s.actionChannel
+	case "nsfw":
+		return s.actionNSFW
 	}
 
 	*out = s.newMsg("bot.arguments.invalid")
 	return s.actionFinish
 }
 
+// [p]starboard top [<board name>]
 func (s *Starboard) actionTop(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	channel, err := helpers.GetChannel(in.ChannelID)
 	helpers.Relax(err)
 
-	topEntries, err := s.getTopStarboardEntries(channel.GuildID, 100)
+	var boardID string
+	if board, _, ok := s.resolveBoard(channel.GuildID, args[1:]); ok {
+		boardID = board.ID
+	}
+
+	topEntries, err := s.getTopStarboardEntries(channel.GuildID, boardID, 100)
 	if err != nil {
 		if strings.Contains(err.Error(), "no starboard entries") {
 			*out = s.newMsg(helpers.GetText("plugins.starboard.top-no-entries"))
@@ -122,6 +141,7 @@ func (s *Starboard) actionTop(args []string, in *discordgo.Message, out **discor
 	return nil
 }
 
+// [p]starboard starrers <message id> [<board name>]
 func (s *Starboard) actionStarrers(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	if len(args) < 2 {
 		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
@@ -131,51 +151,123 @@ func (s *Starboard) actionStarrers(args []string, in *discordgo.Message, out **d
 	channel, err := helpers.GetChannel(in.ChannelID)
 	helpers.Relax(err)
 
-	starboardEntry, err := s.getStarboardEntry(channel.GuildID, args[1])
-	if err != nil {
-		if strings.Contains(err.Error(), "no starboard entry") {
-			*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
-			return s.actionFinish
+	starboardEntries, err := s.getStarboardEntries(channel.GuildID, args[1])
+	helpers.Relax(err)
+
+	var boardName string
+	if len(args) >= 3 {
+		boardName = args[2]
+	}
+
+	for _, starboardEntry := range starboardEntries {
+		if boardName != "" {
+			board, ok := s.getBoardByID(channel.GuildID, starboardEntry.BoardID)
+			if !ok || board.Name != strings.ToLower(boardName) {
+				continue
+			}
 		}
-		helpers.Relax(err)
+
+		embed := s.getStarrersEmbed(starboardEntry)
+		*out = &discordgo.MessageSend{Embed: embed}
+		return s.actionFinish
 	}
 
-	embed := s.getStarrersEmbed(starboardEntry)
-	*out = &discordgo.MessageSend{Embed: embed}
+	*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
 	return s.actionFinish
 }
 
+// [p]starboard status
 func (s *Starboard) actionStatus(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	channel, err := helpers.GetChannel(in.ChannelID)
 	helpers.Relax(err)
 
-	guildSettings := helpers.GuildSettingsGetCached(channel.GuildID)
+	boards := s.getBoards(channel.GuildID)
+	if len(boards) <= 0 {
+		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+		return s.actionFinish
+	}
 
-	var emojiText string
-	for _, emoji := range s.getEmoji(channel.GuildID) {
-		discordEmoji, err := helpers.GetDiscordEmojiFromName(channel.GuildID, emoji)
-		if err == nil && discordEmoji != nil && discordEmoji.ID != "" {
-			emojiText += "<"
-			if discordEmoji.Animated {
-				emojiText += "a"
-			}
-			emojiText += ":" + discordEmoji.APIName() + ">"
-		} else {
-			emojiText += emoji
-		}
-		emojiText += ", "
+	var statusText string
+	for _, board := range boards {
+		statusText += helpers.GetTextF("plugins.starboard.status-board",
+			board.Name, board.ChannelID, s.getBoardMinimum(board), s.getEmojiText(channel.GuildID, board),
+			s.getChannelsText(board), s.getNSFWText(board)) + "\n"
 	}
-	emojiText = strings.TrimRight(emojiText, ", ")
 
-	if guildSettings.StarboardChannelID != "" {
-		*out = s.newMsg(helpers.GetTextF("plugins.starboard.status-set",
-			guildSettings.StarboardChannelID, s.getMinimum(channel.GuildID), emojiText))
-	} else {
-		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.status-set", statusText))
+	return s.actionFinish
+}
+
+// [p]starboard create <board name> <#channel>
+func (s *Starboard) actionCreate(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
+	if !helpers.IsMod(in) {
+		*out = s.newMsg(helpers.GetText("mod.no_permission"))
+		return s.actionFinish
+	}
+
+	if len(args) < 3 {
+		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
+		return s.actionFinish
+	}
+
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	name := strings.ToLower(args[1])
+	if strings.HasPrefix(name, "<") || len(name) > 32 {
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
+	}
+	if _, ok := s.getBoard(channel.GuildID, name); ok {
+		*out = s.newMsg(helpers.GetTextF("plugins.starboard.create-error-duplicate", name))
+		return s.actionFinish
+	}
+	if len(s.getBoards(channel.GuildID)) >= starboardMaxBoards {
+		*out = s.newMsg(helpers.GetTextF("plugins.starboard.create-error-too-many", starboardMaxBoards))
+		return s.actionFinish
+	}
+
+	targetChannel, err := helpers.GetChannelFromMention(in, args[2])
+	if err != nil || targetChannel.GuildID != channel.GuildID {
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
 	}
+
+	board := s.createBoard(channel.GuildID, name, targetChannel.ID, in.Author.ID)
+
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.create-success", board.Name, board.ChannelID))
 	return s.actionFinish
 }
 
+// [p]starboard delete <board name>
+func (s *Starboard) actionDelete(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
+	if !helpers.IsMod(in) {
+		*out = s.newMsg(helpers.GetText("mod.no_permission"))
+		return s.actionFinish
+	}
+
+	if len(args) < 2 {
+		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
+		return s.actionFinish
+	}
+
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	board, ok := s.getBoard(channel.GuildID, args[1])
+	if !ok {
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
+	}
+
+	s.deleteBoard(channel.GuildID, board, in.Author.ID)
+
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.delete-success", board.Name))
+	return s.actionFinish
+}
+
+// [p]starboard set [<board name>] [<#channel>]
+// without a channel the board will be deleted, if there is no board yet a new one will be created
 func (s *Starboard) actionSet(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	if !helpers.IsMod(in) {
 		*out = s.newMsg(helpers.GetText("mod.no_permission"))
@@ -185,33 +277,13 @@ func (s *Starboard) actionSet(args []string, in *discordgo.Message, out **discor
 	channel, err := helpers.GetChannel(in.ChannelID)
 	helpers.Relax(err)
 
-	guildSettings := helpers.GuildSettingsGetCached(channel.GuildID)
+	board, args, ok := s.resolveBoard(channel.GuildID, args[1:])
 
-	if len(args) < 2 {
-		if guildSettings.StarboardChannelID != "" {
-			beforeChannelID := guildSettings.StarboardChannelID
-			guildSettings.StarboardChannelID = ""
-			err = helpers.GuildSettingsSet(channel.GuildID, guildSettings)
-			helpers.Relax(err)
+	if len(args) < 1 {
+		if ok {
+			s.deleteBoard(channel.GuildID, board, in.Author.ID)
 
-			_, err = helpers.EventlogLog(time.Now(), channel.GuildID, beforeChannelID,
-				models.EventlogTargetTypeChannel, in.Author.ID,
-				models.EventlogTypeRobyulStarboardDelete, "",
-				nil,
-				[]models.ElasticEventlogOption{
-					{
-						Key:   "starboard_emoji",
-						Value: strings.Join(s.getEmoji(channel.GuildID), ";"),
-						Type:  models.EventlogTargetTypeEmoji,
-					},
-					{
-						Key:   "starboard_minimum",
-						Value: strconv.Itoa(s.getMinimum(channel.GuildID)),
-					},
-				}, false)
-			helpers.RelaxLog(err)
-
-			*out = s.newMsg(helpers.GetText("plugins.starboard.reset-success"))
+			*out = s.newMsg(helpers.GetTextF("plugins.starboard.delete-success", board.Name))
 			return s.actionFinish
 		} else {
 			*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
@@ -219,7 +291,7 @@ func (s *Starboard) actionSet(args []string, in *discordgo.Message, out **discor
 		return s.actionFinish
 	}
 
-	targetChannel, err := helpers.GetChannelFromMention(in, args[1])
+	targetChannel, err := helpers.GetChannelFromMention(in, args[0])
 	if err != nil {
 		if strings.Contains(err.Error(), "Channel not found") {
 			*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
@@ -227,59 +299,65 @@ func (s *Starboard) actionSet(args []string, in *discordgo.Message, out **discor
 		}
 		helpers.Relax(err)
 	}
-	previousChannelID := guildSettings.StarboardChannelID
-	guildSettings.StarboardChannelID = targetChannel.ID
-	err = helpers.GuildSettingsSet(channel.GuildID, guildSettings)
-	helpers.Relax(err)
 
-	changes := make([]models.ElasticEventlogChange, 0)
+	if !ok {
+		board = s.createBoard(channel.GuildID, starboardDefaultName, targetChannel.ID, in.Author.ID)
 
-	if previousChannelID != "" {
-		changes = []models.ElasticEventlogChange{
-			{
-				Key:      "starboard_channelid",
-				OldValue: previousChannelID,
-				NewValue: guildSettings.StarboardChannelID,
-				Type:     models.EventlogTargetTypeChannel,
-			},
-		}
+		*out = s.newMsg(helpers.GetTextF("plugins.starboard.set-success", board.Name, board.ChannelID))
+		return s.actionFinish
 	}
 
+	previousChannelID := board.ChannelID
+	board.ChannelID = targetChannel.ID
+	err = s.setBoard(channel.GuildID, board)
+	helpers.Relax(err)
+
 	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, targetChannel.ID,
 		models.EventlogTargetTypeChannel, in.Author.ID,
-		models.EventlogTypeRobyulStarboardCreate, "",
-		changes,
-		[]models.ElasticEventlogOption{
+		models.EventlogTypeRobyulStarboardUpdate, "",
+		[]models.ElasticEventlogChange{
 			{
-				Key:   "starboard_emoji",
-				Value: strings.Join(s.getEmoji(channel.GuildID), ";"),
-				Type:  models.EventlogTargetTypeEmoji,
+				Key:      "starboard_channelid",
+				OldValue: previousChannelID,
+				NewValue: board.ChannelID,
+				Type:     models.EventlogTargetTypeChannel,
 			},
+		},
+		[]models.ElasticEventlogOption{
 			{
-				Key:   "starboard_minimum",
-				Value: strconv.Itoa(s.getMinimum(channel.GuildID)),
+				Key:   "starboard_name",
+				Value: board.Name,
 			},
 		}, false)
 	helpers.RelaxLog(err)
 
-	*out = s.newMsg(helpers.GetTextF("plugins.starboard.set-success", guildSettings.StarboardChannelID))
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.set-success", board.Name, board.ChannelID))
 	return s.actionFinish
 }
 
+// [p]starboard minimum [<board name>] <minimum>
 func (s *Starboard) actionMinimum(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	if !helpers.IsMod(in) {
 		*out = s.newMsg(helpers.GetText("mod.no_permission"))
 		return s.actionFinish
 	}
 
-	if len(args) < 2 {
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	board, args, ok := s.resolveBoard(channel.GuildID, args[1:])
+	if !ok {
+		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+		return s.actionFinish
+	}
+
+	if len(args) < 1 {
 		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
 		return s.actionFinish
 	}
 
-	var err error
 	var newMinimum int
-	if newMinimum, err = strconv.Atoi(args[1]); err != nil {
+	if newMinimum, err = strconv.Atoi(args[0]); err != nil {
 		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
 		return s.actionFinish
 	}
@@ -289,53 +367,61 @@ func (s *Starboard) actionMinimum(args []string, in *discordgo.Message, out **di
 		return s.actionFinish
 	}
 
-	channel, err := helpers.GetChannel(in.ChannelID)
-	helpers.Relax(err)
-
-	guildSettings := helpers.GuildSettingsGetCached(channel.GuildID)
-	oldMinimum := guildSettings.StarboardMinimum
-	guildSettings.StarboardMinimum = newMinimum
-	err = helpers.GuildSettingsSet(channel.GuildID, guildSettings)
+	oldMinimum := s.getBoardMinimum(board)
+	board.Minimum = newMinimum
+	err = s.setBoard(channel.GuildID, board)
 	helpers.Relax(err)
 
-	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, guildSettings.StarboardChannelID,
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, board.ChannelID,
 		models.EventlogTargetTypeChannel, in.Author.ID,
 		models.EventlogTypeRobyulStarboardUpdate, "",
 		[]models.ElasticEventlogChange{
 			{
 				Key:      "starboard_minimum",
 				OldValue: strconv.Itoa(oldMinimum),
-				NewValue: strconv.Itoa(guildSettings.StarboardMinimum),
+				NewValue: strconv.Itoa(board.Minimum),
 			},
 		},
-		nil, false)
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "starboard_name",
+				Value: board.Name,
+			},
+		}, false)
 	helpers.RelaxLog(err)
 
-	*out = s.newMsg(helpers.GetTextF("plugins.starboard.minimum-success", guildSettings.StarboardMinimum))
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.minimum-success", board.Minimum, board.Name))
 	return s.actionFinish
 }
 
+// [p]starboard emoji [<board name>] <emoji>
 func (s *Starboard) actionEmoji(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	if !helpers.IsMod(in) {
 		*out = s.newMsg(helpers.GetText("mod.no_permission"))
 		return s.actionFinish
 	}
 
-	if len(args) < 2 {
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	board, args, ok := s.resolveBoard(channel.GuildID, args[1:])
+	if !ok {
+		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+		return s.actionFinish
+	}
+
+	if len(args) < 1 {
 		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
 		return s.actionFinish
 	}
 
-	newEmoji := args[1]
+	newEmoji := args[0]
 
 	if !helpers.IsEmoji(newEmoji) {
 		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
 		return s.actionFinish
 	}
 
-	channel, err := helpers.GetChannel(in.ChannelID)
-	helpers.Relax(err)
-
 	if helpers.IsDiscordEmoji(newEmoji) {
 		discordEmoji, err := helpers.GetDiscordEmojiFromText(channel.GuildID, newEmoji)
 		if err != nil || discordEmoji == nil || discordEmoji.Name == "" {
@@ -345,12 +431,10 @@ func (s *Starboard) actionEmoji(args []string, in *discordgo.Message, out **disc
 		newEmoji = discordEmoji.Name
 	}
 
-	guildSettings := helpers.GuildSettingsGetCached(channel.GuildID)
-
 	options := make([]models.ElasticEventlogOption, 0)
 	removed := false
 	newEmojiList := make([]string, 0)
-	for _, emoji := range guildSettings.StarboardEmoji {
+	for _, emoji := range board.Emoji {
 		if emoji == newEmoji {
 			removed = true
 		} else {
@@ -376,35 +460,172 @@ func (s *Starboard) actionEmoji(args []string, in *discordgo.Message, out **disc
 			},
 		}
 	}
+	options = append(options, models.ElasticEventlogOption{
+		Key:   "starboard_name",
+		Value: board.Name,
+	})
 
-	emojiBefore := s.getEmoji(channel.GuildID)
+	emojiBefore := s.getBoardEmoji(board)
 
-	guildSettings.StarboardEmoji = newEmojiList
+	board.Emoji = newEmojiList
 
-	err = helpers.GuildSettingsSet(channel.GuildID, guildSettings)
+	err = s.setBoard(channel.GuildID, board)
 	helpers.Relax(err)
 
-	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, guildSettings.StarboardChannelID,
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, board.ChannelID,
 		models.EventlogTargetTypeChannel, in.Author.ID,
 		models.EventlogTypeRobyulStarboardUpdate, "",
 		[]models.ElasticEventlogChange{
 			{
 				Key:      "starboard_emoji",
 				OldValue: strings.Join(emojiBefore, ";"),
-				NewValue: strings.Join(s.getEmoji(channel.GuildID), ";"),
+				NewValue: strings.Join(s.getBoardEmoji(board), ";"),
 			},
 		},
 		options, false)
 	helpers.RelaxLog(err)
 
 	if !removed {
-		*out = s.newMsg(helpers.GetTextF("plugins.starboard.emoji-add-success", newEmoji))
+		*out = s.newMsg(helpers.GetTextF("plugins.starboard.emoji-add-success", newEmoji, board.Name))
 	} else {
-		*out = s.newMsg(helpers.GetTextF("plugins.starboard.emoji-remove-success", newEmoji))
+		*out = s.newMsg(helpers.GetTextF("plugins.starboard.emoji-remove-success", newEmoji, board.Name))
 	}
 	return s.actionFinish
 }
 
+// [p]starboard channel [<board name>] <allow|deny> <#channel or category>
+func (s *Starboard) actionChannel(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
+	if !helpers.IsMod(in) {
+		*out = s.newMsg(helpers.GetText("mod.no_permission"))
+		return s.actionFinish
+	}
+
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	board, args, ok := s.resolveBoard(channel.GuildID, args[1:])
+	if !ok {
+		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+		return s.actionFinish
+	}
+
+	if len(args) < 2 {
+		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
+		return s.actionFinish
+	}
+
+	targetChannel, err := helpers.GetChannelFromMention(in, args[1])
+	if err != nil || targetChannel.GuildID != channel.GuildID {
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
+	}
+
+	var list, oppositeList *[]string
+	var key string
+	switch args[0] {
+	case "allow":
+		list, oppositeList = &board.AllowedChannelIDs, &board.DeniedChannelIDs
+		key = "starboard_allowedchannelids"
+	case "deny":
+		list, oppositeList = &board.DeniedChannelIDs, &board.AllowedChannelIDs
+		key = "starboard_deniedchannelids"
+	default:
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
+	}
+
+	change := models.ElasticEventlogChange{
+		Key:      key,
+		OldValue: strings.Join(*list, ";"),
+		Type:     models.EventlogTargetTypeChannel,
+	}
+
+	if sliceContains(*list, targetChannel.ID) {
+		*list = sliceWithout(*list, targetChannel.ID)
+	} else {
+		*list = append(*list, targetChannel.ID)
+		*oppositeList = sliceWithout(*oppositeList, targetChannel.ID)
+	}
+	change.NewValue = strings.Join(*list, ";")
+
+	err = s.setBoard(channel.GuildID, board)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, board.ChannelID,
+		models.EventlogTargetTypeChannel, in.Author.ID,
+		models.EventlogTypeRobyulStarboardUpdate, "",
+		[]models.ElasticEventlogChange{change},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "starboard_name",
+				Value: board.Name,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.channels-success", board.Name, s.getChannelsText(board)))
+	return s.actionFinish
+}
+
+// [p]starboard nsfw [<board name>] <auto|allow|deny>
+func (s *Starboard) actionNSFW(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
+	if !helpers.IsMod(in) {
+		*out = s.newMsg(helpers.GetText("mod.no_permission"))
+		return s.actionFinish
+	}
+
+	channel, err := helpers.GetChannel(in.ChannelID)
+	helpers.Relax(err)
+
+	board, args, ok := s.resolveBoard(channel.GuildID, args[1:])
+	if !ok {
+		*out = s.newMsg(helpers.GetText("plugins.starboard.status-none"))
+		return s.actionFinish
+	}
+
+	if len(args) < 1 {
+		*out = s.newMsg(helpers.GetText("bot.arguments.too-few"))
+		return s.actionFinish
+	}
+
+	oldMode := board.NSFW
+	switch args[0] {
+	case "auto":
+		board.NSFW = models.StarboardNSFWModeAuto
+	case "allow":
+		board.NSFW = models.StarboardNSFWModeAllow
+	case "deny":
+		board.NSFW = models.StarboardNSFWModeDeny
+	default:
+		*out = s.newMsg(helpers.GetText("bot.arguments.invalid"))
+		return s.actionFinish
+	}
+
+	err = s.setBoard(channel.GuildID, board)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, board.ChannelID,
+		models.EventlogTargetTypeChannel, in.Author.ID,
+		models.EventlogTypeRobyulStarboardUpdate, "",
+		[]models.ElasticEventlogChange{
+			{
+				Key:      "starboard_nsfw",
+				OldValue: string(oldMode),
+				NewValue: string(board.NSFW),
+			},
+		},
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "starboard_name",
+				Value: board.Name,
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	*out = s.newMsg(helpers.GetTextF("plugins.starboard.nsfw-success", board.Name, s.getNSFWText(board)))
+	return s.actionFinish
+}
+
 func (s *Starboard) actionFinish(args []string, in *discordgo.Message, out **discordgo.MessageSend) starboardAction {
 	_, err := helpers.SendComplex(in.ChannelID, *out)
 	helpers.RelaxMessage(err, in.ChannelID, in.ID)
@@ -438,21 +659,25 @@ func (s *Starboard) OnMessageDelete(msg *discordgo.MessageDelete, session *disco
 		channel, err := helpers.GetChannel(msg.ChannelID)
 		helpers.Relax(err)
 
-		starboardEntry, err := s.getStarboardEntry(channel.GuildID, msg.ID)
-		if err != nil {
-			return
-		}
+		starboardEntries, err := s.getStarboardEntries(channel.GuildID, msg.ID)
+		helpers.Relax(err)
 
-		s.deleteStarboardEntry(starboardEntry)
+		for _, starboardEntry := range starboardEntries {
+			s.deleteStarboardEntry(starboardEntry)
 
-		err = cache.GetSession().SessionForGuildS(msg.GuildID).ChannelMessageDelete(
-			starboardEntry.StarboardMessageChannelID, starboardEntry.StarboardMessageID)
-		if errD, ok := err.(*discordgo.RESTError); ok {
-			if errD.Message.Message == "404: Not Found" || errD.Message.Code == discordgo.ErrCodeUnknownMessage {
-				return
+			if starboardEntry.StarboardMessageID == "" {
+				continue
 			}
+
+			err = cache.GetSession().SessionForGuildS(msg.GuildID).ChannelMessageDelete(
+				starboardEntry.StarboardMessageChannelID, starboardEntry.StarboardMessageID)
+			if errD, ok := err.(*discordgo.RESTError); ok {
+				if errD.Message.Message == "404: Not Found" || errD.Message.Code == discordgo.ErrCodeUnknownMessage {
+					continue
+				}
+			}
+			helpers.Relax(err)
 		}
-		helpers.Relax(err)
 	}()
 }
 
@@ -471,15 +696,9 @@ func (s *Starboard) OnReactionAdd(reaction *discordgo.MessageReactionAdd, sessio
 		channel, err := helpers.GetChannel(reaction.ChannelID)
 		helpers.Relax(err)
 
-		isStarboardEmoji := false
-		for _, starboardEmoji := range s.getEmoji(channel.GuildID) {
-			if reaction.MessageReaction.Emoji.Name == starboardEmoji {
-				isStarboardEmoji = true
-			}
-		}
-
-		// stop if no starboard emoji
-		if isStarboardEmoji == false {
+		// stop if no starboard accepts the emoji in this channel
+		boards := s.getBoardsForReaction(channel, reaction.MessageReaction.Emoji.Name)
+		if len(boards) <= 0 {
 			return
 		}
 
@@ -490,12 +709,6 @@ func (s *Starboard) OnReactionAdd(reaction *discordgo.MessageReactionAdd, sessio
 		if user.Bot {
 			return
 		}
-		settings := helpers.GuildSettingsGetCached(channel.GuildID)
-
-		// stop if no starboard channel set
-		if settings.StarboardChannelID == "" {
-			return
-		}
 
 		message, err := cache.GetSession().SessionForGuildS(reaction.GuildID).State.Message(reaction.ChannelID, reaction.MessageID)
 		if err != nil {
@@ -518,17 +731,19 @@ func (s *Starboard) OnReactionAdd(reaction *discordgo.MessageReactionAdd, sessio
 			return
 		}
 
-		err = s.AddStar(channel.GuildID, message, reaction.UserID)
-		if err != nil {
-			if errD, ok := err.(*discordgo.RESTError); ok {
-				if errD.Message.Code == discordgo.ErrCodeUnknownMessage ||
-					errD.Message.Code == discordgo.ErrCodeMissingPermissions ||
-					errD.Message.Code == discordgo.ErrCodeMissingAccess {
-					return
+		for _, board := range boards {
+			err = s.AddStar(channel.GuildID, board, message, reaction.UserID)
+			if err != nil {
+				if errD, ok := err.(*discordgo.RESTError); ok {
+					if errD.Message.Code == discordgo.ErrCodeUnknownMessage ||
+						errD.Message.Code == discordgo.ErrCodeMissingPermissions ||
+						errD.Message.Code == discordgo.ErrCodeMissingAccess {
+						continue
+					}
 				}
 			}
+			helpers.Relax(err)
 		}
-		helpers.Relax(err)
 	}()
 }
 
@@ -539,15 +754,9 @@ func (s *Starboard) OnReactionRemove(reaction *discordgo.MessageReactionRemove,
 		channel, err := helpers.GetChannel(reaction.ChannelID)
 		helpers.Relax(err)
 
-		isStarboardEmoji := false
-		for _, starboardEmoji := range s.getEmoji(channel.GuildID) {
-			if reaction.MessageReaction.Emoji.Name == starboardEmoji {
-				isStarboardEmoji = true
-			}
-		}
-
-		// stop if no starboard emoji
-		if isStarboardEmoji == false {
+		// stop if no starboard accepts the emoji in this channel
+		boards := s.getBoardsForReaction(channel, reaction.MessageReaction.Emoji.Name)
+		if len(boards) <= 0 {
 			return
 		}
 
@@ -559,13 +768,6 @@ func (s *Starboard) OnReactionRemove(reaction *discordgo.MessageReactionRemove,
 			return
 		}
 
-		settings := helpers.GuildSettingsGetCached(channel.GuildID)
-
-		// stop if no starboard channel set
-		if settings.StarboardChannelID == "" {
-			return
-		}
-
 		message, err := cache.GetSession().SessionForGuildS(reaction.GuildID).State.Message(reaction.ChannelID, reaction.MessageID)
 		if err != nil {
 			message, err = cache.GetSession().SessionForGuildS(reaction.GuildID).ChannelMessage(reaction.ChannelID, reaction.MessageID)
@@ -577,22 +779,24 @@ func (s *Starboard) OnReactionRemove(reaction *discordgo.MessageReactionRemove,
 			return
 		}
 
-		err = s.RemoveStar(channel.GuildID, message, reaction.UserID)
-		if err != nil {
-			if errD, ok := err.(*discordgo.RESTError); ok {
-				if errD.Message.Code == discordgo.ErrCodeUnknownMessage {
-					return
+		for _, board := range boards {
+			err = s.RemoveStar(channel.GuildID, board, message, reaction.UserID)
+			if err != nil {
+				if errD, ok := err.(*discordgo.RESTError); ok {
+					if errD.Message.Code == discordgo.ErrCodeUnknownMessage {
+						continue
+					}
 				}
 			}
+			helpers.Relax(err)
 		}
-		helpers.Relax(err)
 	}()
 }
 
-func (s *Starboard) AddStar(guildID string, msg *discordgo.Message, starUserID string) error {
+func (s *Starboard) AddStar(guildID string, board models.StarboardBoard, msg *discordgo.Message, starUserID string) error {
 	s.lockGuild(guildID)
 	defer s.unlockGuild(guildID)
-	starboardEntry, err := s.getStarboardEntry(guildID, msg.ID)
+	starboardEntry, err := s.getStarboardEntry(guildID, board.ID, msg.ID)
 	if err != nil {
 		urls := make([]string, 0)
 		for _, attachment := range msg.Attachments {
@@ -616,6 +820,7 @@ func (s *Starboard) AddStar(guildID string, msg *discordgo.Message, starUserID s
 		if strings.Contains(err.Error(), "no starboard entry") {
 			starboardEntry, err = s.createStarboardEntry(
 				guildID,
+				board.ID,
 				msg.ID,
 				msg.ChannelID,
 				msg.Author.ID,
@@ -634,16 +839,16 @@ func (s *Starboard) AddStar(guildID string, msg *discordgo.Message, starUserID s
 		return err
 	}
 
-	if starboardEntry.Stars >= s.getMinimum(guildID) {
-		return s.PostOrUpdateDiscordMessage(starboardEntry)
+	if starboardEntry.Stars >= s.getBoardMinimum(board) {
+		return s.PostOrUpdateDiscordMessage(board, starboardEntry)
 	}
 	return nil
 }
 
-func (s *Starboard) RemoveStar(guildID string, msg *discordgo.Message, starUserID string) error {
+func (s *Starboard) RemoveStar(guildID string, board models.StarboardBoard, msg *discordgo.Message, starUserID string) error {
 	s.lockGuild(guildID)
 	defer s.unlockGuild(guildID)
-	starboardEntry, err := s.getStarboardEntry(guildID, msg.ID)
+	starboardEntry, err := s.getStarboardEntry(guildID, board.ID, msg.ID)
 	if err != nil {
 		if strings.Contains(err.Error(), "no starboard entry") {
 			return nil
@@ -661,8 +866,8 @@ func (s *Starboard) RemoveStar(guildID string, msg *discordgo.Message, starUserI
 				starboardEntry.StarboardMessageChannelID, starboardEntry.StarboardMessageID)
 			return err
 		} else {
-			if starboardEntry.Stars >= s.getMinimum(guildID) {
-				return s.PostOrUpdateDiscordMessage(starboardEntry)
+			if starboardEntry.Stars >= s.getBoardMinimum(board) {
+				return s.PostOrUpdateDiscordMessage(board, starboardEntry)
 			} else {
 				err = cache.GetSession().SessionForGuildS(guildID).ChannelMessageDelete(
 					starboardEntry.StarboardMessageChannelID, starboardEntry.StarboardMessageID)
@@ -676,9 +881,10 @@ func (s *Starboard) RemoveStar(guildID string, msg *discordgo.Message, starUserI
 	return nil
 }
 
-func (s *Starboard) PostOrUpdateDiscordMessage(starEntry models.StarboardEntry) error {
-	settings := helpers.GuildSettingsGetCached(starEntry.GuildID)
-	if settings.StarboardChannelID == "" {
+// PostOrUpdateDiscordMessage posts the entry on the board, or updates the existing post
+// if the channel of the board changed, a new post will be created in the new channel
+func (s *Starboard) PostOrUpdateDiscordMessage(board models.StarboardBoard, starEntry models.StarboardEntry) error {
+	if board.ChannelID == "" {
 		return nil
 	}
 
@@ -699,7 +905,7 @@ func (s *Starboard) PostOrUpdateDiscordMessage(starEntry models.StarboardEntry)
 		channelName = channel.Name
 	}
 
-	emoji := s.getEmoji(channel.GuildID)
+	emoji := s.getBoardEmoji(board)
 
 	content := starEntry.MessageContent
 	for _, url := range starEntry.MessageAttachmentURLs {
@@ -707,7 +913,7 @@ func (s *Starboard) PostOrUpdateDiscordMessage(starEntry models.StarboardEntry)
 	}
 
 	firstEmoji := emoji[0]
-	firstDiscordEmoji, err := helpers.GetDiscordEmojiFromName(channel.GuildID, firstEmoji)
+	firstDiscordEmoji, err := helpers.GetDiscordEmojiFromName(starEntry.GuildID, firstEmoji)
 	if err == nil && firstDiscordEmoji != nil && firstDiscordEmoji.ID != "" {
 		//firstEmoji = "<:" + firstDiscordEmoji.APIName() + ">"
 		firstEmoji = "⭐" // no custom emoji in embed footer?
@@ -752,13 +958,13 @@ func (s *Starboard) PostOrUpdateDiscordMessage(starEntry models.StarboardEntry)
 	}
 	if starEntry.StarboardMessageChannelID != "" &&
 		starEntry.StarboardMessageID != "" &&
-		starEntry.StarboardMessageChannelID == settings.StarboardChannelID {
+		starEntry.StarboardMessageChannelID == board.ChannelID {
 		_, err := helpers.EditEmbed(
-			settings.StarboardChannelID, starEntry.StarboardMessageID, starboardPostEmbed)
+			board.ChannelID, starEntry.StarboardMessageID, starboardPostEmbed)
 		return err
 	} else {
 		starboardPostMessages, err := helpers.SendEmbed(
-			settings.StarboardChannelID, starboardPostEmbed)
+			board.ChannelID, starboardPostEmbed)
 		if err != nil {
 			return err
 		}
@@ -781,7 +987,7 @@ func (s *Starboard) getStarrersEmbed(starEntry models.StarboardEntry) *discordgo
 		}
 	}
 
-	emoji := s.getEmoji(starEntry.GuildID)
+	emoji := s.getEmojiForEntry(starEntry)
 
 	var starrersText string
 	var userName string
@@ -852,8 +1058,6 @@ func (s *Starboard) getTopMessagesEmbeds(starEntries []models.StarboardEntry, pe
 		return pages, err
 	}
 
-	emoji := s.getEmoji(guild.ID)
-
 	pages = make([]*discordgo.MessageEmbed, 0)
 
 	var content string
@@ -891,7 +1095,7 @@ func (s *Starboard) getTopMessagesEmbeds(starEntries []models.StarboardEntry, pe
 			}
 		}
 
-		firstEmoji := emoji[0]
+		firstEmoji := s.getEmojiForEntry(starMessage)[0]
 		firstDiscordEmoji, err := helpers.GetDiscordEmojiFromName(starMessage.GuildID, firstEmoji)
 		if err == nil && firstDiscordEmoji != nil && firstDiscordEmoji.ID != "" {
 			firstEmoji = "<"
@@ -934,9 +1138,9 @@ func (s *Starboard) getTopMessagesEmbeds(starEntries []models.StarboardEntry, pe
 	return pages, nil
 }
 
-func (s *Starboard) getStarboardEntry(guildID string, messageID string) (entryBucket models.StarboardEntry, err error) {
+func (s *Starboard) getStarboardEntry(guildID string, boardID string, messageID string) (entryBucket models.StarboardEntry, err error) {
 	err = helpers.MdbOneWithoutLogging(
-		helpers.MdbCollection(models.StarboardEntriesTable).Find(bson.M{"messageid": messageID, "guildid": guildID}),
+		helpers.MdbCollection(models.StarboardEntriesTable).Find(bson.M{"messageid": messageID, "guildid": guildID, "boardid": boardID}),
 		&entryBucket,
 	)
 	if helpers.IsMdbNotFound(err) {
@@ -946,9 +1150,22 @@ func (s *Starboard) getStarboardEntry(guildID string, messageID string) (entryBu
 	return entryBucket, err
 }
 
-func (s *Starboard) getTopStarboardEntries(guildID string, limit int) (entryBucket []models.StarboardEntry, err error) {
+// getStarboardEntries returns the entries for the message on all boards, sorted by stars
+func (s *Starboard) getStarboardEntries(guildID string, messageID string) (entryBucket []models.StarboardEntry, err error) {
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.StarboardEntriesTable).Find(
+		bson.M{"messageid": messageID, "guildid": guildID}).Sort("-stars"),
+	).All(&entryBucket)
+	return entryBucket, err
+}
+
+// getTopStarboardEntries returns the top entries on the board, or on all boards if $boardID is empty
+func (s *Starboard) getTopStarboardEntries(guildID string, boardID string, limit int) (entryBucket []models.StarboardEntry, err error) {
+	query := bson.M{"guildid": guildID}
+	if boardID != "" {
+		query["boardid"] = boardID
+	}
 	err = helpers.MDbIter(helpers.MdbCollection(models.StarboardEntriesTable).Find(
-		bson.M{"guildid": guildID}).Sort("-stars").Limit(limit),
+		query).Sort("-stars").Limit(limit),
 	).All(&entryBucket)
 
 	if err != nil {
@@ -993,6 +1210,7 @@ func (s *Starboard) decrementStarboardEntry(starEntry *models.StarboardEntry, us
 
 func (s *Starboard) createStarboardEntry(
 	guildID string,
+	boardID string,
 	messageID string,
 	channelID string,
 	authorID string,
@@ -1002,6 +1220,7 @@ func (s *Starboard) createStarboardEntry(
 ) (models.StarboardEntry, error) {
 	_, err := helpers.MDbInsert(models.StarboardEntriesTable, models.StarboardEntry{
 		GuildID:               guildID,
+		BoardID:               boardID,
 		MessageID:             messageID,
 		ChannelID:             channelID,
 		AuthorID:              authorID,
@@ -1015,7 +1234,7 @@ func (s *Starboard) createStarboardEntry(
 	if err != nil {
 		return models.StarboardEntry{}, err
 	} else {
-		return s.getStarboardEntry(guildID, messageID)
+		return s.getStarboardEntry(guildID, boardID, messageID)
 	}
 }
 
@@ -1035,23 +1254,234 @@ func (s *Starboard) deleteStarboardEntry(starEntry models.StarboardEntry) error
 	return errors.New("empty starEntry submitted")
 }
 
-func (s *Starboard) getMinimum(guildID string) int {
+func (s *Starboard) getBoards(guildID string) []models.StarboardBoard {
+	return helpers.GuildSettingsGetCached(guildID).StarboardBoards
+}
+
+func (s *Starboard) getBoard(guildID string, name string) (board models.StarboardBoard, ok bool) {
+	for _, board := range s.getBoards(guildID) {
+		if board.Name == strings.ToLower(name) {
+			return board, true
+		}
+	}
+	return board, false
+}
+
+func (s *Starboard) getBoardByID(guildID string, boardID string) (board models.StarboardBoard, ok bool) {
+	for _, board := range s.getBoards(guildID) {
+		if board.ID == boardID {
+			return board, true
+		}
+	}
+	return board, false
+}
+
+// resolveBoard returns the board named $args[0] and the remaining args,
+// or the first board of the guild and all args if $args[0] is not a board name
+func (s *Starboard) resolveBoard(guildID string, args []string) (board models.StarboardBoard, remainingArgs []string, ok bool) {
+	if len(args) > 0 {
+		if board, ok = s.getBoard(guildID, args[0]); ok {
+			return board, args[1:], true
+		}
+	}
+	boards := s.getBoards(guildID)
+	if len(boards) <= 0 {
+		return board, args, false
+	}
+	return boards[0], args, true
+}
+
+// setBoard replaces the board with the same ID in the guild settings
+func (s *Starboard) setBoard(guildID string, board models.StarboardBoard) error {
 	guildSettings := helpers.GuildSettingsGetCached(guildID)
-	if guildSettings.StarboardMinimum > 0 {
-		return guildSettings.StarboardMinimum
+	boards := make([]models.StarboardBoard, 0, len(guildSettings.StarboardBoards))
+	for _, guildBoard := range guildSettings.StarboardBoards {
+		if guildBoard.ID == board.ID {
+			guildBoard = board
+		}
+		boards = append(boards, guildBoard)
 	}
-	return 1
+	guildSettings.StarboardBoards = boards
+	return helpers.GuildSettingsSet(guildID, guildSettings)
 }
 
-func (s *Starboard) getEmoji(guildID string) (emojis []string) {
+func (s *Starboard) createBoard(guildID string, name string, channelID string, userID string) (board models.StarboardBoard) {
+	board = models.StarboardBoard{
+		ID:        bson.NewObjectId().Hex(),
+		Name:      name,
+		ChannelID: channelID,
+	}
+
 	guildSettings := helpers.GuildSettingsGetCached(guildID)
-	if len(guildSettings.StarboardEmoji) > 0 {
-		return guildSettings.StarboardEmoji
+	guildSettings.StarboardBoards = append(guildSettings.StarboardBoards, board)
+	err := helpers.GuildSettingsSet(guildID, guildSettings)
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, board.ChannelID,
+		models.EventlogTargetTypeChannel, userID,
+		models.EventlogTypeRobyulStarboardCreate, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "starboard_name",
+				Value: board.Name,
+			},
+			{
+				Key:   "starboard_emoji",
+				Value: strings.Join(s.getBoardEmoji(board), ";"),
+				Type:  models.EventlogTargetTypeEmoji,
+			},
+			{
+				Key:   "starboard_minimum",
+				Value: strconv.Itoa(s.getBoardMinimum(board)),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+
+	return board
+}
+
+// deleteBoard removes the board from the guild settings, and deletes all entries of the board
+func (s *Starboard) deleteBoard(guildID string, board models.StarboardBoard, userID string) {
+	guildSettings := helpers.GuildSettingsGetCached(guildID)
+	boards := make([]models.StarboardBoard, 0)
+	for _, guildBoard := range guildSettings.StarboardBoards {
+		if guildBoard.ID != board.ID {
+			boards = append(boards, guildBoard)
+		}
+	}
+	guildSettings.StarboardBoards = boards
+	err := helpers.GuildSettingsSet(guildID, guildSettings)
+	helpers.Relax(err)
+
+	_, err = helpers.MdbCollection(models.StarboardEntriesTable).RemoveAll(bson.M{"guildid": guildID, "boardid": board.ID})
+	helpers.Relax(err)
+
+	_, err = helpers.EventlogLog(time.Now(), guildID, board.ChannelID,
+		models.EventlogTargetTypeChannel, userID,
+		models.EventlogTypeRobyulStarboardDelete, "",
+		nil,
+		[]models.ElasticEventlogOption{
+			{
+				Key:   "starboard_name",
+				Value: board.Name,
+			},
+			{
+				Key:   "starboard_emoji",
+				Value: strings.Join(s.getBoardEmoji(board), ";"),
+				Type:  models.EventlogTargetTypeEmoji,
+			},
+			{
+				Key:   "starboard_minimum",
+				Value: strconv.Itoa(s.getBoardMinimum(board)),
+			},
+		}, false)
+	helpers.RelaxLog(err)
+}
+
+// getBoardsForReaction returns all boards which accept the emoji on messages in the channel
+func (s *Starboard) getBoardsForReaction(channel *discordgo.Channel, emojiName string) (boards []models.StarboardBoard) {
+	for _, board := range s.getBoards(channel.GuildID) {
+		if board.ChannelID == "" || !sliceContains(s.getBoardEmoji(board), emojiName) || !s.boardAcceptsChannel(board, channel) {
+			continue
+		}
+		boards = append(boards, board)
+	}
+	return boards
+}
+
+// boardAcceptsChannel checks the allow and deny lists, and the NSFW mode of the board
+// messages on the board itself are never accepted
+func (s *Starboard) boardAcceptsChannel(board models.StarboardBoard, channel *discordgo.Channel) bool {
+	if channel.ID == board.ChannelID {
+		return false
+	}
+	if len(board.AllowedChannelIDs) > 0 &&
+		!sliceContains(board.AllowedChannelIDs, channel.ID) && !sliceContains(board.AllowedChannelIDs, channel.ParentID) {
+		return false
+	}
+	if sliceContains(board.DeniedChannelIDs, channel.ID) || sliceContains(board.DeniedChannelIDs, channel.ParentID) {
+		return false
+	}
+	if !channel.NSFW {
+		return true
+	}
+	switch board.NSFW {
+	case models.StarboardNSFWModeAllow:
+		return true
+	case models.StarboardNSFWModeDeny:
+		return false
+	}
+	boardChannel, err := helpers.GetChannel(board.ChannelID)
+	return err == nil && boardChannel.NSFW
+}
+
+func (s *Starboard) getBoardMinimum(board models.StarboardBoard) int {
+	if board.Minimum > 0 {
+		return board.Minimum
+	}
+	return 1
+}
+
+func (s *Starboard) getBoardEmoji(board models.StarboardBoard) (emojis []string) {
+	if len(board.Emoji) > 0 {
+		return board.Emoji
 	} else {
 		return []string{"⭐", "🌟"} // :star:, :star2:
 	}
 }
 
+// getEmojiForEntry returns the emoji of the board the entry belongs to
+func (s *Starboard) getEmojiForEntry(starEntry models.StarboardEntry) (emojis []string) {
+	board, _ := s.getBoardByID(starEntry.GuildID, starEntry.BoardID)
+	return s.getBoardEmoji(board)
+}
+
+func (s *Starboard) getEmojiText(guildID string, board models.StarboardBoard) (emojiText string) {
+	for _, emoji := range s.getBoardEmoji(board) {
+		discordEmoji, err := helpers.GetDiscordEmojiFromName(guildID, emoji)
+		if err == nil && discordEmoji != nil && discordEmoji.ID != "" {
+			emojiText += "<"
+			if discordEmoji.Animated {
+				emojiText += "a"
+			}
+			emojiText += ":" + discordEmoji.APIName() + ">"
+		} else {
+			emojiText += emoji
+		}
+		emojiText += ", "
+	}
+	return strings.TrimRight(emojiText, ", ")
+}
+
+func (s *Starboard) getChannelsText(board models.StarboardBoard) (channelsText string) {
+	if len(board.AllowedChannelIDs) <= 0 {
+		channelsText = "All channels"
+	} else {
+		channelsText = "Only in"
+		for _, channelID := range board.AllowedChannelIDs {
+			channelsText += " <#" + channelID + ">"
+		}
+	}
+	if len(board.DeniedChannelIDs) > 0 {
+		channelsText += ", except"
+		for _, channelID := range board.DeniedChannelIDs {
+			channelsText += " <#" + channelID + ">"
+		}
+	}
+	return channelsText
+}
+
+func (s *Starboard) getNSFWText(board models.StarboardBoard) string {
+	switch board.NSFW {
+	case models.StarboardNSFWModeAllow:
+		return "Allowed"
+	case models.StarboardNSFWModeDeny:
+		return "Denied"
+	}
+	return "Only if the starboard channel is NSFW"
+}
+
 func (s *Starboard) lockGuild(guildID string) {
 	if _, ok := starboardStarLocks[guildID]; ok {
 		starboardStarLocks[guildID].Lock()
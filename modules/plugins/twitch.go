@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Seklfreak/Robyul2/cache"
@@ -23,6 +24,11 @@ import (
 
 type Twitch struct{}
 
+var (
+	twitchBundledEntries     map[string][]models.TwitchEntry // [twitch user ID]entries
+	twitchBundledEntriesLock sync.RWMutex
+)
+
 const (
 	twitchStatsEndpoint = "https://api.twitch.tv/kraken/streams/%s"
 	twitchUsersEndpoint = "https://api.twitch.tv/helix/users?login=%s"
@@ -111,121 +117,117 @@ func (m *Twitch) Commands() []string {
 }
 
 func (m *Twitch) Init(session *shardmanager.Manager) {
-	go m.checkTwitchFeedsLoop()
-	cache.GetLogger().WithField("module", "twitch").Info("Started twitch loop (60s)")
+	helpers.RegisterFeedSource(m, metrics.TwitchRefreshTime)
+}
+
+func (m *Twitch) Name() string {
+	return models.FeedSourceTwitch
+}
+
+func (m *Twitch) Interval() time.Duration {
+	return 30 * time.Second
 }
-func (m *Twitch) checkTwitchFeedsLoop() {
-	defer helpers.Recover()
-	defer func() {
-		go func() {
-			cache.GetLogger().WithField("module", "twitch").Info("The checkTwitchFeedsLoop died. Please investigate! Will be restarted in 60 seconds")
-			time.Sleep(60 * time.Second)
-			m.checkTwitchFeedsLoop()
-		}()
-	}()
 
+func (m *Twitch) Workers() int {
+	return 1
+}
+
+func (m *Twitch) Targets() (targets []string, err error) {
 	var entries []models.TwitchEntry
-	var bundledEntries map[string][]models.TwitchEntry
-
-	logger := cache.GetLogger().WithField("module", "twitch")
-
-	for {
-		err := helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitchTable).Find(nil)).All(&entries)
-		helpers.Relax(err)
-
-		// migration from twitch channel names to twitch user ids
-		// for _, entry := range entries {
-		// 	if entry.TwitchUserID != "" {
-		// 		continue
-		// 	}
-		//
-		// 	twichUserID, err := m.getTwitchID(entry.TwitchChannelName)
-		// 	if err != nil {
-		// 		continue
-		// 	}
-		//
-		// 	entry.TwitchUserID = twichUserID
-		//
-		// 	err = helpers.MDbUpdate(models.TwitchTable, entry.ID, entry)
-		// 	if err != nil {
-		// 		continue
-		// 	}
-		//
-		// 	logger.WithField("TwitchUserID", twichUserID).
-		// 		WithField("entryID", entry.ID).
-		// 		WithField("TwitchChannelName", entry.TwitchChannelName).
-		// 		Info("set Twitch User ID as part of migration")
-		// }
-
-		bundledEntries = make(map[string][]models.TwitchEntry, 0)
-
-		for _, entry := range entries {
-			channel, err := helpers.GetChannelWithoutApi(entry.ChannelID)
-			if err != nil || channel == nil || channel.ID == "" {
-				//cache.GetLogger().WithField("module", "twitch").Warn(fmt.Sprintf("skipped twitch @%s for Channel #%s on Guild #%s: channel not found!",
-				//	entry.TwitchChannelName, entry.ChannelID, entry.ServerID))
-				continue
-			}
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitchTable).Find(nil)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
 
-			if entry.TwitchUserID == "" {
-				continue
-			}
+	bundledEntries := make(map[string][]models.TwitchEntry)
+	for _, entry := range entries {
+		if entry.TwitchUserID == "" {
+			continue
+		}
 
-			if _, ok := bundledEntries[entry.TwitchUserID]; ok {
-				bundledEntries[entry.TwitchUserID] = append(bundledEntries[entry.TwitchUserID], entry)
-			} else {
-				bundledEntries[entry.TwitchUserID] = []models.TwitchEntry{entry}
-			}
+		if !helpers.FeedCanPost(entry.ChannelID, entry.EmbedTemplate == "" || helpers.IsEmbedCode(entry.EmbedTemplate)) {
+			continue
 		}
 
-		logger.Infof("checking %d channels for %d feeds", len(bundledEntries), len(entries))
-		start := time.Now()
+		bundledEntries[entry.TwitchUserID] = append(bundledEntries[entry.TwitchUserID], entry)
+	}
 
-		// TODO: Check multiple entries at once
-		for twitchUserID, entries := range bundledEntries {
-			// logger.WithField("TwitchUserID", twitchUserID).
-			// 	WithField("TwitchChannelName", entries[0].TwitchChannelName).Info("checking twitch channel")
+	twitchBundledEntriesLock.Lock()
+	twitchBundledEntries = bundledEntries
+	twitchBundledEntriesLock.Unlock()
 
-			//cache.GetLogger().WithField("module", "twitch").Info(fmt.Sprintf("checking Twitch Channel %s", twitchChannelName))
-			twitchStatus, err := m.getTwitchStatus(twitchUserID)
-			if err != nil &&
-				!strings.Contains(err.Error(), "user not found") &&
-				!strings.Contains(err.Error(), "channel offline") {
-				logger.WithField("twitchUserID", twitchUserID).WithError(err).Error("failure checking twitch channel")
-				continue
-			}
+	for twitchUserID := range bundledEntries {
+		targets = append(targets, twitchUserID)
+	}
+	return targets, nil
+}
 
-			for _, entry := range entries {
-				changes := false
-				if !entry.IsLive {
-					if twitchStatus != nil && twitchStatus.Stream.ID != 0 {
-						go func(gEntry models.TwitchEntry, gTwitchStatus TwitchStatus) {
-							defer helpers.Recover()
-							m.postTwitchLiveToChannel(gEntry, gTwitchStatus)
-						}(entry, *twitchStatus)
-						entry.IsLive = true
-						changes = true
-					}
-				} else {
-					if twitchStatus == nil || twitchStatus.Stream.ID == 0 {
-						entry.IsLive = false
-						changes = true
-					}
-				}
+// Check posts to the feeds if the channel went live, every stream is only posted once
+func (m *Twitch) Check(target string) (err error) {
+	twitchBundledEntriesLock.RLock()
+	entries := twitchBundledEntries[target]
+	twitchBundledEntriesLock.RUnlock()
+
+	twitchStatus, err := m.getTwitchStatus(target)
+	if err != nil &&
+		!strings.Contains(err.Error(), "user not found") &&
+		!strings.Contains(err.Error(), "channel offline") {
+		return err
+	}
+	isLive := twitchStatus != nil && twitchStatus.Stream.ID != 0
 
-				if changes {
-					err = helpers.MDbUpdateWithoutLogging(models.TwitchTable, entry.ID, entry)
-					helpers.Relax(err)
+	for _, entry := range entries {
+		if entry.IsLive == isLive {
+			continue
+		}
+
+		if isLive {
+			streamID := strconv.FormatInt(twitchStatus.Stream.ID, 10)
+			claimed, err := helpers.FeedClaimItem(models.FeedSourceTwitch, entry.ID.Hex(), streamID)
+			if err != nil {
+				return err
+			}
+			if claimed {
+				err = m.postTwitchLiveToChannel(entry, *twitchStatus)
+				if err != nil {
+					helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceTwitch, entry.ID.Hex(), streamID))
+					return err
 				}
 			}
 		}
 
-		elapsed := time.Since(start)
-		logger.Infof("checked %d channels for %d feeds, took %s", len(bundledEntries), len(entries), elapsed)
-		metrics.TwitchRefreshTime.Set(elapsed.Seconds())
+		err = helpers.MDbUpdateQueryWithoutLogging(models.TwitchTable,
+			bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{"islive": isLive}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-		time.Sleep(30 * time.Second)
+func (m *Twitch) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.TwitchEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitchTable).Find(bson.M{"guildid": guildID})).All(&entries)
+	if err != nil {
+		return nil, err
 	}
+
+	for _, entry := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:            helpers.MdbIdToHuman(entry.ID),
+			Name:          entry.TwitchChannelName,
+			ChannelID:     entry.ChannelID,
+			MentionRoleID: entry.MentionRoleID,
+			Settings:      helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		})
+	}
+	return feeds, nil
+}
+
+func (m *Twitch) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	return helpers.MDbUpdateQuery(models.TwitchTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
 }
 
 func (m *Twitch) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
@@ -349,6 +351,9 @@ func (m *Twitch) Action(command string, content string, msg *discordgo.Message,
 					err = helpers.MDbDelete(models.TwitchTable, entryBucket.ID)
 					helpers.Relax(err)
 
+					err = helpers.FeedDeleteItems(models.FeedSourceTwitch, entryBucket.ID.Hex())
+					helpers.RelaxLog(err)
+
 					_, err = helpers.EventlogLog(time.Now(), entryBucket.GuildID, helpers.MdbIdToHuman(entryBucket.ID),
 						models.EventlogTargetTypeRobyulTwitchFeed, msg.Author.ID,
 						models.EventlogTypeRobyulTwitchFeedRemove, "",
@@ -543,15 +548,11 @@ func (m *Twitch) getTwitchStatus(id string) (*TwitchStatus, error) {
 	return &twitchStatus, nil
 }
 
-func (m *Twitch) postTwitchLiveToChannel(entry models.TwitchEntry, twitchStatus TwitchStatus) {
+func (m *Twitch) postTwitchLiveToChannel(entry models.TwitchEntry, twitchStatus TwitchStatus) (err error) {
 	twitchStreamName := twitchStatus.Stream.Channel.DisplayName
 	if strings.ToLower(twitchStatus.Stream.Channel.Name) != strings.ToLower(twitchStatus.Stream.Channel.DisplayName) {
 		twitchStreamName += fmt.Sprintf(" (%s)", twitchStatus.Stream.Channel.Name)
 	}
-	var mentionText string
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
-	}
 
 	twitchChannelEmbed := &discordgo.MessageEmbed{
 		Title:  helpers.GetTextF("plugins.twitch.wentlive-embed-title", twitchStreamName),
@@ -577,9 +578,18 @@ func (m *Twitch) postTwitchLiveToChannel(entry models.TwitchEntry, twitchStatus
 	if twitchChannelEmbed.Description != "" {
 		twitchChannelEmbed.Description = strings.Trim(twitchChannelEmbed.Description, "\n")
 	}
-	_, err := helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + fmt.Sprintf("<%s>", twitchStatus.Stream.Channel.URL),
-		Embed:   twitchChannelEmbed,
-	})
-	helpers.Relax(err)
+	item := helpers.FeedTemplateItem{
+		Title:       twitchStatus.Stream.Channel.Status,
+		URL:         twitchStatus.Stream.Channel.URL,
+		Author:      twitchStreamName,
+		Thumbnail:   twitchStatus.Stream.Channel.Logo,
+		Description: twitchStatus.Stream.Game,
+	}
+	return helpers.FeedPostWithSettings(entry.ChannelID, entry.MentionRoleID,
+		helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		helpers.FeedIdentity{Name: twitchStatus.Stream.Channel.DisplayName, AvatarURL: twitchStatus.Stream.Channel.Logo},
+		item, &discordgo.MessageSend{
+			Content: fmt.Sprintf("<%s>", twitchStatus.Stream.Channel.URL),
+			Embed:   twitchChannelEmbed,
+		})
 }
@@ -34,10 +34,16 @@ var (
 	twitterClient            *twitter.Client
 	twitterStream            *anaconda.Stream
 	twitterStreamNeedsUpdate bool
-	twitterEntriesCache      []models.TwitterEntry
+	twitterEntriesCache      []models.TwitterEntry // used by the stream handler, replaced on every change
+	twitterEntriesCacheLock  sync.RWMutex
 	twitterStreamIsStarting  sync.Mutex
-	twitterEntryLocks        = make(map[string]*sync.Mutex)
-	twitterEntryLock         sync.Mutex
+	// done once the first start of the stream has been attempted
+	twitterStreamFirstStart sync.WaitGroup
+	// accounts covered by the stream, all other accounts are checked by the feed scheduler
+	twitterStreamAccountIDs     = make(map[string]bool)
+	twitterStreamAccountIDsLock sync.RWMutex
+	twitterBundledEntries       map[string][]models.TwitterEntry // [accountID]entries to check via REST
+	twitterBundledEntriesLock   sync.RWMutex
 )
 
 const (
@@ -80,7 +86,7 @@ func (t *Twitter) Init(session *shardmanager.Manager) {
 			for event := range twitterStream.C {
 				switch item := event.(type) {
 				case anaconda.Tweet:
-					for _, entry := range twitterEntriesCache {
+					for _, entry := range getCachedTwitterEntries() {
 						if entry.AccountID != item.User.IdStr {
 							continue
 						}
@@ -95,49 +101,27 @@ func (t *Twitter) Init(session *shardmanager.Manager) {
 							continue
 						}
 
-						entryID := entry.ID
-						t.lockEntry(entryID)
+						tweetText := item.FullText
+						if tweetText == "" {
+							tweetText = item.Text
+						}
+						if !t.matchesFilter(entry.Filter, tweetText,
+							len(item.Entities.Media) > 0 || len(item.ExtendedEntities.Media) > 0) {
+							continue
+						}
 
-						var localEntry models.TwitterEntry
-						err := helpers.MdbOneWithoutLogging(
-							helpers.MdbCollection(models.TwitterTable).Find(bson.M{"_id": entry.ID}),
-							&localEntry,
-						)
+						claimed, err := helpers.FeedClaimItem(models.FeedSourceTwitter, entry.ID.Hex(), item.IdStr)
 						if err != nil {
-							t.unlockEntry(entryID)
 							helpers.RelaxLog(err)
 							continue
 						}
-
-						changes := false
-						tweetAlreadyPosted := false
-
-						for _, postedTweet := range localEntry.PostedTweets {
-							if postedTweet.ID == item.IdStr {
-								tweetAlreadyPosted = true
-							}
-						}
-						if tweetAlreadyPosted == false {
-							// cache.GetLogger().WithField("module", "twitter").Info(fmt.Sprintf("posting tweet (via streaming): #%s to: #%s", item.IdStr, entry.ChannelID))
-							localEntry.PostedTweets = append(localEntry.PostedTweets, models.TwitterTweetEntry{ID: item.IdStr, CreatedAt: item.CreatedAt})
-							changes = true
-							go t.postAnacondaTweetToChannel(localEntry.ChannelID, &item, &item.User, localEntry)
-						}
-
-						if changes == true {
-							err = helpers.MDbUpsertIDWithoutLogging(
-								models.TwitterTable,
-								localEntry.ID,
-								localEntry,
-							)
-							if err != nil {
-								t.unlockEntry(entryID)
+						if claimed {
+							go func(gEntry models.TwitterEntry, gTweet anaconda.Tweet) {
+								defer helpers.Recover()
+								err := t.postAnacondaTweetToChannel(gEntry.ChannelID, &gTweet, &gTweet.User, gEntry)
 								helpers.RelaxLog(err)
-								continue
-							}
+							}(entry, item)
 						}
-
-						t.unlockEntry(entryID)
 					}
 				case anaconda.StallWarning:
 					cache.GetLogger().WithField("module", "twitter").Warn("received stall warning from twitter stream:", item.Message)
@@ -146,16 +130,15 @@ func (t *Twitter) Init(session *shardmanager.Manager) {
 		}
 	}()
 
-	go t.startTwitterStream()
-	go t.updateTwitterStreamLoop()
+	// accounts not covered by the stream are checked through the REST API, all accounts while the stream isn't running
+	twitterStreamFirstStart.Add(1)
+	helpers.RegisterFeedSource(t, metrics.TwitterRefreshTime)
 
-	// go func() {
-	// 	// wait for twitterEntriesCache to initialize
-	// 	time.Sleep(30 * time.Second)
-	// 	// TODO: only to REST API check on start or after stream restarts
-	// 	go t.checkTwitterFeedsLoop()
-	// 	cache.GetLogger().WithField("module", "twitter").Info("started twitter loop (10m)")
-	// }()
+	go func() {
+		defer twitterStreamFirstStart.Done()
+		t.startTwitterStream()
+	}()
+	go t.updateTwitterStreamLoop()
 }
 
 func (t *Twitter) Uninit(session *shardmanager.Manager) {
@@ -172,41 +155,25 @@ func (t *Twitter) startTwitterStream() {
 	var accountIDs []string
 	var idInSlice bool
 
+	var entries []models.TwitterEntry
 	err = helpers.MDbIterWithoutLogging(
-		helpers.MdbCollection(models.TwitterTable).Find(nil).Sort("_id").Select(
-			// avoid selecting growing PostedTweets slice
-			bson.M{"postmode": 1, "accountid": 1, "excluderts": 1, "excludementions": 1, "channelid": 1},
-		),
-	).All(&twitterEntriesCache)
+		helpers.MdbCollection(models.TwitterTable).Find(nil).Sort("_id"),
+	).All(&entries)
 	helpers.Relax(err)
 
-	for _, entry := range twitterEntriesCache {
-		if entry.AccountID == "" {
-			continue
-		}
-
-		// check if channel exists
-		channel, err := helpers.GetChannelWithoutApi(entry.ChannelID)
-		if err != nil || channel == nil || channel.ID == "" {
-			continue
-		}
+	twitterEntriesCacheLock.Lock()
+	twitterEntriesCache = entries
+	twitterEntriesCacheLock.Unlock()
 
-		// check if we can send messages
-		channelPermission, err := cache.GetSession().SessionForGuildS(entry.GuildID).State.UserChannelPermissions(cache.GetSession().SessionForGuildS(entry.GuildID).State.User.ID, channel.ID)
-		if err != nil {
+	for _, entry := range entries {
+		if entry.AccountID == "" {
 			continue
 		}
 
-		if channelPermission&discordgo.PermissionSendMessages != discordgo.PermissionSendMessages {
+		if !helpers.FeedCanPost(entry.ChannelID, entry.PostMode == models.TwitterPostModeRobyulEmbed) {
 			continue
 		}
 
-		if entry.PostMode == models.TwitterPostModeRobyulEmbed {
-			if channelPermission&discordgo.PermissionEmbedLinks != discordgo.PermissionEmbedLinks {
-				continue
-			}
-		}
-
 		idInSlice = false
 
 		for _, accountID := range accountIDs {
@@ -224,6 +191,13 @@ func (t *Twitter) startTwitterStream() {
 		accountIDs = accountIDs[0:twitterStreamLimit]
 	}
 
+	twitterStreamAccountIDsLock.Lock()
+	twitterStreamAccountIDs = make(map[string]bool, len(accountIDs))
+	for _, accountID := range accountIDs {
+		twitterStreamAccountIDs[accountID] = true
+	}
+	twitterStreamAccountIDsLock.Unlock()
+
 	twitterStream = anacondaClient.PublicStreamFilter(url.Values{
 		"follow":         accountIDs,
 		"stall_warnings": []string{"true"},
@@ -236,6 +210,10 @@ func (t *Twitter) stopTwitterStream() {
 	if twitterStream != nil {
 		twitterStream.Stop()
 		twitterStream = nil
+
+		twitterStreamAccountIDsLock.Lock()
+		twitterStreamAccountIDs = make(map[string]bool)
+		twitterStreamAccountIDsLock.Unlock()
 		cache.GetLogger().WithField("module", "twitter").Info("stopped stream")
 	}
 }
@@ -262,172 +240,229 @@ func (t *Twitter) updateTwitterStreamLoop() {
 	}
 }
 
-func (m *Twitter) checkTwitterFeedsLoop() {
-	defer helpers.Recover()
-	defer func() {
-		go func() {
-			cache.GetLogger().WithField("module", "twitter").Error("The checkTwitterFeedsLoop died. Please investigate! Will be restarted in 60 seconds")
-			time.Sleep(60 * time.Second)
-			m.checkTwitterFeedsLoop()
-		}()
-	}()
+func (t *Twitter) Name() string {
+	return models.FeedSourceTwitter
+}
 
-	var err error
-	var bundledEntries map[string][]models.TwitterEntry
-	var accountID int64
+func (t *Twitter) Interval() time.Duration {
+	return 10 * time.Minute
+}
 
-	for {
-		bundledEntries = make(map[string][]models.TwitterEntry, 0)
+func (t *Twitter) Workers() int {
+	return 1
+}
 
-		for _, entry := range twitterEntriesCache {
-			// check if channel exists
-			channel, err := helpers.GetChannelWithoutApi(entry.ChannelID)
-			if err != nil || channel == nil || channel.ID == "" {
-				continue
+// Targets returns all accounts which are not covered by the Twitter stream
+func (t *Twitter) Targets() (targets []string, err error) {
+	// without waiting for the stream the first round would check all accounts through the REST API
+	twitterStreamFirstStart.Wait()
+
+	var entries []models.TwitterEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitterTable).Find(nil)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	bundledEntries := make(map[string][]models.TwitterEntry)
+	twitterStreamAccountIDsLock.RLock()
+	for _, entry := range entries {
+		if entry.AccountID == "" || twitterStreamAccountIDs[entry.AccountID] {
+			continue
+		}
+
+		if !helpers.FeedCanPost(entry.ChannelID, entry.PostMode == models.TwitterPostModeRobyulEmbed) {
+			continue
+		}
+
+		bundledEntries[entry.AccountID] = append(bundledEntries[entry.AccountID], entry)
+	}
+	twitterStreamAccountIDsLock.RUnlock()
+
+	twitterBundledEntriesLock.Lock()
+	twitterBundledEntries = bundledEntries
+	twitterBundledEntriesLock.Unlock()
+
+	for accountID := range bundledEntries {
+		targets = append(targets, accountID)
+	}
+	return targets, nil
+}
+
+// Check posts the tweets of the last hour of the account
+func (t *Twitter) Check(target string) (err error) {
+	// stay below the rate limit of the timeline endpoint
+	defer time.Sleep(5 * time.Second)
+
+	twitterBundledEntriesLock.RLock()
+	entries := twitterBundledEntries[target]
+	twitterBundledEntriesLock.RUnlock()
+
+	accountID, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	twitterUserTweets, _, err := twitterClient.Timelines.UserTimeline(&twitter.UserTimelineParams{
+		UserID:          accountID,
+		Count:           10,
+		ExcludeReplies:  twitter.Bool(true),
+		IncludeRetweets: twitter.Bool(true),
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "34 Sorry, that page does not exist") ||
+			strings.Contains(err.Error(), "50 User not found") ||
+			strings.Contains(err.Error(), "63 User has been suspended") {
+			for _, entry := range entries {
+				err = helpers.MDbDelete(models.TwitterTable, entry.ID)
+				if err != nil {
+					helpers.RelaxLog(err)
+					continue
+				}
+				err = helpers.FeedDeleteItems(models.FeedSourceTwitter, entry.ID.Hex())
+				helpers.RelaxLog(err)
+				cache.GetLogger().WithField("module", "twitter").Infof(
+					"removed entry %s (@%s) because user suspended or deleted",
+					helpers.MdbIdToHuman(entry.ID), entry.AccountScreenName,
+				)
 			}
+			return nil
+		}
+		return err
+	}
 
-			// check if we can send messages
-			channelPermission, err := cache.GetSession().SessionForGuildS(entry.GuildID).State.UserChannelPermissions(cache.GetSession().SessionForGuildS(entry.GuildID).State.User.ID, channel.ID)
-			if err != nil {
+	// https://github.com/golang/go/wiki/SliceTricks#reversing
+	for i := len(twitterUserTweets)/2 - 1; i >= 0; i-- {
+		opp := len(twitterUserTweets) - 1 - i
+		twitterUserTweets[i], twitterUserTweets[opp] = twitterUserTweets[opp], twitterUserTweets[i]
+	}
+
+	// a failed post doesn't stop the other tweets from being posted, the last error is returned
+	var postErr error
+	for _, entry := range entries {
+		for _, tweet := range twitterUserTweets {
+			tweetCreatedAt, err := tweet.CreatedAtTime()
+			if err != nil || time.Now().Sub(tweetCreatedAt) > time.Hour {
 				continue
 			}
 
-			if channelPermission&discordgo.PermissionSendMessages != discordgo.PermissionSendMessages {
+			// exclude RTs?
+			if entry.ExcludeRTs && tweet.RetweetedStatus != nil {
 				continue
 			}
 
-			if entry.PostMode == models.TwitterPostModeRobyulEmbed {
-				if channelPermission&discordgo.PermissionEmbedLinks != discordgo.PermissionEmbedLinks {
-					continue
-				}
+			// exclude Mentions?
+			if entry.ExcludeMentions && strings.HasPrefix(tweet.Text, "@") {
+				continue
 			}
 
-			if _, ok := bundledEntries[entry.AccountID]; ok {
-				bundledEntries[entry.AccountID] = append(bundledEntries[entry.AccountID], entry)
-			} else {
-				bundledEntries[entry.AccountID] = []models.TwitterEntry{entry}
+			tweetText := tweet.FullText
+			if tweetText == "" {
+				tweetText = tweet.Text
 			}
-		}
-
-		cache.GetLogger().WithField("module", "twitter").Infof("checking %d accounts for %d feeds", len(bundledEntries), len(twitterEntriesCache))
-		start := time.Now()
-
-		for twitterAccountID, entries := range bundledEntries {
-			accountID, err = strconv.ParseInt(twitterAccountID, 10, 64)
-			if err != nil {
+			if !t.matchesFilter(entry.Filter, tweetText,
+				(tweet.Entities != nil && len(tweet.Entities.Media) > 0) ||
+					(tweet.ExtendedEntities != nil && len(tweet.ExtendedEntities.Media) > 0)) {
 				continue
 			}
 
-			twitterUserTweets, _, err := twitterClient.Timelines.UserTimeline(&twitter.UserTimelineParams{
-				UserID:          accountID,
-				Count:           10,
-				ExcludeReplies:  twitter.Bool(true),
-				IncludeRetweets: twitter.Bool(true),
-			})
+			claimed, err := helpers.FeedClaimItem(models.FeedSourceTwitter, entry.ID.Hex(), tweet.IDStr)
 			if err != nil {
-				if strings.Contains(err.Error(), "34 Sorry, that page does not exist") ||
-					strings.Contains(err.Error(), "50 User not found") ||
-					strings.Contains(err.Error(), "63 User has been suspended") {
-					for _, entry := range entries {
-						err = helpers.MDbDelete(models.TwitterTable, entry.ID)
-						if err != nil {
-							helpers.RelaxLog(err)
-							continue
-						}
-						cache.GetLogger().WithField("module", "twitter").Infof(
-							"removed entry %s (@%s) because user suspended or deleted",
-							helpers.MdbIdToHuman(entry.ID), entry.AccountScreenName,
-						)
-					}
-					continue
-				}
-				cache.GetLogger().WithField("module", "twitter").Warnf("getting tweets of @%d failed: %s", accountID, err.Error())
+				helpers.RelaxLog(err)
+				postErr = err
+				continue
+			}
+			if !claimed {
 				continue
 			}
 
-			cache.GetLogger().WithField("module", "twitter").Info(fmt.Sprintf("checking Twitter Account %d", accountID))
-
-			// https://github.com/golang/go/wiki/SliceTricks#reversing
-			for i := len(twitterUserTweets)/2 - 1; i >= 0; i-- {
-				opp := len(twitterUserTweets) - 1 - i
-				twitterUserTweets[i], twitterUserTweets[opp] = twitterUserTweets[opp], twitterUserTweets[i]
+			cache.GetLogger().WithField("module", "twitter").Info(fmt.Sprintf("posting tweet (via REST): #%s to: #%s", tweet.IDStr, entry.ChannelID))
+			err = t.postTweetToChannel(entry.ChannelID, &tweet, entry)
+			if err != nil {
+				helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceTwitter, entry.ID.Hex(), tweet.IDStr))
+				helpers.RelaxLog(err)
+				postErr = err
 			}
+		}
+	}
 
-			for _, entry := range entries {
-				entryID := entry.ID
-				m.lockEntry(entryID)
+	return postErr
+}
 
-				err = helpers.MdbOneWithoutLogging(
-					helpers.MdbCollection(models.TwitterTable).Find(bson.M{"_id": entry.ID}),
-					&entry,
-				)
-				if err != nil {
-					m.unlockEntry(entryID)
-					if !helpers.IsMdbNotFound(err) {
-						helpers.RelaxLog(err)
-					}
-					continue
-				}
+// matchesFilter returns true if the tweet passes the filter of the feed
+func (t *Twitter) matchesFilter(filter models.TwitterFilter, text string, hasMedia bool) bool {
+	if filter.MediaOnly && !hasMedia {
+		return false
+	}
 
-				changes := false
+	return helpers.FeedTextFilterMatches(filter.Text, html.UnescapeString(text))
+}
 
-				for _, tweet := range twitterUserTweets {
-					tweetCreatedAt, err := tweet.CreatedAtTime()
-					if err != nil || time.Now().Sub(tweetCreatedAt) > time.Hour {
-						continue
-					}
+// describeFilter returns one line for every rule of the filter
+func (t *Twitter) describeFilter(filter models.TwitterFilter) (lines []string) {
+	lines = helpers.FeedDescribeTextFilter(filter.Text)
+	if filter.MediaOnly {
+		lines = append(lines, "only tweets with media")
+	}
+	return lines
+}
 
-					// exclude RTs?
-					if entry.ExcludeRTs && tweet.RetweetedStatus != nil {
-						continue
-					}
+func (t *Twitter) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.TwitterEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.TwitterTable).Find(bson.M{"guildid": guildID})).All(&entries)
+	if err != nil {
+		return nil, err
+	}
 
-					// exclude Mentions?
-					if entry.ExcludeMentions && strings.HasPrefix(tweet.Text, "@") {
-						continue
-					}
+	for _, entry := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:            helpers.MdbIdToHuman(entry.ID),
+			Name:          "@" + entry.AccountScreenName,
+			ChannelID:     entry.ChannelID,
+			MentionRoleID: entry.MentionRoleID,
+			Settings:      helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		})
+	}
+	return feeds, nil
+}
 
-					tweetAlreadyPosted := false
-					for _, postedTweet := range entry.PostedTweets {
-						if postedTweet.ID == tweet.IDStr {
-							tweetAlreadyPosted = true
-						}
-					}
-					if tweetAlreadyPosted == false {
-						cache.GetLogger().WithField("module", "twitter").Info(fmt.Sprintf("posting tweet (via REST): #%s to: #%s", tweet.IDStr, entry.ChannelID))
-						entry.PostedTweets = append(entry.PostedTweets, models.TwitterTweetEntry{ID: tweet.IDStr, CreatedAt: tweet.CreatedAt})
-						changes = true
-						tweetToPost := tweet
-						go m.postTweetToChannel(entry.ChannelID, &tweetToPost, entry)
-					}
+func (t *Twitter) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	err = helpers.MDbUpdateQuery(models.TwitterTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
+	if err != nil {
+		return err
+	}
 
-				}
-				if changes == true {
-					err = helpers.MDbUpsertIDWithoutLogging(
-						models.TwitterTable,
-						entry.ID,
-						entry,
-					)
-					if err != nil {
-						m.unlockEntry(entryID)
-						helpers.RelaxLog(err)
-						continue
-					}
-				}
+	var entry models.TwitterEntry
+	err = helpers.MdbOne(helpers.MdbCollection(models.TwitterTable).Find(bson.M{"_id": helpers.HumanToMdbId(feedID)}), &entry)
+	if err != nil {
+		return err
+	}
 
-				m.unlockEntry(entryID)
-			}
-			time.Sleep(5 * time.Second)
-		}
+	updateCachedTwitterEntry(entry)
+	return nil
+}
 
-		elapsed := time.Since(start)
-		cache.GetLogger().WithField("module", "twitter").Infof("checked %d accounts for %d feeds, took %s", len(bundledEntries), len(twitterEntriesCache), elapsed)
-		metrics.TwitterRefreshTime.Set(elapsed.Seconds())
+// getCachedTwitterEntries returns the entries used by the stream handler
+func getCachedTwitterEntries() (entries []models.TwitterEntry) {
+	twitterEntriesCacheLock.RLock()
+	defer twitterEntriesCacheLock.RUnlock()
+	return twitterEntriesCache
+}
 
-		if len(bundledEntries) <= 10 {
-			time.Sleep(10 * time.Minute)
+// updateCachedTwitterEntry replaces the cached entry, so changed filters and settings apply without reconnecting the stream
+// the cache is copied, handlers iterating the previous entries aren't affected
+func updateCachedTwitterEntry(entry models.TwitterEntry) {
+	twitterEntriesCacheLock.Lock()
+	defer twitterEntriesCacheLock.Unlock()
+
+	entries := make([]models.TwitterEntry, len(twitterEntriesCache))
+	copy(entries, twitterEntriesCache)
+	for i := range entries {
+		if entries[i].ID == entry.ID {
+			entries[i] = entry
 		}
 	}
+	twitterEntriesCache = entries
 }
 
 func (m *Twitter) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
@@ -513,13 +548,6 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 				if strings.Contains(strings.ToLower(content), " text") {
 					postMode = models.TwitterPostModeText
 				}
-				// Create DB Entries
-				var dbTweets []models.TwitterTweetEntry
-				for _, tweet := range twitterUserTweets {
-					tweetEntry := models.TwitterTweetEntry{ID: tweet.IDStr, CreatedAt: tweet.CreatedAt}
-					dbTweets = append(dbTweets, tweetEntry)
-
-				}
 				// exclude RTs or Mentions?
 				var excludeRTs, excludeMentions bool
 				if strings.Contains(strings.ToLower(msg.Content), " exclude-rts") {
@@ -536,7 +564,6 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 						ChannelID:         targetChannel.ID,
 						AccountScreenName: twitterUser.ScreenName,
 						AccountID:         twitterUser.IDStr,
-						PostedTweets:      dbTweets,
 						MentionRoleID:     mentionRole.ID,
 						PostMode:          postMode,
 						ExcludeRTs:        excludeRTs,
@@ -545,6 +572,12 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 				)
 				helpers.Relax(err)
 
+				// don't post the current tweets
+				for _, tweet := range twitterUserTweets {
+					_, err = helpers.FeedClaimItem(models.FeedSourceTwitter, newID.Hex(), tweet.IDStr)
+					helpers.Relax(err)
+				}
+
 				twitterStreamNeedsUpdate = true
 
 				postModeText := "robyul embed"
@@ -619,6 +652,9 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 					err = helpers.MDbDelete(models.TwitterTable, entryBucket.ID)
 					helpers.Relax(err)
 
+					err = helpers.FeedDeleteItems(models.FeedSourceTwitter, entryBucket.ID.Hex())
+					helpers.RelaxLog(err)
+
 					twitterStreamNeedsUpdate = true
 
 					postModeText := "robyul embed"
@@ -675,6 +711,96 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 					return
 				}
 			})
+		case "filter", "filters": // [p]twitter filter <id> [<rule> [<value>]]
+			helpers.RequireMod(msg, func() {
+				if len(args) < 2 {
+					helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.too-few"))
+					return
+				}
+
+				channel, err := helpers.GetChannel(msg.ChannelID)
+				helpers.Relax(err)
+
+				var entryBucket models.TwitterEntry
+				err = helpers.MdbOne(
+					helpers.MdbCollection(models.TwitterTable).Find(bson.M{"_id": helpers.HumanToMdbId(args[1]), "guildid": channel.GuildID}),
+					&entryBucket,
+				)
+				if helpers.IsMdbNotFound(err) || entryBucket.ID == "" {
+					helpers.SendMessage(msg.ChannelID, helpers.GetText("plugins.twitter.filter-account-not-found"))
+					return
+				}
+				helpers.Relax(err)
+
+				if len(args) < 3 {
+					filterLines := m.describeFilter(entryBucket.Filter)
+					if len(filterLines) <= 0 {
+						helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.twitter.filter-none", entryBucket.AccountScreenName))
+						return
+					}
+					helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.twitter.filter-list",
+						entryBucket.AccountScreenName, strings.Join(filterLines, "\n")))
+					return
+				}
+
+				beforeFilter := entryBucket.Filter
+				rule := strings.ToLower(args[2])
+				value := strings.Join(args[3:], " ")
+
+				switch rule {
+				case "media-only", "media":
+					entryBucket.Filter.MediaOnly = !entryBucket.Filter.MediaOnly
+				case "reset":
+					entryBucket.Filter = models.TwitterFilter{}
+				default:
+					known, err := helpers.FeedSetTextFilterRule(&entryBucket.Filter.Text, rule, value)
+					if !known {
+						helpers.SendMessage(msg.ChannelID, helpers.GetText("bot.arguments.invalid"))
+						return
+					}
+					if err != nil {
+						helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.twitter.filter-invalid-regex", err.Error()))
+						return
+					}
+				}
+
+				err = helpers.MDbUpdate(models.TwitterTable, entryBucket.ID, entryBucket)
+				helpers.Relax(err)
+
+				filterLines := m.describeFilter(entryBucket.Filter)
+
+				_, err = helpers.EventlogLog(time.Now(), channel.GuildID, helpers.MdbIdToHuman(entryBucket.ID),
+					models.EventlogTargetTypeRobyulTwitterFeed, msg.Author.ID,
+					models.EventlogTypeRobyulTwitterFeedUpdate, "",
+					[]models.ElasticEventlogChange{
+						{
+							Key:      "twitter_filter",
+							OldValue: strings.Join(m.describeFilter(beforeFilter), "; "),
+							NewValue: strings.Join(filterLines, "; "),
+						},
+					},
+					[]models.ElasticEventlogOption{
+						{
+							Key:   "twitter_channelid",
+							Value: entryBucket.ChannelID,
+							Type:  models.EventlogTargetTypeChannel,
+						},
+						{
+							Key:   "twitter_accountscreename",
+							Value: entryBucket.AccountScreenName,
+						},
+					}, false)
+				helpers.RelaxLog(err)
+
+				updateCachedTwitterEntry(entryBucket)
+
+				if len(filterLines) <= 0 {
+					helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.twitter.filter-none", entryBucket.AccountScreenName))
+					return
+				}
+				helpers.SendMessage(msg.ChannelID, helpers.GetTextF("plugins.twitter.filter-updated",
+					entryBucket.AccountScreenName, strings.Join(filterLines, "\n")))
+			})
 		case "list": // [p]twitter list
 			currentChannel, err := helpers.GetChannel(msg.ChannelID)
 			helpers.Relax(err)
@@ -712,6 +838,9 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 				if entry.ExcludeMentions {
 					specialText += " ignoring Mentions"
 				}
+				if len(m.describeFilter(entry.Filter)) > 0 {
+					specialText += " filtered"
+				}
 				resultMessage += fmt.Sprintf("`%s`: Twitter Account `@%s` posting to <#%s>%s\n",
 					helpers.MdbIdToHuman(entry.ID), entry.AccountScreenName, entry.ChannelID, specialText)
 			}
@@ -789,15 +918,26 @@ func (m *Twitter) Action(command string, content string, msg *discordgo.Message,
 	}
 }
 
-func (m *Twitter) postTweetToChannel(channelID string, tweet *twitter.Tweet, entry models.TwitterEntry) {
+func (m *Twitter) postTweetToChannel(channelID string, tweet *twitter.Tweet, entry models.TwitterEntry) (err error) {
+	identity := helpers.FeedIdentity{
+		Name:      fmt.Sprintf("%s (@%s)", tweet.User.Name, tweet.User.ScreenName),
+		AvatarURL: tweet.User.ProfileImageURLHttps,
+	}
+	item := helpers.FeedTemplateItem{
+		Title:       helpers.GetText("plugins.twitter.tweet-embed-title"),
+		URL:         fmt.Sprintf(TwitterFriendlyStatus, tweet.User.ScreenName, tweet.IDStr),
+		Author:      "@" + tweet.User.ScreenName,
+		Description: html.UnescapeString(tweet.Text),
+	}
+	if tweet.Entities != nil && len(tweet.Entities.Media) > 0 {
+		item.Thumbnail = tweet.Entities.Media[0].MediaURLHttps
+	}
+
 	if entry.PostMode == models.TwitterPostModeDiscordEmbed || entry.PostMode == models.TwitterPostModeText {
 		content := fmt.Sprintf("%s", fmt.Sprintf(TwitterFriendlyStatus, tweet.User.ScreenName, tweet.IDStr))
 		if entry.PostMode == models.TwitterPostModeText {
 			content = "<" + content + ">"
 		}
-		if entry.MentionRoleID != "" {
-			content = fmt.Sprintf("<@&%s>\n%s", entry.MentionRoleID, content)
-		}
 		if entry.PostMode == models.TwitterPostModeText {
 			// hide URL previews
 			content += "\n" + helpers.URLRegex.ReplaceAllStringFunc(tweet.Text, func(link string) string {
@@ -820,11 +960,9 @@ func (m *Twitter) postTweetToChannel(channelID string, tweet *twitter.Tweet, ent
 			}
 		}
 
-		helpers.SendComplex(
-			channelID, &discordgo.MessageSend{
-				Content: content,
-			})
-		return
+		return m.postToChannel(channelID, entry, identity, item, &discordgo.MessageSend{
+			Content: content,
+		})
 	}
 
 	twitterNameModifier := ""
@@ -895,26 +1033,32 @@ func (m *Twitter) postTweetToChannel(channelID string, tweet *twitter.Tweet, ent
 	}
 
 	content := fmt.Sprintf("<%s>", fmt.Sprintf(TwitterFriendlyStatus, tweet.User.ScreenName, tweet.IDStr))
-	if entry.MentionRoleID != "" {
-		content = fmt.Sprintf("<@&%s>\n%s", entry.MentionRoleID, content)
-	}
-
-	helpers.SendComplex(
-		channelID, &discordgo.MessageSend{
-			Content: content,
-			Embed:   channelEmbed,
-		})
+	return m.postToChannel(channelID, entry, identity, item, &discordgo.MessageSend{
+		Content: content,
+		Embed:   channelEmbed,
+	})
 }
 
-func (m *Twitter) postAnacondaTweetToChannel(channelID string, tweet *anaconda.Tweet, twitterUser *anaconda.User, entry models.TwitterEntry) {
+func (m *Twitter) postAnacondaTweetToChannel(channelID string, tweet *anaconda.Tweet, twitterUser *anaconda.User, entry models.TwitterEntry) (err error) {
+	identity := helpers.FeedIdentity{
+		Name:      fmt.Sprintf("%s (@%s)", twitterUser.Name, twitterUser.ScreenName),
+		AvatarURL: twitterUser.ProfileImageUrlHttps,
+	}
+	item := helpers.FeedTemplateItem{
+		Title:       helpers.GetText("plugins.twitter.tweet-embed-title"),
+		URL:         fmt.Sprintf(TwitterFriendlyStatus, twitterUser.ScreenName, tweet.IdStr),
+		Author:      "@" + twitterUser.ScreenName,
+		Description: html.UnescapeString(tweet.Text),
+	}
+	if len(tweet.Entities.Media) > 0 {
+		item.Thumbnail = tweet.Entities.Media[0].Media_url_https
+	}
+
 	if entry.PostMode == models.TwitterPostModeDiscordEmbed || entry.PostMode == models.TwitterPostModeText {
 		content := fmt.Sprintf("%s", fmt.Sprintf(TwitterFriendlyStatus, twitterUser.ScreenName, tweet.IdStr))
 		if entry.PostMode == models.TwitterPostModeText {
 			content = "<" + content + ">"
 		}
-		if entry.MentionRoleID != "" {
-			content = fmt.Sprintf("<@&%s>\n%s", entry.MentionRoleID, content)
-		}
 		if entry.PostMode == models.TwitterPostModeText {
 			// hide URL previews
 			content += "\n" + helpers.URLRegex.ReplaceAllStringFunc(tweet.Text, func(link string) string {
@@ -938,11 +1082,9 @@ func (m *Twitter) postAnacondaTweetToChannel(channelID string, tweet *anaconda.T
 			}
 		}
 
-		helpers.SendComplex(
-			channelID, &discordgo.MessageSend{
-				Content: content,
-			})
-		return
+		return m.postToChannel(channelID, entry, identity, item, &discordgo.MessageSend{
+			Content: content,
+		})
 	}
 
 	twitterNameModifier := ""
@@ -1009,15 +1151,18 @@ func (m *Twitter) postAnacondaTweetToChannel(channelID string, tweet *anaconda.T
 	}
 
 	content := fmt.Sprintf("<%s>", fmt.Sprintf(TwitterFriendlyStatus, twitterUser.ScreenName, tweet.IdStr))
-	if entry.MentionRoleID != "" {
-		content = fmt.Sprintf("<@&%s>\n%s", entry.MentionRoleID, content)
-	}
+	return m.postToChannel(channelID, entry, identity, item, &discordgo.MessageSend{
+		Content: content,
+		Embed:   channelEmbed,
+	})
+}
 
-	helpers.SendComplex(
-		channelID, &discordgo.MessageSend{
-			Content: content,
-			Embed:   channelEmbed,
-		})
+// postToChannel posts the tweet with the settings of the feed, as the account if the feed posts through a webhook
+func (m *Twitter) postToChannel(channelID string, entry models.TwitterEntry, identity helpers.FeedIdentity,
+	item helpers.FeedTemplateItem, data *discordgo.MessageSend) (err error) {
+	return helpers.FeedPostWithSettings(channelID, entry.MentionRoleID,
+		helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		identity, item, data)
 }
 
 func (m *Twitter) bestVideoVariant(videoVariants []twitter.VideoVariant) (bestVariant twitter.VideoVariant) {
@@ -1089,29 +1234,6 @@ func (m *Twitter) handleError(err error) string {
 	panic(err)
 }
 
-func (m *Twitter) lockEntry(entryID bson.ObjectId) {
-	twitterEntryLock.Lock()
-	defer twitterEntryLock.Unlock()
-
-	key := string(entryID)
-	if _, ok := twitterEntryLocks[key]; ok && twitterEntryLocks[key] != nil {
-		twitterEntryLocks[key].Lock()
-		return
-	}
-	twitterEntryLocks[key] = new(sync.Mutex)
-	twitterEntryLocks[key].Lock()
-}
-
-func (m *Twitter) unlockEntry(entryID bson.ObjectId) {
-	twitterEntryLock.Lock()
-	defer twitterEntryLock.Unlock()
-
-	key := string(entryID)
-	if _, ok := twitterEntryLocks[key]; ok && twitterEntryLocks[key] != nil {
-		twitterEntryLocks[key].Unlock()
-	}
-}
-
 func (t *Twitter) OnMessage(content string, msg *discordgo.Message, session *discordgo.Session) {
 
 }
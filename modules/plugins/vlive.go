@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
@@ -42,6 +43,11 @@ const (
 
 type VLive struct{}
 
+var (
+	vliveBundledEntries     map[string][]models.VliveEntry // [channel code]entries
+	vliveBundledEntriesLock sync.RWMutex
+)
+
 func (r *VLive) Commands() []string {
 	return []string{
 		"vlive",
@@ -49,196 +55,188 @@ func (r *VLive) Commands() []string {
 }
 
 func (r *VLive) Init(session *shardmanager.Manager) {
-	go r.checkVliveFeedsLoop()
-	cache.GetLogger().WithField("module", "vlive").Info("Started vlive loop (0s)")
+	helpers.RegisterFeedSource(r, metrics.VliveRefreshTime)
+}
+
+func (r *VLive) Name() string {
+	return models.FeedSourceVlive
+}
+
+func (r *VLive) Interval() time.Duration {
+	return 60 * time.Second
 }
-func (r *VLive) checkVliveFeedsLoop() {
+
+func (r *VLive) Workers() int {
+	return VLiveWorkers
+}
+
+func (r *VLive) Targets() (targets []string, err error) {
 	var entries []models.VliveEntry
-	var entriesLength int
-	var bundledEntries map[string][]models.VliveEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.VliveTable).Find(nil)).All(&entries)
+	if err != nil {
+		return nil, err
+	}
 
-	defer helpers.Recover()
-	defer func() {
-		go func() {
-			cache.GetLogger().WithField("module", "vlive").Error("The checkVliveFeedsLoop died. Please investigate! Will be restarted in 60 seconds")
-			time.Sleep(60 * time.Second)
-			r.checkVliveFeedsLoop()
-		}()
-	}()
+	bundledEntries := make(map[string][]models.VliveEntry)
+	for _, entry := range entries {
+		if !helpers.FeedCanPost(entry.ChannelID, entry.EmbedTemplate == "" || helpers.IsEmbedCode(entry.EmbedTemplate)) {
+			continue
+		}
 
-	for {
-		bundledEntries = make(map[string][]models.VliveEntry, 0)
+		bundledEntries[entry.VLiveChannel.Code] = append(bundledEntries[entry.VLiveChannel.Code], entry)
+	}
 
-		err := helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.VliveTable).Find(nil)).All(&entries)
-		helpers.Relax(err)
+	vliveBundledEntriesLock.Lock()
+	vliveBundledEntries = bundledEntries
+	vliveBundledEntriesLock.Unlock()
 
-		entriesLength = len(entries)
+	for code := range bundledEntries {
+		targets = append(targets, code)
+	}
+	return targets, nil
+}
 
-		for _, entry := range entries {
-			// check if channel exists
-			channel, err := helpers.GetChannelWithoutApi(entry.ChannelID)
-			if err != nil || channel == nil || channel.ID == "" {
+// Check posts the new VODs, upcoming videos, lives, notices, and celeb posts of the V Live channel
+func (r *VLive) Check(target string) (err error) {
+	vliveBundledEntriesLock.RLock()
+	entries := vliveBundledEntries[target]
+	vliveBundledEntriesLock.RUnlock()
+
+	updatedVliveChannel, err := r.getVLiveChannelByVliveChannelId(target)
+	if err != nil {
+		return err
+	}
+
+	// a failed post doesn't stop the other items from being posted, the last error is returned
+	var postErr error
+	for _, entry := range entries {
+		for _, vod := range updatedVliveChannel.VOD {
+			// don't post playlists
+			if vod.Type == "PLAYLIST" {
 				continue
 			}
-
-			// check if we can send messages
-			channelPermission, err := cache.GetSession().SessionForGuildS(entry.GuildID).State.UserChannelPermissions(cache.GetSession().SessionForGuildS(entry.GuildID).State.User.ID, channel.ID)
+			err = r.postItem(entry, fmt.Sprintf(models.VliveItemVOD, vod.Seq), func() error {
+				return r.postVodToChannel(entry, vod, updatedVliveChannel)
+			})
 			if err != nil {
-				continue
+				helpers.RelaxLog(err)
+				postErr = err
 			}
-
-			if channelPermission&discordgo.PermissionSendMessages != discordgo.PermissionSendMessages ||
-				channelPermission&discordgo.PermissionEmbedLinks != discordgo.PermissionEmbedLinks {
-				continue
+		}
+		for _, upcoming := range updatedVliveChannel.Upcoming {
+			err = r.postItem(entry, fmt.Sprintf(models.VliveItemUpcoming, upcoming.Seq), func() error {
+				return r.postUpcomingToChannel(entry, upcoming, updatedVliveChannel)
+			})
+			if err != nil {
+				helpers.RelaxLog(err)
+				postErr = err
 			}
-
-			if _, ok := bundledEntries[entry.VLiveChannel.Code]; ok {
-				bundledEntries[entry.VLiveChannel.Code] = append(bundledEntries[entry.VLiveChannel.Code], entry)
-			} else {
-				bundledEntries[entry.VLiveChannel.Code] = []models.VliveEntry{entry}
+		}
+		for _, live := range updatedVliveChannel.Live {
+			err = r.postItem(entry, fmt.Sprintf(models.VliveItemLive, live.Seq), func() error {
+				return r.postLiveToChannel(entry, live, updatedVliveChannel)
+			})
+			if err != nil {
+				helpers.RelaxLog(err)
+				postErr = err
 			}
 		}
-
-		entries = nil
-
-		cache.GetLogger().WithField("module", "vlive").Info(fmt.Sprintf("checking %d channels for %d feeds with %d workers", len(bundledEntries), len(entries), VLiveWorkers))
-		start := time.Now()
-
-		jobs := make(chan map[string][]models.VliveEntry, 0)
-		results := make(chan int, 0)
-
-		workerEntries := make(map[int]map[string][]models.VliveEntry, 0)
-		for w := 1; w <= VLiveWorkers; w++ {
-			go r.feedWorker(w, jobs, results)
-			workerEntries[w] = make(map[string][]models.VliveEntry)
+		for _, notice := range updatedVliveChannel.Notices {
+			err = r.postItem(entry, fmt.Sprintf(models.VliveItemNotice, notice.Number), func() error {
+				return r.postNoticeToChannel(entry, notice, updatedVliveChannel)
+			})
+			if err != nil {
+				helpers.RelaxLog(err)
+				postErr = err
+			}
 		}
-
-		lastWorker := 1
-		for code, codeEntries := range bundledEntries {
-			workerEntries[lastWorker][code] = codeEntries
-			lastWorker++
-			if lastWorker > VLiveWorkers {
-				lastWorker = 1
+		for _, celeb := range updatedVliveChannel.Celebs {
+			err = r.postItem(entry, fmt.Sprintf(models.VliveItemCeleb, celeb.ID), func() error {
+				return r.postCelebToChannel(entry, celeb, updatedVliveChannel)
+			})
+			if err != nil {
+				helpers.RelaxLog(err)
+				postErr = err
 			}
 		}
+	}
 
-		for _, workerEntry := range workerEntries {
-			jobs <- workerEntry
-		}
-		close(jobs)
+	return postErr
+}
 
-		for a := 1; a <= VLiveWorkers; a++ {
-			<-results
-		}
-		elapsed := time.Since(start)
-		cache.GetLogger().WithField("module", "vlive").Info(fmt.Sprintf("checked %d channels for %d feeds with %d workers, took %s", len(bundledEntries), len(entries), VLiveWorkers, elapsed))
-		metrics.VliveRefreshTime.Set(elapsed.Seconds())
+func (r *VLive) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.VliveEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.VliveTable).Find(bson.M{"guildid": guildID})).All(&entries)
+	if err != nil {
+		return nil, err
+	}
 
-		bundledEntries = nil
+	for _, entry := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:            helpers.MdbIdToHuman(entry.ID),
+			Name:          entry.VLiveChannel.Name,
+			ChannelID:     entry.ChannelID,
+			MentionRoleID: entry.MentionRoleID,
+			Settings:      helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		})
+	}
+	return feeds, nil
+}
 
-		if entriesLength <= 10 {
-			time.Sleep(60 * time.Second)
-		}
+func (r *VLive) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	return helpers.MDbUpdateQuery(models.VliveTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
+}
+
+// postItem posts the item if it hasn't been posted to the feed before
+func (r *VLive) postItem(entry models.VliveEntry, itemID string, post func() error) (err error) {
+	claimed, err := helpers.FeedClaimItem(models.FeedSourceVlive, entry.ID.Hex(), itemID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	err = post()
+	if err != nil {
+		helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceVlive, entry.ID.Hex(), itemID))
 	}
+	return err
 }
 
-func (r *VLive) feedWorker(id int, jobs <-chan map[string][]models.VliveEntry, results chan<- int) {
-	defer helpers.Recover()
+// postToChannel posts the item with the template of the entry, or the default message
+func (r *VLive) postToChannel(entry models.VliveEntry, vliveChannel models.VliveChannelInfo,
+	item helpers.FeedTemplateItem, defaultMessage *discordgo.MessageSend) (err error) {
+	return helpers.FeedPostWithSettings(entry.ChannelID, entry.MentionRoleID,
+		helpers.FeedSettings{EmbedTemplate: entry.EmbedTemplate, PostAsWebhook: entry.PostAsWebhook},
+		helpers.FeedIdentity{Name: vliveChannel.Name, AvatarURL: vliveChannel.ProfileImgUrl},
+		item, defaultMessage)
+}
 
-	for job := range jobs {
-		//cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Infof("worker %d started for %d channels", id, len(job))
-		for channelCode, entries := range job {
-			//cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("checking V Live Channel %s for %d channels", entries[0].VLiveChannel.Name, len(entries)))
-			updatedVliveChannel, err := r.getVLiveChannelByVliveChannelId(channelCode)
-			if err != nil {
-				cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Warnf("updating vlive channel %s failed: %s", channelCode, err.Error())
-				continue
-			}
-			for _, entry := range entries {
-				changes := false
+// claimItems marks all current items of the V Live channel as posted, used when adding a feed
+func (r *VLive) claimItems(entryID bson.ObjectId, vliveChannel models.VliveChannelInfo) (err error) {
+	var itemIDs []string
+	for _, vod := range vliveChannel.VOD {
+		itemIDs = append(itemIDs, fmt.Sprintf(models.VliveItemVOD, vod.Seq))
+	}
+	for _, upcoming := range vliveChannel.Upcoming {
+		itemIDs = append(itemIDs, fmt.Sprintf(models.VliveItemUpcoming, upcoming.Seq))
+	}
+	for _, live := range vliveChannel.Live {
+		itemIDs = append(itemIDs, fmt.Sprintf(models.VliveItemLive, live.Seq))
+	}
+	for _, notice := range vliveChannel.Notices {
+		itemIDs = append(itemIDs, fmt.Sprintf(models.VliveItemNotice, notice.Number))
+	}
+	for _, celeb := range vliveChannel.Celebs {
+		itemIDs = append(itemIDs, fmt.Sprintf(models.VliveItemCeleb, celeb.ID))
+	}
 
-				for _, vod := range updatedVliveChannel.VOD {
-					// don't post playlists
-					if vod.Type == "PLAYLIST" {
-						continue
-					}
-					videoAlreadyPosted := false
-					for _, postedVod := range entry.PostedVOD {
-						if postedVod.Seq == vod.Seq {
-							videoAlreadyPosted = true
-						}
-					}
-					if videoAlreadyPosted == false {
-						// cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("Posting VOD: #%d", vod.Seq))
-						entry.PostedVOD = append(entry.PostedVOD, vod)
-						changes = true
-						go r.postVodToChannel(entry, vod, updatedVliveChannel)
-					}
-				}
-				for _, upcoming := range updatedVliveChannel.Upcoming {
-					videoAlreadyPosted := false
-					for _, postedUpcoming := range entry.PostedUpcoming {
-						if postedUpcoming.Seq == upcoming.Seq {
-							videoAlreadyPosted = true
-						}
-					}
-					if videoAlreadyPosted == false {
-						// cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("Posting Upcoming: #%d", upcoming.Seq))
-						entry.PostedUpcoming = append(entry.PostedUpcoming, upcoming)
-						changes = true
-						go r.postUpcomingToChannel(entry, upcoming, updatedVliveChannel)
-					}
-				}
-				for _, live := range updatedVliveChannel.Live {
-					videoAlreadyPosted := false
-					for _, postedLive := range entry.PostedLive {
-						if postedLive.Seq == live.Seq {
-							videoAlreadyPosted = true
-						}
-					}
-					if videoAlreadyPosted == false {
-						// cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("Posting Live: #%d", live.Seq))
-						entry.PostedLive = append(entry.PostedLive, live)
-						changes = true
-						go r.postLiveToChannel(entry, live, updatedVliveChannel)
-					}
-				}
-				for _, notice := range updatedVliveChannel.Notices {
-					noticeAlreadyPosted := false
-					for _, postedNotice := range entry.PostedNotices {
-						if postedNotice.Number == notice.Number {
-							noticeAlreadyPosted = true
-						}
-					}
-					if noticeAlreadyPosted == false {
-						// cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("Posting Notice: #%d", notice.Number))
-						entry.PostedNotices = append(entry.PostedNotices, notice)
-						changes = true
-						go r.postNoticeToChannel(entry, notice, updatedVliveChannel)
-					}
-				}
-				for _, celeb := range updatedVliveChannel.Celebs {
-					celebAlreadyPosted := false
-					for _, postedCeleb := range entry.PostedCelebs {
-						if postedCeleb.ID == celeb.ID {
-							celebAlreadyPosted = true
-						}
-					}
-					if celebAlreadyPosted == false {
-						// cache.GetLogger().WithField("module", "vlive").WithField("worker", id).Info(fmt.Sprintf("Posting Celeb: #%s", celeb.ID))
-						entry.PostedCelebs = append(entry.PostedCelebs, celeb)
-						changes = true
-						go r.postCelebToChannel(entry, celeb, updatedVliveChannel)
-					}
-				}
-				if changes == true {
-					err = helpers.MDbUpdateWithoutLogging(models.VliveTable, entry.ID, entry)
-					helpers.Relax(err)
-				}
-			}
+	for _, itemID := range itemIDs {
+		_, err = helpers.FeedClaimItem(models.FeedSourceVlive, entryID.Hex(), itemID)
+		if err != nil {
+			return err
 		}
 	}
-	results <- len(jobs)
+	return nil
 }
 
 func (r *VLive) Action(command string, content string, msg *discordgo.Message, session *discordgo.Session) {
@@ -312,18 +310,16 @@ func (r *VLive) Action(command string, content string, msg *discordgo.Message, s
 				}
 				// create new entry in db
 				newID, err := helpers.MDbInsert(models.VliveTable, models.VliveEntry{
-					GuildID:        targetChannel.GuildID,
-					ChannelID:      targetChannel.ID,
-					VLiveChannel:   vliveChannel,
-					PostedVOD:      vliveChannel.VOD,
-					PostedUpcoming: vliveChannel.Upcoming,
-					PostedLive:     vliveChannel.Live,
-					PostedNotices:  vliveChannel.Notices,
-					PostedCelebs:   vliveChannel.Celebs,
-					MentionRoleID:  mentionRole.ID,
+					GuildID:       targetChannel.GuildID,
+					ChannelID:     targetChannel.ID,
+					VLiveChannel:  vliveChannel,
+					MentionRoleID: mentionRole.ID,
 				})
 				helpers.Relax(err)
 
+				err = r.claimItems(newID, vliveChannel)
+				helpers.Relax(err)
+
 				_, err = helpers.EventlogLog(time.Now(), targetChannel.GuildID, helpers.MdbIdToHuman(newID),
 					models.EventlogTargetTypeRobyulVliveFeed, msg.Author.ID,
 					models.EventlogTypeRobyulVliveFeedAdd, "",
@@ -383,6 +379,9 @@ func (r *VLive) Action(command string, content string, msg *discordgo.Message, s
 					err = helpers.MDbDelete(models.VliveTable, entryBucket.ID)
 					helpers.Relax(err)
 
+					err = helpers.FeedDeleteItems(models.FeedSourceVlive, entryBucket.ID.Hex())
+					helpers.RelaxLog(err)
+
 					_, err = helpers.EventlogLog(time.Now(), channel.GuildID, entryId,
 						models.EventlogTargetTypeRobyulVliveFeed, msg.Author.ID,
 						models.EventlogTypeRobyulVliveFeedRemove, "",
@@ -698,7 +697,7 @@ func (r *VLive) getVLiveChannelByVliveChannelId(channelId string) (models.VliveC
 	return vliveChannel, nil
 }
 
-func (r *VLive) postVodToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) {
+func (r *VLive) postVodToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) (err error) {
 	channelEmbed := &discordgo.MessageEmbed{
 		Title:     helpers.GetTextF("plugins.vlive.channel-embed-title-vod", vliveChannel.Name),
 		URL:       vod.Url,
@@ -711,20 +710,19 @@ func (r *VLive) postVodToChannel(entry models.VliveEntry, vod models.VliveVideoI
 		Image:       &discordgo.MessageEmbedImage{URL: vod.Thumbnail},
 		Color:       helpers.GetDiscordColorFromHex(vliveChannel.Color),
 	}
-	mentionText := ""
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
+	item := helpers.FeedTemplateItem{
+		Title:     vod.Title,
+		URL:       vod.Url,
+		Author:    vliveChannel.Name,
+		Thumbnail: vod.Thumbnail,
 	}
-	helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + fmt.Sprintf("<%s>", vod.Url),
+	return r.postToChannel(entry, vliveChannel, item, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<%s>", vod.Url),
 		Embed:   channelEmbed,
 	})
-	// if err != nil {
-	//	 cache.GetLogger().WithField("module", "vlive").Warnf("posting vod: #%d to channel: #%s failed: %s", vod.Seq, entry.ChannelID, err)
-	// }
 }
 
-func (r *VLive) postUpcomingToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) {
+func (r *VLive) postUpcomingToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) (err error) {
 	channelEmbed := &discordgo.MessageEmbed{
 		Title:     helpers.GetTextF("plugins.vlive.channel-embed-title-upcoming", vliveChannel.Name, vod.Date),
 		URL:       vliveChannel.Url,
@@ -737,21 +735,20 @@ func (r *VLive) postUpcomingToChannel(entry models.VliveEntry, vod models.VliveV
 		Image:       &discordgo.MessageEmbedImage{URL: vod.Thumbnail},
 		Color:       helpers.GetDiscordColorFromHex(vliveChannel.Color),
 	}
-	mentionText := ""
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
-	}
 	postText := fmt.Sprintf("<%s>", vliveChannel.Url)
-	helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + postText,
+	item := helpers.FeedTemplateItem{
+		Title:     vod.Title,
+		URL:       vliveChannel.Url,
+		Author:    vliveChannel.Name,
+		Thumbnail: vod.Thumbnail,
+	}
+	return r.postToChannel(entry, vliveChannel, item, &discordgo.MessageSend{
+		Content: postText,
 		Embed:   channelEmbed,
 	})
-	// if err != nil {
-	// 	cache.GetLogger().WithField("module", "vlive").Warnf("posting upcoming: #%d to channel: #%s failed: %s", vod.Seq, entry.ChannelID, err)
-	// }
 }
 
-func (r *VLive) postLiveToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) {
+func (r *VLive) postLiveToChannel(entry models.VliveEntry, vod models.VliveVideoInfo, vliveChannel models.VliveChannelInfo) (err error) {
 	channelEmbed := &discordgo.MessageEmbed{
 		Title:     helpers.GetTextF("plugins.vlive.channel-embed-title-live", vliveChannel.Name),
 		URL:       vod.Url,
@@ -764,20 +761,19 @@ func (r *VLive) postLiveToChannel(entry models.VliveEntry, vod models.VliveVideo
 		Image:       &discordgo.MessageEmbedImage{URL: vod.Thumbnail},
 		Color:       helpers.GetDiscordColorFromHex(vliveChannel.Color),
 	}
-	mentionText := ""
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
+	item := helpers.FeedTemplateItem{
+		Title:     vod.Title,
+		URL:       vod.Url,
+		Author:    vliveChannel.Name,
+		Thumbnail: vod.Thumbnail,
 	}
-	helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + fmt.Sprintf("<%s>", vod.Url),
+	return r.postToChannel(entry, vliveChannel, item, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<%s>", vod.Url),
 		Embed:   channelEmbed,
 	})
-	// if err != nil {
-	// 	cache.GetLogger().WithField("module", "vlive").Warnf("posting live: #%d to channel: #%s failed: %s", vod.Seq, entry.ChannelID, err)
-	// }
 }
 
-func (r *VLive) postNoticeToChannel(entry models.VliveEntry, notice models.VliveNoticeInfo, vliveChannel models.VliveChannelInfo) {
+func (r *VLive) postNoticeToChannel(entry models.VliveEntry, notice models.VliveNoticeInfo, vliveChannel models.VliveChannelInfo) (err error) {
 	channelEmbed := &discordgo.MessageEmbed{
 		Title:     helpers.GetTextF("plugins.vlive.channel-embed-title-notice", vliveChannel.Name),
 		URL:       notice.Url,
@@ -790,20 +786,20 @@ func (r *VLive) postNoticeToChannel(entry models.VliveEntry, notice models.Vlive
 		Image:       &discordgo.MessageEmbedImage{URL: notice.ImageUrl},
 		Color:       helpers.GetDiscordColorFromHex(vliveChannel.Color),
 	}
-	mentionText := ""
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
+	item := helpers.FeedTemplateItem{
+		Title:       notice.Title,
+		URL:         notice.Url,
+		Author:      vliveChannel.Name,
+		Thumbnail:   notice.ImageUrl,
+		Description: notice.Summary,
 	}
-	helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + fmt.Sprintf("<%s>", notice.Url),
+	return r.postToChannel(entry, vliveChannel, item, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<%s>", notice.Url),
 		Embed:   channelEmbed,
 	})
-	// if err != nil {
-	// 	cache.GetLogger().WithField("module", "vlive").Warnf("posting notice: #%d to channel: #%s failed: %s", notice.Number, entry.ChannelID, err)
-	// }
 }
 
-func (r *VLive) postCelebToChannel(entry models.VliveEntry, celeb models.VliveCelebInfo, vliveChannel models.VliveChannelInfo) {
+func (r *VLive) postCelebToChannel(entry models.VliveEntry, celeb models.VliveCelebInfo, vliveChannel models.VliveChannelInfo) (err error) {
 	channelEmbed := &discordgo.MessageEmbed{
 		Title:     helpers.GetTextF("plugins.vlive.channel-embed-title-celeb", vliveChannel.Name),
 		URL:       celeb.Url,
@@ -815,15 +811,14 @@ func (r *VLive) postCelebToChannel(entry models.VliveEntry, celeb models.VliveCe
 		Description: fmt.Sprintf("%s ...", celeb.Summary),
 		Color:       helpers.GetDiscordColorFromHex(vliveChannel.Color),
 	}
-	mentionText := ""
-	if entry.MentionRoleID != "" {
-		mentionText = fmt.Sprintf("<@&%s>\n", entry.MentionRoleID)
+	item := helpers.FeedTemplateItem{
+		Title:       channelEmbed.Title,
+		URL:         celeb.Url,
+		Author:      vliveChannel.Name,
+		Description: celeb.Summary,
 	}
-	helpers.SendComplex(entry.ChannelID, &discordgo.MessageSend{
-		Content: mentionText + fmt.Sprintf("<%s>", celeb.Url),
+	return r.postToChannel(entry, vliveChannel, item, &discordgo.MessageSend{
+		Content: fmt.Sprintf("<%s>", celeb.Url),
 		Embed:   channelEmbed,
 	})
-	// if err != nil {
-	// 	cache.GetLogger().WithField("module", "vlive").Warnf("posting celeb: #%s to channel: #%s failed: %s", celeb.ID, entry.ChannelID, err)
-	// }
 }
@@ -2,14 +2,13 @@ package youtube
 
 import (
 	"fmt"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"gopkg.in/mgo.v2/bson"
 
 	youtubeService "github.com/Seklfreak/Robyul2/services/youtube"
 
-	"github.com/Seklfreak/Robyul2/cache"
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/models"
 	"github.com/bwmarrin/discordgo"
@@ -17,86 +16,129 @@ import (
 )
 
 type feeds struct {
-	service *youtubeService.Service
-	running uint32
+	service  *youtubeService.Service
+	websub   *youtubeService.WebSub // nil if push notifications aren't configured
+	register sync.Once              // Init is called again by _yt system restart
+
+	avatars     map[string]channelAvatar // [YouTube channel ID], used for feeds posted through webhooks
+	avatarsLock sync.Mutex
+}
+
+type channelAvatar struct {
+	URL       string
+	FetchedAt time.Time
 }
 
+const (
+	channelAvatarCacheDuration = 24 * time.Hour
+)
+
 func (f *feeds) Init(e *youtubeService.Service) {
 	if e == nil {
 		helpers.Relax(fmt.Errorf("feeds loop initialize failed"))
 	}
 	f.service = e
+	f.websub = newWebSubFromConfig()
 
-	f.start()
-}
-
-func (f *feeds) start() {
-	// Checks if feeds loop is already running
-	if atomic.SwapUint32(&f.running, uint32(1)) == 1 {
-		logger().Error("feeds loop already running")
-		return
-	}
+	websubFeedsLock.Lock()
+	websubFeeds = f
+	websubFeedsLock.Unlock()
 
-	go f.run()
+	f.register.Do(func() {
+		helpers.RegisterFeedSource(f, nil)
+		go f.websubLoop()
+	})
 }
 
-func (f *feeds) run() {
-	defer helpers.Recover()
-	defer func() {
-		// Atomically set 'running' variable to 0(false)
-		atomic.StoreUint32(&f.running, uint32(0))
-
-		logger().Error("The feeds loop died. Please investigate! Will be restarted in 60 seconds")
-		time.Sleep(60 * time.Second)
+func (f *feeds) Name() string {
+	return models.FeedSourceYoutube
+}
 
-		f.start()
-	}()
+func (f *feeds) Interval() time.Duration {
+	return 10 * time.Second
+}
 
-	for ; ; time.Sleep(10 * time.Second) {
-		err := f.service.UpdateCheckingInterval()
-		helpers.Relax(err)
+func (f *feeds) Workers() int {
+	return 1
+}
 
-		f.check()
+// Targets returns the IDs of all feeds whose next check time has been reached
+func (f *feeds) Targets() (targets []string, err error) {
+	err = f.service.UpdateCheckingInterval()
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (f *feeds) check() {
-	t := time.Now().Unix()
 	var entries []models.YoutubeChannelEntry
-	err := helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(
-		bson.M{"nextchecktime": bson.M{"$lte": t}},
-	)).All(&entries)
-	helpers.Relax(err)
-	// TODO: test
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(
+		bson.M{"nextchecktime": bson.M{"$lte": time.Now().Unix()}},
+	).Select(bson.M{"_id": 1})).All(&entries)
+	if err != nil {
+		return nil, err
+	}
 
 	for _, e := range entries {
-		e = f.checkChannelFeeds(e)
-
-		// update next check time
-		e = f.setNextCheckTime(e)
-		err = helpers.MDbUpdateWithoutLogging(models.YoutubeChannelTable, e.ID, e)
-		helpers.Relax(err)
+		targets = append(targets, helpers.MdbIdToHuman(e.ID))
 	}
+	return targets, nil
 }
 
-func (f *feeds) checkChannelFeeds(e models.YoutubeChannelEntry) models.YoutubeChannelEntry {
-	// check if we have access to channel
-	channel, err := helpers.GetChannelWithoutApi(e.ChannelID)
-	if err != nil || channel == nil || channel.ID == "" {
-		return e
+func (f *feeds) Check(target string) (err error) {
+	var e models.YoutubeChannelEntry
+	err = helpers.MdbOneWithoutLogging(
+		helpers.MdbCollection(models.YoutubeChannelTable).Find(bson.M{"_id": helpers.HumanToMdbId(target)}),
+		&e,
+	)
+	if err != nil {
+		if helpers.IsMdbNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	update := bson.M{"nextchecktime": f.getNextCheckTime()}
+	if !f.isPushed(e.YoutubeChannelID) &&
+		helpers.FeedCanPost(e.ChannelID, e.EmbedTemplate == "" || helpers.IsEmbedCode(e.EmbedTemplate)) {
+		err = f.checkChannelFeeds(e)
+		if err == nil {
+			update["lastsuccessfulchecktime"] = e.NextCheckTime
+		}
 	}
 
-	// check if we can send messages and embed links in channel
-	channelPermission, err := cache.GetSession().SessionForGuildS(channel.GuildID).State.UserChannelPermissions(cache.GetSession().SessionForGuildS(channel.GuildID).State.User.ID, channel.ID)
+	updateErr := helpers.MDbUpdateQueryWithoutLogging(models.YoutubeChannelTable,
+		bson.M{"_id": e.ID}, bson.M{"$set": update})
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+func (f *feeds) GuildFeeds(guildID string) (feeds []helpers.FeedInfo, err error) {
+	var entries []models.YoutubeChannelEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(
+		bson.M{"guildid": guildID},
+	)).All(&entries)
 	if err != nil {
-		return e
+		return nil, err
 	}
 
-	if channelPermission&discordgo.PermissionSendMessages != discordgo.PermissionSendMessages ||
-		channelPermission&discordgo.PermissionEmbedLinks != discordgo.PermissionEmbedLinks {
-		return e
+	for _, e := range entries {
+		feeds = append(feeds, helpers.FeedInfo{
+			ID:        helpers.MdbIdToHuman(e.ID),
+			Name:      e.YoutubeChannelID,
+			ChannelID: e.ChannelID,
+			Settings:  helpers.FeedSettings{EmbedTemplate: e.EmbedTemplate, PostAsWebhook: e.PostAsWebhook},
+		})
 	}
+	return feeds, nil
+}
+
+func (f *feeds) SetFeedSettings(feedID string, settings helpers.FeedSettings) (err error) {
+	return helpers.MDbUpdateQuery(models.YoutubeChannelTable, bson.M{"_id": helpers.HumanToMdbId(feedID)},
+		bson.M{"$set": bson.M{"embedtemplate": settings.EmbedTemplate, "postaswebhook": settings.PostAsWebhook}})
+}
 
+func (f *feeds) checkChannelFeeds(e models.YoutubeChannelEntry) (err error) {
 	// set iso8601 time which will be used search query filter "published after"
 	lastSuccessfulCheckTime := time.Unix(e.LastSuccessfulCheckTime, 0)
 	publishedAfter := lastSuccessfulCheckTime.
@@ -107,12 +149,9 @@ func (f *feeds) checkChannelFeeds(e models.YoutubeChannelEntry) models.YoutubeCh
 	feeds, err := f.service.GetChannelFeeds(e.YoutubeChannelID, publishedAfter)
 	if err != nil {
 		logger().Warn("check channel feeds error: " + err.Error() + " id: " + e.YoutubeChannelID)
-		return e
+		return err
 	}
 
-	newPostedVideos := make([]string, 0)
-	alreadyPostedVideos := make([]string, 0)
-
 	// check if posted videos and post new videos
 	for i := len(feeds) - 1; i >= 0; i-- {
 		feed := feeds[i]
@@ -124,64 +163,98 @@ func (f *feeds) checkChannelFeeds(e models.YoutubeChannelEntry) models.YoutubeCh
 		videoId := feed.ContentDetails.Upload.VideoId
 
 		// check if the video is already posted
-		if f.isPosted(videoId, e.YoutubePostedVideos) {
-			alreadyPostedVideos = append(alreadyPostedVideos, videoId)
-			continue
+		claimed, err := helpers.FeedClaimItem(models.FeedSourceYoutube, e.ID.Hex(), videoId)
+		if err != nil {
+			return err
 		}
-
-		// make a message and send to discord channel
-		msg := &discordgo.MessageSend{
-			Content: fmt.Sprintf(youtubeVideoBaseUrl, videoId),
-			Embed: &discordgo.MessageEmbed{
-				Author: &discordgo.MessageEmbedAuthor{
-					Name: feed.Snippet.ChannelTitle,
-					URL:  fmt.Sprintf(youtubeChannelBaseUrl, feed.Snippet.ChannelId),
-				},
-				Title:       helpers.GetTextF("plugins.youtube.channel-embed-title-vod", feed.Snippet.ChannelTitle),
-				URL:         fmt.Sprintf(youtubeVideoBaseUrl, videoId),
-				Description: fmt.Sprintf("**%s**", feed.Snippet.Title),
-				Image:       &discordgo.MessageEmbedImage{URL: feed.Snippet.Thumbnails.High.Url},
-				Footer:      &discordgo.MessageEmbedFooter{Text: "YouTube"},
-				Color:       helpers.GetDiscordColorFromHex(youtubeColor),
-			},
+		if !claimed {
+			continue
 		}
 
-		_, err = helpers.SendComplex(e.ChannelID, msg)
+		err = f.postVideo(e, videoId, feed.Snippet.ChannelId, feed.Snippet.ChannelTitle,
+			feed.Snippet.Title, feed.Snippet.Thumbnails.High.Url)
 		if err != nil {
-			logger().Warn(err)
-			break
+			return err
 		}
+	}
 
-		newPostedVideos = append(newPostedVideos, videoId)
+	return nil
+}
 
-		logger().WithFields(logrus.Fields{
-			"title":   feed.Snippet.Title,
-			"channel": e.ChannelID,
-		}).Info("posting video")
+// postVideo sends the claimed video to the discord channel of the entry, and releases it again if that fails
+func (f *feeds) postVideo(e models.YoutubeChannelEntry, videoId, channelId, channelTitle, title, thumbnailUrl string) (err error) {
+	// make a message and send to discord channel
+	msg := &discordgo.MessageSend{
+		Content: fmt.Sprintf(youtubeVideoBaseUrl, videoId),
+		Embed: &discordgo.MessageEmbed{
+			Author: &discordgo.MessageEmbedAuthor{
+				Name: channelTitle,
+				URL:  fmt.Sprintf(youtubeChannelBaseUrl, channelId),
+			},
+			Title:       helpers.GetTextF("plugins.youtube.channel-embed-title-vod", channelTitle),
+			URL:         fmt.Sprintf(youtubeVideoBaseUrl, videoId),
+			Description: fmt.Sprintf("**%s**", title),
+			Image:       &discordgo.MessageEmbedImage{URL: thumbnailUrl},
+			Footer:      &discordgo.MessageEmbedFooter{Text: "YouTube"},
+			Color:       helpers.GetDiscordColorFromHex(youtubeColor),
+		},
 	}
 
-	if err == nil {
-		e.LastSuccessfulCheckTime = e.NextCheckTime
-		e.YoutubePostedVideos = alreadyPostedVideos
+	item := helpers.FeedTemplateItem{
+		Title:     title,
+		URL:       fmt.Sprintf(youtubeVideoBaseUrl, videoId),
+		Author:    channelTitle,
+		Thumbnail: thumbnailUrl,
+	}
+	identity := helpers.FeedIdentity{Name: channelTitle}
+	if e.PostAsWebhook {
+		identity.AvatarURL = f.getChannelAvatar(channelId)
+	}
+	err = helpers.FeedPostWithSettings(e.ChannelID, "",
+		helpers.FeedSettings{EmbedTemplate: e.EmbedTemplate, PostAsWebhook: e.PostAsWebhook},
+		identity,
+		item, msg)
+	if err != nil {
+		logger().Warn(err)
+		helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceYoutube, e.ID.Hex(), videoId))
+		return err
 	}
-	e.YoutubePostedVideos = append(e.YoutubePostedVideos, newPostedVideos...)
 
-	return e
+	logger().WithFields(logrus.Fields{
+		"title":   title,
+		"channel": e.ChannelID,
+	}).Info("posting video")
+	return nil
 }
 
-func (f *feeds) setNextCheckTime(e models.YoutubeChannelEntry) models.YoutubeChannelEntry {
-	e.NextCheckTime = time.Now().
-		Add(time.Duration(f.service.GetCheckingInterval()) * time.Second).
-		Unix()
+// getChannelAvatar returns the avatar of the YouTube channel, or an empty string if it can't be requested
+// avatars are cached, a channel can be followed by many feeds
+func (f *feeds) getChannelAvatar(channelId string) (avatarURL string) {
+	f.avatarsLock.Lock()
+	defer f.avatarsLock.Unlock()
 
-	return e
-}
+	if avatar, ok := f.avatars[channelId]; ok && time.Since(avatar.FetchedAt) < channelAvatarCacheDuration {
+		return avatar.URL
+	}
 
-func (f *feeds) isPosted(id string, postedIds []string) bool {
-	for _, posted := range postedIds {
-		if id == posted {
-			return true
-		}
+	channel, err := f.service.GetChannelSingle(channelId)
+	if err != nil {
+		logger().WithField("channel", channelId).Warnf("failed to get channel avatar: %s", err.Error())
+		return ""
 	}
-	return false
+	if channel != nil && channel.Snippet != nil && channel.Snippet.Thumbnails != nil && channel.Snippet.Thumbnails.High != nil {
+		avatarURL = channel.Snippet.Thumbnails.High.Url
+	}
+
+	if f.avatars == nil {
+		f.avatars = make(map[string]channelAvatar)
+	}
+	f.avatars[channelId] = channelAvatar{URL: avatarURL, FetchedAt: time.Now()}
+	return avatarURL
+}
+
+func (f *feeds) getNextCheckTime() int64 {
+	return time.Now().
+		Add(time.Duration(f.service.GetCheckingInterval()) * time.Second).
+		Unix()
 }
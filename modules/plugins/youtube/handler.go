@@ -304,6 +304,9 @@ func (h *Handler) actionDeleteChannel(args []string, in *discordgo.Message, out
 		return h.actionFinish
 	}
 
+	err = helpers.FeedDeleteItems(models.FeedSourceYoutube, entryBucket.ID.Hex())
+	helpers.RelaxLog(err)
+
 	h.service.DecQuotaEntryCount()
 
 	_, err = helpers.EventlogLog(time.Now(), channel.GuildID, args[2],
@@ -0,0 +1,424 @@
+package youtube
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	youtubeService "github.com/Seklfreak/Robyul2/services/youtube"
+	youtubeAPI "google.golang.org/api/youtube/v3"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	websubLease         = 5 * 24 * time.Hour
+	websubRenewBefore   = 24 * time.Hour
+	websubRetryInterval = time.Hour // the hub didn't verify the previous request in time
+	websubLoopInterval  = 10 * time.Minute
+)
+
+var (
+	websubFeeds     *feeds
+	websubFeedsLock sync.RWMutex
+
+	errWebSubDisabled            = errors.New("youtube websub is not configured")
+	errWebSubUnknownSubscription = errors.New("unknown websub subscription")
+)
+
+// newWebSubFromConfig returns nil if youtube.websub_callback_url or youtube.websub_secret aren't set,
+// all channels are polled then
+func newWebSubFromConfig() *youtubeService.WebSub {
+	config := helpers.GetConfig()
+	if !config.ExistsP("youtube.websub_callback_url") || !config.ExistsP("youtube.websub_secret") {
+		return nil
+	}
+
+	callbackURL, _ := config.Path("youtube.websub_callback_url").Data().(string)
+	secret, _ := config.Path("youtube.websub_secret").Data().(string)
+	if callbackURL == "" || secret == "" {
+		return nil
+	}
+
+	return youtubeService.NewWebSub(youtubeService.WebSubHubURL, callbackURL, secret)
+}
+
+func getWebSubFeeds() (f *feeds, err error) {
+	websubFeedsLock.RLock()
+	defer websubFeedsLock.RUnlock()
+
+	if websubFeeds == nil || websubFeeds.websub == nil {
+		return nil, errWebSubDisabled
+	}
+	return websubFeeds, nil
+}
+
+// WebSubVerify returns the challenge to answer the verification of intent of the hub with,
+// only requests we sent recently are confirmed, and subscriptions only for channels which still have feeds
+func WebSubVerify(token string, query url.Values) (challenge string, err error) {
+	_, err = getWebSubFeeds()
+	if err != nil {
+		return "", err
+	}
+
+	channelID, err := youtubeService.WebSubTopicChannelID(query.Get("hub.topic"))
+	if err != nil {
+		return "", err
+	}
+
+	subscription, err := findWebSubSubscription(channelID, token)
+	if err != nil {
+		return "", err
+	}
+
+	mode := query.Get("hub.mode")
+	if mode == "denied" {
+		// the channel will be polled, and the subscription requested again after websubRetryInterval
+		logger().WithField("channel", channelID).Warn("hub denied subscription: " + query.Get("hub.reason"))
+		return "", helpers.MDbUpdateQueryWithoutLogging(models.YoutubeWebSubTable,
+			bson.M{"_id": subscription.ID},
+			bson.M{"$set": bson.M{"leaseexpiresat": time.Time{}}},
+		)
+	}
+
+	if subscription.RequestedAt.Before(time.Now().Add(-websubRetryInterval)) {
+		return "", errors.New("no pending websub request for channel " + channelID)
+	}
+
+	challenge = query.Get("hub.challenge")
+	if challenge == "" {
+		return "", errors.New("missing websub challenge")
+	}
+
+	feedsCount, err := helpers.MdbCollection(models.YoutubeChannelTable).Find(
+		bson.M{"youtubechannelid": channelID},
+	).Count()
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case "subscribe":
+		if feedsCount <= 0 {
+			return "", errors.New("no feeds for channel " + channelID)
+		}
+
+		leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+		if err != nil || leaseSeconds <= 0 {
+			return "", errors.New("invalid websub lease")
+		}
+		lease := time.Duration(leaseSeconds) * time.Second
+		if lease > websubLease {
+			lease = websubLease
+		}
+
+		// the request is answered, so it can't be confirmed a second time
+		err = helpers.MDbUpdateQueryWithoutLogging(models.YoutubeWebSubTable,
+			bson.M{"_id": subscription.ID},
+			bson.M{"$set": bson.M{"leaseexpiresat": time.Now().Add(lease), "requestedat": time.Time{}}},
+		)
+		if err != nil {
+			return "", err
+		}
+	case "unsubscribe":
+		if feedsCount > 0 {
+			return "", errors.New("channel " + channelID + " still has feeds")
+		}
+
+		err = helpers.MDbDeleteWithoutLogging(models.YoutubeWebSubTable, subscription.ID)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", errors.New("invalid websub mode")
+	}
+
+	return challenge, nil
+}
+
+// WebSubNotify posts the videos of a push notification of the hub,
+// returns youtubeService.ErrWebSubInvalidSignature if it hasn't been signed with our secret
+func WebSubNotify(token string, body []byte, signature string) (err error) {
+	f, err := getWebSubFeeds()
+	if err != nil {
+		return err
+	}
+
+	err = f.websub.VerifySignature(body, signature)
+	if err != nil {
+		return err
+	}
+
+	videos, err := youtubeService.ParseWebSubNotification(body)
+	if err != nil {
+		return err
+	}
+
+	for _, video := range videos {
+		// the token of the callback URL has to belong to the channel of the video
+		_, err = findWebSubSubscription(video.ChannelID, token)
+		if err != nil {
+			logger().WithField("channel", video.ChannelID).Warn("ignored websub notification: " + err.Error())
+			continue
+		}
+
+		err = f.postPushedVideo(video)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findWebSubSubscription returns the subscription of the channel if the token matches
+// WebSubCheckToken returns an error if no subscription uses the token of the callback URL,
+// so requests to unknown callbacks can be refused before reading them
+func WebSubCheckToken(token string) (err error) {
+	_, err = getWebSubFeeds()
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		return errWebSubUnknownSubscription
+	}
+	count, err := helpers.MdbCount(models.YoutubeWebSubTable, bson.M{"token": token})
+	if err != nil {
+		return err
+	}
+	if count <= 0 {
+		return errWebSubUnknownSubscription
+	}
+	return nil
+}
+
+func findWebSubSubscription(channelID, token string) (subscription models.YoutubeWebSubEntry, err error) {
+	err = helpers.MdbOneWithoutLogging(
+		helpers.MdbCollection(models.YoutubeWebSubTable).Find(bson.M{"youtubechannelid": channelID}),
+		&subscription,
+	)
+	if err != nil {
+		if helpers.IsMdbNotFound(err) {
+			return subscription, errWebSubUnknownSubscription
+		}
+		return subscription, err
+	}
+
+	if subscription.Token == "" || subtle.ConstantTimeCompare([]byte(subscription.Token), []byte(token)) != 1 {
+		return subscription, errWebSubUnknownSubscription
+	}
+	return subscription, nil
+}
+
+// postPushedVideo posts the video to all feeds of its channel which haven't posted it yet,
+// the details are only looked up if at least one feed claimed it
+func (f *feeds) postPushedVideo(video youtubeService.WebSubVideo) (err error) {
+	var entries []models.YoutubeChannelEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(
+		bson.M{"youtubechannelid": video.ChannelID},
+	)).All(&entries)
+	if err != nil {
+		return err
+	}
+
+	var details *youtubeAPI.Video
+	for _, e := range entries {
+		if !helpers.FeedCanPost(e.ChannelID, e.EmbedTemplate == "" || helpers.IsEmbedCode(e.EmbedTemplate)) {
+			continue
+		}
+
+		// the hub also pushes title changes of old videos, polling uses the same window
+		if !video.Published.IsZero() &&
+			video.Published.Before(time.Unix(e.LastSuccessfulCheckTime, 0).Add(-1*time.Hour)) {
+			continue
+		}
+
+		claimed, err := helpers.FeedClaimItem(models.FeedSourceYoutube, e.ID.Hex(), video.VideoID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		if details == nil {
+			details, err = f.service.GetVideoSingle(video.VideoID)
+			if err != nil || details == nil {
+				helpers.RelaxLog(helpers.FeedReleaseItem(models.FeedSourceYoutube, e.ID.Hex(), video.VideoID))
+				// private or deleted before we got to it
+				return err
+			}
+		}
+
+		err = f.postVideo(e, details.Id, details.Snippet.ChannelId, details.Snippet.ChannelTitle,
+			details.Snippet.Title, details.Snippet.Thumbnails.High.Url)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isPushed returns true if the hub pushes the uploads of the channel, polling is the fallback otherwise
+func (f *feeds) isPushed(channelID string) bool {
+	if f.websub == nil {
+		return false
+	}
+
+	count, err := helpers.MdbCollection(models.YoutubeWebSubTable).Find(bson.M{
+		"youtubechannelid": channelID,
+		"leaseexpiresat":   bson.M{"$gt": time.Now()},
+	}).Count()
+	return err == nil && count > 0
+}
+
+func (f *feeds) websubLoop() {
+	defer helpers.Recover()
+	defer func() {
+		go func() {
+			logger().Error("the websubLoop died. Please investigate! Will be restarted in 60 seconds")
+			time.Sleep(60 * time.Second)
+			f.websubLoop()
+		}()
+	}()
+
+	for {
+		if f.websub != nil {
+			err := f.renewWebSubSubscriptions()
+			if err != nil {
+				logger().WithError(err).Warn("renewing websub subscriptions failed")
+			}
+		}
+
+		time.Sleep(websubLoopInterval)
+	}
+}
+
+// renewWebSubSubscriptions subscribes to new channels, renews leases before they expire,
+// and unsubscribes from channels without feeds
+func (f *feeds) renewWebSubSubscriptions() (err error) {
+	var entries []models.YoutubeChannelEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeChannelTable).Find(nil).
+		Select(bson.M{"youtubechannelid": 1})).All(&entries)
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []models.YoutubeWebSubEntry
+	err = helpers.MDbIterWithoutLogging(helpers.MdbCollection(models.YoutubeWebSubTable).Find(nil)).All(&subscriptions)
+	if err != nil {
+		return err
+	}
+
+	channelIDs := make(map[string]bool)
+	for _, e := range entries {
+		if e.YoutubeChannelID != "" {
+			channelIDs[e.YoutubeChannelID] = true
+		}
+	}
+
+	now := time.Now()
+	subscribed := make(map[string]bool)
+	pushed := make(map[string]bool)
+	for _, subscription := range subscriptions {
+		if !channelIDs[subscription.YoutubeChannelID] {
+			if subscription.RequestedAt.After(now.Add(-websubRetryInterval)) {
+				continue
+			}
+			f.requestWebSubUnsubscription(subscription)
+			continue
+		}
+
+		subscribed[subscription.YoutubeChannelID] = true
+		if subscription.LeaseExpiresAt.After(now) {
+			pushed[subscription.YoutubeChannelID] = true
+		}
+
+		if subscription.LeaseExpiresAt.After(now.Add(websubRenewBefore)) ||
+			subscription.RequestedAt.After(now.Add(-websubRetryInterval)) {
+			continue
+		}
+		f.requestWebSubSubscription(subscription.YoutubeChannelID)
+	}
+
+	for channelID := range channelIDs {
+		if !subscribed[channelID] {
+			f.requestWebSubSubscription(channelID)
+		}
+	}
+
+	// only polled entries spend quota
+	var polledCount int64
+	for _, e := range entries {
+		if !pushed[e.YoutubeChannelID] {
+			polledCount++
+		}
+	}
+	f.service.SetQuotaEntryCount(polledCount)
+
+	return nil
+}
+
+func (f *feeds) requestWebSubSubscription(channelID string) {
+	var subscription models.YoutubeWebSubEntry
+	err := helpers.MdbOneWithoutLogging(
+		helpers.MdbCollection(models.YoutubeWebSubTable).Find(bson.M{"youtubechannelid": channelID}),
+		&subscription,
+	)
+	if err != nil && !helpers.IsMdbNotFound(err) {
+		helpers.RelaxLog(err)
+		return
+	}
+
+	token := subscription.Token
+	if token == "" {
+		token, err = youtubeService.NewWebSubToken()
+		if err != nil {
+			helpers.RelaxLog(err)
+			return
+		}
+	}
+
+	// stored first, the hub might verify the request before Subscribe returns
+	err = helpers.MDbUpsertWithoutLogging(models.YoutubeWebSubTable,
+		bson.M{"youtubechannelid": channelID},
+		bson.M{"$set": bson.M{"token": token, "requestedat": time.Now()}},
+	)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return
+	}
+
+	err = f.websub.Subscribe(channelID, token, websubLease)
+	if err != nil {
+		logger().WithError(err).WithField("channel", channelID).Warn("websub subscription request failed")
+	}
+}
+
+// requestWebSubUnsubscription asks the hub to stop pushing a channel without feeds,
+// the subscription is deleted once the hub verified the request
+func (f *feeds) requestWebSubUnsubscription(subscription models.YoutubeWebSubEntry) {
+	if subscription.Token == "" {
+		helpers.RelaxLog(helpers.MDbDeleteWithoutLogging(models.YoutubeWebSubTable, subscription.ID))
+		return
+	}
+
+	err := helpers.MDbUpdateQueryWithoutLogging(models.YoutubeWebSubTable,
+		bson.M{"_id": subscription.ID},
+		bson.M{"$set": bson.M{"requestedat": time.Now(), "leaseexpiresat": time.Time{}}},
+	)
+	if err != nil {
+		helpers.RelaxLog(err)
+		return
+	}
+
+	err = f.websub.Unsubscribe(subscription.YoutubeChannelID, subscription.Token)
+	if err != nil {
+		logger().WithError(err).WithField("channel", subscription.YoutubeChannelID).Warn("websub unsubscription request failed")
+	}
+}
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Context is passed to a Handler
+type Context struct {
+	Msg     *discordgo.Message
+	Session *discordgo.Session
+	// the name or alias the top level command was invoked with
+	Invoked string
+	Command *Command
+	Args    map[string]interface{}
+}
+
+// Has returns true if the argument was given, useful for optional parameters
+func (c *Context) Has(name string) bool {
+	_, ok := c.Args[name]
+	return ok
+}
+
+func (c *Context) String(name string) string {
+	value, _ := c.Args[name].(string)
+	return value
+}
+
+func (c *Context) Int(name string) int {
+	value, _ := c.Args[name].(int)
+	return value
+}
+
+func (c *Context) User(name string) *discordgo.User {
+	value, _ := c.Args[name].(*discordgo.User)
+	return value
+}
+
+func (c *Context) Channel(name string) *discordgo.Channel {
+	value, _ := c.Args[name].(*discordgo.Channel)
+	return value
+}
+
+func (c *Context) Role(name string) *discordgo.Role {
+	value, _ := c.Args[name].(*discordgo.Role)
+	return value
+}
+
+func (c *Context) Duration(name string) time.Duration {
+	value, _ := c.Args[name].(time.Duration)
+	return value
+}
+
+func (c *Context) Emoji(name string) *Emoji {
+	value, _ := c.Args[name].(*Emoji)
+	return value
+}
@@ -0,0 +1,189 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	helpColor         = 0x0FADED
+	helpFieldsPerPage = 8
+)
+
+// String returns a human readable name for the permission
+func (p Permission) String() string {
+	switch p {
+	case PermissionMod:
+		return "Mod"
+	case PermissionAdmin:
+		return "Admin"
+	case PermissionAdminOrStaff:
+		return "Admin or Robyul Staff"
+	case PermissionRobyulMod:
+		return "Robyul Mod"
+	case PermissionBotAdmin:
+		return "Bot Admin"
+	default:
+		return "Everyone"
+	}
+}
+
+// Find returns the command for a path like gallery add, or nil
+func Find(path string) *Command {
+	names := strings.Fields(strings.ToLower(path))
+	if len(names) <= 0 {
+		return nil
+	}
+
+	command := lookup(names[0])
+	for _, name := range names[1:] {
+		if command == nil {
+			return nil
+		}
+		command = command.Subcommand(name)
+	}
+	return command
+}
+
+// SendHelp sends the help for a command or a module, returns false if nothing matched the query
+func SendHelp(msg *discordgo.Message, query string) (found bool) {
+	prefix := helpers.GetPrefixForServer(msg.GuildID)
+
+	query = strings.TrimPrefix(strings.TrimSpace(query), prefix)
+	if query == "" {
+		return false
+	}
+
+	if command := Find(query); command != nil {
+		err := helpers.SendPagedMessage(msg, getCommandHelpEmbed(command, prefix), helpFieldsPerPage)
+		helpers.RelaxLog(err)
+		return true
+	}
+
+	for _, module := range helpers.Modules {
+		for _, moduleName := range module.Names {
+			if strings.ToLower(query) != moduleName {
+				continue
+			}
+
+			embed := getModuleHelpEmbed(module.Permission, prefix)
+			if embed == nil {
+				return false
+			}
+			err := helpers.SendPagedMessage(msg, embed, helpFieldsPerPage)
+			helpers.RelaxLog(err)
+			return true
+		}
+	}
+
+	return false
+}
+
+func getCommandHelpEmbed(command *Command, prefix string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       prefix + command.FullName(),
+		Description: getCommandHelpText(command, prefix),
+		Color:       helpColor,
+	}
+
+	for _, subcommand := range command.Subcommands {
+		embed.Fields = append(embed.Fields, getCommandHelpFields(subcommand, prefix)...)
+	}
+
+	return embed
+}
+
+func getModuleHelpEmbed(module models.ModulePermissionsModule, prefix string) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "Module " + helpers.GetModuleNameById(module),
+		Description: fmt.Sprintf("Use `%shelp <command>` to learn more about a command.", prefix),
+		Color:       helpColor,
+	}
+
+	for _, command := range All() {
+		if command.EffectiveModule() != module {
+			continue
+		}
+		embed.Fields = append(embed.Fields, getCommandHelpFields(command, prefix)...)
+	}
+
+	if len(embed.Fields) <= 0 {
+		return nil
+	}
+
+	return embed
+}
+
+// getCommandHelpFields returns a field for the command and all of its subcommands that can be run
+func getCommandHelpFields(command *Command, prefix string) (fields []*discordgo.MessageEmbedField) {
+	if command.Handler != nil || command.legacy {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  command.Usage(prefix),
+			Value: getCommandHelpText(command, prefix),
+		})
+	}
+
+	for _, subcommand := range command.Subcommands {
+		fields = append(fields, getCommandHelpFields(subcommand, prefix)...)
+	}
+
+	return fields
+}
+
+func getCommandHelpText(command *Command, prefix string) (text string) {
+	if command.Description != "" {
+		text += command.Description + "\n"
+	}
+	if command.Handler != nil {
+		text += fmt.Sprintf("**Usage:** `%s`\n", command.Usage(prefix))
+	}
+	if len(command.Aliases) > 0 {
+		text += fmt.Sprintf("**Aliases:** `%s`\n", strings.Join(command.Aliases, "`, `"))
+	}
+	// permissions of legacy commands are checked in the Action of their plugin
+	if !command.legacy {
+		text += fmt.Sprintf("**Permission:** %s\n", command.EffectivePermission())
+	}
+	if module := command.EffectiveModule(); module != 0 {
+		text += fmt.Sprintf("**Module:** `%s`\n", helpers.GetModuleNameById(module))
+	}
+	return text
+}
+
+// Export returns the metadata of all commands, for example for the command list on the website
+func Export(prefix string) (result []models.Rest_Command) {
+	result = make([]models.Rest_Command, 0)
+	for _, command := range All() {
+		result = append(result, exportCommand(command, prefix))
+	}
+	return result
+}
+
+func exportCommand(command *Command, prefix string) (result models.Rest_Command) {
+	result = models.Rest_Command{
+		Name:        command.FullName(),
+		Aliases:     command.Aliases,
+		Description: command.Description,
+		Subcommands: make([]models.Rest_Command, 0),
+	}
+	if !command.legacy {
+		result.Permission = command.EffectivePermission().String()
+	}
+	if result.Aliases == nil {
+		result.Aliases = make([]string, 0)
+	}
+	if command.Handler != nil || command.legacy {
+		result.Usage = command.Usage(prefix)
+	}
+	if module := command.EffectiveModule(); module != 0 {
+		result.Module = helpers.GetModuleNameById(module)
+	}
+	for _, subcommand := range command.Subcommands {
+		result.Subcommands = append(result.Subcommands, exportCommand(subcommand, prefix))
+	}
+	return result
+}
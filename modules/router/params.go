@@ -0,0 +1,283 @@
+package router
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ParamType is the type of a command parameter
+type ParamType int
+
+const (
+	// ParamString is a single word
+	ParamString ParamType = iota
+	// ParamQuoted is a single word, or multiple words in quotes
+	ParamQuoted
+	// ParamRest is everything after the previous parameters
+	ParamRest
+	ParamInt
+	ParamUser
+	ParamChannel
+	ParamRole
+	ParamDuration
+	ParamEmoji
+)
+
+// Param is a parameter of a command
+type Param struct {
+	Name string
+	Type ParamType
+	// optional parameters are skipped if they are missing or can not be parsed
+	Optional bool
+}
+
+// Emoji is a parsed unicode or custom emoji
+type Emoji struct {
+	ID       string
+	Name     string
+	Animated bool
+}
+
+// APIName returns the emoji in the format used for reactions
+func (e *Emoji) APIName() string {
+	if e.ID == "" {
+		return e.Name
+	}
+	return e.Name + ":" + e.ID
+}
+
+var (
+	durationRegex = regexp.MustCompile(`^(?i)(\d+(w|d|h|m|s))+$`)
+	durationPart  = regexp.MustCompile(`(?i)(\d+)(w|d|h|m|s)`)
+	roleMention   = regexp.MustCompile(`^<@&(\d+)>$`)
+
+	errInvalidDuration = errors.New("invalid duration")
+)
+
+// TypeName returns a human readable name for the type
+func (t ParamType) TypeName() string {
+	switch t {
+	case ParamInt:
+		return "number"
+	case ParamUser:
+		return "user"
+	case ParamChannel:
+		return "channel"
+	case ParamRole:
+		return "role"
+	case ParamDuration:
+		return "duration"
+	case ParamEmoji:
+		return "emoji"
+	default:
+		return "text"
+	}
+}
+
+func (p Param) usage() string {
+	name := p.Name
+	if p.Type == ParamRest {
+		name += "…"
+	}
+	if p.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + name + ">"
+}
+
+type token struct {
+	text  string
+	start int
+}
+
+// tokenize splits content into words, text in quotes is kept together
+func tokenize(content string) (tokens []token) {
+	runes := []rune(content)
+	var current []rune
+	start := -1
+	var quote rune
+	byteOffset := 0
+
+	for _, r := range runes {
+		switch {
+		case quote != 0 && isClosingQuote(quote, r):
+			tokens = append(tokens, token{text: string(current), start: start})
+			current = nil
+			start = -1
+			quote = 0
+		case quote == 0 && start == -1 && isOpeningQuote(r):
+			quote = r
+			start = byteOffset
+			current = []rune{}
+		case quote == 0 && unicode.IsSpace(r):
+			if start != -1 {
+				tokens = append(tokens, token{text: string(current), start: start})
+				current = nil
+				start = -1
+			}
+		default:
+			if start == -1 {
+				start = byteOffset
+			}
+			current = append(current, r)
+		}
+		byteOffset += len(string(r))
+	}
+	if start != -1 {
+		tokens = append(tokens, token{text: string(current), start: start})
+	}
+
+	return tokens
+}
+
+func isOpeningQuote(r rune) bool {
+	return r == '"' || r == '“'
+}
+
+func isClosingQuote(opening, r rune) bool {
+	if opening == '“' {
+		return r == '”' || r == '"'
+	}
+	return r == '"' || r == '”'
+}
+
+// parseParams parses content into the given params, keyed by param name
+func parseParams(params []Param, content string, msg *discordgo.Message) (args map[string]interface{}, err error) {
+	args = make(map[string]interface{})
+	tokens := tokenize(content)
+	position := 0
+
+	for _, param := range params {
+		if param.Type == ParamRest {
+			var rest string
+			if position < len(tokens) {
+				rest = strings.TrimSpace(content[tokens[position].start:])
+			}
+			if rest == "" {
+				if param.Optional {
+					continue
+				}
+				return nil, errors.New(helpers.GetTextF("bot.arguments.missing", param.Name))
+			}
+			args[param.Name] = rest
+			position = len(tokens)
+			continue
+		}
+
+		if position >= len(tokens) {
+			if param.Optional {
+				continue
+			}
+			return nil, errors.New(helpers.GetTextF("bot.arguments.missing", param.Name))
+		}
+
+		value, err := parseValue(param.Type, tokens[position].text, msg)
+		if err != nil {
+			if param.Optional {
+				continue
+			}
+			return nil, errors.New(helpers.GetTextF("bot.arguments.invalid-param",
+				tokens[position].text, param.Type.TypeName(), param.Name))
+		}
+		args[param.Name] = value
+		position++
+	}
+
+	if position < len(tokens) {
+		return nil, errors.New(helpers.GetText("bot.arguments.too-many"))
+	}
+
+	return args, nil
+}
+
+func parseValue(paramType ParamType, text string, msg *discordgo.Message) (value interface{}, err error) {
+	switch paramType {
+	case ParamInt:
+		return strconv.Atoi(text)
+	case ParamUser:
+		user, err := helpers.GetUserFromMention(text)
+		if err != nil || user == nil || user.ID == "" {
+			return nil, errors.New("user not found")
+		}
+		return user, nil
+	case ParamChannel:
+		channel, err := helpers.GetChannelFromMention(msg, text)
+		if err != nil || channel == nil || channel.ID == "" {
+			return nil, errors.New("channel not found")
+		}
+		return channel, nil
+	case ParamRole:
+		return getRole(msg, text)
+	case ParamDuration:
+		return ParseDuration(text)
+	case ParamEmoji:
+		if emojiID, emojiName, animated := helpers.ParseCustomEmoji(text); emojiID != "" {
+			return &Emoji{ID: emojiID, Name: emojiName, Animated: animated}, nil
+		}
+		if helpers.IsUnicodeEmoji(text) {
+			return &Emoji{Name: text}, nil
+		}
+		return nil, errors.New("emoji not found")
+	default:
+		return text, nil
+	}
+}
+
+// getRole finds a role on the guild of the message by mention, ID, or name
+func getRole(msg *discordgo.Message, text string) (*discordgo.Role, error) {
+	channel, err := helpers.GetChannel(msg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	guild, err := helpers.GetGuild(channel.GuildID)
+	if err != nil {
+		return nil, err
+	}
+
+	if matches := roleMention.FindStringSubmatch(text); len(matches) == 2 {
+		text = matches[1]
+	}
+	for _, role := range guild.Roles {
+		if role.ID == text || strings.ToLower(role.Name) == strings.ToLower(text) {
+			return role, nil
+		}
+	}
+	return nil, errors.New("role not found")
+}
+
+// ParseDuration parses durations like 1w2d, 3h30m, or 90s
+func ParseDuration(text string) (duration time.Duration, err error) {
+	if !durationRegex.MatchString(text) {
+		return 0, errInvalidDuration
+	}
+
+	for _, part := range durationPart.FindAllStringSubmatch(text, -1) {
+		amount, err := strconv.Atoi(part[1])
+		if err != nil {
+			return 0, errInvalidDuration
+		}
+		switch strings.ToLower(part[2]) {
+		case "w":
+			duration += time.Duration(amount) * 7 * 24 * time.Hour
+		case "d":
+			duration += time.Duration(amount) * 24 * time.Hour
+		case "h":
+			duration += time.Duration(amount) * time.Hour
+		case "m":
+			duration += time.Duration(amount) * time.Minute
+		case "s":
+			duration += time.Duration(amount) * time.Second
+		}
+	}
+	if duration <= 0 {
+		return 0, errInvalidDuration
+	}
+	return duration, nil
+}
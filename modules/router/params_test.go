@@ -0,0 +1,47 @@
+package router
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	content := `add "hello world" #channel “smart quotes” rest of line`
+	tokens := tokenize(content)
+
+	expected := []string{"add", "hello world", "#channel", "smart quotes", "rest", "of", "line"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("router.tokenize() returned %d tokens, expected %d", len(tokens), len(expected))
+	}
+	for i, token := range tokens {
+		if token.text != expected[i] {
+			t.Fatalf("router.tokenize() returned %q at position %d, expected %q", token.text, i, expected[i])
+		}
+	}
+
+	if content[tokens[4].start:] != "rest of line" {
+		t.Fatalf("router.tokenize() returned wrong start offset %d for %q", tokens[4].start, tokens[4].text)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	duration, err := ParseDuration("1w2d3h4m5s")
+	if err != nil || duration != 9*24*time.Hour+3*time.Hour+4*time.Minute+5*time.Second {
+		t.Fatalf("router.ParseDuration() failed to parse a valid duration")
+	}
+
+	duration, err = ParseDuration("90m")
+	if err != nil || duration != 90*time.Minute {
+		t.Fatalf("router.ParseDuration() failed to parse a valid duration")
+	}
+
+	_, err = ParseDuration("soon")
+	if err == nil {
+		t.Fatalf("router.ParseDuration() parsed an invalid duration")
+	}
+
+	_, err = ParseDuration("0m")
+	if err == nil {
+		t.Fatalf("router.ParseDuration() parsed an empty duration")
+	}
+}
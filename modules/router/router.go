@@ -0,0 +1,311 @@
+// Package router dispatches commands that are declared with typed parameters.
+// Plugins can implement modules.RoutedPlugin to register their commands here, commands that are not registered
+// keep being passed to the Action of their Plugin or ExtendedPlugin.
+package router
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Permission is the permission level required to run a command
+type Permission int
+
+const (
+	PermissionEveryone Permission = iota
+	PermissionMod
+	PermissionAdmin
+	PermissionAdminOrStaff
+	PermissionRobyulMod
+	PermissionBotAdmin
+)
+
+// Handler is called with the parsed arguments once a command matched
+type Handler func(ctx *Context)
+
+// Command is a command, or a subcommand of a command
+type Command struct {
+	Name    string
+	Aliases []string
+	// short description, shown in usage texts
+	Description string
+	Params      []Param
+	// permission required to run this command, parent permissions have to be fulfilled as well
+	Permission Permission
+	// module used for the module permissions, subcommands inherit the module of their parent if unset
+	Module      models.ModulePermissionsModule
+	Subcommands []*Command
+	// Handler can be nil for commands that only group subcommands
+	Handler Handler
+
+	parent *Command
+	// set for commands that are still handled by the Action of their plugin
+	legacy bool
+}
+
+var (
+	commands       = make(map[string]*Command)
+	legacyCommands = make(map[string]*Command)
+	commandsLock   sync.RWMutex
+)
+
+// Register adds commands to the router, it panics if a name or alias is already taken
+func Register(newCommands ...*Command) {
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+
+	for _, command := range newCommands {
+		command.link(nil)
+		for _, name := range command.Names() {
+			if occupant, ok := commands[name]; ok {
+				panic("router: command " + name + " is already registered by " + occupant.Name)
+			}
+			commands[name] = command
+		}
+	}
+}
+
+// RegisterLegacy adds the metadata of commands that are still handled by the Action of their plugin,
+// they are listed in the help and the command export but never dispatched.
+// Commands whose name is already registered are skipped, as are aliases that are taken.
+func RegisterLegacy(newCommands ...*Command) {
+	commandsLock.Lock()
+	defer commandsLock.Unlock()
+
+	for _, command := range newCommands {
+		if commands[command.Name] != nil || legacyCommands[command.Name] != nil {
+			continue
+		}
+
+		command.legacy = true
+		command.link(nil)
+
+		aliases := make([]string, 0)
+		for _, alias := range command.Aliases {
+			if commands[alias] != nil || legacyCommands[alias] != nil {
+				continue
+			}
+			aliases = append(aliases, alias)
+		}
+		command.Aliases = aliases
+
+		for _, name := range command.Names() {
+			legacyCommands[name] = command
+		}
+	}
+}
+
+// Get returns the top level command for a name or alias, or nil
+func Get(name string) *Command {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+
+	return commands[name]
+}
+
+// lookup returns the routed or legacy top level command for a name or alias, or nil
+func lookup(name string) *Command {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+
+	if command, ok := commands[name]; ok {
+		return command
+	}
+	return legacyCommands[name]
+}
+
+// Resolve returns the name of the routed or legacy command for a name or alias, ok is false for unknown commands
+func Resolve(name string) (canonical string, ok bool) {
+	command := lookup(name)
+	if command == nil {
+		return "", false
+	}
+	return command.Name, true
+}
+
+// All returns all top level commands sorted by name, including legacy commands
+func All() (result []*Command) {
+	commandsLock.RLock()
+	defer commandsLock.RUnlock()
+
+	seen := make(map[*Command]bool)
+	for _, registered := range []map[string]*Command{commands, legacyCommands} {
+		for _, command := range registered {
+			if seen[command] {
+				continue
+			}
+			seen[command] = true
+			result = append(result, command)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Dispatch runs the command matching name with the given content, returns false if no command is registered for name
+func Dispatch(name string, content string, msg *discordgo.Message, session *discordgo.Session) (handled bool) {
+	command := Get(name)
+	if command == nil {
+		return false
+	}
+
+	// walk down the subcommands
+	path := []*Command{command}
+	tokens := tokenize(content)
+	consumed := 0
+	for consumed < len(tokens) {
+		subcommand := command.Subcommand(tokens[consumed].text)
+		if subcommand == nil {
+			break
+		}
+		command = subcommand
+		path = append(path, command)
+		consumed++
+	}
+
+	if module := command.EffectiveModule(); module != 0 && !helpers.ModuleIsAllowed(msg.ChannelID, msg.ID, msg.Author.ID, module) {
+		return true
+	}
+
+	requirePermissions(msg, path, func() {
+		if command.Handler == nil {
+			helpers.SendMessage(msg.ChannelID, command.UsageText(helpers.GetPrefixForServer(msg.GuildID)))
+			return
+		}
+
+		var rest string
+		if consumed < len(tokens) {
+			rest = content[tokens[consumed].start:]
+		}
+		args, err := parseParams(command.Params, rest, msg)
+		if err != nil {
+			helpers.SendMessage(msg.ChannelID,
+				err.Error()+"\n"+command.UsageText(helpers.GetPrefixForServer(msg.GuildID)))
+			return
+		}
+
+		command.Handler(&Context{
+			Msg:     msg,
+			Session: session,
+			Invoked: name,
+			Command: command,
+			Args:    args,
+		})
+	})
+
+	return true
+}
+
+// requirePermissions calls cb if the author fulfils the permissions of every command in path
+func requirePermissions(msg *discordgo.Message, path []*Command, cb helpers.Callback) {
+	if len(path) <= 0 {
+		cb()
+		return
+	}
+
+	next := func() {
+		requirePermissions(msg, path[1:], cb)
+	}
+
+	switch path[0].Permission {
+	case PermissionMod:
+		helpers.RequireMod(msg, next)
+	case PermissionAdmin:
+		helpers.RequireAdmin(msg, next)
+	case PermissionAdminOrStaff:
+		helpers.RequireAdminOrStaff(msg, next)
+	case PermissionRobyulMod:
+		helpers.RequireRobyulMod(msg, next)
+	case PermissionBotAdmin:
+		helpers.RequireBotAdmin(msg, next)
+	default:
+		next()
+	}
+}
+
+func (c *Command) link(parent *Command) {
+	c.parent = parent
+	for _, subcommand := range c.Subcommands {
+		subcommand.link(c)
+	}
+}
+
+// Names returns the name and all aliases of the command
+func (c *Command) Names() []string {
+	return append([]string{c.Name}, c.Aliases...)
+}
+
+// Parent returns the parent command, or nil for top level commands
+func (c *Command) Parent() *Command {
+	return c.parent
+}
+
+// Subcommand returns the subcommand matching name or one of its aliases, or nil
+func (c *Command) Subcommand(name string) *Command {
+	name = strings.ToLower(name)
+	for _, subcommand := range c.Subcommands {
+		for _, subcommandName := range subcommand.Names() {
+			if subcommandName == name {
+				return subcommand
+			}
+		}
+	}
+	return nil
+}
+
+// EffectiveModule returns the module of the command, or of the closest parent that has one set
+func (c *Command) EffectiveModule() models.ModulePermissionsModule {
+	for command := c; command != nil; command = command.parent {
+		if command.Module != 0 {
+			return command.Module
+		}
+	}
+	return 0
+}
+
+// EffectivePermission returns the highest permission required by the command or any of its parents
+func (c *Command) EffectivePermission() (permission Permission) {
+	for command := c; command != nil; command = command.parent {
+		if command.Permission > permission {
+			permission = command.Permission
+		}
+	}
+	return permission
+}
+
+// FullName returns the names of all parents and the command, for example gallery add
+func (c *Command) FullName() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.FullName() + " " + c.Name
+}
+
+// Usage returns the usage of the command, for example _gallery add <source channel> <target channel>
+func (c *Command) Usage(prefix string) string {
+	usage := prefix + c.FullName()
+	for _, param := range c.Params {
+		usage += " " + param.usage()
+	}
+	return usage
+}
+
+// UsageText returns the usage of the command, or of its subcommands if it has no handler
+func (c *Command) UsageText(prefix string) (text string) {
+	if c.Handler != nil {
+		return helpers.GetTextF("bot.arguments.usage", c.Usage(prefix))
+	}
+
+	var usages []string
+	for _, subcommand := range c.Subcommands {
+		usages = append(usages, subcommand.Usage(prefix))
+	}
+	return helpers.GetTextF("bot.arguments.usage", strings.Join(usages, "`\n`"))
+}
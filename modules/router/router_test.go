@@ -0,0 +1,38 @@
+package router
+
+import (
+	"testing"
+)
+
+func TestRegisterLegacy(t *testing.T) {
+	Register(&Command{Name: "routed", Aliases: []string{"r"}, Handler: func(ctx *Context) {}})
+	RegisterLegacy(
+		&Command{Name: "routed"},
+		&Command{Name: "legacy", Aliases: []string{"l", "r"}},
+	)
+
+	if Get("legacy") != nil {
+		t.Fatalf("router.Get() returned a legacy command, it would be dispatched")
+	}
+	if Find("routed").legacy {
+		t.Fatalf("router.RegisterLegacy() replaced a routed command")
+	}
+
+	for name, expected := range map[string]string{"routed": "routed", "r": "routed", "legacy": "legacy", "l": "legacy"} {
+		canonical, ok := Resolve(name)
+		if !ok || canonical != expected {
+			t.Fatalf("router.Resolve(%q) = %q, %v, expected %q", name, canonical, ok, expected)
+		}
+	}
+	if _, ok := Resolve("unknown"); ok {
+		t.Fatalf("router.Resolve() resolved an unknown command")
+	}
+
+	legacy := Find("l")
+	if legacy == nil || len(legacy.Aliases) != 1 || legacy.Aliases[0] != "l" {
+		t.Fatalf("router.RegisterLegacy() kept an alias that is taken by a routed command")
+	}
+	if export := exportCommand(legacy, "_"); export.Permission != "" || export.Usage != "_legacy" {
+		t.Fatalf("router.exportCommand() returned %+v for a legacy command", export)
+	}
+}
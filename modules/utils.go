@@ -11,7 +11,7 @@ import (
 	"github.com/Seklfreak/Robyul2/helpers"
 	"github.com/Seklfreak/Robyul2/metrics"
 	"github.com/Seklfreak/Robyul2/modules/plugins/levels"
-	"github.com/Seklfreak/Robyul2/ratelimits"
+	"github.com/Seklfreak/Robyul2/modules/router"
 	"github.com/Seklfreak/Robyul2/shardmanager"
 	"github.com/bwmarrin/discordgo"
 )
@@ -20,6 +20,9 @@ import (
 func Init(session *shardmanager.Manager) {
 	checkDuplicateCommands()
 
+	// help is handled by the bot itself
+	router.RegisterLegacy(&router.Command{Name: "help", Aliases: []string{"h"}})
+
 	pluginCount := len(PluginList)
 	extendedPluginCount := len(PluginExtendedList)
 	pluginCache = make(map[string]*Plugin)
@@ -43,6 +46,9 @@ func Init(session *shardmanager.Manager) {
 		))
 		listeners = ""
 
+		registerRoutes(*ref)
+		registerLegacyCommands(*ref, (*ref).Commands())
+
 		(*ref).Init(session)
 	}
 
@@ -67,6 +73,9 @@ func Init(session *shardmanager.Manager) {
 			generator.SetProfileGenerator((*ref).(*levels.Levels))
 		}
 
+		registerRoutes(*ref)
+		registerLegacyCommands(*ref, (*ref).Commands())
+
 		(*ref).Init(session)
 	}
 
@@ -87,6 +96,23 @@ func Init(session *shardmanager.Manager) {
 	)
 }
 
+// registerRoutes registers the commands of plugins that implement RoutedPlugin with the router
+func registerRoutes(plugin BaseModule) {
+	routedPlugin, ok := plugin.(RoutedPlugin)
+	if !ok {
+		return
+	}
+
+	routes := routedPlugin.Routes()
+	router.Register(routes...)
+
+	cache.GetLogger().WithField("module", "modules").Info(fmt.Sprintf(
+		"[ROUTED-PLUG] %s registered %d routed commands",
+		helpers.Typeof(plugin),
+		len(routes),
+	))
+}
+
 // Uninit deintializes the plugins
 func Uninit(session *shardmanager.Manager) {
 	extendedPluginCount := len(PluginExtendedList)
@@ -122,12 +148,14 @@ func CallBotPlugin(command string, content string, msg *discordgo.Message) {
 	// Defer a recovery in case anything panics
 	defer helpers.RecoverDiscord(msg)
 
-	// Consume a key for this action
-	ratelimits.Container.Drain(1, msg.Author.ID)
-
 	// Track metrics
 	metrics.CommandsExecuted.Add(1)
 
+	// Call the router, for plugins that have been migrated to it
+	if router.Dispatch(command, content, msg, cache.GetSession().SessionForGuildS(msg.GuildID)) {
+		return
+	}
+
 	// Call the module
 	if ref, ok := pluginCache[command]; ok {
 		(*ref).Action(command, content, msg, cache.GetSession().SessionForGuildS(msg.GuildID))
@@ -2,8 +2,11 @@ package ratelimits
 
 import (
 	"errors"
-	"sync"
+	"fmt"
 	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/go-redis/redis"
 )
 
 const (
@@ -18,118 +21,153 @@ const (
 
 	// How many keys may drop at a time
 	DROP_SIZE = 3
-)
-
-// Global pointer to a container instance
-var Container = &BucketContainer{}
 
-// Container struct to lock the bucket map
-type BucketContainer struct {
-	sync.RWMutex
+	// How long users are put into the chill zone after running out of keys
+	CHILL_DURATION = 15 * time.Second
 
-	// Maps discord ids to key-counts
-	buckets map[string]int8
-}
+	bucketKey = "robyul2-discord:ratelimits:bucket:%s"
+	chillKey  = "robyul2-discord:ratelimits:chill:%s"
+)
 
-// Allocates the map and starts routines
-func (b *BucketContainer) Init() {
-	b.Lock()
-	b.buckets = make(map[string]int8)
-	b.Unlock()
+var (
+	// Global pointer to a container instance
+	Container = &BucketContainer{}
 
-	go b.Refiller()
-}
+	ErrNoKeys    = errors.New("No keys left")
+	ErrChillZone = errors.New("User is in the chill zone")
+)
 
-// Refills user buckets in a set interval
-func (b *BucketContainer) Refiller() {
-	for {
-		b.Lock()
-		for user, keys := range b.buckets {
-			// Chill zone
-			if keys == -1 {
-				b.buckets[user]++
-				continue
-			}
-
-			// Chill zone exit
-			if keys == 0 {
-				b.buckets[user] = BUCKET_INITIAL_FILL
-				continue
-			}
-
-			// More free keys for nice users :3
-			if keys < BUCKET_UPPER_BOUND {
-				b.buckets[user] += DROP_SIZE
-				continue
-			}
-		}
-		b.Unlock()
-
-		time.Sleep(DROP_INTERVAL)
+// drains ARGV[2] keys from the bucket KEYS[1] after refilling it, returns {drained, keys left}
+// a cost of 0 only returns the keys left
+var drainScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local cost = tonumber(ARGV[2])
+local initialFill = tonumber(ARGV[3])
+local upperBound = tonumber(ARGV[4])
+local interval = tonumber(ARGV[5])
+local dropSize = tonumber(ARGV[6])
+
+local bucket = redis.call("HMGET", KEYS[1], "keys", "last")
+local keys = tonumber(bucket[1])
+local last = tonumber(bucket[2])
+if keys == nil or last == nil then
+	keys = initialFill
+	last = now
+end
+
+local drops = math.floor((now - last) / interval)
+if drops > 0 then
+	if keys < upperBound then
+		keys = math.min(upperBound, keys + drops * dropSize)
+	end
+	last = last + drops * interval
+end
+
+local drained = 0
+if cost > 0 and cost <= keys then
+	keys = keys - cost
+	drained = 1
+end
+
+redis.call("HMSET", KEYS[1], "keys", keys, "last", last)
+redis.call("EXPIRE", KEYS[1], 3600)
+return {drained, keys}
+`)
+
+// Container struct for the buckets, the buckets themselves are stored in redis so they survive restarts
+type BucketContainer struct{}
+
+// Loads the drain script into redis
+func (b *BucketContainer) Init() {
+	err := drainScript.Load(cache.GetRedisClient()).Err()
+	if err != nil {
+		cache.GetLogger().WithField("module", "ratelimits").Error("failed to load drain script: ", err.Error())
 	}
 }
 
-// Check if the user has a bucket. If not create one
-func (b *BucketContainer) CreateBucketIfNotExists(user string) {
-	if b.buckets == nil {
-		return
+// drain runs the drain script for $user, returns if the keys have been drained and how many keys are left
+func (b *BucketContainer) drain(amount int, user string) (drained bool, keysLeft int, err error) {
+	result, err := drainScript.Run(
+		cache.GetRedisClient(),
+		[]string{fmt.Sprintf(bucketKey, user)},
+		time.Now().Unix(), amount, BUCKET_INITIAL_FILL, BUCKET_UPPER_BOUND, int(DROP_INTERVAL.Seconds()), DROP_SIZE,
+	).Result()
+	if err != nil {
+		return false, 0, err
 	}
 
-	b.RLock()
-	_, e := b.buckets[user]
-	b.RUnlock()
-
-	if !e {
-		b.Lock()
-		b.buckets[user] = BUCKET_INITIAL_FILL
-		b.Unlock()
+	values, ok := result.([]interface{})
+	if !ok || len(values) < 2 {
+		return false, 0, errors.New("unexpected result from drain script")
 	}
-}
+	drainedValue, _ := values[0].(int64)
+	keysValue, _ := values[1].(int64)
 
-// Drains $amount from $user if he has enough keys left
-func (b *BucketContainer) Drain(amount int8, user string) error {
-	b.CreateBucketIfNotExists(user)
-
-	// Check if there are enough keys left
-	b.RLock()
-	userAmount := b.buckets[user]
-	b.RUnlock()
+	return drainedValue == 1, int(keysValue), nil
+}
 
-	if amount > userAmount {
-		return errors.New("No keys left")
+// Drains $amount from $user if he has enough keys left and is not in the chill zone
+func (b *BucketContainer) Drain(amount int, user string) error {
+	if b.IsChilling(user) {
+		return ErrChillZone
 	}
 
-	// Remove keys from bucket
-	b.Lock()
-	b.buckets[user] -= amount
-	b.Unlock()
+	drained, _, err := b.drain(amount, user)
+	if err != nil {
+		// don't block commands if redis is unavailable
+		cache.GetLogger().WithField("module", "ratelimits").Error("failed to drain bucket: ", err.Error())
+		return nil
+	}
+	if !drained {
+		return ErrNoKeys
+	}
 
 	return nil
 }
 
 // Check if the user still has keys
 func (b *BucketContainer) HasKeys(user string) bool {
-	b.CreateBucketIfNotExists(user)
+	if b.IsChilling(user) {
+		return false
+	}
 
-	b.RLock()
-	defer b.RUnlock()
+	return b.Get(user) > 0
+}
 
-	return b.buckets[user] > 0
+// Get returns the keys $user has left
+func (b *BucketContainer) Get(user string) int {
+	_, keysLeft, err := b.drain(0, user)
+	if err != nil {
+		cache.GetLogger().WithField("module", "ratelimits").Error("failed to get bucket: ", err.Error())
+		return BUCKET_INITIAL_FILL
+	}
+
+	return keysLeft
 }
 
-func (b *BucketContainer) Get(user string) int8 {
-	b.RLock()
-	defer b.RUnlock()
+// Chill puts $user into the chill zone, the bucket gets filled again afterwards
+func (b *BucketContainer) Chill(user string) {
+	redisClient := cache.GetRedisClient()
+	err := redisClient.Set(fmt.Sprintf(chillKey, user), 1, CHILL_DURATION).Err()
+	if err == nil {
+		err = redisClient.Del(fmt.Sprintf(bucketKey, user)).Err()
+	}
+	if err != nil {
+		cache.GetLogger().WithField("module", "ratelimits").Error("failed to chill user: ", err.Error())
+	}
+}
 
-	return b.buckets[user]
+// IsChilling returns true if $user is in the chill zone
+func (b *BucketContainer) IsChilling(user string) bool {
+	return b.ChillRemaining(user) > 0
 }
 
-func (b *BucketContainer) Set(user string, value int8) {
-	if b.buckets == nil {
-		return
+// ChillRemaining returns how long $user will stay in the chill zone
+func (b *BucketContainer) ChillRemaining(user string) time.Duration {
+	remaining, err := cache.GetRedisClient().PTTL(fmt.Sprintf(chillKey, user)).Result()
+	if err != nil || remaining < 0 {
+		return 0
 	}
 
-	b.Lock()
-	b.buckets[user] = value
-	b.Unlock()
+	return remaining
 }
@@ -0,0 +1,151 @@
+package ratelimits
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/cache"
+	"github.com/Seklfreak/Robyul2/helpers"
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+)
+
+const (
+	// The cost of commands without a configured cost
+	DEFAULT_COMMAND_COST = 1
+
+	// The highest cost a command may have, commands with a higher cost could never be run
+	MAX_COMMAND_COST = BUCKET_UPPER_BOUND
+
+	cooldownKey       = "robyul2-discord:ratelimits:cooldown:%s:%s"
+	cooldownNoticeKey = "robyul2-discord:ratelimits:cooldown-notice:%s:%s"
+)
+
+// CommandCosts maps commands to the keys they cost, guilds can override them
+// keys are the names of the commands, aliases are resolved before the lookup
+var CommandCosts = map[string]int{
+	"profile":     8,
+	"gif-profile": 16,
+	"lastfm":      6,
+	"melon":       4,
+	"ichart":      4,
+	"gaon":        4,
+	"spoiler":     4,
+	"color":       2,
+	"translator":  2,
+	"weather":     2,
+}
+
+// CooldownError is returned if a user has to wait before running commands in a channel again
+type CooldownError struct {
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("Channel cooldown, %s remaining", e.Remaining.String())
+}
+
+// ResolveCommand returns the name of the command $command is an alias of, or $command if it is unknown
+func ResolveCommand(command string) string {
+	command = strings.ToLower(command)
+
+	if canonical, ok := router.Resolve(command); ok {
+		return canonical
+	}
+	return command
+}
+
+// GetCommandCost returns the cost of $command on $guildID, taking the guild overrides into account
+func GetCommandCost(guildID string, command string) int {
+	return commandCost(command, helpers.GuildSettingsGetCached(guildID).RatelimitCommandCosts)
+}
+
+// commandCost returns the cost of $command, the first matching override takes precedence over CommandCosts
+func commandCost(command string, overrides []models.RatelimitCommandCost) int {
+	command = ResolveCommand(command)
+
+	for _, override := range overrides {
+		// overrides set before aliases were resolved can be stored by an alias
+		if ResolveCommand(override.Command) == command {
+			return override.Cost
+		}
+	}
+
+	if cost, ok := CommandCosts[command]; ok {
+		return cost
+	}
+
+	return DEFAULT_COMMAND_COST
+}
+
+// GetChannelCooldown returns the cooldown configured for $channelID, or 0
+func GetChannelCooldown(guildID string, channelID string) time.Duration {
+	return channelCooldown(channelID, helpers.GuildSettingsGetCached(guildID).RatelimitChannelCooldowns)
+}
+
+// channelCooldown returns the cooldown of $channelID in $cooldowns, or 0
+func channelCooldown(channelID string, cooldowns []models.RatelimitChannelCooldown) time.Duration {
+	for _, cooldown := range cooldowns {
+		if cooldown.ChannelID == channelID {
+			return cooldown.Cooldown
+		}
+	}
+
+	return 0
+}
+
+// DrainForCommand checks the channel cooldown and drains the cost of $command from $userID
+// returns ErrChillZone, ErrNoKeys, or a *CooldownError if the command should not be run
+func (b *BucketContainer) DrainForCommand(guildID, channelID, userID, command string) error {
+	if b.IsChilling(userID) {
+		return ErrChillZone
+	}
+
+	if remaining := b.CooldownRemaining(channelID, userID); remaining > 0 {
+		return &CooldownError{Remaining: remaining}
+	}
+
+	cost := GetCommandCost(guildID, command)
+	if cost > 0 {
+		err := b.Drain(cost, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	if cooldown := GetChannelCooldown(guildID, channelID); cooldown > 0 {
+		err := cache.GetRedisClient().Set(fmt.Sprintf(cooldownKey, channelID, userID), 1, cooldown).Err()
+		if err != nil {
+			cache.GetLogger().WithField("module", "ratelimits").Error("failed to set channel cooldown: ", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// ClaimCooldownNotice returns true for the first call during a cooldown of $userID in $channelID,
+// so the cooldown is only announced once instead of for every command sent during it
+func (b *BucketContainer) ClaimCooldownNotice(channelID, userID string, remaining time.Duration) bool {
+	claimed, err := cache.GetRedisClient().SetNX(fmt.Sprintf(cooldownNoticeKey, channelID, userID), 1, remaining).Result()
+	if err != nil {
+		cache.GetLogger().WithField("module", "ratelimits").Error("failed to claim cooldown notice: ", err.Error())
+		return false
+	}
+
+	return claimed
+}
+
+// CooldownRemaining returns how long $userID has to wait before running commands in $channelID again
+func (b *BucketContainer) CooldownRemaining(channelID, userID string) time.Duration {
+	return cooldownRemaining(cache.GetRedisClient().PTTL(fmt.Sprintf(cooldownKey, channelID, userID)).Result())
+}
+
+// cooldownRemaining returns the TTL of a cooldown key, expired and missing keys have a negative TTL
+func cooldownRemaining(ttl time.Duration, err error) time.Duration {
+	if err != nil || ttl < 0 {
+		return 0
+	}
+
+	return ttl
+}
@@ -0,0 +1,87 @@
+package ratelimits
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/models"
+	"github.com/Seklfreak/Robyul2/modules/router"
+)
+
+func init() {
+	router.Register(&router.Command{Name: "profile", Aliases: []string{"prof"}, Handler: func(ctx *router.Context) {}})
+	router.RegisterLegacy(&router.Command{Name: "translator", Aliases: []string{"t", "translate"}})
+}
+
+func TestResolveCommand(t *testing.T) {
+	for command, expected := range map[string]string{
+		"profile":   "profile",
+		"PROF":      "profile",
+		"t":         "translator",
+		"Translate": "translator",
+		"unknown":   "unknown",
+		"UNKNOWN":   "unknown",
+	} {
+		if resolved := ResolveCommand(command); resolved != expected {
+			t.Fatalf("ratelimits.ResolveCommand(%q) returned %q, expected %q", command, resolved, expected)
+		}
+	}
+}
+
+func TestCommandCost(t *testing.T) {
+	for _, testCase := range []struct {
+		command   string
+		overrides []models.RatelimitCommandCost
+		expected  int
+	}{
+		{"profile", nil, CommandCosts["profile"]},
+		{"prof", nil, CommandCosts["profile"]},
+		{"T", nil, CommandCosts["translator"]},
+		{"unknown", nil, DEFAULT_COMMAND_COST},
+		{"profile", []models.RatelimitCommandCost{{Command: "profile", Cost: 3}}, 3},
+		{"prof", []models.RatelimitCommandCost{{Command: "profile", Cost: 0}}, 0},
+		// overrides stored by an alias
+		{"translator", []models.RatelimitCommandCost{{Command: "translate", Cost: 5}}, 5},
+		{"t", []models.RatelimitCommandCost{{Command: "lastfm", Cost: 5}}, CommandCosts["translator"]},
+		{"unknown", []models.RatelimitCommandCost{{Command: "UNKNOWN", Cost: 7}}, 7},
+	} {
+		if cost := commandCost(testCase.command, testCase.overrides); cost != testCase.expected {
+			t.Fatalf("ratelimits.commandCost(%q, %+v) returned %d, expected %d",
+				testCase.command, testCase.overrides, cost, testCase.expected)
+		}
+	}
+}
+
+func TestChannelCooldown(t *testing.T) {
+	cooldowns := []models.RatelimitChannelCooldown{{ChannelID: "1", Cooldown: time.Minute}}
+
+	if cooldown := channelCooldown("1", cooldowns); cooldown != time.Minute {
+		t.Fatalf("ratelimits.channelCooldown() returned %s, expected 1m0s", cooldown)
+	}
+	if cooldown := channelCooldown("2", cooldowns); cooldown != 0 {
+		t.Fatalf("ratelimits.channelCooldown() returned %s for a channel without a cooldown", cooldown)
+	}
+}
+
+func TestCooldownRemaining(t *testing.T) {
+	for _, testCase := range []struct {
+		ttl      time.Duration
+		err      error
+		expected time.Duration
+	}{
+		{30 * time.Second, nil, 30 * time.Second},
+		{time.Millisecond, nil, time.Millisecond},
+		// expired or missing key
+		{-2, nil, 0},
+		{-2 * time.Millisecond, nil, 0},
+		// key without expiry
+		{-1, nil, 0},
+		{30 * time.Second, errors.New("redis unavailable"), 0},
+	} {
+		if remaining := cooldownRemaining(testCase.ttl, testCase.err); remaining != testCase.expected {
+			t.Fatalf("ratelimits.cooldownRemaining(%s, %v) returned %s, expected %s",
+				testCase.ttl, testCase.err, remaining, testCase.expected)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package rest
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"sort"
 	"strconv"
@@ -20,6 +21,9 @@ import (
 	"github.com/Seklfreak/Robyul2/models"
 	"github.com/Seklfreak/Robyul2/modules/plugins"
 	"github.com/Seklfreak/Robyul2/modules/plugins/levels"
+	"github.com/Seklfreak/Robyul2/modules/plugins/youtube"
+	"github.com/Seklfreak/Robyul2/modules/router"
+	youtubeService "github.com/Seklfreak/Robyul2/services/youtube"
 	"github.com/bradfitz/slice"
 	"github.com/bwmarrin/discordgo"
 	restful "github.com/emicklei/go-restful"
@@ -159,6 +163,24 @@ func NewRestServices() []*restful.WebService {
 	service.Route(service.GET("").Filter(webkeyAuthenticate).To(GetAllBackgrounds))
 	services = append(services, service)
 
+	service = new(restful.WebService)
+	service.
+		Path("/commands").
+		Consumes(restful.MIME_JSON).
+		Produces(restful.MIME_JSON)
+	service.Route(service.GET("").Filter(webkeyAuthenticate).To(GetAllCommands))
+	services = append(services, service)
+
+	// called by the YouTube WebSub hub, requests are authenticated by the token of the subscription and the signature
+	service = new(restful.WebService)
+	service.
+		Path("/youtube/websub").
+		Consumes("*/*").
+		Produces("text/plain")
+	service.Route(service.GET("/{token}").To(YouTubeWebSubVerify))
+	service.Route(service.POST("/{token}").To(YouTubeWebSubNotify))
+	services = append(services, service)
+
 	service = new(restful.WebService)
 	service.Route(service.GET("/ping").Filter(webkeyAuthenticate).To(Ping))
 	services = append(services, service)
@@ -528,7 +550,8 @@ func GetRankings(request *restful.Request, response *restful.Response) {
 				}
 			}
 
-			expForLevel := levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP))
+			curve := levels.GetCurve(guildID)
+			expForLevel := curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP))
 
 			result.Ranks = append(result.Ranks, models.Rest_Ranking_Rank_Item{
 				User:                userItem,
@@ -538,8 +561,8 @@ func GetRankings(request *restful.Request, response *restful.Response) {
 				Level:               rankingItem.Level,
 				Ranking:             i,
 				NextLevelCurrentEXP: rankingItem.EXP - expForLevel,
-				NextLevelTotalEXP:   levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP)+1) - expForLevel,
-				Progress:            levels.GetProgressToNextLevelFromExp(rankingItem.EXP),
+				NextLevelTotalEXP:   curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP)+1) - expForLevel,
+				Progress:            curve.ProgressToNextLevelFromExp(rankingItem.EXP),
 			})
 		}
 		i += 1
@@ -597,7 +620,8 @@ func GetUserRanking(request *restful.Request, response *restful.Response) {
 		Bot:           user.Bot,
 	}
 
-	expForLevel := levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP))
+	curve := levels.GetCurve(guildID)
+	expForLevel := curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP))
 
 	result := models.Rest_Ranking_Rank_Item{
 		User:                userItem,
@@ -607,8 +631,8 @@ func GetUserRanking(request *restful.Request, response *restful.Response) {
 		IsMember:            isMember,
 		GuildID:             guildID,
 		NextLevelCurrentEXP: rankingItem.EXP - expForLevel,
-		NextLevelTotalEXP:   levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP)+1) - expForLevel,
-		Progress:            levels.GetProgressToNextLevelFromExp(rankingItem.EXP),
+		NextLevelTotalEXP:   curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP)+1) - expForLevel,
+		Progress:            curve.ProgressToNextLevelFromExp(rankingItem.EXP),
 	}
 
 	response.WriteEntity(result)
@@ -647,7 +671,8 @@ func GetAllUserRanking(request *restful.Request, response *restful.Response) {
 			continue
 		}
 
-		expForLevel := levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP))
+		curve := levels.GetCurve(guild.ID)
+		expForLevel := curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP))
 
 		result = append(result, models.Rest_Ranking_Rank_Item{
 			User:                userItem,
@@ -657,8 +682,8 @@ func GetAllUserRanking(request *restful.Request, response *restful.Response) {
 			Level:               rankingItem.Level,
 			Ranking:             rankingItem.Ranking,
 			NextLevelCurrentEXP: rankingItem.EXP - expForLevel,
-			NextLevelTotalEXP:   levels.GetExpForLevel(levels.GetLevelFromExp(rankingItem.EXP)+1) - expForLevel,
-			Progress:            levels.GetProgressToNextLevelFromExp(rankingItem.EXP),
+			NextLevelTotalEXP:   curve.ExpForLevel(curve.LevelFromExp(rankingItem.EXP)+1) - expForLevel,
+			Progress:            curve.ProgressToNextLevelFromExp(rankingItem.EXP),
 		})
 	}
 
@@ -1831,7 +1856,58 @@ func GetAllBackgrounds(request *restful.Request, response *restful.Response) {
 	return
 }
 
+func GetAllCommands(request *restful.Request, response *restful.Response) {
+	prefix := request.QueryParameter("prefix")
+	if prefix == "" {
+		prefix = models.Config{}.Default("").Prefix
+	}
+
+	response.WriteEntity(router.Export(prefix))
+	return
+}
+
 func Ping(_ *restful.Request, response *restful.Response) {
 	response.Write([]byte("pong"))
 	return
 }
+
+func YouTubeWebSubVerify(request *restful.Request, response *restful.Response) {
+	challenge, err := youtube.WebSubVerify(request.PathParameter("token"), request.Request.URL.Query())
+	if err != nil {
+		cache.GetLogger().WithField("module", "rest").WithError(err).Warn("refused youtube websub verification")
+		response.WriteErrorString(404, "404: Not Found")
+		return
+	}
+
+	response.Write([]byte(challenge))
+}
+
+// notifications of the hub contain one entry, anything bigger is refused
+const youtubeWebSubMaxBodySize = 256 * 1024
+
+func YouTubeWebSubNotify(request *restful.Request, response *restful.Response) {
+	// the endpoint is public, so unknown callbacks are refused before reading the body
+	err := youtube.WebSubCheckToken(request.PathParameter("token"))
+	if err != nil {
+		response.WriteErrorString(404, "404: Not Found")
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(response.ResponseWriter, request.Request.Body, youtubeWebSubMaxBodySize))
+	if err != nil {
+		response.WriteErrorString(400, "400: Bad Request")
+		return
+	}
+
+	err = youtube.WebSubNotify(request.PathParameter("token"), body, request.HeaderParameter("X-Hub-Signature"))
+	if err != nil {
+		cache.GetLogger().WithField("module", "rest").WithError(err).Warn("failed to handle youtube websub notification")
+		// notifications with an invalid signature are acknowledged and ignored, the hub retries all other errors
+		if err != youtubeService.ErrWebSubInvalidSignature {
+			response.WriteErrorString(500, "500: Internal Server Error")
+			return
+		}
+	}
+
+	response.WriteHeader(http.StatusNoContent)
+}
@@ -110,6 +110,14 @@ func (q *quota) DecEntryCount() {
 	}
 }
 
+// SetEntryCount replaces the count of entries, e.g. if some of them don't have to be polled anymore
+func (q *quota) SetEntryCount(count int64) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.entriesCount = count
+}
+
 func (q *quota) UpdateCheckingInterval() error {
 	q.Lock()
 	defer q.Unlock()
@@ -152,6 +152,10 @@ func (s *Service) DecQuotaEntryCount() {
 	s.quota.DecEntryCount()
 }
 
+func (s *Service) SetQuotaEntryCount(count int64) {
+	s.quota.SetEntryCount(count)
+}
+
 func (s *Service) UpdateCheckingInterval() error {
 	return s.quota.UpdateCheckingInterval()
 }
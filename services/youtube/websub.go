@@ -0,0 +1,193 @@
+package youtube
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Seklfreak/Robyul2/helpers"
+)
+
+const (
+	WebSubHubURL = "https://pubsubhubbub.appspot.com/subscribe"
+
+	websubTopicURL = "https://www.youtube.com/xml/feeds/videos.xml?channel_id=%s"
+)
+
+var (
+	ErrWebSubInvalidSignature = errors.New("invalid websub signature")
+	ErrWebSubInvalidTopic     = errors.New("invalid websub topic")
+)
+
+// WebSub subscribes to the uploads of YouTube channels at a WebSub hub,
+// the hub pushes new videos to the callback URL instead of us polling the API.
+type WebSub struct {
+	hubURL      string
+	callbackURL string
+	secret      string
+}
+
+// WebSubVideo is a video from a push notification of the hub.
+type WebSubVideo struct {
+	VideoID   string
+	ChannelID string
+	Title     string
+	Published time.Time
+}
+
+type websubNotification struct {
+	Entries []struct {
+		VideoID   string `xml:"http://www.youtube.com/xml/schemas/2015 videoId"`
+		ChannelID string `xml:"http://www.youtube.com/xml/schemas/2015 channelId"`
+		Title     string `xml:"title"`
+		Published string `xml:"published"`
+	} `xml:"entry"`
+}
+
+func NewWebSub(hubURL, callbackURL, secret string) *WebSub {
+	return &WebSub{
+		hubURL:      hubURL,
+		callbackURL: callbackURL,
+		secret:      secret,
+	}
+}
+
+// NewWebSubToken returns a random token for the callback URL of a subscription.
+func NewWebSubToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// Subscribe asks the hub to push the uploads of the channel for the lease duration,
+// the subscription is active once the hub verified it with a request to the callback URL.
+// The token is appended to the callback URL, so only the hub knows the callback of the subscription.
+func (w *WebSub) Subscribe(channelID, token string, lease time.Duration) error {
+	return w.request("subscribe", channelID, token, lease)
+}
+
+func (w *WebSub) Unsubscribe(channelID, token string) error {
+	return w.request("unsubscribe", channelID, token, 0)
+}
+
+func (w *WebSub) request(mode, channelID, token string, lease time.Duration) error {
+	form := url.Values{
+		"hub.callback": {strings.TrimSuffix(w.callbackURL, "/") + "/" + url.PathEscape(token)},
+		"hub.mode":     {mode},
+		"hub.topic":    {fmt.Sprintf(websubTopicURL, channelID)},
+		"hub.verify":   {"async"},
+	}
+	if mode == "subscribe" {
+		form.Set("hub.secret", w.secret)
+		form.Set("hub.lease_seconds", strconv.Itoa(int(lease.Seconds())))
+	}
+
+	request, err := http.NewRequest("POST", w.hubURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("User-Agent", helpers.DEFAULT_UA)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := helpers.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("hub returned %s for %s of %s: %s",
+			response.Status, mode, channelID, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// VerifySignature checks the X-Hub-Signature header of a push notification against the body.
+func (w *WebSub) VerifySignature(body []byte, signature string) error {
+	parts := strings.SplitN(signature, "=", 2)
+	if len(parts) != 2 {
+		return ErrWebSubInvalidSignature
+	}
+
+	var newHash func() hash.Hash
+	switch parts[0] {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return ErrWebSubInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return ErrWebSubInvalidSignature
+	}
+
+	mac := hmac.New(newHash, []byte(w.secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return ErrWebSubInvalidSignature
+	}
+	return nil
+}
+
+// WebSubTopicChannelID returns the YouTube channel ID of a topic URL the hub asks us to verify.
+func WebSubTopicChannelID(topic string) (channelID string, err error) {
+	topicURL, err := url.Parse(topic)
+	if err != nil {
+		return "", ErrWebSubInvalidTopic
+	}
+
+	channelID = topicURL.Query().Get("channel_id")
+	if topicURL.Host != "www.youtube.com" || topicURL.Path != "/xml/feeds/videos.xml" || channelID == "" {
+		return "", ErrWebSubInvalidTopic
+	}
+	return channelID, nil
+}
+
+// ParseWebSubNotification returns the videos of the Atom feed pushed by the hub,
+// deleted videos are sent as at:deleted-entry and skipped.
+func ParseWebSubNotification(body []byte) (videos []WebSubVideo, err error) {
+	var notification websubNotification
+	err = xml.Unmarshal(body, &notification)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range notification.Entries {
+		if entry.VideoID == "" || entry.ChannelID == "" {
+			continue
+		}
+
+		published, _ := time.Parse(time.RFC3339, entry.Published)
+		videos = append(videos, WebSubVideo{
+			VideoID:   entry.VideoID,
+			ChannelID: entry.ChannelID,
+			Title:     entry.Title,
+			Published: published,
+		})
+	}
+	return videos, nil
+}
@@ -0,0 +1,109 @@
+package youtube
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+const testWebSubNotification = `<?xml version='1.0' encoding='UTF-8'?>
+<feed xmlns:yt="http://www.youtube.com/xml/schemas/2015" xmlns="http://www.w3.org/2005/Atom">
+	<link rel="hub" href="https://pubsubhubbub.appspot.com"/>
+	<title>YouTube video feed</title>
+	<entry>
+		<id>yt:video:VIDEO_ID</id>
+		<yt:videoId>VIDEO_ID</yt:videoId>
+		<yt:channelId>CHANNEL_ID</yt:channelId>
+		<title>Video title</title>
+		<link rel="alternate" href="https://www.youtube.com/watch?v=VIDEO_ID"/>
+		<published>2018-01-02T15:04:05+00:00</published>
+		<updated>2018-01-02T15:05:05.123456789+00:00</updated>
+	</entry>
+</feed>`
+
+func TestWebSub(t *testing.T) {
+	var hubRequests []url.Values
+	hub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		hubRequests = append(hubRequests, r.PostForm)
+		if r.PostForm.Get("hub.topic") == "https://www.youtube.com/xml/feeds/videos.xml?channel_id=UNKNOWN" {
+			http.Error(w, "Invalid topic", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	websub := NewWebSub(hub.URL, "https://api.example.com/youtube/websub", "secret")
+
+	err := websub.Subscribe("CHANNEL_ID", "TOKEN", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Subscribe() returned error: %s", err.Error())
+	}
+	err = websub.Unsubscribe("CHANNEL_ID", "TOKEN")
+	if err != nil {
+		t.Fatalf("Unsubscribe() returned error: %s", err.Error())
+	}
+	if websub.Subscribe("UNKNOWN", "TOKEN", 24*time.Hour) == nil {
+		t.Errorf("Subscribe() didn't return the error of the hub")
+	}
+
+	if len(hubRequests) != 3 {
+		t.Fatalf("got %d hub requests, expected 3", len(hubRequests))
+	}
+	subscribe := hubRequests[0]
+	if subscribe.Get("hub.mode") != "subscribe" || subscribe.Get("hub.secret") != "secret" ||
+		subscribe.Get("hub.lease_seconds") != "86400" ||
+		subscribe.Get("hub.callback") != "https://api.example.com/youtube/websub/TOKEN" {
+		t.Errorf("got subscription request %v", subscribe)
+	}
+	if hubRequests[1].Get("hub.mode") != "unsubscribe" || hubRequests[1].Get("hub.secret") != "" {
+		t.Errorf("got unsubscription request %v", hubRequests[1])
+	}
+
+	channelID, err := WebSubTopicChannelID(subscribe.Get("hub.topic"))
+	if err != nil || channelID != "CHANNEL_ID" {
+		t.Errorf("WebSubTopicChannelID() = %q, %v, expected CHANNEL_ID", channelID, err)
+	}
+	_, err = WebSubTopicChannelID("https://example.com/xml/feeds/videos.xml?channel_id=CHANNEL_ID")
+	if err != ErrWebSubInvalidTopic {
+		t.Errorf("WebSubTopicChannelID() accepted a foreign topic")
+	}
+
+	// the hub signs notifications with the secret of the subscription
+	mac := hmac.New(sha1.New, []byte(subscribe.Get("hub.secret")))
+	mac.Write([]byte(testWebSubNotification))
+	signature := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	if err = websub.VerifySignature([]byte(testWebSubNotification), signature); err != nil {
+		t.Errorf("VerifySignature() rejected a valid signature: %s", err.Error())
+	}
+	for _, invalidSignature := range []string{"", "sha1=", "md5=" + signature[5:], signature[:len(signature)-2] + "00"} {
+		if websub.VerifySignature([]byte(testWebSubNotification), invalidSignature) != ErrWebSubInvalidSignature {
+			t.Errorf("VerifySignature() accepted %q", invalidSignature)
+		}
+	}
+
+	videos, err := ParseWebSubNotification([]byte(testWebSubNotification))
+	if err != nil {
+		t.Fatalf("ParseWebSubNotification() returned error: %s", err.Error())
+	}
+	if len(videos) != 1 {
+		t.Fatalf("got %d videos, expected 1", len(videos))
+	}
+	expected := WebSubVideo{
+		VideoID:   "VIDEO_ID",
+		ChannelID: "CHANNEL_ID",
+		Title:     "Video title",
+		Published: time.Date(2018, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	if videos[0].VideoID != expected.VideoID || videos[0].ChannelID != expected.ChannelID ||
+		videos[0].Title != expected.Title || !videos[0].Published.Equal(expected.Published) {
+		t.Errorf("got video %+v, expected %+v", videos[0], expected)
+	}
+}